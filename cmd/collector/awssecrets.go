@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/awssecrets"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/redact"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/pflag"
+)
+
+// awsSecretsTimeout bounds reading the referenced AWS secrets and parameters at startup
+const awsSecretsTimeout = 30 * time.Second
+
+// applyAwsSecrets replaces secret flags set to 'awssm://<name>[#<key>]' or 'ssm://<path>' with the referenced
+// values of AWS Secrets Manager or SSM Parameter Store. Resolved values are masked in the log output.
+func applyAwsSecrets(flags *pflag.FlagSet) error {
+	var references []*pflag.Flag
+	flags.VisitAll(func(f *pflag.Flag) {
+		if _, ok := f.Annotations[secretAnnotation]; ok && awssecrets.IsReference(f.Value.String()) {
+			references = append(references, f)
+		}
+	})
+	if len(references) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), awsSecretsTimeout)
+	defer cancel()
+
+	resolver := awssecrets.NewResolver()
+	for _, f := range references {
+		reference := f.Value.String()
+		value, err := resolver.Resolve(ctx, reference)
+		if err != nil {
+			return fmt.Errorf("could not resolve %s: %w", f.Name, err)
+		}
+		redact.Register(value)
+
+		if err := flags.Set(f.Name, value); err != nil {
+			return fmt.Errorf("could not assign %s: %w", f.Name, err)
+		}
+		log.Debug().Str("flag", f.Name).Str("reference", reference).Msg("Read secret from AWS")
+	}
+	return nil
+}
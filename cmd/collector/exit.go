@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+
+	imagecollector "github.com/SDA-SE/image-metadata-collector/pkg/collector"
+)
+
+// Exit codes allow wrapper scripts and CronJob alerting to distinguish failure classes, a termination signal exits
+// with 128 + the signal number
+const (
+	ExitCodeFailure = 1
+	ExitCodeConfig  = 2
+	ExitCodeKube    = 3
+	ExitCodeStorage = 4
+)
+
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode classifies err, nil stays nil
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the exit code of the outermost classified error, unclassified errors are general failures
+func exitCode(err error) int {
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return ExitCodeFailure
+}
+
+// classify maps the error classes of the collector library to exit codes
+func classify(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, imagecollector.ErrConfig):
+		return withExitCode(ExitCodeConfig, err)
+	case errors.Is(err, imagecollector.ErrKube):
+		return withExitCode(ExitCodeKube, err)
+	case errors.Is(err, imagecollector.ErrStorage):
+		return withExitCode(ExitCodeStorage, err)
+	default:
+		return err
+	}
+}
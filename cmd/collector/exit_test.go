@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	err := errors.New("failed")
+
+	assert.Equal(t, ExitCodeFailure, exitCode(err))
+	assert.Equal(t, ExitCodeStorage, exitCode(withExitCode(ExitCodeStorage, err)))
+	assert.Equal(t, ExitCodeKube, exitCode(fmt.Errorf("wrapped: %w", withExitCode(ExitCodeKube, err))))
+	assert.Equal(t, 143, exitCode(withExitCode(143, withExitCode(ExitCodeStorage, err))))
+	assert.Nil(t, withExitCode(ExitCodeConfig, nil))
+	assert.ErrorIs(t, withExitCode(ExitCodeConfig, err), err)
+}
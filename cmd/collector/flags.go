@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// deprecatedFlags maps renamed flags, e.g. names which break the kebab-case convention, to their replacement. The old
+// names keep working as hidden aliases, using them prints a deprecation warning.
+var deprecatedFlags = map[string]string{
+	"ScanLifetimeMaxDays":      "scan-lifetime-max-days",
+	"negated_namespace_filter": "negated-namespace-filter",
+}
+
+// normalizeFlagName accepts underscores instead of dashes, e.g. --kube_config. Deprecated aliases are kept as they
+// are, otherwise they would collide with their replacement.
+func normalizeFlagName(f *pflag.FlagSet, name string) pflag.NormalizedName {
+	if _, ok := deprecatedFlags[name]; ok {
+		return pflag.NormalizedName(name)
+	}
+	return pflag.NormalizedName(strings.ReplaceAll(name, "_", "-"))
+}
+
+// addDeprecatedAliases registers the deprecated names as hidden flags sharing the value of their replacement, so
+// flags, environment variables and config file keys with the old name still work
+func addDeprecatedAliases(c *cobra.Command) {
+	flags := c.PersistentFlags()
+	for alias, name := range deprecatedFlags {
+		flag := flags.Lookup(name)
+		if flag == nil {
+			panic(fmt.Sprintf("replacement %s of deprecated flag %s does not exist", name, alias))
+		}
+
+		flags.AddFlag(&pflag.Flag{
+			Name:       alias,
+			Usage:      flag.Usage,
+			Value:      flag.Value,
+			DefValue:   flag.DefValue,
+			Hidden:     true,
+			Deprecated: fmt.Sprintf("use --%s instead", name),
+		})
+	}
+}
+
+// negatedBool is a bool flag setting the negation of its value, so a flag defaulting to true can set a config field
+// whose zero value is the default, e.g. --json-indent and OutputConfig.JsonCompact
+type negatedBool struct {
+	value *bool
+}
+
+func (b negatedBool) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*b.value = !v
+	return nil
+}
+
+func (b negatedBool) String() string {
+	if b.value == nil {
+		return "true"
+	}
+	return strconv.FormatBool(!*b.value)
+}
+
+func (b negatedBool) Type() string {
+	return "bool"
+}
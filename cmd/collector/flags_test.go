@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecatedFlags(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		args                   []string
+		scanLifetimeMaxDays    int64
+		namespaceFilterNegated string
+	}{
+		{name: "Default", args: []string{}, scanLifetimeMaxDays: 120},
+		{name: "NewNames", args: []string{"--scan-lifetime-max-days", "30", "--negated-namespace-filter", "-pr-"}, scanLifetimeMaxDays: 30, namespaceFilterNegated: "-pr-"},
+		{name: "DeprecatedNames", args: []string{"--ScanLifetimeMaxDays", "30", "--negated_namespace_filter", "-pr-"}, scanLifetimeMaxDays: 30, namespaceFilterNegated: "-pr-"},
+		{name: "Underscores", args: []string{"--scan_lifetime_max_days", "30"}, scanLifetimeMaxDays: 30},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cmd := newCommand(cfg)
+			cmd.Flags().SetOutput(io.Discard)
+			assert.NoError(t, cmd.ParseFlags(tc.args))
+			assert.Equal(t, tc.scanLifetimeMaxDays, cfg.CollectorImage.ScanLifetimeMaxDays)
+			assert.Equal(t, tc.namespaceFilterNegated, cfg.CollectorImage.NamespaceFilterNegated)
+		})
+	}
+
+	for alias, name := range deprecatedFlags {
+		flag := newCommand(&config.Config{}).PersistentFlags().Lookup(alias)
+		assert.NotNil(t, flag, "Expected alias %s", alias)
+		assert.True(t, flag.Hidden)
+		assert.Contains(t, flag.Deprecated, name)
+	}
+}
+
+func TestDeprecatedFlagsFromConfig(t *testing.T) {
+	v := viper.New()
+	v.Set("ScanLifetimeMaxDays", 60)
+
+	cfg := &config.Config{}
+	cmd := newCommand(cfg)
+	assert.NoError(t, cmd.ParseFlags([]string{}))
+	assert.NoError(t, bindFlags(cmd.PersistentFlags(), v))
+	assert.Equal(t, int64(60), cfg.CollectorImage.ScanLifetimeMaxDays)
+
+	cfg = &config.Config{}
+	cmd = newCommand(cfg)
+	assert.NoError(t, cmd.ParseFlags([]string{"--scan-lifetime-max-days", "30"}))
+	assert.NoError(t, bindFlags(cmd.PersistentFlags(), v))
+	assert.Equal(t, int64(30), cfg.CollectorImage.ScanLifetimeMaxDays, "Expected the flag to win over the deprecated key")
+}
+
+func TestJsonIndentFlag(t *testing.T) {
+	for args, compact := range map[string]bool{"": false, "--json-indent": false, "--json-indent=true": false, "--json-indent=false": true} {
+		cfg := &config.Config{}
+		cmd := newCommand(cfg)
+		assert.NoError(t, cmd.ParseFlags(strings.Fields(args)))
+		assert.Equal(t, compact, cfg.OutputConfig.JsonCompact, args)
+	}
+	assert.Equal(t, "true", newCommand(&config.Config{}).PersistentFlags().Lookup("json-indent").DefValue)
+}
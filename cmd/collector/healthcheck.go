@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/config"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/heartbeat"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// newHealthcheckCommand checks the heartbeat file, it serves as exec liveness probe as the image contains no shell
+func newHealthcheckCommand(cfg *config.Config) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "healthcheck",
+		Short: "Check that the heartbeat file was updated recently, e.g. as liveness probe",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			if cfg.HeartbeatConfig.HeartbeatFile == "" {
+				return withExitCode(ExitCodeConfig, errors.New("healthcheck requires --heartbeat-file"))
+			}
+			return heartbeat.Check(cfg.HeartbeatConfig.HeartbeatFile, cfg.HeartbeatConfig.HeartbeatMaxAge, time.Now())
+		},
+	}
+	c.Flags().DurationVar(&cfg.HeartbeatConfig.HeartbeatMaxAge, "heartbeat-max-age", time.Hour, "Maximum age of the heartbeat, has to be longer than the interval plus the duration of a run")
+	return c
+}
+
+// heartbeater writes the heartbeat file after successful runs, standby replicas of a leader election write it
+// periodically as they never run
+type heartbeater struct {
+	fileName string
+	leading  atomic.Bool
+}
+
+func (h *heartbeater) beat() {
+	if h.fileName == "" {
+		return
+	}
+	if err := heartbeat.Write(h.fileName, time.Now()); err != nil {
+		log.Warn().Err(err).Str("fileName", h.fileName).Msg("Could not write heartbeat")
+	}
+}
+
+// setLeader records the leadership, it wraps the callback of the leader election
+func (h *heartbeater) setLeader(onLeading func(bool)) func(bool) {
+	return func(leading bool) {
+		h.leading.Store(leading)
+		onLeading(leading)
+	}
+}
+
+// beatWhileStandby writes the heartbeat every interval while this replica does not lead, until stopped is closed
+func (h *heartbeater) beatWhileStandby(interval time.Duration, stopped <-chan struct{}) {
+	if h.fileName == "" {
+		return
+	}
+	h.beat()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopped:
+			return
+		case <-ticker.C:
+			if !h.leading.Load() {
+				h.beat()
+			}
+		}
+	}
+}
+
+// beatWhileRunning writes the heartbeat every interval until stopped is closed, e.g. while the operator waits for
+// changes
+func (h *heartbeater) beatWhileRunning(interval time.Duration, stopped <-chan struct{}) {
+	if h.fileName == "" {
+		return
+	}
+	h.beat()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopped:
+			return
+		case <-ticker.C:
+			h.beat()
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/redact"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/pflag"
+)
+
+// secretRefSuffix names the flags referencing a Kubernetes Secret for a secret flag, e.g. --api-key-secret-ref
+const secretRefSuffix = "-secret-ref"
+
+// secretRefAnnotation holds the secret flag a reference flag is assigned to
+const secretRefAnnotation = "collector_secret_ref"
+
+// kubeSecretsTimeout bounds reading the referenced Kubernetes Secrets at startup
+const kubeSecretsTimeout = 30 * time.Second
+
+// addSecretRefs adds a <flag>-secret-ref flag for each secret flag
+func addSecretRefs(flags *pflag.FlagSet, names ...string) {
+	for _, name := range names {
+		ref := name + secretRefSuffix
+		flags.String(ref, "", fmt.Sprintf("Read --%s from a Kubernetes Secret in the format <namespace>/<name>/<key>", name))
+		if err := flags.SetAnnotation(ref, secretRefAnnotation, []string{name}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// parseSecretRef parses a reference in the format '<namespace>/<name>/<key>'
+func parseSecretRef(ref string) (namespace, name, key string, err error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("secret reference %s has to be in the format <namespace>/<name>/<key>", ref)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// applyKubeSecrets reads the Kubernetes Secrets referenced by the <flag>-secret-ref flags into their secret flags,
+// so credentials don't have to be mounted into the pod. Secret values are masked in the log output.
+func applyKubeSecrets(flags *pflag.FlagSet, cfg *kubeclient.KubeConfig) error {
+	var refs []*pflag.Flag
+	flags.VisitAll(func(f *pflag.Flag) {
+		if _, ok := f.Annotations[secretRefAnnotation]; ok && f.Value.String() != "" {
+			refs = append(refs, f)
+		}
+	})
+	if len(refs) == 0 {
+		return nil
+	}
+
+	client, err := kubeclient.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kubeSecretsTimeout)
+	defer cancel()
+
+	for _, f := range refs {
+		target := f.Annotations[secretRefAnnotation][0]
+		namespace, name, key, err := parseSecretRef(f.Value.String())
+		if err != nil {
+			return err
+		}
+
+		value, err := client.GetSecretValue(ctx, namespace, name, key)
+		if err != nil {
+			return fmt.Errorf("could not resolve %s: %w", f.Name, err)
+		}
+		redact.Register(value)
+
+		if err := flags.Set(target, value); err != nil {
+			return fmt.Errorf("could not assign %s: %w", target, err)
+		}
+		log.Debug().Str("flag", target).Str("namespace", namespace).Str("secret", name).Msg("Read secret from Kubernetes")
+	}
+	return nil
+}
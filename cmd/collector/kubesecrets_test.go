@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	namespace, name, key, err := parseSecretRef("collector/api/api-key")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"collector", "api", "api-key"}, []string{namespace, name, key})
+
+	for _, ref := range []string{"collector/api", "collector/api/key/extra", "/api/key", "collector//key"} {
+		_, _, _, err = parseSecretRef(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestSecretRefFlags(t *testing.T) {
+	f := newCommand(&config.Config{}).PersistentFlags().Lookup("api-key-secret-ref")
+	if assert.NotNil(t, f) {
+		assert.Equal(t, []string{"api-key"}, f.Annotations[secretRefAnnotation])
+	}
+}
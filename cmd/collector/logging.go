@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/redact"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// configureLogging sets the global log level and the output format, json for cluster deployments and a colored
+// console format for local runs. debug is the shorthand of the debug level. Secrets are masked in both formats.
+func configureLogging(level, format string, debug bool) error {
+	logLevel, err := zerolog.ParseLevel(level)
+	if err != nil || level == "" {
+		return fmt.Errorf("log level %s is not supported [trace, debug, info, warn, error]", level)
+	}
+	if debug && logLevel > zerolog.DebugLevel {
+		logLevel = zerolog.DebugLevel
+	}
+
+	switch format {
+	case "json":
+		log.Logger = zerolog.New(redact.NewWriter(os.Stderr)).With().Timestamp().Caller().Logger()
+	case "console":
+		log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: redact.NewWriter(os.Stderr), TimeFormat: time.RFC3339}).With().Timestamp().Caller().Logger()
+	default:
+		return fmt.Errorf("log format %s is not supported [json, console]", format)
+	}
+
+	zerolog.SetGlobalLevel(logLevel)
+	return nil
+}
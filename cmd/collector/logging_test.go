@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigureLogging(t *testing.T) {
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+
+	testCases := []struct {
+		name          string
+		level         string
+		format        string
+		debug         bool
+		expectedLevel zerolog.Level
+		expectError   bool
+	}{
+		{name: "Default", level: "info", format: "json", expectedLevel: zerolog.InfoLevel},
+		{name: "Console", level: "warn", format: "console", expectedLevel: zerolog.WarnLevel},
+		{name: "DebugShorthand", level: "info", format: "json", debug: true, expectedLevel: zerolog.DebugLevel},
+		{name: "TraceWithDebug", level: "trace", format: "json", debug: true, expectedLevel: zerolog.TraceLevel},
+		{name: "InvalidLevel", level: "verbose", format: "json", expectError: true},
+		{name: "EmptyLevel", level: "", format: "json", expectError: true},
+		{name: "InvalidFormat", level: "info", format: "text", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := configureLogging(tc.level, tc.format, tc.debug)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedLevel, zerolog.GlobalLevel())
+		})
+	}
+}
@@ -1,14 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/SDA-SE/image-metadata-collector/internal/collector"
 	"github.com/SDA-SE/image-metadata-collector/internal/config"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/admission"
 	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/leader"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/metrics"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/profile"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/server"
 	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/crd"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/tlsconfig"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/vault"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+	imagecollector "github.com/SDA-SE/image-metadata-collector/pkg/collector"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -29,58 +45,262 @@ const LongDescription = `Image Metadata Collector is a tool that will scan
 func main() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	log.Logger = log.With().Caller().Logger()
+	// Until the flags are parsed and the configured level is set
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 
-	err := newCommand().Execute()
+	err := newCommand(&config.Config{}).Execute()
+	stopProfiling()
 	if err != nil {
-		log.Fatal().Err(err).Msg("Error running collector")
+		code := exitCode(err)
+		if code > 128 {
+			log.Warn().Err(err).Int("exitCode", code).Msg("Collector stopped by signal")
+		} else {
+			log.Error().Err(err).Int("exitCode", code).Msg("Error running collector")
+		}
+		os.Exit(code)
 	}
 }
 
-func newCommand() *cobra.Command {
-	cfg := &config.Config{}
+// stopProfiling writes the profiles requested by --cpu-profile and --heap-profile, it is replaced once profiling started
+var stopProfiling = func() {}
+
+// resolveSecretsAnnotation marks the commands which access the cluster or the storage, only they resolve the secret
+// references of AWS, kubernetes and Vault, so e.g. version and healthcheck work without credentials
+const resolveSecretsAnnotation = "collector_resolve_secrets"
 
+func newCommand(cfg *config.Config) *cobra.Command {
 	c := &cobra.Command{
-		Use:   AppName,
-		Short: ShortDescription,
-		Long:  LongDescription,
+		Use:         AppName,
+		Short:       ShortDescription,
+		Long:        LongDescription,
+		Annotations: map[string]string{resolveSecretsAnnotation: ""},
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			return initializeConfig(cmd)
+			// Invalid flags are reported with usage before, configuration errors don't need it
+			cmd.SilenceUsage = true
+			if err := initializeConfig(cmd); err != nil {
+				return withExitCode(ExitCodeConfig, err)
+			}
+
+			if err := tlsconfig.Apply(&cfg.TLSConfig); err != nil {
+				return withExitCode(ExitCodeConfig, err)
+			}
+
+			if _, ok := cmd.Annotations[resolveSecretsAnnotation]; ok {
+				if err := applyAwsSecrets(cmd.Flags()); err != nil {
+					return withExitCode(ExitCodeConfig, err)
+				}
+
+				if err := applyKubeSecrets(cmd.Flags(), &cfg.KubeConfig); err != nil {
+					return withExitCode(ExitCodeConfig, err)
+				}
+
+				reader, err := applyVaultSecrets(cmd.Flags(), &cfg.VaultConfig)
+				if err != nil {
+					return withExitCode(ExitCodeConfig, err)
+				}
+				vaultSecrets = reader
+			}
+
+			stop, err := profile.Start(&cfg.ProfileConfig)
+			if err != nil {
+				return withExitCode(ExitCodeConfig, err)
+			}
+			stopProfiling = stop
+			return nil
 		},
-		Run: func(cmd *cobra.Command, args []string) {
-			run(cfg)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Usage is only helpful for invalid flags, which are reported before RunE
+			cmd.SilenceUsage = true
+			return run(cfg)
 		},
+		// Errors are logged with their exit code in main
+		SilenceErrors: true,
 	}
+	c.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(ExitCodeConfig, err)
+	})
+
+	c.AddCommand(newCollectCommand(cfg))
+	c.AddCommand(newConfigCommand())
+	c.AddCommand(newHealthcheckCommand(cfg))
+	c.AddCommand(newPublishCommand(cfg))
+	c.AddCommand(newSchemaCommand())
+	c.AddCommand(newCrdCommand())
+	c.AddCommand(newValidateCommand(cfg))
+	c.AddCommand(newVersionCommand())
+	c.AddCommand(newWebhookCommand(cfg))
 
 	// Run Configuration
-	c.PersistentFlags().BoolVar(&cfg.Debug, "debug", false, "Set logging level to debug, default logging level is info")
-	c.Flags().StringSliceVarP(&cfg.RunConfig.ImageFilter, "image-filter", "s", []string{}, "Images to set the skip flag to true. Images as regex comma seperated without spaces. e.g. 'mock-service,mongo,openpolicyagent/opa,/istio/")
+	c.PersistentFlags().StringVar(&cfg.ConfigFile, "config", "", "Path to a YAML, TOML or JSON config file with flag names as keys, flags and environment variables take precedence")
+	c.Flags().DurationVar(&cfg.Interval, "interval", 0, "Run continuously and collect images every interval e.g. '15m', by default the collector runs once and exits")
+	c.Flags().BoolVar(&cfg.Operator, "operator", false, "Watch the pods and update the report of a namespace as soon as its pods change instead of collecting the whole cluster, requires --split-by namespace")
+	c.Flags().DurationVar(&cfg.OperatorDebounce, "operator-debounce", 10*time.Second, "Collect the changes of the pods for this duration before updating the reports, so a rollout updates a report once")
+	c.Flags().Float64Var(&cfg.IntervalJitter, "interval-jitter", 0.1, "Random delay added to every interval as fraction of the interval, e.g. 0.1 delays a 15m interval by up to 90s")
+	c.PersistentFlags().DurationVar(&cfg.Timeout, "timeout", 0, "Maximum duration of a run including collection and storage e.g. '10m', 0 disables the timeout")
+	c.PersistentFlags().DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", 25*time.Second, "Time an in-flight run gets to finish its storage write after SIGTERM/SIGINT before it is aborted")
+	c.PersistentFlags().IntVar(&cfg.MaxRunRetries, "max-run-retries", 0, "Retry a run which failed because of the cluster, the storage or the timeout up to this many times, 0 disables retries")
+	c.PersistentFlags().DurationVar(&cfg.RunRetryBackoff, "run-retry-backoff", 30*time.Second, "Wait before the first retry of a failed run, doubled with every retry up to 5m")
+	c.Flags().BoolVar(&cfg.LeaderElectionConfig.LeaderElect, "leader-elect", false, "Run with several replicas, only the replica holding the lease collects images, requires --interval")
+	c.Flags().StringVar(&cfg.LeaderElectionConfig.LeaderElectLeaseName, "leader-elect-lease-name", "image-metadata-collector", "Name of the Lease used for leader election")
+	c.Flags().StringVar(&cfg.LeaderElectionConfig.LeaderElectNamespace, "leader-elect-namespace", "", "Namespace of the Lease, defaults to the namespace of the pod")
+	c.Flags().DurationVar(&cfg.LeaderElectionConfig.LeaderElectLeaseDuration, "leader-elect-lease-duration", 15*time.Second, "Time standby replicas wait before taking over a lease which is no longer renewed")
+	c.Flags().DurationVar(&cfg.LeaderElectionConfig.LeaderElectRenewDeadline, "leader-elect-renew-deadline", 10*time.Second, "Time the leader retries renewing the lease before it gives up the leadership")
+	c.Flags().DurationVar(&cfg.LeaderElectionConfig.LeaderElectRetryPeriod, "leader-elect-retry-period", 2*time.Second, "Time between attempts to acquire or renew the lease")
+	c.Flags().StringVar(&cfg.PushConfig.PushgatewayUrl, "pushgateway-url", "", "Push the run metrics to this Prometheus Pushgateway after every run, e.g. 'http://pushgateway:9091', basic auth credentials can be given in the URL")
+	c.Flags().StringVar(&cfg.PushConfig.PushgatewayJob, "pushgateway-job", "image-metadata-collector", "Job label of the pushed metrics")
+	c.Flags().StringSliceVar(&cfg.PushConfig.PushgatewayGrouping, "pushgateway-grouping", []string{}, "Additional grouping labels of the pushed metrics, e.g. 'environment=prod', comma seperated")
+	c.Flags().DurationVar(&cfg.PushConfig.PushgatewayTimeout, "pushgateway-timeout", 10*time.Second, "Timeout for pushing the metrics")
+	c.Flags().StringVar(&cfg.ServerConfig.ListenAddress, "listen-address", "", "Address of the HTTP server exposing /healthz, /readyz and /metrics e.g. ':8080', disabled by default")
+	c.Flags().BoolVar(&cfg.ServerConfig.Pprof, "pprof", false, "Expose the Go runtime profiles under /debug/pprof/ on the HTTP server, requires --listen-address")
+	c.PersistentFlags().StringVar(&cfg.HeartbeatConfig.HeartbeatFile, "heartbeat-file", "", "Write the time of the last successful run to this file, the healthcheck command checks its age")
+	c.PersistentFlags().BoolVar(&cfg.StatusConfig.StatusEvents, "status-events", false, "Create a Kubernetes Event summarizing every run, a Warning event if the run failed")
+	c.PersistentFlags().StringVar(&cfg.StatusConfig.StatusConfigMap, "status-configmap", "", "Update this ConfigMap with the outcome, counts and last successful time of every run, disabled by default")
+	c.PersistentFlags().StringVar(&cfg.StatusConfig.StatusNamespace, "status-namespace", "", "Namespace of the status events and ConfigMap, defaults to the namespace of the pod")
+	c.PersistentFlags().StringVar(&cfg.NotifyConfig.SlackWebhookUrl, "slack-webhook-url", "", "Slack incoming webhook receiving a summary after every run")
+	c.PersistentFlags().StringVar(&cfg.VaultConfig.VaultAddress, "vault-address", "", "Vault server to read the secrets given by --vault-secret from at startup, e.g. https://vault.example.com:8200")
+	c.PersistentFlags().StringVar(&cfg.VaultConfig.VaultToken, "vault-token", "", "Vault token, by default the collector logs in with its service account token")
+	c.PersistentFlags().StringVar(&cfg.VaultConfig.VaultRole, "vault-role", "", "Role of the Vault Kubernetes auth method")
+	c.PersistentFlags().StringVar(&cfg.VaultConfig.VaultAuthMount, "vault-auth-mount", "kubernetes", "Mount path of the Vault Kubernetes auth method")
+	c.PersistentFlags().StringVar(&cfg.VaultConfig.VaultJwtFile, "vault-jwt-file", vault.DefaultJwtFile, "Service account token used to log in at Vault")
+	c.PersistentFlags().StringSliceVar(&cfg.VaultConfig.VaultSecrets, "vault-secret", []string{}, "Assign a key of a KV v2 secret to a flag or environment variable, e.g. 'api-key=secret/collector#api-key,env:AWS_SECRET_ACCESS_KEY=secret/collector#s3-secret', comma seperated")
+
+	c.PersistentFlags().StringVar(&cfg.TLSConfig.TlsMinVersion, "tls-min-version", "1.2", "Minimum TLS version of all outbound connections, 1.2 or 1.3")
+	c.PersistentFlags().StringSliceVar(&cfg.TLSConfig.TlsCipherSuites, "tls-cipher-suites", []string{}, "Allowed TLS 1.2 cipher suites of all outbound connections, e.g. 'TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256', comma seperated")
+	c.PersistentFlags().BoolVar(&cfg.TLSConfig.TlsFips, "tls-fips", false, "Restrict the TLS cipher suites and curves of all outbound connections to FIPS 140 approved ones")
+	c.PersistentFlags().StringVar(&cfg.TLSConfig.CaBundle, "ca-bundle", "", "PEM file of CAs trusted by all outbound connections in addition to the system CAs, e.g. of a private PKI")
+	c.PersistentFlags().StringVar(&cfg.NotifyConfig.MSTeamsWebhookUrl, "notify-msteams-url", "", "MS Teams incoming webhook or Workflows webhook receiving a summary as Adaptive Card after every run")
+	c.PersistentFlags().StringVar(&cfg.NotifyConfig.NotifyStateFile, "notify-state-file", "", "Keep the images of the last run in this file to count new images across CronJob runs")
+	c.PersistentFlags().BoolVar(&cfg.NotifyConfig.NotifyStateFromStorage, "notify-state-from-storage", false, "Count new images against the previous report read from the storage instead of a state file, requires the s3, git or fs storage and the json or ndjson output")
+	c.PersistentFlags().StringVar(&cfg.ProfileConfig.CpuProfile, "cpu-profile", "", "Write a CPU profile of the whole process to this file when it exits")
+	c.PersistentFlags().StringVar(&cfg.ProfileConfig.HeapProfile, "heap-profile", "", "Write a heap profile to this file when the process exits")
+	c.PersistentFlags().BoolVar(&cfg.StrictConfig, "strict-config", false, "Fail on unknown config file keys, unknown COLLECTOR_* environment variables and deprecated flags instead of logging a warning")
+	c.PersistentFlags().BoolVar(&cfg.Debug, "debug", false, "Set logging level to debug, same as --log-level debug")
+	c.PersistentFlags().StringVar(&cfg.LogLevel, "log-level", "info", "Logging level [trace, debug, info, warn, error]")
+	c.PersistentFlags().StringVar(&cfg.LogFormat, "log-format", "json", "Logging format, json for cluster deployments or console for local runs [json, console]")
+	c.PersistentFlags().StringSliceVarP(&cfg.RunConfig.ImageFilter, "image-filter", "s", []string{}, "Images to set the skip flag to true. Images as regex comma seperated without spaces. e.g. 'mock-service,mongo,openpolicyagent/opa,/istio/")
+	c.PersistentFlags().StringVar(&cfg.RunConfig.FilterRulesFile, "filter-rules-file", "", "YAML file with ordered allow and deny rules matching namespace, image, labels and container type, the first matching rule decides if the image is skipped before the image filters apply")
+	c.PersistentFlags().StringSliceVar(&cfg.RunConfig.InternalRegistries, "internal-registries", []string{}, "Registry hostnames to set is_internal_registry for, images of other registries are pulled from public registries, e.g. 'registry.example.com,*.azurecr.io', comma seperated")
+	c.PersistentFlags().BoolVar(&cfg.RunConfig.NormalizeImages, "normalize-images", false, "Expand short image names to their full reference, map the Docker Hub hosts to docker.io and strip the default registry port, e.g. 'nginx' to 'docker.io/library/nginx:latest', before the image filters and filter rules match them")
+	c.PersistentFlags().BoolVar(&cfg.RunConfig.SkipWindows, "skip-windows", false, "Set the skip flag of images running on Windows nodes, e.g. for scanners which only support Linux images, even if a filter rule allows them")
+	c.PersistentFlags().BoolVar(&cfg.RunConfig.SkipSuspended, "skip-suspended", false, "Set the skip flag of images of suspended CronJobs, requires --suspended-cronjobs")
+	c.PersistentFlags().StringVar(&cfg.RunConfig.MetadataPrecedence, "metadata-precedence", collector.MetadataPrecedenceMerged, "Read the team, contact and scan configuration from the merged labels and annotations of pods and namespaces ('merged') or only from the namespaces ('namespace-only')")
 	// Kubernetes Config
 	c.PersistentFlags().StringVar(&cfg.KubeConfig.ConfigFile, "kube-config", "", "absolute path to the kubeconfig file")
 	c.PersistentFlags().StringVar(&cfg.KubeConfig.Context, "kube-context", "", "The context to use to talk to the Kubernetes apiserver. If unset defaults to whatever your current-context is (kubectl config current-context)")
 	c.PersistentFlags().StringVar(&cfg.KubeConfig.MasterUrl, "master-url", "", "URL of the API server")
+	c.PersistentFlags().DurationVar(&cfg.KubeConfig.ProgressInterval, "progress-interval", 30*time.Second, "Log the number of processed namespaces and images with an ETA at this interval while listing the pods, 0 disables it")
+	c.PersistentFlags().BoolVar(&cfg.KubeConfig.NodeOperatingSystem, "node-operating-system", false, "Read the operating system of pods without OS field or kubernetes.io/os nodeSelector from the label of their node, requires to list nodes (deployment/nodes)")
+	c.PersistentFlags().DurationVar(&cfg.KubeConfig.TerminatedLookback, "terminated-pods-lookback", 0, "Report the images of succeeded and failed pods, e.g. of nightly CronJobs, only if they were created within this duration e.g. '24h', marked as terminated. 0 reports all pods")
+	c.PersistentFlags().BoolVar(&cfg.KubeConfig.SuspendedCronJobs, "suspended-cronjobs", false, "Mark the images of the Jobs of suspended CronJobs as suspended, requires to list CronJobs and Jobs (deployment/cronjobs)")
+	c.PersistentFlags().BoolVar(&cfg.KubeConfig.ServiceExposure, "service-exposure", false, "Add the exposure of pods selected by a LoadBalancer Service or a Service behind an Ingress, requires to list Services and Ingresses (deployment/services)")
+	c.PersistentFlags().StringVar(&cfg.AdmissionConfig.AdmissionConfigMap, "admission-configmap", "", "ConfigMap of the images recorded by the webhook command, runs add the recorded images of pods which are not running anymore, disabled by default")
+	c.PersistentFlags().StringVar(&cfg.AdmissionConfig.AdmissionNamespace, "admission-namespace", "", "Namespace of the ConfigMap of the recorded images, defaults to the namespace of the pod")
+	c.PersistentFlags().DurationVar(&cfg.AdmissionConfig.AdmissionRetention, "admission-retention", admission.DefaultRetention, "Report recorded images for this duration after their last pod was created, should exceed the interval between runs")
+	c.PersistentFlags().StringVar(&cfg.KubeConfig.InputFile, "input-file", "", "Read the images from a JSON dump ('-' for stdin) instead of the cluster, e.g. to reproduce the annotation handling of a run")
 
 	// Output/Storage Config
-	c.PersistentFlags().StringVar(&cfg.StorageConfig.StorageFlag, "storage", "api", "Write output to storage location [api, s3, git, local fs]")
-	c.PersistentFlags().StringVar(&cfg.StorageConfig.FileName, "filename", "", "Output filename, defaults to '<environment>-output.json'")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.StorageFlag, "storage", "api", "Write output to storage location [api, s3, git, oci, servicenow, crd, grpc, local fs]")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.FileName, "filename", "", "Output filename, defaults to '<environment>-output.json' (extension depends on the output format)")
+	c.PersistentFlags().StringVar(&cfg.OutputConfig.OutputFormat, "output-format", "json", "Format of the output [json, ndjson, spdx, csv, prometheus, protobuf, template]")
+	c.PersistentFlags().StringVar(&cfg.OutputConfig.OutputTemplateFile, "output-template-file", "", "Render the images with the given Go template instead, e.g. 'inventory.md.tmpl' produces a Markdown report")
+	c.PersistentFlags().VarPF(negatedBool{&cfg.OutputConfig.JsonCompact}, "json-indent", "", "Indent the json output, --json-indent=false writes compact json").NoOptDefVal = "true"
+	c.PersistentFlags().StringSliceVar(&cfg.OutputConfig.CsvColumns, "csv-columns", collector.DefaultCsvColumns, "Columns of the csv output, any json field name of the report or 'tag', comma seperated")
+	c.PersistentFlags().StringVar(&cfg.OutputConfig.OutputCompat, "output-compat", "", "Serialize the json output with the field names of the legacy ClusterImageScanner image collector [legacy]")
+	c.PersistentFlags().StringVar(&cfg.OutputConfig.SplitBy, "split-by", "", "Write one report per group instead of a single report [namespace, team], a custom filename has to contain the placeholder e.g. 'reports/{team}.json', not supported by the api storage")
+	c.PersistentFlags().StringVar(&cfg.OutputConfig.GroupBy, "group-by", "", "Report one entry per owning Deployment, StatefulSet, CronJob etc. with its images and namespaces instead of one per container, only for the json and ndjson output format [workload]")
+	c.PersistentFlags().StringVar(&cfg.OutputConfig.OutputFilter, "output-filter", "", "JMESPath expression evaluated on the json fields of every image, only images for which it is true are reported, e.g. \"container_type == 'application'\", unlike the skip flag the other images are left out")
+	c.PersistentFlags().StringVar(&cfg.OutputConfig.PostProcessFile, "post-process-file", "", "YAML file with JSON Patch rules applied to the entries of the json or ndjson report before it is stored, selected by a JMESPath 'when' expression, e.g. to force the team of a namespace")
+	c.PersistentFlags().BoolVar(&cfg.OutputConfig.ValidateOutput, "validate-output", false, "Validate the json or ndjson output against the JSON Schema of the report before storing it")
+	c.PersistentFlags().BoolVar(&cfg.OutputConfig.AllowPartial, "allow-partial", false, "Report the images of a run in which some namespaces or enrichments failed, wrapped with the errors and the images which can't be pulled into '{\"images\": [...], \"collector_version\": \"...\", \"errors\": [...], \"pull_warnings\": [...]}', only for the json output format")
+	c.PersistentFlags().BoolVar(&cfg.OutputConfig.ReportAnnotationWarnings, "report-annotation-warnings", false, "Add the unknown scans and contact annotations, e.g. typos, to the report as '{\"images\": [...], \"collector_version\": \"...\", \"errors\": [...], \"pull_warnings\": [...], \"annotation_warnings\": [...]}', only for the json output format")
+	c.PersistentFlags().BoolVar(&cfg.OutputConfig.TrackSeen, "track-seen", false, "Add first_seen and last_seen to the images, the first seen time is kept from the previous report read back from the s3, git or fs storage, only for the json and ndjson output format")
+	c.PersistentFlags().IntVar(&cfg.OutputConfig.StaleRuns, "stale-runs", 0, "Keep images of the previous report which were not collected, e.g. of paused CronJobs, for this number of runs marked as stale before dropping them, requires the storage to read the previous report like --track-seen")
+	c.PersistentFlags().BoolVar(&cfg.EnrichConfig.EnrichRegistry, "enrich-registry", false, "Read the creation time and the org.opencontainers.image.source and .revision labels from the image config in the registry")
+	c.PersistentFlags().BoolVar(&cfg.EnrichConfig.EnrichSbom, "enrich-sbom", false, "Discover SBOMs, attestations and signatures attached to the images with the OCI referrers API or cosign tags in the registry")
+	c.PersistentFlags().IntVar(&cfg.EnrichConfig.EnrichConcurrency, "enrichment-concurrency", 8, "Number of images resolved in parallel by the registry, SBOM and Harbor enrichment")
+	c.PersistentFlags().IntVar(&cfg.EnrichConfig.RegistryHostConcurrency, "registry-host-concurrency", 0, "Maximum number of requests in flight per registry and Harbor host during the enrichment, 0 is unlimited")
+	c.PersistentFlags().StringSliceVar(&cfg.EnrichConfig.RegistryRateLimits, "registry-rate-limit", []string{}, "Requests per second to a registry during the enrichment, e.g. 'docker.io=5', '*=20' limits all other registries, comma seperated")
+	c.PersistentFlags().BoolVar(&cfg.RiskConfig.ScoreRisk, "risk-score", false, "Add risk_score and risk_factors to the images, weighing the exposure, privileged, root, mutable tag, missing attestation and replicas")
+	c.PersistentFlags().StringSliceVar(&cfg.RiskConfig.RiskWeights, "risk-weights", []string{}, "Weights of the risk factors overriding the defaults, e.g. 'exposed=50,replicas=0' [exposed, privileged, root, mutable-tag, unsigned, replicas], comma seperated")
+	c.PersistentFlags().StringVar(&cfg.EnrichConfig.RegistryConfigFile, "registry-config", "", "Docker config.json with the credentials of private registries for the registry enrichment")
+	c.PersistentFlags().StringSliceVar(&cfg.EnrichConfig.RegistryPlainHttp, "registry-plain-http", []string{}, "Registries accessed without TLS by the registry enrichment, e.g. 'localhost:5000', comma seperated")
+	c.PersistentFlags().StringSliceVar(&cfg.EnrichConfig.RegistryCredentialHelpers, "registry-credential-helpers", []string{}, "Cloud registries whose credentials are fetched with the identity of the collector for the registry enrichment [ecr, google, azure], comma seperated")
+	c.PersistentFlags().BoolVar(&cfg.EnrichConfig.RegistryPullSecrets, "registry-pull-secrets", false, "Use the imagePullSecrets of the pods for the registry enrichment, requires get permission on secrets")
+	c.PersistentFlags().StringSliceVar(&cfg.EnrichConfig.HarborEndpoints, "harbor-endpoint", []string{}, "Harbor API of a registry to add the project owner, severity threshold and scan status, e.g. 'harbor.example.com=https://harbor.example.com', comma seperated")
+	c.PersistentFlags().StringVar(&cfg.SkipAuditConfig.SkipAuditFile, "skip-audit-file", "", "Write why images were skipped as one json object per line to this file, '-' for stdout unless the report is written to stdout")
+	c.PersistentFlags().StringVar(&cfg.SkipAuditConfig.SkipAuditObject, "skip-audit-object", "", "Store why images were skipped as one json object per line with the configured storage under this name, e.g. 'skipped.ndjson', only supported by the fs, s3, git and oci storage")
+	c.PersistentFlags().BoolVar(&cfg.StorageConfig.CompressOutput, "compress-output", false, "Gzip the output and append .gz to the file/object name (s3, git, fs and oci storage)")
+	c.PersistentFlags().BoolVar(&cfg.RunLockConfig.RunLock, "run-lock", false, "Hold a kubernetes Lease while collecting and storing, so overlapping runs can't interleave writes to the same target")
+	c.PersistentFlags().StringVar(&cfg.RunLockConfig.RunLockName, "run-lock-name", "image-metadata-collector-run", "Name of the Lease used as run lock, runs writing to different targets can use different names")
+	c.PersistentFlags().StringVar(&cfg.RunLockConfig.RunLockNamespace, "run-lock-namespace", "", "Namespace of the run lock Lease, defaults to the namespace of the pod")
+	c.PersistentFlags().DurationVar(&cfg.RunLockConfig.RunLockTimeout, "run-lock-timeout", time.Minute, "Time to wait for another run to release the run lock before failing")
+	c.PersistentFlags().BoolVar(&cfg.StorageConfig.Preflight, "preflight", false, "Verify storage connectivity and credentials before collecting images")
+	c.PersistentFlags().BoolVar(&cfg.StorageConfig.Checksum, "checksum", false, "Store a SHA-256 checksum alongside the output (s3 checksum header, .sha256 file for fs/git, verified response header for api)")
+	c.PersistentFlags().StringSliceVar(&cfg.StorageConfig.EncryptAgeRecipients, "encrypt-age-recipients", []string{}, "Encrypt the output for the given age recipients (age1...), comma seperated")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.EncryptPgpPublicKeyFile, "encrypt-pgp-public-key-file", "", "Encrypt the output for the keys in the given armored PGP public key file")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.FsBaseDir, "fs-base-dir", "", "Base directory for the fs storage, missing directories are created")
+	c.PersistentFlags().IntVar(&cfg.StorageConfig.FsKeep, "fs-keep", 0, "Number of previous reports to keep next to the current one for the fs storage, 0 keeps none")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.FsRotation, "fs-rotation", "numbered", "Naming of previous reports for the fs storage [numbered (output-1.json), timestamp (output-20060102T150405Z.json)]")
+	c.PersistentFlags().BoolVar(&cfg.StorageConfig.StdoutCompact, "stdout-compact", false, "Print compact instead of indented JSON for the stdout storage")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.StdoutColor, "stdout-color", "auto", "Syntax highlighting for the stdout storage [auto (if stdout is a terminal), always, never]")
 	c.PersistentFlags().StringVar(&cfg.StorageConfig.S3BucketName, "s3-bucket", "", "S3 Bucket to store image collector results")
 	c.PersistentFlags().StringVar(&cfg.StorageConfig.S3Endpoint, "s3-endpoint", "", "S3 Endpoint (e.g. minio)")
 	c.PersistentFlags().StringVar(&cfg.StorageConfig.S3Region, "s3-region", "", "S3 region")
 	c.PersistentFlags().BoolVar(&cfg.StorageConfig.S3Insecure, "s3-insecure", false, "Insecure bucket connection")
 	c.PersistentFlags().StringVar(&cfg.StorageConfig.GitPassword, "git-password", "", "Git Password to connect")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.GitPasswordFile, "git-password-file", "", "Path to a file containing the Git Password, e.g. a mounted secret")
 	c.PersistentFlags().StringVar(&cfg.StorageConfig.GitUrl, "git-url", "", "Git URL to connect, use ")
 	c.PersistentFlags().StringVar(&cfg.StorageConfig.GitPrivateKeyFile, "git-private-key-file", "", "Path to the private ssh/github key file")
 	c.PersistentFlags().StringVar(&cfg.StorageConfig.GitDirectory, "git-directory", "", "Directory to clone to")
 	c.PersistentFlags().Int64Var(&cfg.StorageConfig.GithubAppId, "github-app-id", 0, "Github AppId")
 	c.PersistentFlags().Int64Var(&cfg.StorageConfig.GithubInstallationId, "github-installation-id", 0, "Github InstallationId")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.OciRepository, "oci-repository", "", "OCI repository to push the output as artifact to, e.g. registry.example.com/team/image-reports")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.OciTag, "oci-tag", "", "Tag of the pushed artifact, defaults to the environment name. Split reports and the skip audit object are tagged with their file name")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.OciUsername, "oci-username", "", "Registry username")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.OciPassword, "oci-password", "", "Registry password or token")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.OciPasswordFile, "oci-password-file", "", "Path to a file containing the registry password or token, e.g. a mounted secret")
+	c.PersistentFlags().BoolVar(&cfg.StorageConfig.OciPlainHttp, "oci-plain-http", false, "Connect to the registry via plain http")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ServiceNowInstanceUrl, "servicenow-instance-url", "", "ServiceNow instance to store the images as CMDB CI records in, e.g. https://example.service-now.com")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ServiceNowUsername, "servicenow-username", "", "ServiceNow user with write access to the CI table")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ServiceNowPassword, "servicenow-password", "", "ServiceNow password")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ServiceNowPasswordFile, "servicenow-password-file", "", "Path to a file containing the ServiceNow password, e.g. a mounted secret")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ServiceNowTable, "servicenow-table", "cmdb_ci_docker_image", "CMDB table of the CI records")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ServiceNowMappingFile, "servicenow-mapping-file", "", "JSON file mapping report fields to CI fields, e.g. '{\"key\":[\"name\"],\"fields\":{\"name\":\"image\",\"u_team\":\"team\"}}', defaults to name, environment and short_description")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.GrpcEndpoint, "grpc-endpoint", "", "ReportIngestion gRPC service the images are streamed to, e.g. https://ingest.example.com:443, http:// connects without TLS, requires --output-format protobuf")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.GrpcToken, "grpc-token", "", "Bearer token sent to the gRPC service")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.GrpcTokenFile, "grpc-token-file", "", "Path to a file containing the bearer token of the gRPC service, e.g. a mounted secret")
+	c.PersistentFlags().DurationVar(&cfg.StorageConfig.GrpcTimeout, "grpc-timeout", 5*time.Minute, "Timeout of streaming a report to the gRPC service")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.CrdNamespace, "crd-namespace", "", "Namespace of the ImageCollectionReport custom resource, print the CRDs with the crd command")
+	c.PersistentFlags().BoolVar(&cfg.StorageConfig.CrdClusterScoped, "crd-cluster-scoped", false, "Store the report as cluster-scoped ClusterImageCollectionReport instead")
+	c.PersistentFlags().DurationVar(&cfg.StorageConfig.ServiceNowTimeout, "servicenow-timeout", 30*time.Second, "Timeout of a single ServiceNow Table API request")
 	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiKey, "api-key", "", "API Key")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiKeyFile, "api-key-file", "", "Path to a file containing the API Key, e.g. a mounted secret")
 	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiSignature, "api-signature", "", "API Signature")
-	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiEndpoint, "api-endpoint", "", "API Endpoint, e.g. https://example.io/v1/account/$ACCOUNT/cluster/$CLUSTER/image-collector-report/images")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiSignatureFile, "api-signature-file", "", "Path to a file containing the API Signature, e.g. a mounted secret")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiEndpoint, "api-endpoint", "", "API Endpoint, $ACCOUNT, $CLUSTER and $ENVIRONMENT are substituted with the path escaped --api-account, --api-cluster and --environment-name, e.g. https://example.io/v1/account/$ACCOUNT/cluster/$CLUSTER/image-collector-report/images")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiAccount, "api-account", "", "Account substituted for $ACCOUNT in the API endpoint")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiCluster, "api-cluster", "", "Cluster substituted for $CLUSTER in the API endpoint")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiOAuthTokenUrl, "api-oauth-token-url", "", "OAuth2 token URL, enables the client-credentials grant instead of the API Key")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiOAuthClientId, "api-oauth-client-id", "", "OAuth2 client id")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiOAuthClientSecret, "api-oauth-client-secret", "", "OAuth2 client secret")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiOAuthClientSecretFile, "api-oauth-client-secret-file", "", "Path to a file containing the OAuth2 client secret, e.g. a mounted secret")
+	c.PersistentFlags().StringSliceVar(&cfg.StorageConfig.ApiOAuthScopes, "api-oauth-scopes", []string{}, "OAuth2 scopes to request, comma seperated")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiClientCertFile, "api-client-cert-file", "", "Path to the PEM encoded client certificate for mutual TLS")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiClientKeyFile, "api-client-key-file", "", "Path to the PEM encoded client key for mutual TLS")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiCaFile, "api-ca-file", "", "Path to the PEM encoded CA bundle to verify the API server certificate")
+	c.PersistentFlags().DurationVar(&cfg.StorageConfig.ApiTimeout, "api-timeout", 60*time.Second, "Timeout for a complete API request including reading the response, 0 disables the timeout")
+	c.PersistentFlags().DurationVar(&cfg.StorageConfig.ApiDialTimeout, "api-dial-timeout", 10*time.Second, "Timeout for establishing the connection (incl. TLS handshake) to the API")
+	c.PersistentFlags().StringVar(&cfg.StorageConfig.ApiMethod, "api-method", "PUT", "HTTP method used to send the report to the API [PUT, POST, PATCH]")
+	c.PersistentFlags().IntSliceVar(&cfg.StorageConfig.ApiSuccessStatusCodes, "api-success-status-codes", []int{200}, "HTTP status codes accepted as successful API response, comma seperated e.g. '200,201,202'")
+	c.PersistentFlags().Float64Var(&cfg.StorageConfig.ApiRequestsPerSecond, "api-requests-per-second", 0, "Maximum number of requests per second sent to the API, 0 disables the limit")
+	c.PersistentFlags().IntVar(&cfg.StorageConfig.ApiRetryAttempts, "api-retry-attempts", 3, "Number of retries if the API throttles a request (429/503), honoring the Retry-After header")
 
 	// Annotation Key/Name Config
 	c.PersistentFlags().StringVar(&cfg.AnnotationNames.Base, "annotation-name-base", "sdase.org/", "Annotation name for general annotations")
 	c.PersistentFlags().StringVar(&cfg.AnnotationNames.Scans, "annotation-name-scans", "clusterscanner.sdase.org/", "Annotation name for scan related annotations")
 	c.PersistentFlags().StringVar(&cfg.AnnotationNames.Contact, "annotation-name-contact", "contact.sdase.org/", "Annotation name for contact related annotations")
 	c.PersistentFlags().StringVar(&cfg.AnnotationNames.DefectDojo, "annotation-name-defect-dojo", "defectdojo.sdase.org/", "Annotation name for defectdojo related annotations")
+	c.PersistentFlags().StringSliceVar(&cfg.AnnotationNames.BackstageMapping, "backstage-mapping", []string{}, "Map Backstage annotations of namespaces and pods to the product, description, team, slack or email field if the sdase.org annotation is missing, e.g. 'backstage.io/kubernetes-id=product,backstage.io/owner=team', comma seperated")
 
 	// Deployment wide Defaults
 	c.PersistentFlags().StringVar(&cfg.CollectorImage.Environment, "environment-name", "", "Name of the environment")
@@ -95,7 +315,7 @@ func newCommand() *cobra.Command {
 	c.PersistentFlags().BoolVar(&cfg.CollectorImage.IsScanRunAsPrivileged, "is-scan-run-as-privileged", true, "Default enable/disable RunAsPrivileged scan")
 	c.PersistentFlags().BoolVar(&cfg.CollectorImage.IsPotentiallyRunningAsRoot, "is-scan-potentially-running-as-root", true, "Default enable/disable PotentiallyRunningAsRoot scan")
 	c.PersistentFlags().BoolVar(&cfg.CollectorImage.IsPotentiallyRunningAsPrivileged, "is-scan-potentially-running-as-privileged", true, "Default enable/disable PotentiallyRunningAsPrivileged scan")
-	c.PersistentFlags().Int64Var(&cfg.CollectorImage.ScanLifetimeMaxDays, "ScanLifetimeMaxDays", 120, "Default max days for (base) image lifetime scan")
+	c.PersistentFlags().Int64Var(&cfg.CollectorImage.ScanLifetimeMaxDays, "scan-lifetime-max-days", 120, "Default max days for (base) image lifetime scan")
 	c.PersistentFlags().BoolVar(&cfg.CollectorImage.Skip, "skip", false, "Default behaviour for skipping scans for images")
 	c.PersistentFlags().StringSliceVar(&cfg.CollectorImage.EngagementTags, "engagement-tags", []string{}, "Default engagement tags to use")
 	c.PersistentFlags().StringVar(&cfg.CollectorImage.ContainerType, "container-type", "application", "Default container-type to use")
@@ -104,19 +324,44 @@ func newCommand() *cobra.Command {
 	c.PersistentFlags().StringVar(&cfg.CollectorImage.Slack, "slack", "", "Default slack channel to use")
 	c.PersistentFlags().StringVar(&cfg.CollectorImage.Email, "email", "", "Default email to use")
 	c.PersistentFlags().StringVar(&cfg.CollectorImage.NamespaceFilter, "namespace-filter", "", "Default namespace filter to use")
-	c.PersistentFlags().StringVar(&cfg.CollectorImage.NamespaceFilterNegated, "negated_namespace_filter", "", "Default negated namespace filter to use")
-
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	if cfg.Debug {
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
-	}
+	c.PersistentFlags().StringVar(&cfg.CollectorImage.NamespaceFilterNegated, "negated-namespace-filter", "", "Default negated namespace filter to use")
 
 	c.PersistentFlags().AddGoFlagSet(flag.CommandLine)
+
+	secrets := []string{"api-key", "api-signature", "api-oauth-client-secret", "git-password", "oci-password", "servicenow-password", "grpc-token", "vault-token", "slack-webhook-url", "notify-msteams-url"}
+	markSecret(c.PersistentFlags(), secrets...)
+	addSecretRefs(c.PersistentFlags(), secrets...)
+
+	c.SetGlobalNormalizationFunc(normalizeFlagName)
+	addDeprecatedAliases(c)
 	return c
 }
 
 // initializeConfig reads in ENV variables if set.
 func initializeConfig(cmd *cobra.Command) error {
+	v, err := newViper(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := bindFlags(cmd.Flags(), v); err != nil {
+		return err
+	}
+
+	logLevel, _ := cmd.Flags().GetString("log-level")
+	logFormat, _ := cmd.Flags().GetString("log-format")
+	debug, _ := cmd.Flags().GetBool("debug")
+	if err := configureLogging(logLevel, logFormat, debug); err != nil {
+		return err
+	}
+	registerSecrets(cmd.Flags())
+
+	strict, _ := cmd.Flags().GetBool("strict-config")
+	return checkConfigKeys(cmd, v, os.Environ(), strict)
+}
+
+// newViper reads the environment variables and the config file given by the --config flag
+func newViper(cmd *cobra.Command) (*viper.Viper, error) {
 	v := viper.New()
 
 	v.SetEnvPrefix(AppName)
@@ -125,56 +370,497 @@ func initializeConfig(cmd *cobra.Command) error {
 	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 
 	v.AutomaticEnv()
-	bindFlags(cmd, v)
 
-	return nil
+	// Flags and environment variables take precedence over the config file
+	if configFile, _ := cmd.Flags().GetString("config"); configFile != "" {
+		v.SetConfigFile(configFile)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("could not read config file %s: %w", configFile, err)
+		}
+		log.Debug().Str("configFile", v.ConfigFileUsed()).Msg("Using config file")
+	}
+
+	return v, nil
 }
 
-// bindFlags binds each cobra flag to its associated viper configuration
-func bindFlags(cmd *cobra.Command, v *viper.Viper) {
-	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+// bindFlags binds each flag to its associated viper configuration
+func bindFlags(flags *pflag.FlagSet, v *viper.Viper) error {
+	var errs []error
+
+	flags.VisitAll(func(f *pflag.Flag) {
 		configName := f.Name
 
+		// A deprecated alias shares the value of its replacement, which wins if it is given on the command line
+		if replacement, ok := deprecatedFlags[f.Name]; ok && flags.Changed(replacement) {
+			return
+		}
+
 		if !f.Changed && v.IsSet(configName) {
 			val := v.Get(configName)
-			err := cmd.Flags().Set(f.Name, fmt.Sprintf("%v", val))
+
+			// pflag only warns about deprecated flags on the command line
+			if f.Deprecated != "" {
+				log.Warn().Str("key", configName).Msgf("Configuration key %s is deprecated, %s", configName, f.Deprecated)
+			}
+
+			// Lists from the config file replace the flag default instead of being formatted as '[a b]'
+			if list, ok := val.([]any); ok {
+				values := make([]string, len(list))
+				for i, item := range list {
+					values[i] = fmt.Sprintf("%v", item)
+				}
+				if sliceValue, ok := f.Value.(pflag.SliceValue); ok {
+					if err := sliceValue.Replace(values); err != nil {
+						errs = append(errs, fmt.Errorf("could not set flag %s: %w", f.Name, err))
+					}
+					return
+				}
+				val = strings.Join(values, ",")
+			}
+
+			err := flags.Set(f.Name, fmt.Sprintf("%v", val))
 			if err != nil {
-				log.Fatal().Stack().Err(err).Msg("Could not set flag " + f.Name)
+				errs = append(errs, fmt.Errorf("could not set flag %s: %w", f.Name, err))
 			}
 
 		}
 	})
+
+	return errors.Join(errs...)
+}
+
+// newSchemaCommand prints the JSON Schema of the report
+func newSchemaCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the report",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, err := collector.JsonSchemaMarshal()
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), string(schema))
+			return err
+		},
+	}
+}
+
+// newCrdCommand prints the CustomResourceDefinitions of the custom resource storage
+func newCrdCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "crd",
+		Short: "Print the CustomResourceDefinitions of the ImageCollectionReport custom resources",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifests, err := crd.Manifests()
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(manifests)
+			return err
+		},
+	}
+}
+
+// newVersionCommand prints the version, commit, build date and Go version
+func newVersionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the version of the collector",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := fmt.Fprintln(cmd.OutOrStdout(), version.Get())
+			return err
+		},
+	}
+}
+
+// newValidateCommand checks the configuration, kubernetes connectivity and the storage without collecting images
+func newValidateCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:         "validate",
+		Short:       "Validate the configuration, kubernetes connectivity and storage",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{resolveSecretsAnnotation: ""},
+		// The failed checks are already logged
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return validate(cmd.Context(), cfg)
+		},
+	}
+}
+
+// validate runs all checks and logs every problem, the returned error only summarizes them
+func validate(ctx context.Context, cfg *config.Config) error {
+	failed := 0
+	check := func(name string, err error) {
+		if err != nil {
+			failed++
+			log.Error().Err(err).Str("check", name).Msg("Validation failed")
+			return
+		}
+		log.Info().Str("check", name).Msg("Validation succeeded")
+	}
+
+	outputFormat, err := collector.NewOutputFormat(&cfg.OutputConfig)
+	if err == nil && cfg.OutputConfig.SplitBy != "" {
+		_, err = cfg.StorageConfig.SplitReportFileName(cfg.Environment, cfg.OutputConfig.SplitBy, "", outputFormat.Extension)
+	}
+	check("output", err)
+
+	check("filters and annotations", collector.Validate(&cfg.AnnotationNames, &cfg.CollectorImage, &cfg.RunConfig))
+	if cfg.RiskConfig.ScoreRisk {
+		_, err = collector.ParseRiskWeights(cfg.RiskConfig.RiskWeights)
+		check("risk weights", err)
+	}
+
+	if cfg.KubeConfig.InputFile == "" {
+		k8client, err := kubeclient.NewClient(&cfg.KubeConfig)
+		if err == nil {
+			err = k8client.Check(ctx)
+		}
+		check("kubernetes", err)
+	}
+
+	cfg.StorageConfig.CrdKubeConfig = cfg.KubeConfig
+	storager, err := storage.NewStorage(&cfg.StorageConfig)
+	if err == nil {
+		err = storage.Preflight(ctx, storager)
+	}
+	check("storage", err)
+
+	if failed > 0 {
+		return fmt.Errorf("%d validation check(s) failed", failed)
+	}
+	return nil
 }
 
-// run starts the collector and metrics endpoint
-func run(cfg *config.Config) {
-	k8client := kubeclient.NewClient(&cfg.KubeConfig)
+// run starts the collector and metrics endpoint, with an interval the images are collected and stored repeatedly
+func run(cfg *config.Config) error {
+	if cfg.Interval > 0 && cfg.KubeConfig.InputFile == kubeclient.StdinInputFile {
+		return withExitCode(ExitCodeConfig, errors.New("images can only be read from stdin once, use an input file with --interval"))
+	}
+	if cfg.LeaderElectionConfig.LeaderElect && cfg.Interval <= 0 && !cfg.Operator {
+		return withExitCode(ExitCodeConfig, errors.New("leader election requires --interval or --operator"))
+	}
+	if cfg.Operator {
+		switch {
+		case cfg.Interval > 0:
+			return withExitCode(ExitCodeConfig, errors.New("--operator updates the reports on changes and can't be combined with --interval"))
+		case cfg.KubeConfig.InputFile != "":
+			return withExitCode(ExitCodeConfig, errors.New("--operator watches the cluster and can't read images from an input file"))
+		case cfg.OutputConfig.SplitBy != "namespace":
+			return withExitCode(ExitCodeConfig, errors.New("--operator maintains one report per namespace and requires --split-by namespace"))
+		}
+	}
+	if cfg.ServerConfig.Pprof && cfg.ServerConfig.ListenAddress == "" {
+		return withExitCode(ExitCodeConfig, errors.New("--pprof requires --listen-address"))
+	}
 
-	storage, err := storage.NewStorage(&cfg.StorageConfig, cfg.Environment)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stop := handleShutdown(cancel, cfg.ShutdownTimeout)
+
+	log.Info().Str("version", version.Version).Str("commit", version.Get().Commit).Msg("Starting " + version.Name)
+
+	runMetrics := metrics.New()
+	if cfg.ServerConfig.ListenAddress != "" {
+		srv := server.New(&cfg.ServerConfig, runMetrics)
+		go func() {
+			log.Info().Str("address", srv.Addr).Msg("Starting HTTP server")
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error().Err(err).Msg("HTTP server failed")
+			}
+		}()
+		defer func() {
+			shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancelShutdown()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+	}
 
+	opts := options(cfg)
+	opts.Observer = runMetrics
+	c, err := imagecollector.New(opts)
 	if err != nil {
-		log.Fatal().Stack().Err(err).Msg("Could not create storage for: " + cfg.StorageConfig.StorageFlag)
+		return classify(err)
+	}
+	// refreshSecrets reads rotated Vault secrets between runs, the collector is recreated with the changed values, e.g.
+	// of the storage credentials
+	refreshSecrets := func(ctx context.Context) {
+		if !vaultSecrets.refresh(ctx) {
+			return
+		}
+		opts := options(cfg)
+		opts.Observer = runMetrics
+		recreated, err := imagecollector.New(opts)
+		if err != nil {
+			log.Error().Err(err).Msg("Could not apply the changed Vault secrets, keeping the current ones")
+			return
+		}
+		c = recreated
+	}
+	// The Vault token is renewed while the collector runs continuously, so the secrets can be read between runs
+	if vaultSecrets != nil && (cfg.Operator || cfg.Interval > 0) {
+		go vaultSecrets.client.KeepRenewed(ctx)
 	}
 
-	collectorDefaults := &cfg.CollectorImage
-	annotationNames := &cfg.AnnotationNames
-	runConfig := &cfg.RunConfig
+	if cfg.StorageConfig.Preflight {
+		if err = c.Check(ctx); err != nil {
+			return classify(err)
+		}
+		log.Info().Str("storage", cfg.StorageConfig.StorageFlag).Msg("Preflight check succeeded")
+	}
 
-	// Collect images from K8
-	k8Images, err := k8client.GetAllImagesForAllNamespaces()
+	heartbeats := &heartbeater{fileName: cfg.HeartbeatConfig.HeartbeatFile}
+	notifications, err := newNotifier(ctx, cfg, c)
+	if err != nil {
+		return err
+	}
+	statuses, err := newStatusReporter(cfg)
 	if err != nil {
-		log.Fatal().Stack().Err(err).Msg("Could not retrieve images from K8")
+		return err
+	}
+
+	// observeRun collects and publishes the images, the outcome is logged, counted and reported
+	observeRun := func(ctx context.Context, phase *runPhase, collect func(ctx context.Context) ([]imagecollector.Image, error), publish func(ctx context.Context, images []imagecollector.Image, errs ...imagecollector.RunError) (imagecollector.Report, error)) error {
+		start := time.Now()
+		phase.Set(phaseCollecting)
+		images, err := collect(ctx)
+		runErrors, err := partialErrors(err)
+
+		var report imagecollector.Report
+		if err == nil {
+			phase.Set(phaseStoring)
+			report, err = publish(ctx, images, runErrors...)
+		}
+		for _, result := range report.Results {
+			log.Info().Str("location", result.Location).Int("size", result.Size).Interface("metadata", result.Metadata).Msg("Stored collected images")
+		}
+		if err == nil {
+			logRunSummary(start, report)
+			heartbeats.beat()
+		}
+		runMetrics.ObserveRun(start, len(report.Images), err)
+		pushMetrics(cfg, runMetrics)
+		notifications.notify(ctx, start, images, err)
+		statuses.report(ctx, start, report, err)
+		return classify(err)
+	}
+
+	collectAndObserve := func(ctx context.Context) error {
+		return withTimeout(ctx, cfg.Timeout, func(ctx context.Context, phase *runPhase) error {
+			phase.Set(phaseLocking)
+			return withRunLock(ctx, cfg, stop.Stopping(), func(ctx context.Context) error {
+				return observeRun(ctx, phase, c.Collect, c.Publish)
+			})
+		})
+	}
+
+	if cfg.Operator {
+		if notifications != nil {
+			// The new images of a summary are counted against the whole cluster, not a few namespaces
+			log.Warn().Msg("Run summaries are not posted in operator mode")
+			notifications = nil
+		}
+		reconcile := func(ctx context.Context, namespaces []string) error {
+			refreshSecrets(ctx)
+			return withTimeout(ctx, cfg.Timeout, func(ctx context.Context, phase *runPhase) error {
+				// Namespaces without images get an empty report, e.g. after their last pod was deleted
+				return observeRun(ctx, phase, func(ctx context.Context) ([]imagecollector.Image, error) {
+					return c.CollectNamespaces(ctx, namespaces)
+				}, func(ctx context.Context, images []imagecollector.Image, errs ...imagecollector.RunError) (imagecollector.Report, error) {
+					return c.PublishNamespaces(ctx, namespaces, images, errs...)
+				})
+			})
+		}
+		operateWhileLeading := func(ctx context.Context) error {
+			go heartbeats.beatWhileRunning(operatorHeartbeatInterval, ctx.Done())
+			return operate(ctx, cfg.OperatorDebounce, stop.Stopping(), c.Watch, reconcile)
+		}
+
+		if cfg.LeaderElectionConfig.LeaderElect {
+			var k8client *kubeclient.Client
+			if k8client, err = kubeclient.NewClient(&cfg.KubeConfig); err != nil {
+				return withExitCode(ExitCodeKube, fmt.Errorf("could not create kubernetes client for leader election: %w", err))
+			}
+			go heartbeats.beatWhileStandby(operatorHeartbeatInterval, ctx.Done())
+			err = leader.Run(ctx, k8client.Clientset, &cfg.LeaderElectionConfig, stop.Stopping(), heartbeats.setLeader(runMetrics.SetLeader), operateWhileLeading)
+		} else {
+			err = operateWhileLeading(ctx)
+		}
+		err = classify(err)
+		if code := stop.ExitCode(); code != 0 && exitCode(err) != code {
+			return stoppedBySignal(code, err)
+		}
+		return err
+	}
+
+	collectWithRetries := func(ctx context.Context) error {
+		return withRunRetries(ctx, cfg.MaxRunRetries, cfg.RunRetryBackoff, stop.Stopping(), collectAndObserve)
+	}
+
+	if cfg.Interval <= 0 {
+		err = collectWithRetries(ctx)
+		if code := stop.ExitCode(); code != 0 {
+			return stoppedBySignal(code, err)
+		}
+		return err
 	}
 
-	// Convert & Clean k8 images to collector images
-	images, err := collector.ConvertImages(k8Images, collectorDefaults, annotationNames, runConfig)
+	reloads := watchConfig(ctx, cfg.ConfigFile)
+
+	// A failed run is retried with the next interval instead of stopping the collector. The context is cancelled
+	// when the leadership is lost.
+	collectRepeatedly := func(ctx context.Context) error {
+		var err error
+		for {
+			if err = collectWithRetries(ctx); err != nil {
+				log.Error().Stack().Err(err).Int("exitCode", exitCode(err)).Msg("Could not collect images")
+			}
+
+			wait := nextRunIn(cfg.Interval, cfg.IntervalJitter)
+			log.Info().Dur("wait", wait).Msg("Waiting for next run")
+			timer := time.NewTimer(wait)
+
+		waiting:
+			for {
+				select {
+				case <-stop.Stopping():
+					timer.Stop()
+					return stoppedBySignal(stop.ExitCode(), err)
+				case <-ctx.Done():
+					timer.Stop()
+					return err
+				case <-reloads:
+					// Reloads are applied between runs only, so a run never sees a partially updated configuration
+					if reloadErr := applyReload(cfg, os.Args[1:]); reloadErr != nil {
+						log.Error().Err(reloadErr).Msg("Could not reload configuration, keeping the current one")
+					} else {
+						c.Reconfigure(cfg.AnnotationNames, cfg.CollectorImage, cfg.RunConfig)
+					}
+				case <-timer.C:
+					break waiting
+				}
+			}
+
+			refreshSecrets(ctx)
+		}
+	}
+
+	if !cfg.LeaderElectionConfig.LeaderElect {
+		return collectRepeatedly(ctx)
+	}
+
+	k8client, err := kubeclient.NewClient(&cfg.KubeConfig)
 	if err != nil {
-		log.Fatal().Stack().Err(err).Msg("Could not collect images")
+		return withExitCode(ExitCodeKube, fmt.Errorf("could not create kubernetes client for leader election: %w", err))
+	}
+	go heartbeats.beatWhileStandby(cfg.Interval, ctx.Done())
+	err = leader.Run(ctx, k8client.Clientset, &cfg.LeaderElectionConfig, stop.Stopping(), heartbeats.setLeader(runMetrics.SetLeader), collectRepeatedly)
+	if code := stop.ExitCode(); code != 0 && exitCode(err) != code {
+		return stoppedBySignal(code, err)
+	}
+	return err
+}
+
+// stoppedBySignal returns the error of the last run, if any, with the signal exit code
+func stoppedBySignal(code int, err error) error {
+	if err == nil {
+		err = errors.New("stopped by signal")
 	}
+	return withExitCode(code, err)
+}
 
-	// Store images
-	err = collector.Store(images, storage, collector.JsonIndentMarshal)
+// nextRunIn returns the interval with a random jitter added, so collectors of several clusters do not hit the
+// storage at the same time
+func nextRunIn(interval time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	return interval + time.Duration(rand.Float64()*jitter*float64(interval))
+}
+
+// pushMetrics pushes the run metrics to the Pushgateway if configured, a failed push does not fail the run
+func pushMetrics(cfg *config.Config, m *metrics.Metrics) {
+	if cfg.PushConfig.PushgatewayUrl == "" {
+		return
+	}
+	// The run context may already be cancelled or timed out, the metrics of such runs are pushed anyway
+	if err := m.Push(context.Background(), &cfg.PushConfig); err != nil {
+		log.Warn().Err(err).Msg("Could not push metrics to the Pushgateway")
+		return
+	}
+	log.Debug().Str("job", cfg.PushConfig.PushgatewayJob).Msg("Pushed metrics to the Pushgateway")
+}
+
+// withRunLock calls fn while holding the run lock if it is enabled
+func withRunLock(ctx context.Context, cfg *config.Config, stopping <-chan struct{}, fn func(ctx context.Context) error) error {
+	if !cfg.RunLockConfig.RunLock {
+		return fn(ctx)
+	}
+
+	k8client, err := kubeclient.NewClient(&cfg.KubeConfig)
 	if err != nil {
-		log.Fatal().Stack().Err(err).Msg("Could not store collected images")
+		return withExitCode(ExitCodeKube, fmt.Errorf("could not create kubernetes client for the run lock: %w", err))
+	}
+	return leader.WithLock(ctx, k8client.Clientset, &cfg.RunLockConfig, stopping, fn)
+}
+
+// logRunSummary logs one event per successful run instead of per image
+func logRunSummary(start time.Time, report imagecollector.Report) {
+	skipped := 0
+	namespaces := map[string]struct{}{}
+	for _, image := range report.Images {
+		if image.Skip {
+			skipped++
+		}
+		namespaces[image.Namespace] = struct{}{}
+	}
+
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Int("namespaces", len(namespaces)).
+		Int("images", len(report.Images)).
+		Int("skipped", skipped).
+		Int("reports", len(report.Results)).
+		Int("pullWarnings", len(report.Warnings)).
+		Int("errors", len(report.Errors)).
+		Int("annotationWarnings", len(report.AnnotationWarnings)).
+		Msg("Run finished")
+
+	for _, warning := range report.Warnings {
+		log.Warn().Str("namespace", warning.Namespace).Str("image", warning.Image).Str("reason", warning.Reason).Msg("Image can't be pulled")
+	}
+}
+
+// partialErrors accepts the errors of a partial collection, they are logged and returned so they can be added to
+// the report. Other errors are returned unchanged.
+func partialErrors(err error) ([]imagecollector.RunError, error) {
+	var partial *imagecollector.PartialError
+	if !errors.As(err, &partial) {
+		return nil, err
+	}
+	for _, runError := range partial.Errors {
+		log.Warn().Str("stage", runError.Stage).Str("namespace", runError.Namespace).Str("image", runError.Image).Str("error", runError.Error).Msg("Reporting partial result")
+	}
+	return partial.Errors, nil
+}
+
+// options maps the command line configuration to the collector library options
+func options(cfg *config.Config) imagecollector.Options {
+	return imagecollector.Options{
+		AnnotationNames: cfg.AnnotationNames,
+		Defaults:        cfg.CollectorImage,
+		RunConfig:       cfg.RunConfig,
+		OutputConfig:    cfg.OutputConfig,
+		SkipAuditConfig: cfg.SkipAuditConfig,
+		EnrichConfig:    cfg.EnrichConfig,
+		RiskConfig:      cfg.RiskConfig,
+		KubeConfig:      cfg.KubeConfig,
+		AdmissionConfig: cfg.AdmissionConfig,
+		StorageConfig:   cfg.StorageConfig,
 	}
 }
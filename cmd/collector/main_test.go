@@ -0,0 +1,20 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSecretsOnlyForRuns(t *testing.T) {
+	cmd := newCommand(&config.Config{})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"version", "--vault-secret", "api-key=secret/collector#api-key"})
+	assert.NoError(t, cmd.Execute(), "version does not read the secrets")
+
+	cmd = newCommand(&config.Config{})
+	cmd.SetArgs([]string{"validate", "--vault-secret", "api-key=secret/collector#api-key"})
+	assert.ErrorContains(t, cmd.Execute(), "vault-secret requires vault-address")
+}
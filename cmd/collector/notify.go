@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/config"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/notify"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+	imagecollector "github.com/SDA-SE/image-metadata-collector/pkg/collector"
+
+	"github.com/rs/zerolog/log"
+)
+
+// notifyTimeout bounds posting a summary, a slow webhook must not delay the next run
+const notifyTimeout = 10 * time.Second
+
+// notifier posts a summary of every run, a failed notification is logged and never fails the run
+type notifier struct {
+	notifiers   map[string]notify.Notifier
+	tracker     *notify.Tracker
+	environment string
+}
+
+// newNotifier returns nil if no webhook is configured, the last run is read from the state file or the previous report
+// of the collector
+func newNotifier(ctx context.Context, cfg *config.Config, c *imagecollector.Collector) (*notifier, error) {
+	httpClient := &http.Client{Transport: version.Transport(nil), Timeout: notifyTimeout}
+	notifiers := map[string]notify.Notifier{}
+	if cfg.NotifyConfig.SlackWebhookUrl != "" {
+		notifiers["slack"] = notify.NewSlack(httpClient, cfg.NotifyConfig.SlackWebhookUrl)
+	}
+	if cfg.NotifyConfig.MSTeamsWebhookUrl != "" {
+		notifiers["msteams"] = notify.NewMSTeams(httpClient, cfg.NotifyConfig.MSTeamsWebhookUrl)
+	}
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+
+	if !cfg.NotifyConfig.NotifyStateFromStorage {
+		tracker, err := notify.NewTracker(cfg.NotifyConfig.NotifyStateFile)
+		if err != nil {
+			return nil, withExitCode(ExitCodeConfig, err)
+		}
+		return &notifier{notifiers: notifiers, tracker: tracker, environment: cfg.CollectorImage.Environment}, nil
+	}
+
+	if cfg.NotifyConfig.NotifyStateFile != "" {
+		return nil, withExitCode(ExitCodeConfig, errors.New("--notify-state-file and --notify-state-from-storage can't be combined"))
+	}
+	previous, err := c.PreviousImages(ctx)
+	if err != nil {
+		return nil, classify(err)
+	}
+	var keys []string
+	if previous != nil {
+		keys = imageKeys(previous)
+	}
+	tracker := notify.NewTrackerFromKeys(keys)
+	return &notifier{notifiers: notifiers, tracker: tracker, environment: cfg.CollectorImage.Environment}, nil
+}
+
+// notify posts the summary of the run which started at start, new images are only counted for successful runs
+func (n *notifier) notify(ctx context.Context, start time.Time, images []imagecollector.Image, err error) {
+	if n == nil {
+		return
+	}
+
+	summary := notify.Summary{Environment: n.environment, Duration: time.Since(start), Images: len(images), NewImages: -1, Err: err}
+	if err == nil {
+		var trackErr error
+		if summary.NewImages, trackErr = n.tracker.Update(imageKeys(images)); trackErr != nil {
+			log.Warn().Err(trackErr).Msg("Could not write notify state file")
+		}
+	}
+
+	// The run context may already be cancelled after a failure
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+	defer cancel()
+	for name, target := range n.notifiers {
+		if notifyErr := target.Notify(ctx, summary); notifyErr != nil {
+			log.Warn().Err(notifyErr).Str("notifier", name).Msg("Could not post run summary")
+		}
+	}
+}
+
+// imageKeys identifies the images by their id, the image is used if the id is unknown
+func imageKeys(images []imagecollector.Image) []string {
+	keys := make([]string, 0, len(images))
+	for _, image := range images {
+		key := image.ImageId
+		if key == "" {
+			key = image.Image
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// operatorHeartbeatInterval writes the heartbeat while the operator waits for changes
+const operatorHeartbeatInterval = time.Minute
+
+// operate keeps the reports of the namespaces up to date while watch reports pod changes, until the context is done
+// or stopping is closed. Changes are collected for the debounce duration, so a rollout updates a report once instead
+// of once per pod. A failed reconcile is logged and retried after the debounce duration.
+func operate(ctx context.Context, debounce time.Duration, stopping <-chan struct{}, watch func(ctx context.Context, onChange func(namespace string)) error, reconcile func(ctx context.Context, namespaces []string) error) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changes := make(chan string, 64)
+	watched := make(chan error, 1)
+	go func() {
+		watched <- watch(watchCtx, func(namespace string) {
+			select {
+			case changes <- namespace:
+			case <-watchCtx.Done():
+			}
+		})
+	}()
+
+	pending := map[string]struct{}{}
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-stopping:
+			return nil
+		case <-ctx.Done():
+			return nil
+		case err := <-watched:
+			return err
+		case namespace := <-changes:
+			if len(pending) == 0 {
+				timer.Reset(debounce)
+			}
+			pending[namespace] = struct{}{}
+		case <-timer.C:
+			namespaces := make([]string, 0, len(pending))
+			for namespace := range pending {
+				namespaces = append(namespaces, namespace)
+			}
+			sort.Strings(namespaces)
+			pending = map[string]struct{}{}
+
+			log.Info().Strs("namespaces", namespaces).Msg("Updating reports of changed namespaces")
+			if err := reconcile(ctx, namespaces); err != nil {
+				log.Error().Stack().Err(err).Int("exitCode", exitCode(err)).Strs("namespaces", namespaces).Msg("Could not update reports, retrying")
+				for _, namespace := range namespaces {
+					pending[namespace] = struct{}{}
+				}
+				timer.Reset(debounce)
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	imagecollector "github.com/SDA-SE/image-metadata-collector/pkg/collector"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestOperate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var reconciled [][]string
+	done := make(chan struct{})
+	watch := func(ctx context.Context, onChange func(namespace string)) error {
+		for _, namespace := range []string{"b", "a", "b", "a"} {
+			onChange(namespace)
+		}
+		<-ctx.Done()
+		return nil
+	}
+	reconcile := func(ctx context.Context, namespaces []string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		reconciled = append(reconciled, namespaces)
+		if len(reconciled) == 1 {
+			return errors.New("storage unavailable")
+		}
+		close(done)
+		return nil
+	}
+
+	go func() {
+		<-done
+		cancel()
+	}()
+	err := operate(ctx, 10*time.Millisecond, nil, watch, reconcile)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "b"}, {"a", "b"}}, reconciled, "changes are debounced and retried after a failure")
+}
+
+func TestOperateWatchError(t *testing.T) {
+	watchErr := errors.New("forbidden")
+	err := operate(context.Background(), time.Millisecond, nil, func(ctx context.Context, onChange func(namespace string)) error {
+		return watchErr
+	}, func(ctx context.Context, namespaces []string) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, watchErr)
+}
+
+// reportStorager passes the stored reports of the namespaces to reports
+type reportStorager struct {
+	reports chan imagecollector.StorageReport
+}
+
+func (s *reportStorager) Store(ctx context.Context, report imagecollector.StorageReport) (imagecollector.StoreResult, error) {
+	s.reports <- report
+	return imagecollector.StoreResult{Location: report.FileName}, nil
+}
+
+func TestOperateDeletedLastPod(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:1.0"}}},
+	}
+	clientset := testclient.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}}, pod)
+	storager := &reportStorager{reports: make(chan imagecollector.StorageReport, 10)}
+	c, err := imagecollector.New(imagecollector.Options{
+		Defaults:     imagecollector.Image{Environment: "prod"},
+		OutputConfig: imagecollector.OutputConfig{SplitBy: "namespace"},
+		Clientset:    clientset,
+		Storager:     storager,
+	})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	reconcile := func(ctx context.Context, namespaces []string) error {
+		images, err := c.CollectNamespaces(ctx, namespaces)
+		if err != nil {
+			return err
+		}
+		_, err = c.PublishNamespaces(ctx, namespaces, images)
+		return err
+	}
+	operated := make(chan error, 1)
+	go func() {
+		operated <- operate(ctx, 10*time.Millisecond, nil, c.Watch, reconcile)
+	}()
+
+	nextReport := func() string {
+		t.Helper()
+		select {
+		case report := <-storager.reports:
+			return string(report.Content)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the report of the namespace to be stored")
+			return ""
+		}
+	}
+	assert.Contains(t, nextReport(), "app:1.0")
+
+	assert.NoError(t, clientset.CoreV1().Pods("ns").Delete(ctx, "app", metav1.DeleteOptions{}))
+	assert.Equal(t, "[]", strings.TrimSpace(nextReport()), "Expected the report to be cleared after the last pod was deleted")
+
+	cancel()
+	assert.NoError(t, <-operated)
+}
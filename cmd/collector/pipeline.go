@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/config"
+	imagecollector "github.com/SDA-SE/image-metadata-collector/pkg/collector"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// stdio is used as file name to read from stdin or write to stdout
+const stdio = "-"
+
+// newCollectCommand collects the images without storing them, so air-gapped pipelines can publish them from another
+// network zone
+func newCollectCommand(cfg *config.Config) *cobra.Command {
+	var out string
+	c := &cobra.Command{
+		Use:         "collect",
+		Short:       "Collect the images and write them to a file instead of the storage",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{resolveSecretsAnnotation: ""},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return collect(cfg, out)
+		},
+	}
+	c.Flags().StringVar(&out, "out", "images.json", "File to write the collected images to, '-' writes to stdout")
+	return c
+}
+
+// newPublishCommand stores images written by the collect command without access to the cluster
+func newPublishCommand(cfg *config.Config) *cobra.Command {
+	var in string
+	c := &cobra.Command{
+		Use:         "publish",
+		Short:       "Store images written by the collect command with the configured output format and storage",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{resolveSecretsAnnotation: ""},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return publish(cfg, in)
+		},
+	}
+	c.Flags().StringVar(&in, "in", "images.json", "File to read the collected images from, '-' reads from stdin")
+	return c
+}
+
+func collect(cfg *config.Config, out string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := handleShutdown(cancel, cfg.ShutdownTimeout)
+
+	opts := options(cfg)
+	opts.StorageConfig = imagecollector.StorageConfig{}
+	// The seen times and stale images are tracked when the images are published
+	opts.OutputConfig.TrackSeen = false
+	opts.OutputConfig.StaleRuns = 0
+	c, err := imagecollector.New(opts)
+	if err != nil {
+		return classify(err)
+	}
+
+	var images []imagecollector.Image
+	err = withTimeout(ctx, cfg.Timeout, func(ctx context.Context, phase *runPhase) error {
+		phase.Set(phaseCollecting)
+		images, err = c.Collect(ctx)
+		// The images file has no envelope, the errors of a partial collection are only logged
+		_, err = partialErrors(err)
+		return err
+	})
+	if err != nil {
+		if code := stop.ExitCode(); code != 0 {
+			return stoppedBySignal(code, classify(err))
+		}
+		return classify(err)
+	}
+
+	if out == stdio {
+		return imagecollector.WriteImages(os.Stdout, images)
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return withExitCode(ExitCodeConfig, fmt.Errorf("could not create %s: %w", out, err))
+	}
+	if err = imagecollector.WriteImages(file, images); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	log.Info().Str("fileName", out).Int("images", len(images)).Msg("Wrote collected images")
+	return nil
+}
+
+func publish(cfg *config.Config, in string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := handleShutdown(cancel, cfg.ShutdownTimeout)
+
+	var reader io.Reader = os.Stdin
+	if in != stdio {
+		file, err := os.Open(in)
+		if err != nil {
+			return withExitCode(ExitCodeConfig, fmt.Errorf("could not open %s: %w", in, err))
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	images, err := imagecollector.ReadImages(reader)
+	if err != nil {
+		return classify(err)
+	}
+
+	c, err := imagecollector.New(options(cfg))
+	if err != nil {
+		return classify(err)
+	}
+
+	if cfg.StorageConfig.Preflight {
+		if err = c.Check(ctx); err != nil {
+			return classify(err)
+		}
+	}
+
+	err = withTimeout(ctx, cfg.Timeout, func(ctx context.Context, phase *runPhase) error {
+		phase.Set(phaseLocking)
+		return withRunLock(ctx, cfg, stop.Stopping(), func(ctx context.Context) error {
+			phase.Set(phaseStoring)
+			report, err := c.Publish(ctx, images)
+			for _, result := range report.Results {
+				log.Info().Str("location", result.Location).Int("size", result.Size).Interface("metadata", result.Metadata).Msg("Stored collected images")
+			}
+			return err
+		})
+	})
+	if code := stop.ExitCode(); code != 0 {
+		return stoppedBySignal(code, classify(err))
+	}
+	return classify(err)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/collector"
+	"github.com/SDA-SE/image-metadata-collector/internal/config"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+const configChangeDebounce = 500 * time.Millisecond
+
+// watchConfig notifies on SIGHUP and whenever the config file changes. The directory of the file is watched as
+// mounted ConfigMaps are updated by swapping a symlink.
+func watchConfig(ctx context.Context, configFile string) <-chan struct{} {
+	reloads := make(chan struct{}, 1)
+	notify := func() {
+		// Pending reloads are coalesced, a single reload reads the latest state
+		select {
+		case reloads <- struct{}{}:
+		default:
+		}
+	}
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				signal.Stop(hangups)
+				return
+			case <-hangups:
+				log.Info().Msg("Received SIGHUP, reloading configuration")
+				notify()
+			}
+		}
+	}()
+
+	if configFile == "" {
+		return reloads
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not watch config file, only SIGHUP reloads the configuration")
+		return reloads
+	}
+	if err = watcher.Add(filepath.Dir(configFile)); err != nil {
+		watcher.Close()
+		log.Warn().Err(err).Msg("Could not watch config file, only SIGHUP reloads the configuration")
+		return reloads
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		// Editors and ConfigMap updates cause several events, the file is read once they settled
+		var debounce *time.Timer
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-watcher.Events:
+				name := filepath.Base(event.Name)
+				if name == filepath.Base(configFile) || name == "..data" {
+					if debounce != nil {
+						debounce.Stop()
+					}
+					debounce = time.AfterFunc(configChangeDebounce, func() {
+						log.Info().Str("event", event.String()).Msg("Config file changed, reloading configuration")
+						notify()
+					})
+				}
+			case err := <-watcher.Errors:
+				log.Warn().Err(err).Msg("Error watching config file")
+			}
+		}
+	}()
+
+	return reloads
+}
+
+// reloadConfig parses the command line arguments again, so flags, environment variables and the config file keep
+// their precedence
+func reloadConfig(args []string) (*config.Config, error) {
+	cfg := &config.Config{}
+	c := newCommand(cfg)
+	if err := c.ParseFlags(args); err != nil {
+		return nil, err
+	}
+	if err := initializeConfig(c); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyReload replaces the filters, defaults and annotation names of the running configuration, other settings
+// like the storage require a restart
+func applyReload(cfg *config.Config, args []string) error {
+	newCfg, err := reloadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	if err = collector.Validate(&newCfg.AnnotationNames, &newCfg.CollectorImage, &newCfg.RunConfig); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	var changes []config.Change
+	changes = append(changes, config.Diff(&cfg.RunConfig, &newCfg.RunConfig)...)
+	changes = append(changes, config.Diff(&cfg.CollectorImage, &newCfg.CollectorImage)...)
+	changes = append(changes, config.Diff(&cfg.AnnotationNames, &newCfg.AnnotationNames)...)
+
+	if len(changes) == 0 {
+		log.Info().Msg("Configuration reloaded, nothing changed")
+		return nil
+	}
+
+	cfg.RunConfig = newCfg.RunConfig
+	cfg.CollectorImage = newCfg.CollectorImage
+	cfg.AnnotationNames = newCfg.AnnotationNames
+
+	log.Info().Interface("changes", changes).Msg("Configuration reloaded")
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxRunRetryBackoff caps the exponential backoff between retries of a failed run
+const maxRunRetryBackoff = 5 * time.Minute
+
+// withRunRetries retries the whole run after a backoff while it fails with a retryable error, at most maxRetries
+// times. The backoff doubles with every retry. Retries stop as soon as a termination signal is received.
+func withRunRetries(ctx context.Context, maxRetries int, backoff time.Duration, stopping <-chan struct{}, run func(ctx context.Context) error) error {
+	err := run(ctx)
+	for retry := 1; retry <= maxRetries && isRetryable(ctx, err); retry++ {
+		wait := retryBackoff(backoff, retry)
+		log.Warn().Err(err).Int("retry", retry).Int("maxRetries", maxRetries).Dur("wait", wait).Msg("Run failed, retrying")
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-stopping:
+			timer.Stop()
+			return err
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		err = run(ctx)
+	}
+	return err
+}
+
+// retryBackoff doubles the backoff for every retry up to maxRunRetryBackoff, it is doubled step by step as a shift by
+// the retry count overflows with many retries
+func retryBackoff(backoff time.Duration, retry int) time.Duration {
+	wait := backoff
+	for i := 1; i < retry && wait < maxRunRetryBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxRunRetryBackoff)
+}
+
+// isRetryable reports whether a failed run may succeed when it is repeated, i.e. it failed because of the cluster,
+// the storage or a run timeout. Configuration errors and cancelled runs are not retried.
+func isRetryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	switch exitCode(err) {
+	case ExitCodeKube, ExitCodeStorage:
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRunRetries(t *testing.T) {
+	storageErr := withExitCode(ExitCodeStorage, errors.New("storage unavailable"))
+
+	runs := 0
+	err := withRunRetries(context.Background(), 3, time.Millisecond, nil, func(ctx context.Context) error {
+		runs++
+		if runs < 3 {
+			return storageErr
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, runs)
+
+	runs = 0
+	err = withRunRetries(context.Background(), 2, time.Millisecond, nil, func(ctx context.Context) error {
+		runs++
+		return storageErr
+	})
+	assert.ErrorIs(t, err, storageErr)
+	assert.Equal(t, 3, runs)
+
+	runs = 0
+	err = withRunRetries(context.Background(), 2, time.Millisecond, nil, func(ctx context.Context) error {
+		runs++
+		return withExitCode(ExitCodeConfig, errors.New("invalid"))
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, runs, "configuration errors are not retried")
+
+	stopping := make(chan struct{})
+	close(stopping)
+	runs = 0
+	err = withRunRetries(context.Background(), 2, time.Hour, stopping, func(ctx context.Context) error {
+		runs++
+		return storageErr
+	})
+	assert.ErrorIs(t, err, storageErr)
+	assert.Equal(t, 1, runs, "no retries after a termination signal")
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, retryBackoff(10*time.Second, 1))
+	assert.Equal(t, 40*time.Second, retryBackoff(10*time.Second, 3))
+	assert.Equal(t, maxRunRetryBackoff, retryBackoff(10*time.Second, 6))
+	assert.Equal(t, maxRunRetryBackoff, retryBackoff(10*time.Second, 100), "the backoff does not overflow")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// shutdown handles SIGTERM and SIGINT. The first signal stops scheduling new runs and gives an in-flight run the
+// grace period to finish its storage write, afterwards or on a second signal the run context is cancelled.
+type shutdown struct {
+	stopping chan struct{}
+
+	mu     sync.Mutex
+	signal syscall.Signal
+}
+
+func handleShutdown(cancel context.CancelFunc, gracePeriod time.Duration) *shutdown {
+	s := &shutdown{stopping: make(chan struct{})}
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	go func() {
+		sig := <-signals
+		s.mu.Lock()
+		s.signal = sig.(syscall.Signal)
+		s.mu.Unlock()
+		close(s.stopping)
+
+		log.Warn().Str("signal", sig.String()).Dur("gracePeriod", gracePeriod).Msg("Received signal, shutting down")
+
+		select {
+		case sig = <-signals:
+			log.Warn().Str("signal", sig.String()).Msg("Received second signal, aborting")
+		case <-time.After(gracePeriod):
+			log.Warn().Msg("Grace period exceeded, aborting")
+		}
+		cancel()
+	}()
+
+	return s
+}
+
+// Stopping is closed as soon as a termination signal was received
+func (s *shutdown) Stopping() <-chan struct{} {
+	return s.stopping
+}
+
+// ExitCode returns 128 + the signal number after a termination signal, like a shell does, otherwise 0
+func (s *shutdown) ExitCode() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.signal == 0 {
+		return 0
+	}
+	return 128 + int(s.signal)
+}
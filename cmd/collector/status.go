@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/config"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/status"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+	imagecollector "github.com/SDA-SE/image-metadata-collector/pkg/collector"
+
+	"github.com/rs/zerolog/log"
+)
+
+// statusTimeout bounds publishing the status, an unavailable API server must not delay the next run
+const statusTimeout = 10 * time.Second
+
+// statusReporter publishes the outcome of every run, a failure is logged and never fails the run
+type statusReporter struct {
+	reporter *status.Reporter
+}
+
+// newStatusReporter returns nil if neither events nor the ConfigMap are enabled
+func newStatusReporter(cfg *config.Config) (*statusReporter, error) {
+	if !cfg.StatusConfig.Enabled() {
+		return nil, nil
+	}
+
+	k8client, err := kubeclient.NewClient(&cfg.KubeConfig)
+	if err != nil {
+		return nil, withExitCode(ExitCodeKube, fmt.Errorf("could not create kubernetes client for the status: %w", err))
+	}
+	reporter, err := status.NewReporter(k8client.Clientset, &cfg.StatusConfig, version.Name)
+	if err != nil {
+		return nil, withExitCode(ExitCodeConfig, err)
+	}
+	return &statusReporter{reporter: reporter}, nil
+}
+
+// report publishes the outcome of the run which started at start
+func (s *statusReporter) report(ctx context.Context, start time.Time, report imagecollector.Report, err error) {
+	if s == nil {
+		return
+	}
+
+	outcome := status.Outcome{Start: start, Duration: time.Since(start), Images: len(report.Images), Errors: len(report.Errors), Err: err}
+	for _, image := range report.Images {
+		if image.Skip {
+			outcome.Skipped++
+		}
+	}
+
+	// The run context may already be cancelled after a failure
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), statusTimeout)
+	defer cancel()
+	if reportErr := s.reporter.Report(ctx, outcome); reportErr != nil {
+		log.Warn().Err(reportErr).Msg("Could not publish run status")
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// envPrefix is the prefix of the environment variables, e.g. COLLECTOR_S3_BUCKET for --s3-bucket
+var envPrefix = strings.ToUpper(AppName) + "_"
+
+// kubernetesServiceEnv matches the variables kubernetes injects for a service named 'collector', e.g.
+// COLLECTOR_SERVICE_HOST or COLLECTOR_PORT_8080_TCP
+var kubernetesServiceEnv = []string{envPrefix + "SERVICE_", envPrefix + "PORT"}
+
+// checkConfigKeys reports config file keys and COLLECTOR_* environment variables which are silently ignored
+// otherwise, e.g. a typo like COLLECTOR_S3_BUKET. With strict set they and deprecated flags fail the startup,
+// otherwise pflag already warned about the deprecated flags.
+func checkConfigKeys(cmd *cobra.Command, v *viper.Viper, environ []string, strict bool) error {
+	flags := allFlags(cmd.Root())
+
+	names := make([]string, 0, len(flags))
+	envNames := map[string]string{}
+	for name, flag := range flags {
+		envNames[envName(name)] = name
+		// Deprecated aliases are known but not suggested
+		if flag.Deprecated == "" {
+			names = append(names, name)
+		}
+	}
+
+	var problems []string
+
+	for _, key := range v.AllKeys() {
+		if _, ok := flags[key]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown config file key %s%s", key, suggest(key, names)))
+		}
+	}
+
+	for _, env := range environ {
+		name, _, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, envPrefix) || isKubernetesServiceEnv(name) {
+			continue
+		}
+		if _, ok := envNames[name]; !ok {
+			flagName := strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(name, envPrefix)), "_", "-")
+			problems = append(problems, fmt.Sprintf("unknown environment variable %s%s", name, suggestEnv(flagName, names)))
+		}
+	}
+
+	slices.Sort(problems)
+	if !strict {
+		for _, problem := range problems {
+			log.Warn().Msg(problem)
+		}
+		return nil
+	}
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed && f.Deprecated != "" {
+			problems = append(problems, fmt.Sprintf("flag %s is deprecated, %s", f.Name, f.Deprecated))
+		}
+	})
+
+	errs := make([]error, len(problems))
+	for i, problem := range problems {
+		errs[i] = errors.New(problem)
+	}
+	return errors.Join(errs...)
+}
+
+// allFlags returns the lower cased names of the flags of all commands, the config file is shared by all of them
+func allFlags(root *cobra.Command) map[string]*pflag.Flag {
+	flags := map[string]*pflag.Flag{}
+	commands := []*cobra.Command{root}
+	for len(commands) > 0 {
+		c := commands[0]
+		commands = append(commands[1:], c.Commands()...)
+
+		visit := func(f *pflag.Flag) { flags[strings.ToLower(f.Name)] = f }
+		c.PersistentFlags().VisitAll(visit)
+		c.LocalNonPersistentFlags().VisitAll(visit)
+	}
+	return flags
+}
+
+// envName returns the environment variable viper reads for the flag
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func isKubernetesServiceEnv(name string) bool {
+	for _, prefix := range kubernetesServiceEnv {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggest returns ', did you mean <name>?' for the most similar flag name, if any is similar enough
+func suggest(name string, names []string) string {
+	if closest := closestName(name, names); closest != "" {
+		return fmt.Sprintf(", did you mean %s?", closest)
+	}
+	return ""
+}
+
+func suggestEnv(name string, names []string) string {
+	if closest := closestName(name, names); closest != "" {
+		return fmt.Sprintf(", did you mean %s?", envName(closest))
+	}
+	return ""
+}
+
+// closestName returns the name with the smallest edit distance, names further away than a third of the length are
+// not considered similar
+func closestName(name string, names []string) string {
+	closest, best := "", len(name)/3+1
+	for _, candidate := range names {
+		if d := levenshtein(name, candidate); d < best || (d == best && closest != "" && candidate < closest) {
+			closest, best = candidate, d
+		}
+	}
+	return closest
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
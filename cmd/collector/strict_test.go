@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConfigKeys(t *testing.T) {
+	cmd := newCommand(&config.Config{})
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.NoError(t, v.ReadConfig(strings.NewReader("s3-bucket: reports\ns3-buket: typo\ninterval: 15m\n")))
+
+	environ := []string{
+		"COLLECTOR_S3_BUCKET=reports",
+		"COLLECTOR_S3_BUKET=typo",
+		"COLLECTOR_SCANLIFETIMEMAXDAYS=30",
+		"COLLECTOR_SERVICE_HOST=10.0.0.1",
+		"COLLECTOR_PORT_8080_TCP=tcp://10.0.0.1:8080",
+		"HOME=/root",
+	}
+
+	assert.NoError(t, checkConfigKeys(cmd, v, environ, false))
+
+	err := checkConfigKeys(cmd, v, environ, true)
+	assert.EqualError(t, err, "unknown config file key s3-buket, did you mean s3-bucket?\nunknown environment variable COLLECTOR_S3_BUKET, did you mean COLLECTOR_S3_BUCKET?")
+
+	assert.NoError(t, checkConfigKeys(cmd, viper.New(), []string{"COLLECTOR_OUT=images.json"}, true), "flags of subcommands are known")
+}
+
+func TestClosestName(t *testing.T) {
+	names := []string{"s3-bucket", "s3-region", "storage"}
+
+	assert.Equal(t, "s3-bucket", closestName("s3-buket", names))
+	assert.Equal(t, "storage", closestName("storag", names))
+	assert.Equal(t, "", closestName("completely-different", names))
+}
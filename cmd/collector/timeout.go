@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Phases of a run, reported when the run times out
+const (
+	phaseStarting   = "starting"
+	phaseLocking    = "waiting for the run lock"
+	phaseCollecting = "collecting images"
+	phaseStoring    = "storing the report"
+)
+
+// runPhase tracks the phase of a run, so a timeout tells where the run was stuck
+type runPhase struct {
+	mu   sync.Mutex
+	name string
+}
+
+func (p *runPhase) Set(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.name = name
+}
+
+func (p *runPhase) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.name
+}
+
+// withTimeout bounds fn by the timeout, 0 disables it. The phase in progress is logged as soon as the deadline hits,
+// even if fn does not return, and is added to the error of fn.
+func withTimeout(ctx context.Context, timeout time.Duration, fn func(ctx context.Context, phase *runPhase) error) error {
+	phase := &runPhase{name: phaseStarting}
+	if timeout <= 0 {
+		return fn(ctx, phase)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stop := context.AfterFunc(ctx, func() {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			log.Error().Str("phase", phase.String()).Dur("timeout", timeout).Msg("Run timed out")
+		}
+	})
+	defer stop()
+
+	err := fn(ctx, phase)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("run timed out after %s while %s: %w", timeout, phase, err)
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTimeout(t *testing.T) {
+	err := withTimeout(context.Background(), 10*time.Millisecond, func(ctx context.Context, phase *runPhase) error {
+		phase.Set(phaseStoring)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "run timed out after 10ms while storing the report")
+
+	errFailed := errors.New("failed")
+	err = withTimeout(context.Background(), time.Minute, func(ctx context.Context, phase *runPhase) error {
+		return errFailed
+	})
+	assert.Equal(t, errFailed, err)
+
+	err = withTimeout(context.Background(), 0, func(ctx context.Context, phase *runPhase) error {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok, "Expected no deadline without timeout")
+		return nil
+	})
+	assert.NoError(t, err)
+}
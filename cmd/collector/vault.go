@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/redact"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/vault"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/pflag"
+)
+
+// vaultLoginTimeout bounds logging in and reading the secrets
+const vaultLoginTimeout = 30 * time.Second
+
+// envTargetPrefix marks Vault secrets assigned to environment variables, e.g. the AWS credentials of the s3 storage
+const envTargetPrefix = "env:"
+
+// vaultSecrets read the secrets at startup, the collector running continuously renews the token and reads them again
+// between runs to pick up rotated secrets
+var vaultSecrets *secretReader
+
+// secretReader assigns the mapped Vault secrets to their flags or environment variables
+type secretReader struct {
+	client  *vault.Client
+	flags   *pflag.FlagSet
+	secrets []vault.Secret
+	values  map[string]string
+}
+
+// applyVaultSecrets reads the mapped Vault secrets into their flags or environment variables, it returns nil if
+// Vault is not configured. Secret values are masked in the log output.
+func applyVaultSecrets(flags *pflag.FlagSet, cfg *vault.VaultConfig) (*secretReader, error) {
+	if cfg.VaultAddress == "" {
+		if len(cfg.VaultSecrets) > 0 {
+			return nil, fmt.Errorf("vault-secret requires vault-address")
+		}
+		return nil, nil
+	}
+
+	secrets := make([]vault.Secret, 0, len(cfg.VaultSecrets))
+	for _, mapping := range cfg.VaultSecrets {
+		secret, err := vault.ParseSecret(mapping)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(secret.Target, envTargetPrefix) && flags.Lookup(secret.Target) == nil {
+			return nil, fmt.Errorf("Vault secret %s is assigned to the unknown flag %s", mapping, secret.Target)
+		}
+		secrets = append(secrets, secret)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vaultLoginTimeout)
+	defer cancel()
+
+	client, err := vault.Login(ctx, &http.Client{Transport: version.Transport(nil), Timeout: vaultLoginTimeout}, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &secretReader{client: client, flags: flags, secrets: secrets, values: map[string]string{}}
+	if _, err = reader.read(ctx); err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// read assigns the current values of the secrets and reports whether any of them changed since the last read
+func (r *secretReader) read(ctx context.Context) (bool, error) {
+	changed := false
+	for _, secret := range r.secrets {
+		value, err := r.client.Read(ctx, secret)
+		if err != nil {
+			return changed, err
+		}
+		if previous, ok := r.values[secret.Target]; ok && previous == value {
+			continue
+		}
+		redact.Register(value)
+
+		if name, ok := strings.CutPrefix(secret.Target, envTargetPrefix); ok {
+			err = os.Setenv(name, value)
+		} else {
+			err = r.flags.Set(secret.Target, value)
+		}
+		if err != nil {
+			return changed, fmt.Errorf("could not assign Vault secret %s: %w", secret.Path, err)
+		}
+		if _, ok := r.values[secret.Target]; ok {
+			changed = true
+		}
+		r.values[secret.Target] = value
+		log.Debug().Str("target", secret.Target).Str("path", secret.Path).Msg("Read secret from Vault")
+	}
+	return changed, nil
+}
+
+// refresh reads the secrets again between the runs of a collector running continuously and reports whether they
+// changed, a failure keeps the current values
+func (r *secretReader) refresh(ctx context.Context) bool {
+	if r == nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, vaultLoginTimeout)
+	defer cancel()
+
+	changed, err := r.read(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Could not read the Vault secrets again, keeping the current ones")
+	}
+	if changed {
+		log.Info().Msg("Vault secrets changed")
+	}
+	return changed
+}
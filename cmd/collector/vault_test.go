@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/config"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/vault"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaultSecretsRefresh(t *testing.T) {
+	apiKey := "first"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/collector" || r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"api-key":"` + apiKey + `","region":"eu-central-1"}}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{}
+	cmd := newCommand(cfg)
+	t.Setenv("COLLECTOR_TEST_REGION", "")
+	reader, err := applyVaultSecrets(cmd.PersistentFlags(), &vault.VaultConfig{
+		VaultAddress: server.URL,
+		VaultToken:   "vault-token",
+		VaultSecrets: []string{"api-key=secret/collector#api-key", "env:COLLECTOR_TEST_REGION=secret/collector#region"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "first", cfg.StorageConfig.ApiKey)
+	assert.Equal(t, "eu-central-1", os.Getenv("COLLECTOR_TEST_REGION"))
+
+	assert.False(t, reader.refresh(context.Background()), "Expected unchanged secrets")
+
+	apiKey = "rotated"
+	assert.True(t, reader.refresh(context.Background()), "Expected the rotated secret")
+	assert.Equal(t, "rotated", cfg.StorageConfig.ApiKey)
+
+	server.Close()
+	assert.False(t, reader.refresh(context.Background()), "Expected the current secrets to be kept if Vault fails")
+	assert.Equal(t, "rotated", cfg.StorageConfig.ApiKey)
+
+	assert.False(t, (*secretReader)(nil).refresh(context.Background()), "Expected nothing to refresh without Vault")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/config"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/admission"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// newWebhookCommand runs the validating admission webhook which records the images of created pods, the runs of the
+// collector merge them with the same --admission-configmap
+func newWebhookCommand(cfg *config.Config) *cobra.Command {
+	c := &cobra.Command{
+		Use:         "webhook",
+		Short:       "Record the images of created pods as validating admission webhook, so runs report short-lived pods as well",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{resolveSecretsAnnotation: ""},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return serveWebhook(cfg)
+		},
+	}
+	c.Flags().StringVar(&cfg.WebhookConfig.WebhookListenAddress, "webhook-listen-address", ":8443", "Address of the HTTPS server receiving the AdmissionReviews on /validate")
+	c.Flags().StringVar(&cfg.WebhookConfig.WebhookTlsCertFile, "webhook-tls-cert-file", "", "PEM encoded serving certificate of the webhook, its CA is the caBundle of the ValidatingWebhookConfiguration")
+	c.Flags().StringVar(&cfg.WebhookConfig.WebhookTlsKeyFile, "webhook-tls-key-file", "", "PEM encoded key of the serving certificate")
+	c.Flags().DurationVar(&cfg.WebhookConfig.WebhookFlushInterval, "webhook-flush-interval", 10*time.Second, "Write the recorded images to the ConfigMap at this interval, so a burst of pods updates it once")
+	return c
+}
+
+// serveWebhook records the admitted images until a termination signal, the recorded images are written once more
+// before it exits
+func serveWebhook(cfg *config.Config) error {
+	switch {
+	case !cfg.AdmissionConfig.Enabled():
+		return withExitCode(ExitCodeConfig, errors.New("the webhook requires --admission-configmap"))
+	case cfg.WebhookConfig.WebhookTlsCertFile == "" || cfg.WebhookConfig.WebhookTlsKeyFile == "":
+		return withExitCode(ExitCodeConfig, errors.New("the webhook requires --webhook-tls-cert-file and --webhook-tls-key-file, the API server only calls webhooks via HTTPS"))
+	case cfg.WebhookConfig.WebhookFlushInterval <= 0:
+		return withExitCode(ExitCodeConfig, errors.New("--webhook-flush-interval must be positive"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := handleShutdown(cancel, cfg.ShutdownTimeout)
+
+	log.Info().Str("version", version.Version).Str("commit", version.Get().Commit).Msg("Starting " + version.Name + " webhook")
+
+	k8client, err := kubeclient.NewClient(&cfg.KubeConfig)
+	if err != nil {
+		return withExitCode(ExitCodeKube, fmt.Errorf("could not create kubernetes client for the webhook: %w", err))
+	}
+	recorder, err := admission.NewRecorder(k8client.Clientset, &cfg.AdmissionConfig)
+	if err != nil {
+		return withExitCode(ExitCodeConfig, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate", admission.Handler(recorder))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	srv := &http.Server{Addr: cfg.WebhookConfig.WebhookListenAddress, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	recordCtx, stopRecording := context.WithCancel(ctx)
+	flushed := make(chan struct{})
+	go func() {
+		recorder.Run(recordCtx, cfg.WebhookConfig.WebhookFlushInterval)
+		close(flushed)
+	}()
+
+	served := make(chan error, 1)
+	go func() {
+		log.Info().Str("address", srv.Addr).Msg("Starting webhook server")
+		served <- srv.ListenAndServeTLS(cfg.WebhookConfig.WebhookTlsCertFile, cfg.WebhookConfig.WebhookTlsKeyFile)
+	}()
+
+	select {
+	case err = <-served:
+		err = fmt.Errorf("webhook server failed: %w", err)
+	case <-stop.Stopping():
+		shutdownCtx, cancelShutdown := context.WithTimeout(ctx, 5*time.Second)
+		_ = srv.Shutdown(shutdownCtx)
+		cancelShutdown()
+	}
+
+	// The requests admitted until the server stopped are written by the final flush
+	stopRecording()
+	<-flushed
+
+	if code := stop.ExitCode(); code != 0 {
+		return stoppedBySignal(code, err)
+	}
+	return withExitCode(ExitCodeFailure, err)
+}
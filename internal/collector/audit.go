@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Reasons for skipping an image
+const (
+	SkipReasonAnnotation             = "annotation"
+	SkipReasonNamespaceFilter        = "namespace-filter"
+	SkipReasonNegatedNamespaceFilter = "negated-namespace-filter"
+	SkipReasonImageFilter            = "image-filter"
+	SkipReasonFilterRule             = "filter-rule"
+	SkipReasonWindows                = "windows"
+	SkipReasonSuspended              = "suspended"
+)
+
+type SkipAuditConfig struct {
+	// SkipAuditFile writes the skip decisions to a local file, '-' for stdout
+	SkipAuditFile string
+	// SkipAuditObject stores the skip decisions with the configured storage under this name
+	SkipAuditObject string
+}
+
+// SkipDecision records why an image is left out of the report. Detail is the matching filter, empty for the skip
+// annotation.
+type SkipDecision struct {
+	Namespace string `json:"namespace"`
+	Image     string `json:"image"`
+	ImageId   string `json:"image_id"`
+	Reason    string `json:"reason"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// CountSkipReasons returns the number of skipped images by reason
+func CountSkipReasons(decisions []SkipDecision) map[string]int {
+	counts := map[string]int{}
+	for _, decision := range decisions {
+		counts[decision.Reason]++
+	}
+	return counts
+}
+
+// WriteSkipDecisions writes one json object per line
+func WriteSkipDecisions(w io.Writer, decisions []SkipDecision) error {
+	encoder := json.NewEncoder(w)
+	for _, decision := range decisions {
+		if err := encoder.Encode(decision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
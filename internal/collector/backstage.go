@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// backstageFields are the image fields Backstage annotations can be mapped to
+var backstageFields = map[string]func(*CollectorImage) *string{
+	"product":     func(ci *CollectorImage) *string { return &ci.Product },
+	"description": func(ci *CollectorImage) *string { return &ci.Description },
+	"team":        func(ci *CollectorImage) *string { return &ci.Team },
+	"slack":       func(ci *CollectorImage) *string { return &ci.Slack },
+	"email":       func(ci *CollectorImage) *string { return &ci.Email },
+}
+
+// withBackstageDefaults returns the defaults overridden by the mapped Backstage annotations, so the annotations of
+// the collector still take precedence. The mapping is in the format '<annotation>=<field>', e.g.
+// 'backstage.io/owner=team'.
+func withBackstageDefaults(tags map[string]string, defaults *CollectorImage, mapping []string) *CollectorImage {
+	if len(mapping) == 0 {
+		return defaults
+	}
+
+	withBackstage := *defaults
+	for _, rule := range mapping {
+		annotation, field, _ := strings.Cut(rule, "=")
+		value, ok := tags[annotation]
+		target, known := backstageFields[field]
+		if !ok || !known || value == "" {
+			continue
+		}
+		if field == "team" {
+			value = entityName(value)
+		}
+		*target(&withBackstage) = value
+	}
+	return &withBackstage
+}
+
+// entityName returns the name of a Backstage entity reference like 'group:default/platform'
+func entityName(ref string) string {
+	if _, name, found := strings.Cut(ref, ":"); found {
+		ref = name
+	}
+	if _, name, found := strings.Cut(ref, "/"); found {
+		ref = name
+	}
+	return ref
+}
+
+// validateBackstageMapping checks the format and the fields of the mapping
+func validateBackstageMapping(mapping []string) []error {
+	var errs []error
+	for _, rule := range mapping {
+		annotation, field, ok := strings.Cut(rule, "=")
+		if !ok || annotation == "" {
+			errs = append(errs, fmt.Errorf("Backstage mapping %q has to be in the format annotation=field", rule))
+			continue
+		}
+		if _, known := backstageFields[field]; !known {
+			fields := make([]string, 0, len(backstageFields))
+			for name := range backstageFields {
+				fields = append(fields, name)
+			}
+			slices.Sort(fields)
+			errs = append(errs, fmt.Errorf("Backstage mapping %q has an unknown field, supported are %v", rule, fields))
+		}
+	}
+	return errs
+}
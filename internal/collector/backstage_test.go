@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertBackstage(t *testing.T) {
+	annotationNames := &AnnotationNames{
+		Base:             "sdase.org/",
+		Contact:          "contact.sdase.org/",
+		BackstageMapping: []string{"backstage.io/kubernetes-id=product", "backstage.io/owner=team", "backstage.io/unused=email"},
+	}
+	defaults := &CollectorImage{Product: "default-product", Team: "default-team", Email: "default@example.com"}
+
+	testCases := []struct {
+		name            string
+		annotations     map[string]string
+		expectedProduct string
+		expectedTeam    string
+	}{
+		{
+			name:            "NoAnnotations",
+			expectedProduct: "default-product",
+			expectedTeam:    "default-team",
+		},
+		{
+			name:            "Backstage",
+			annotations:     map[string]string{"backstage.io/kubernetes-id": "payments", "backstage.io/owner": "group:default/platform"},
+			expectedProduct: "payments",
+			expectedTeam:    "platform",
+		},
+		{
+			name:            "CollectorAnnotationsTakePrecedence",
+			annotations:     map[string]string{"backstage.io/kubernetes-id": "payments", "backstage.io/owner": "platform", "contact.sdase.org/team": "checkout"},
+			expectedProduct: "payments",
+			expectedTeam:    "checkout",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			image := convertK8ImageToCollectorImage(kubeclient.Image{Image: "nginx", Annotations: tc.annotations}, defaults, annotationNames, &RunConfig{})
+
+			assert.Equal(t, tc.expectedProduct, image.Product)
+			assert.Equal(t, tc.expectedTeam, image.Team)
+			assert.Equal(t, "default@example.com", image.Email)
+		})
+	}
+	assert.Equal(t, "default-product", defaults.Product, "Expected the defaults to be unchanged")
+}
+
+func TestEntityName(t *testing.T) {
+	assert.Equal(t, "platform", entityName("group:default/platform"))
+	assert.Equal(t, "jdoe", entityName("user:jdoe"))
+	assert.Equal(t, "platform", entityName("platform"))
+}
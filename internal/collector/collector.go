@@ -1,13 +1,19 @@
 package collector
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"maps"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
 
 	"github.com/rs/zerolog/log"
 )
@@ -17,6 +23,10 @@ type AnnotationNames struct {
 	Scans      string
 	Contact    string
 	DefectDojo string
+
+	// BackstageMapping maps Backstage annotations to image fields in the format '<annotation>=<field>', they are
+	// used if the image has no annotation of the collector for the field
+	BackstageMapping []string
 }
 
 type CollectorImage struct {
@@ -52,104 +62,323 @@ type CollectorImage struct {
 	IsScanRunAsPrivileged            bool  `json:"is_scan_run_as_privileged"`
 	IsPotentiallyRunningAsPrivileged bool  `json:"is_scan_potentially_running_as_privileged"`
 	ScanLifetimeMaxDays              int64 `json:"scan_lifetime_max_days"`
+
+	// Fields from the image config in the registry, only set with the registry enrichment
+	ImageCreated  string `json:"image_created,omitempty"`
+	ImageSource   string `json:"image_source,omitempty"`
+	ImageRevision string `json:"image_revision,omitempty"`
+
+	// Attachments in the registry, only set with the SBOM enrichment
+	HasSbom        bool   `json:"has_sbom,omitempty"`
+	SbomFormat     string `json:"sbom_format,omitempty"`
+	HasAttestation bool   `json:"has_attestation,omitempty"`
+	HasSignature   bool   `json:"has_signature,omitempty"`
+
+	// Fields from the Harbor project of the image, only set for registries with a Harbor endpoint
+	HarborProjectOwner      string `json:"harbor_project_owner,omitempty"`
+	HarborSeverityThreshold string `json:"harbor_severity_threshold,omitempty"`
+	HarborScanStatus        string `json:"harbor_scan_status,omitempty"`
+
+	// ImageMismatch is set if the repository of the running image id differs from the image of the pod spec, e.g.
+	// after an admission webhook mutated the pod or a stale image cache of the kubelet. StatusImage is the repository
+	// of the running image id then, e.g. mirror.local/sdase/app.
+	ImageMismatch bool   `json:"image_mismatch,omitempty"`
+	StatusImage   string `json:"status_image,omitempty"`
+
+	// IsInternalRegistry is set for images of the registries given by --internal-registries
+	IsInternalRegistry bool `json:"is_internal_registry,omitempty"`
+
+	// IsWindows is set for images of pods running on Windows nodes
+	IsWindows bool `json:"is_windows,omitempty"`
+
+	// Terminated is set for images of succeeded and failed pods, e.g. of a completed CronJob run
+	Terminated bool `json:"terminated,omitempty"`
+	// Suspended is set for images of the Jobs of suspended CronJobs, the workload is dormant
+	Suspended bool `json:"suspended,omitempty"`
+
+	// Replicas is the number of pods in the namespace running the image, e.g. to weigh the risk of an image of a
+	// large Deployment higher than the one of a single debug pod
+	Replicas int `json:"replicas,omitempty"`
+
+	// WorkloadKind and Workload name the workload owning the pods, e.g. a Deployment, see GroupWorkloads
+	WorkloadKind string `json:"workload_kind,omitempty"`
+	Workload     string `json:"workload,omitempty"`
+
+	// ContainerPorts are the ports declared by the containers running the image
+	ContainerPorts []int32 `json:"container_ports,omitempty"`
+
+	// Exposure is LoadBalancer or Ingress if the pods are exposed outside the cluster by a Service of this kind, so
+	// internet-facing images can be scanned first
+	Exposure string `json:"exposure,omitempty"`
+
+	// RiskScore is the sum of the weights of the RiskFactors applying to the image, see ScoreImages
+	RiskScore   int      `json:"risk_score,omitempty"`
+	RiskFactors []string `json:"risk_factors,omitempty"`
+
+	// PullError is set if the image currently can't be pulled, e.g. ImagePullBackOff after its tag was deleted
+	PullError string `json:"pull_error,omitempty"`
+
+	// Fields of the Helm release, only set for images deployed with Helm
+	HelmChart        string `json:"helm_chart,omitempty"`
+	HelmChartVersion string `json:"helm_chart_version,omitempty"`
+	HelmRelease      string `json:"helm_release,omitempty"`
+
+	// The Flux Kustomization and HelmRelease which deployed the image in the format '<namespace>/<name>'
+	FluxKustomization string `json:"flux_kustomization,omitempty"`
+	FluxHelmRelease   string `json:"flux_helmrelease,omitempty"`
+
+	// FirstSeen and LastSeen are the times of the first and the last run which reported the image in its namespace,
+	// only set if the output tracks them, see TrackSeen
+	FirstSeen string `json:"first_seen,omitempty"`
+	LastSeen  string `json:"last_seen,omitempty"`
+
+	// Stale images were not reported by the last MissedRuns runs and are kept until the grace period ends, see
+	// KeepStale
+	Stale      bool `json:"stale,omitempty"`
+	MissedRuns int  `json:"missed_runs,omitempty"`
+
+	// PullSecrets are the imagePullSecrets of the pod in the format '<namespace>/<name>', the registry enrichment
+	// authenticates with them, they are not reported
+	PullSecrets []string `json:"-"`
 }
 
 type RunConfig struct {
 	ImageFilter     []string
 	NamespaceToTeam []string
+
+	// InternalRegistries are the hostnames of the registries operated by the organization, images of other registries
+	// are pulled from public registries
+	InternalRegistries []string
+
+	// SkipWindows skips the images of pods running on Windows nodes
+	SkipWindows bool
+	// SkipSuspended skips the images of suspended CronJobs
+	SkipSuspended bool
+
+	// NormalizeImages expands the images and image ids to their full reference, see NormalizeImage
+	NormalizeImages bool
+
+	// MetadataPrecedence selects the labels and annotations the team, contact and scan configuration is read from,
+	// MetadataPrecedenceMerged by default
+	MetadataPrecedence string
+
+	// FilterRulesFile holds ordered allow and deny rules, which are evaluated after the skip annotation and the
+	// namespace filters and before the image filters. It is read on every run.
+	FilterRulesFile string
+	filterRules     []FilterRule
+
+	// collectedAt is the time of the collection, skip-until annotations are compared against it
+	collectedAt time.Time
 }
 
+// Precedence policies of the metadata of pods and namespaces
+const (
+	// MetadataPrecedenceMerged merges the metadata of pods and namespaces, the namespace wins on conflicts
+	MetadataPrecedenceMerged = "merged"
+	// MetadataPrecedenceNamespaceOnly ignores the metadata of pods, for organizations that forbid per-pod overrides
+	MetadataPrecedenceNamespaceOnly = "namespace-only"
+)
+
+// ContainerTypeSidecar is the container type of the images of native sidecars unless annotated otherwise
+const ContainerTypeSidecar = "sidecar"
+
 // convertK8ImageToCollectorImage by considering the images labels, annotations and cluster wide defaults
-func convertK8ImageToCollectorImage(k8Image kubeclient.Image, defaults *CollectorImage, annotationNames *AnnotationNames) *CollectorImage {
-	tags := k8Image.Labels
-	if tags == nil {
-		tags = k8Image.Annotations
-	} else {
-		maps.Copy(tags, k8Image.Annotations)
+func convertK8ImageToCollectorImage(k8Image kubeclient.Image, defaults *CollectorImage, annotationNames *AnnotationNames, runConfig *RunConfig) *CollectorImage {
+	tags := imageTags(k8Image)
+
+	// The team, contact and scan configuration is read from the metadata selected by the precedence policy
+	metadata := tags
+	if runConfig.MetadataPrecedence == MetadataPrecedenceNamespaceOnly {
+		metadata = map[string]string{}
+		maps.Copy(metadata, k8Image.NamespaceLabels)
+		maps.Copy(metadata, k8Image.NamespaceAnnotations)
 	}
+	defaults = withBackstageDefaults(metadata, defaults, annotationNames.BackstageMapping)
 
-	collectorImage := &CollectorImage{
-		Namespace: k8Image.NamespaceName,
-		Image:     k8Image.Image,
-		ImageId:   k8Image.ImageId,
+	// Native sidecars are of type sidecar unless a container-type annotation names their container, e.g.
+	// container-type.envoy, which takes precedence over the container-type annotation of the pod for any container
+	containerType := GetOrDefaultString(metadata, annotationNames.Base+"container-type", defaults.ContainerType)
+	if k8Image.Sidecar {
+		containerType = ContainerTypeSidecar
+	}
+	if k8Image.Container != "" {
+		containerType = GetOrDefaultString(metadata, annotationNames.Base+"container-type."+k8Image.Container, containerType)
+	}
 
-		Environment:            GetOrDefaultString(tags, annotationNames.Base+"environment", defaults.Environment),
-		Product:                GetOrDefaultString(tags, annotationNames.Base+"product", defaults.Product),
-		Description:            GetOrDefaultString(tags, annotationNames.Base+"description", defaults.Description),
+	collectorImage := &CollectorImage{
+		Namespace:      k8Image.NamespaceName,
+		Image:          k8Image.Image,
+		ImageId:        k8Image.ImageId,
+		PullError:      k8Image.PullError,
+		IsWindows:      k8Image.OperatingSystem == kubeclient.OperatingSystemWindows,
+		Terminated:     k8Image.Terminated,
+		Suspended:      k8Image.Suspended,
+		Replicas:       k8Image.Replicas,
+		WorkloadKind:   k8Image.WorkloadKind,
+		Workload:       k8Image.Workload,
+		ContainerPorts: k8Image.ContainerPorts,
+		Exposure:       k8Image.Exposure,
+
+		Environment:            GetOrDefaultString(metadata, annotationNames.Base+"environment", defaults.Environment),
+		Product:                GetOrDefaultString(metadata, annotationNames.Base+"product", defaults.Product),
+		Description:            GetOrDefaultString(metadata, annotationNames.Base+"description", defaults.Description),
 		AppKubernetesIoName:    GetOrDefaultString(tags, "app.kubernetes.io/name", ""),
 		AppKubernetesIoVersion: GetOrDefaultString(tags, "app.kubernetes.io/version", ""),
-		ContainerType:          GetOrDefaultString(tags, annotationNames.Base+"container-type", defaults.ContainerType),
-		Skip:                   GetOrDefaultBool(tags, annotationNames.Scans+"skip", defaults.Skip),
-		NamespaceFilter:        GetOrDefaultString(tags, annotationNames.Scans+"namespace-filter", defaults.NamespaceFilter),
-		NamespaceFilterNegated: GetOrDefaultString(tags, annotationNames.Scans+"negated_namespace_filter", defaults.NamespaceFilterNegated),
-		EngagementTags:         GetOrDefaultStringSlice(tags, annotationNames.DefectDojo+"engagement-tags", defaults.EngagementTags),
-
-		Team:  GetOrDefaultString(tags, annotationNames.Contact+"team", defaults.Team),
-		Slack: GetOrDefaultString(tags, annotationNames.Contact+"slack", defaults.Slack),
-		Email: GetOrDefaultString(tags, annotationNames.Contact+"email", defaults.Email),
-
-		IsScanBaseimageLifetime:          GetOrDefaultBool(tags, annotationNames.Scans+"is-scan-baseimage-lifetime", defaults.IsScanBaseimageLifetime),
-		IsScanDependencyCheck:            GetOrDefaultBool(tags, annotationNames.Scans+"is-scan-dependency-check", defaults.IsScanDependencyCheck),
-		IsScanDependencyTrack:            GetOrDefaultBool(tags, annotationNames.Scans+"is-scan-dependency-track", defaults.IsScanDependencyTrack),
-		IsScanDistroless:                 GetOrDefaultBool(tags, annotationNames.Scans+"is-scan-distroless", defaults.IsScanDistroless),
-		IsScanLifetime:                   GetOrDefaultBool(tags, annotationNames.Scans+"is-scan-lifetime", defaults.IsScanLifetime),
-		IsScanMalware:                    GetOrDefaultBool(tags, annotationNames.Scans+"is-scan-malware", defaults.IsScanMalware),
-		IsScanNewVersion:                 GetOrDefaultBool(tags, annotationNames.Scans+"is-scan-new-version", defaults.IsScanNewVersion),
-		IsScanRunAsRoot:                  GetOrDefaultBool(tags, annotationNames.Scans+"is-scan-runasroot", defaults.IsScanRunAsRoot),
-		IsPotentiallyRunningAsRoot:       GetOrDefaultBool(tags, annotationNames.Scans+"is-scan-potentially-running-as-root", defaults.IsPotentiallyRunningAsRoot),
-		IsScanRunAsPrivileged:            GetOrDefaultBool(tags, annotationNames.Scans+"is-scan-run-as-privileged", defaults.IsScanRunAsPrivileged),
-		IsPotentiallyRunningAsPrivileged: GetOrDefaultBool(tags, annotationNames.Scans+"is-scan-potentially-running-as-privileged", defaults.IsPotentiallyRunningAsPrivileged),
-		ScanLifetimeMaxDays:              GetOrDefaultInt64(tags, annotationNames.Scans+"scan-lifetime-max-days", defaults.ScanLifetimeMaxDays),
+		ContainerType:          containerType,
+		Skip:                   GetOrDefaultBool(metadata, annotationNames.Scans+"skip", defaults.Skip),
+		NamespaceFilter:        GetOrDefaultString(metadata, annotationNames.Scans+"namespace-filter", defaults.NamespaceFilter),
+		NamespaceFilterNegated: GetOrDefaultString(metadata, annotationNames.Scans+"negated_namespace_filter", defaults.NamespaceFilterNegated),
+		EngagementTags:         GetOrDefaultStringSlice(metadata, annotationNames.DefectDojo+"engagement-tags", defaults.EngagementTags),
+
+		Team:  GetOrDefaultString(metadata, annotationNames.Contact+"team", defaults.Team),
+		Slack: GetOrDefaultString(metadata, annotationNames.Contact+"slack", defaults.Slack),
+		Email: GetOrDefaultString(metadata, annotationNames.Contact+"email", defaults.Email),
+
+		IsScanBaseimageLifetime:          GetOrDefaultBool(metadata, annotationNames.Scans+"is-scan-baseimage-lifetime", defaults.IsScanBaseimageLifetime),
+		IsScanDependencyCheck:            GetOrDefaultBool(metadata, annotationNames.Scans+"is-scan-dependency-check", defaults.IsScanDependencyCheck),
+		IsScanDependencyTrack:            GetOrDefaultBool(metadata, annotationNames.Scans+"is-scan-dependency-track", defaults.IsScanDependencyTrack),
+		IsScanDistroless:                 GetOrDefaultBool(metadata, annotationNames.Scans+"is-scan-distroless", defaults.IsScanDistroless),
+		IsScanLifetime:                   GetOrDefaultBool(metadata, annotationNames.Scans+"is-scan-lifetime", defaults.IsScanLifetime),
+		IsScanMalware:                    GetOrDefaultBool(metadata, annotationNames.Scans+"is-scan-malware", defaults.IsScanMalware),
+		IsScanNewVersion:                 GetOrDefaultBool(metadata, annotationNames.Scans+"is-scan-new-version", defaults.IsScanNewVersion),
+		IsScanRunAsRoot:                  GetOrDefaultBool(metadata, annotationNames.Scans+"is-scan-runasroot", defaults.IsScanRunAsRoot),
+		IsPotentiallyRunningAsRoot:       GetOrDefaultBool(metadata, annotationNames.Scans+"is-scan-potentially-running-as-root", defaults.IsPotentiallyRunningAsRoot),
+		IsScanRunAsPrivileged:            GetOrDefaultBool(metadata, annotationNames.Scans+"is-scan-run-as-privileged", defaults.IsScanRunAsPrivileged),
+		IsPotentiallyRunningAsPrivileged: GetOrDefaultBool(metadata, annotationNames.Scans+"is-scan-potentially-running-as-privileged", defaults.IsPotentiallyRunningAsPrivileged),
+		ScanLifetimeMaxDays:              GetOrDefaultInt64(metadata, annotationNames.Scans+"scan-lifetime-max-days", defaults.ScanLifetimeMaxDays),
+	}
+
+	collectedAt := runConfig.collectedAt
+	if collectedAt.IsZero() {
+		collectedAt = time.Now()
+	}
+	withSkipUntil(collectorImage, metadata, annotationNames.Scans+"skip-until", collectedAt)
+
+	withHelmRelease(collectorImage, tags)
+	withFluxSource(collectorImage, tags)
+
+	for _, secret := range k8Image.PullSecrets {
+		collectorImage.PullSecrets = append(collectorImage.PullSecrets, k8Image.NamespaceName+"/"+secret)
+	}
+
+	if IsImageMismatch(k8Image.Image, k8Image.ImageId) {
+		log.Debug().Str("namespace", k8Image.NamespaceName).Str("image", k8Image.Image).Str("imageId", k8Image.ImageId).Msg("Running image differs from the pod spec")
+		collectorImage.ImageMismatch = true
+		collectorImage.StatusImage = normalizedRepository(k8Image.ImageId)
 	}
 
 	return collectorImage
 
 }
 
+// imageTags merges the labels and annotations of the image into a new map, as the maps of the image may be shared
+// with the namespace
+func imageTags(k8Image kubeclient.Image) map[string]string {
+	tags := map[string]string{}
+	maps.Copy(tags, k8Image.Labels)
+	maps.Copy(tags, k8Image.Annotations)
+	return tags
+}
+
 func isSkipImage(ci *CollectorImage, imageFilter *RunConfig) bool {
-	return isSkipImageByNamespace(ci) || isSkipImageByImageFilter(ci, imageFilter)
+	reason, _ := skipReason(ci, nil, imageFilter)
+	return reason != ""
+}
+
+// skipReason returns the first reason the image is skipped for and the matching filter, the reason is empty if it is
+// not skipped. The skip annotation includes the deployment wide default. An allow rule keeps the image even if an
+// image filter matches.
+func skipReason(ci *CollectorImage, tags map[string]string, runConfig *RunConfig) (string, string) {
+	if reason, detail := namespaceSkipReason(ci); reason != "" {
+		return reason, detail
+	}
+	// The scanners can't handle Windows images, an allow rule doesn't keep them
+	if runConfig.SkipWindows && ci.IsWindows {
+		return SkipReasonWindows, ""
+	}
+	switch action, rule := matchingFilterRule(ci, tags, runConfig.filterRules); action {
+	case FilterActionAllow:
+		return "", ""
+	case FilterActionDeny:
+		return SkipReasonFilterRule, rule
+	}
+	if runConfig.SkipSuspended && ci.Suspended {
+		return SkipReasonSuspended, ""
+	}
+	if imageFilter, ok := matchingImageFilter(ci, runConfig); ok {
+		return SkipReasonImageFilter, imageFilter
+	}
+	return "", ""
 }
 
 func isSkipImageByImageFilter(ci *CollectorImage, runConfig *RunConfig) bool {
+	_, ok := matchingImageFilter(ci, runConfig)
+	return ok
+}
+
+// matchingImageFilter returns the first image filter matching the image
+func matchingImageFilter(ci *CollectorImage, runConfig *RunConfig) (string, bool) {
 	for _, imageFilter := range runConfig.ImageFilter {
 		log.Debug().Msgf("image %s (imagefilter %s)", ci.Image, imageFilter)
 		matched, err := regexp.MatchString(imageFilter, ci.Image)
 		if matched && err == nil {
-			return true
+			return imageFilter, true
 		}
 	}
 
-	return false
+	return "", false
 }
 
 // considering the images labels, annotations and deployment wide defaults
 func isSkipImageByNamespace(ci *CollectorImage) bool {
-	isNamespaceFilter, _ := regexp.MatchString(ci.NamespaceFilter, ci.Namespace)
-	if ci.NamespaceFilter == "" {
-		isNamespaceFilter = false
-	}
+	reason, _ := namespaceSkipReason(ci)
+	return reason != ""
+}
 
-	isNamespaceFilterNegated := false
-	isNamespaceFilterMatch, _ := regexp.MatchString(ci.NamespaceFilterNegated, ci.Namespace)
-	if ci.NamespaceFilterNegated != "" {
-		isNamespaceFilterNegated = isNamespaceFilterMatch
+// namespaceSkipReason returns the reason the image is skipped for by its annotation or the namespace filters and the
+// matching filter
+func namespaceSkipReason(ci *CollectorImage) (string, string) {
+	switch {
+	case ci.Skip:
+		return SkipReasonAnnotation, ""
+	case ci.NamespaceFilter != "" && matches(ci.NamespaceFilter, ci.Namespace):
+		return SkipReasonNamespaceFilter, ci.NamespaceFilter
+	case ci.NamespaceFilterNegated != "" && matches(ci.NamespaceFilterNegated, ci.Namespace):
+		return SkipReasonNegatedNamespaceFilter, ci.NamespaceFilterNegated
 	}
+	return "", ""
+}
 
-	return ci.Skip || isNamespaceFilter || isNamespaceFilterNegated
+func matches(pattern, value string) bool {
+	matched, _ := regexp.MatchString(pattern, value)
+	return matched
 }
 
-// applies replacement and other rules to specific fields
-func cleanCollectorImage(ci *CollectorImage, imageFilter *RunConfig) {
+// applies replacement and other rules to specific fields, returns why the image is skipped or nil
+func cleanCollectorImage(ci *CollectorImage, tags map[string]string, imageFilter *RunConfig) *SkipDecision {
 	ci.Image = strings.Replace(ci.Image, "docker-pullable://", "", -1)
 	ci.ImageId = cleanCollectorImageId(ci)
+	if imageFilter.NormalizeImages {
+		ci.Image = NormalizeImage(ci.Image)
+		// Image ids without repository, e.g. sha256:abc, are kept
+		if strings.Contains(ci.ImageId, "@") {
+			ci.ImageId = NormalizeImage(ci.ImageId)
+		}
+	}
+	ci.IsInternalRegistry = IsInternalRegistry(ci.Image, imageFilter.InternalRegistries)
 
-	ci.Skip = isSkipImage(ci, imageFilter)
+	reason, detail := skipReason(ci, tags, imageFilter)
+	ci.Skip = reason != ""
+	if !ci.Skip {
+		return nil
+	}
+	return &SkipDecision{Namespace: ci.Namespace, Image: ci.Image, ImageId: ci.ImageId, Reason: reason, Detail: detail}
 }
 
 func cleanCollectorImageId(ci *CollectorImage) string {
 	var imageId = strings.Replace(ci.ImageId, "docker-pullable://", "", -1)
 	if imageId == "" {
-		log.Info().Msgf("ImageId is empty for image %s (ns %s). Using image name as imageId", ci.Image, ci.Namespace)
+		log.Debug().Str("image", ci.Image).Str("namespace", ci.Namespace).Msg("ImageId is empty, using image name as imageId")
 		imageId = ci.Image
 	}
 	return imageId
@@ -157,16 +386,41 @@ func cleanCollectorImageId(ci *CollectorImage) string {
 
 // images from kubernetes, convert, clean and store them in the storage
 func ConvertImages(k8Images *[]kubeclient.Image, defaults *CollectorImage, annotationNames *AnnotationNames, runConfig *RunConfig) (*[]CollectorImage, error) {
+	images, _, err := ConvertImagesWithSkipDecisions(k8Images, defaults, annotationNames, runConfig)
+	return images, err
+}
+
+// ConvertImagesWithSkipDecisions converts the images like ConvertImages and returns why images were skipped
+func ConvertImagesWithSkipDecisions(k8Images *[]kubeclient.Image, defaults *CollectorImage, annotationNames *AnnotationNames, runConfig *RunConfig) (*[]CollectorImage, []SkipDecision, error) {
 	var images []CollectorImage
+	var decisions []SkipDecision
 
+	rules, err := LoadFilterRules(runConfig.FilterRulesFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	withRules := *runConfig
+	withRules.filterRules = rules
+	withRules.collectedAt = time.Now()
+	runConfig = &withRules
+
+	mismatches := 0
 	for _, k8Image := range *k8Images {
-		collectorImage := convertK8ImageToCollectorImage(k8Image, defaults, annotationNames)
-		cleanCollectorImage(collectorImage, runConfig)
+		collectorImage := convertK8ImageToCollectorImage(k8Image, defaults, annotationNames, runConfig)
+		if decision := cleanCollectorImage(collectorImage, imageTags(k8Image), runConfig); decision != nil {
+			decisions = append(decisions, *decision)
+		}
+		if collectorImage.ImageMismatch {
+			mismatches++
+		}
 		images = append(images, *collectorImage)
 
 	}
+	if mismatches > 0 {
+		log.Warn().Int("images", mismatches).Msg("Running images differ from the pod spec, see image_mismatch and status_image")
+	}
 
-	return &images, nil
+	return &images, decisions, nil
 }
 
 // TODO: Write Tests. Not written yet due to upcomming refactor
@@ -174,15 +428,12 @@ func ConvertImages(k8Images *[]kubeclient.Image, defaults *CollectorImage, annot
 func Store(images *[]CollectorImage, storage io.Writer, jsonMarshal JsonMarshal) error {
 
 	if images == nil {
-		err := errors.New("cannot marshal nil")
-		log.Fatal().Stack().Err(err)
-		return err
+		return errors.New("cannot marshal nil")
 	}
 
 	data, err := jsonMarshal(images)
 	if err != nil {
-		log.Fatal().Stack().Err(err).Msg("Could not marshal json images")
-		return err
+		return fmt.Errorf("could not marshal json images: %w", err)
 	}
 
 	if _, err = storage.Write(data); err != nil {
@@ -191,3 +442,27 @@ func Store(images *[]CollectorImage, storage io.Writer, jsonMarshal JsonMarshal)
 
 	return nil
 }
+
+// StoreReport marshals the images into the report and stores it with the provided Storager implementation
+func StoreReport(ctx context.Context, images *[]CollectorImage, storager backend.Storager, report backend.Report, jsonMarshal JsonMarshal) (backend.StoreResult, error) {
+	if images == nil {
+		return backend.StoreResult{}, errors.New("cannot marshal nil")
+	}
+
+	data, err := jsonMarshal(images)
+	if err != nil {
+		return backend.StoreResult{}, err
+	}
+
+	report.Content = data
+	if report.ContentType == "" {
+		report.ContentType = "application/json"
+	}
+	if report.Metadata == nil {
+		report.Metadata = map[string]string{}
+	}
+	report.Metadata["images"] = strconv.Itoa(len(*images))
+	report.Metadata["collectorVersion"] = version.Version
+
+	return storager.Store(ctx, report)
+}
@@ -4,10 +4,13 @@ import (
 	// "sort"
 	// "strings"
 	"bytes"
+	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -322,7 +325,7 @@ func TestCleanCollectorImageSkipSet(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			initialSkip := tc.targetImage.Skip
-			cleanCollectorImage(&tc.targetImage, &runConfig)
+			cleanCollectorImage(&tc.targetImage, nil, &runConfig)
 
 			if tc.expectedChanged {
 				assert.NotEqual(t, tc.targetImage.Skip, initialSkip, "Expected Skip to change but it did not change")
@@ -417,7 +420,7 @@ func TestCleanCollectorImageImageNameAndID(t *testing.T) {
 			initialImage := tc.targetImage.Image
 			initialImageId := tc.targetImage.ImageId
 
-			cleanCollectorImage(&tc.targetImage, &runConfig)
+			cleanCollectorImage(&tc.targetImage, nil, &runConfig)
 
 			if tc.expectedImgChanged {
 				assert.NotEqual(t, tc.targetImage.Image, initialImage, "Expected Image to change but it did not change")
@@ -437,6 +440,82 @@ func TestCleanCollectorImageImageNameAndID(t *testing.T) {
 	}
 }
 
+func TestSkipWindows(t *testing.T) {
+	k8Images := []kubeclient.Image{
+		{Image: "app:1.0", NamespaceName: "ns", OperatingSystem: "linux"},
+		{Image: "iis:1.0", NamespaceName: "ns", OperatingSystem: kubeclient.OperatingSystemWindows},
+	}
+	runConfig := RunConfig{SkipWindows: true}
+
+	images, decisions, err := ConvertImagesWithSkipDecisions(&k8Images, &CollectorImage{}, &AnnotationNames{}, &runConfig)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.False(t, (*images)[0].IsWindows)
+	assert.False(t, (*images)[0].Skip)
+	assert.True(t, (*images)[1].IsWindows)
+	assert.True(t, (*images)[1].Skip)
+	assert.Equal(t, []SkipDecision{{Namespace: "ns", Image: "iis:1.0", ImageId: "iis:1.0", Reason: SkipReasonWindows}}, decisions)
+
+	allowAll := RunConfig{SkipWindows: true, filterRules: []FilterRule{{Name: "allow-all", Action: FilterActionAllow, Namespace: ".*"}}}
+	image := CollectorImage{Namespace: "ns", Image: "iis:1.0", IsWindows: true}
+	assert.NotNil(t, cleanCollectorImage(&image, nil, &allowAll), "Expected the Windows image to be skipped even if a rule allows it")
+
+	runConfig.SkipWindows = false
+	images, _, err = ConvertImagesWithSkipDecisions(&k8Images, &CollectorImage{}, &AnnotationNames{}, &runConfig)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.True(t, (*images)[1].IsWindows)
+	assert.False(t, (*images)[1].Skip)
+}
+
+func TestSkipSuspended(t *testing.T) {
+	k8Images := []kubeclient.Image{
+		{Image: "nightly:1.0", NamespaceName: "ns"},
+		{Image: "dormant:1.0", NamespaceName: "ns", Suspended: true},
+	}
+	runConfig := RunConfig{SkipSuspended: true}
+
+	images, decisions, err := ConvertImagesWithSkipDecisions(&k8Images, &CollectorImage{}, &AnnotationNames{}, &runConfig)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.False(t, (*images)[0].Skip)
+	assert.True(t, (*images)[1].Suspended)
+	assert.True(t, (*images)[1].Skip)
+	assert.Equal(t, []SkipDecision{{Namespace: "ns", Image: "dormant:1.0", ImageId: "dormant:1.0", Reason: SkipReasonSuspended}}, decisions)
+
+	runConfig.filterRules = []FilterRule{{Name: "keep", Action: FilterActionAllow, Image: "dormant"}}
+	image := CollectorImage{Namespace: "ns", Image: "dormant:1.0", Suspended: true}
+	assert.Nil(t, cleanCollectorImage(&image, nil, &runConfig), "Expected an allow rule to keep the image of the suspended CronJob")
+}
+
+func TestSidecarContainerType(t *testing.T) {
+	k8Images := []kubeclient.Image{
+		{Image: "app:1.0", NamespaceName: "ns"},
+		{Image: "envoy:1.0", NamespaceName: "ns", Sidecar: true},
+		{Image: "proxy:1.0", NamespaceName: "ns", Container: "proxy", Sidecar: true, Annotations: map[string]string{"sda.se/container-type": "batch"}},
+		{Image: "agent:1.0", NamespaceName: "ns", Container: "agent", Sidecar: true, Annotations: map[string]string{"sda.se/container-type": "batch", "sda.se/container-type.agent": "monitoring"}},
+		{Image: "job:1.0", NamespaceName: "ns", Container: "job", Annotations: map[string]string{"sda.se/container-type": "batch", "sda.se/container-type.agent": "monitoring"}},
+	}
+
+	images, err := ConvertImages(&k8Images, &CollectorImage{ContainerType: "application"}, &AnnotationNames{Base: "sda.se/"}, &RunConfig{})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, "application", (*images)[0].ContainerType)
+	assert.Equal(t, ContainerTypeSidecar, (*images)[1].ContainerType)
+	assert.Equal(t, ContainerTypeSidecar, (*images)[2].ContainerType, "Expected the pod annotation not to apply to the sidecar")
+	assert.Equal(t, "monitoring", (*images)[3].ContainerType, "Expected the container annotation to take precedence")
+	assert.Equal(t, "batch", (*images)[4].ContainerType)
+}
+
+func TestCleanCollectorImageNormalizeImages(t *testing.T) {
+	runConfig := RunConfig{NormalizeImages: true, ImageFilter: []string{"^docker.io/library/mongo"}}
+
+	image := CollectorImage{Image: "docker-pullable://nginx:1.25", ImageId: "docker-pullable://nginx@sha256:1234"}
+	assert.Nil(t, cleanCollectorImage(&image, nil, &runConfig))
+	assert.Equal(t, "docker.io/library/nginx:1.25", image.Image)
+	assert.Equal(t, "docker.io/library/nginx@sha256:1234", image.ImageId)
+
+	image = CollectorImage{Image: "mongo:7", ImageId: "sha256:1234"}
+	assert.NotNil(t, cleanCollectorImage(&image, nil, &runConfig), "Expected the image filter to match the normalized image")
+	assert.Equal(t, "sha256:1234", image.ImageId)
+}
+
 func TestConvert(t *testing.T) {
 	defaults := CollectorImage{
 		Environment: "myEnv",
@@ -849,6 +928,69 @@ func TestConvert(t *testing.T) {
 	}
 }
 
+func TestConvertImageMismatch(t *testing.T) {
+	k8Images := []kubeclient.Image{
+		{Image: "quay.io/sdase/app:1.0", ImageId: "quay.io/sdase/app@sha256:1234"},
+		{Image: "quay.io/sdase/app:1.0", ImageId: "mirror.local/sdase/app@sha256:1234"},
+	}
+
+	results, err := ConvertImages(&k8Images, &CollectorImage{}, &AnnotationNames{}, &RunConfig{})
+
+	assert.NoError(t, err)
+	assert.False(t, (*results)[0].ImageMismatch)
+	assert.Empty(t, (*results)[0].StatusImage)
+	assert.True(t, (*results)[1].ImageMismatch)
+	assert.Equal(t, "mirror.local/sdase/app", (*results)[1].StatusImage)
+}
+
+func TestConvertMetadataPrecedence(t *testing.T) {
+	annotationNames := AnnotationNames{Base: "sdase.org/", Contact: "contact.sdase.org/", Scans: "scans.sdase.org/"}
+	k8Images := []kubeclient.Image{{
+		Image:                "app:1.0",
+		Labels:               map[string]string{"app.kubernetes.io/name": "app"},
+		Annotations:          map[string]string{"contact.sdase.org/team": "pod-team", "scans.sdase.org/skip": "true", "contact.sdase.org/slack": "#ns"},
+		NamespaceAnnotations: map[string]string{"contact.sdase.org/slack": "#ns"},
+	}}
+
+	merged, err := ConvertImages(&k8Images, &CollectorImage{Team: "default-team"}, &annotationNames, &RunConfig{MetadataPrecedence: MetadataPrecedenceMerged})
+	assert.NoError(t, err)
+	assert.Equal(t, "pod-team", (*merged)[0].Team)
+	assert.True(t, (*merged)[0].Skip)
+
+	namespaceOnly, err := ConvertImages(&k8Images, &CollectorImage{Team: "default-team"}, &annotationNames, &RunConfig{MetadataPrecedence: MetadataPrecedenceNamespaceOnly})
+	assert.NoError(t, err)
+	assert.Equal(t, "default-team", (*namespaceOnly)[0].Team)
+	assert.Equal(t, "#ns", (*namespaceOnly)[0].Slack)
+	assert.False(t, (*namespaceOnly)[0].Skip)
+	// Fields describing the workload are still read from the pod
+	assert.Equal(t, "app", (*namespaceOnly)[0].AppKubernetesIoName)
+}
+
+func TestConvertSkipUntil(t *testing.T) {
+	annotationNames := AnnotationNames{Scans: "scans.sdase.org/"}
+	collectedAt := time.Date(2025, 12, 31, 23, 0, 0, 0, time.UTC)
+	runConfig := &RunConfig{collectedAt: collectedAt}
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    bool
+	}{
+		{name: "UntilEndOfDay", annotations: map[string]string{"scans.sdase.org/skip-until": "2025-12-31"}, expected: true},
+		{name: "Expired", annotations: map[string]string{"scans.sdase.org/skip-until": "2025-12-30"}, expected: false},
+		{name: "ExpiredOverridesSkip", annotations: map[string]string{"scans.sdase.org/skip": "true", "scans.sdase.org/skip-until": "2025-12-30"}, expected: false},
+		{name: "Time", annotations: map[string]string{"scans.sdase.org/skip-until": "2025-12-31T22:00:00Z"}, expected: false},
+		{name: "Invalid", annotations: map[string]string{"scans.sdase.org/skip": "true", "scans.sdase.org/skip-until": "next year"}, expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			image := convertK8ImageToCollectorImage(kubeclient.Image{Image: "app:1.0", Annotations: tc.annotations}, &CollectorImage{}, &annotationNames, runConfig)
+			assert.Equal(t, tc.expected, image.Skip)
+		})
+	}
+}
+
 func TestStore(t *testing.T) {
 	defaults := CollectorImage{
 		Environment: "myEnv",
@@ -959,3 +1101,60 @@ func TestStore(t *testing.T) {
 	}
 
 }
+
+type mockStorager struct {
+	report backend.Report
+}
+
+func (m *mockStorager) Store(ctx context.Context, report backend.Report) (backend.StoreResult, error) {
+	m.report = report
+	return backend.StoreResult{Location: report.FileName, Size: len(report.Content)}, nil
+}
+
+func TestStoreReport(t *testing.T) {
+	images := []CollectorImage{{Namespace: "myNamespace", Image: "quay.io/name:tag"}}
+	jsonResult, _ := JsonIndentMarshal(&images)
+
+	storager := &mockStorager{}
+	result, err := StoreReport(context.Background(), &images, storager, backend.Report{FileName: "env-output.json", Environment: "env"}, JsonIndentMarshal)
+
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, jsonResult, storager.report.Content)
+	assert.Equal(t, "env-output.json", storager.report.FileName)
+	assert.Equal(t, "application/json", storager.report.ContentType)
+	assert.Equal(t, "1", storager.report.Metadata["images"])
+	assert.Equal(t, "env-output.json", result.Location)
+	assert.Equal(t, len(jsonResult), result.Size)
+
+	_, err = StoreReport(context.Background(), nil, storager, backend.Report{}, JsonIndentMarshal)
+	assert.Error(t, err, "Expected error but got none")
+}
+
+func TestSkipReason(t *testing.T) {
+	testCases := []struct {
+		name        string
+		targetImage CollectorImage
+		imageFilter []string
+		expected    string
+		detail      string
+	}{
+		{name: "NotSkipped", targetImage: CollectorImage{Namespace: "name", Image: "nginx"}, expected: ""},
+		{name: "Annotation", targetImage: CollectorImage{Namespace: "name", Skip: true, NamespaceFilter: ".*"}, expected: SkipReasonAnnotation},
+		{name: "NamespaceFilter", targetImage: CollectorImage{Namespace: "name", NamespaceFilter: "^name$"}, expected: SkipReasonNamespaceFilter, detail: "^name$"},
+		{name: "NegatedNamespaceFilter", targetImage: CollectorImage{Namespace: "name", NamespaceFilterNegated: "^name$"}, expected: SkipReasonNegatedNamespaceFilter, detail: "^name$"},
+		{name: "ImageFilter", targetImage: CollectorImage{Namespace: "name", Image: "quay.io/nginx"}, imageFilter: []string{"mongo", "quay.io"}, expected: SkipReasonImageFilter, detail: "quay.io"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runConfig := RunConfig{ImageFilter: tc.imageFilter}
+			decision := cleanCollectorImage(&tc.targetImage, nil, &runConfig)
+			assert.Equal(t, tc.expected != "", tc.targetImage.Skip)
+			if tc.expected == "" {
+				assert.Nil(t, decision)
+				return
+			}
+			assert.Equal(t, tc.expected, decision.Reason)
+			assert.Equal(t, tc.detail, decision.Detail)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultCsvColumns are used if no columns are configured
+var DefaultCsvColumns = []string{"namespace", "image", "tag", "team", "product", "environment", "skip"}
+
+// csvTagColumn is not a field of CollectorImage but derived from the image reference
+const csvTagColumn = "tag"
+
+// csvFieldIndex maps the json names of the CollectorImage fields to their index
+var csvFieldIndex = func() map[string]int {
+	index := map[string]int{}
+	t := reflect.TypeOf(CollectorImage{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			index[name] = i
+		}
+	}
+	return index
+}()
+
+// NewCsvMarshal returns a marshal function writing one row per image with the given columns, the column names are
+// the json field names of the report plus 'tag'
+func NewCsvMarshal(columns []string) (JsonMarshal, error) {
+	if len(columns) == 0 {
+		columns = DefaultCsvColumns
+	}
+
+	for _, column := range columns {
+		if _, ok := csvFieldIndex[column]; !ok && column != csvTagColumn {
+			return nil, fmt.Errorf("CSV column %s is not supported", column)
+		}
+	}
+
+	return func(v any) ([]byte, error) {
+		images, err := imagesFromAny(v)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+
+		if err = w.Write(columns); err != nil {
+			return nil, err
+		}
+
+		row := make([]string, len(columns))
+		for _, image := range images {
+			value := reflect.ValueOf(image)
+			for i, column := range columns {
+				if column == csvTagColumn {
+					row[i] = ParseImageReference(image.Image).Tag
+					continue
+				}
+				row[i] = csvValue(value.Field(csvFieldIndex[column]))
+			}
+			if err = w.Write(row); err != nil {
+				return nil, err
+			}
+		}
+
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	}, nil
+}
+
+func csvValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Slice:
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = csvValue(v.Index(i))
+		}
+		return strings.Join(values, ";")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,207 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/harbor"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/registry"
+
+	"github.com/rs/zerolog/log"
+)
+
+// OCI annotation keys recorded from the image config labels
+const (
+	LabelImageSource   = "org.opencontainers.image.source"
+	LabelImageRevision = "org.opencontainers.image.revision"
+)
+
+type EnrichConfig struct {
+	// EnrichRegistry reads the creation time and the OCI labels from the image config in the registry
+	EnrichRegistry bool
+	// EnrichSbom discovers SBOMs and attestations attached to the images in the registry
+	EnrichSbom bool
+	// EnrichConcurrency is the number of images enriched in parallel
+	EnrichConcurrency int
+
+	// RegistryConfigFile is a docker config.json with the credentials of private registries
+	RegistryConfigFile string
+	// RegistryPlainHttp lists registries accessed without TLS, e.g. a local registry:5000
+	RegistryPlainHttp []string
+	// RegistryCredentialHelpers names the cloud registries (ecr, google, azure) whose credentials are fetched with
+	// the identity of the collector
+	RegistryCredentialHelpers []string
+	// RegistryPullSecrets uses the imagePullSecrets of the pods for their images
+	RegistryPullSecrets bool
+	// RegistryHostConcurrency limits the requests in flight per registry and Harbor host, 0 is unlimited
+	RegistryHostConcurrency int
+	// RegistryRateLimits limit the requests per second per registry, in the format '<registry>=<requests per second>'
+	// and '*=<requests per second>' for all other registries
+	RegistryRateLimits []string
+
+	// HarborEndpoints add the project metadata and scan status of images in Harbor registries, in the format
+	// '<registry host>=<harbor url>'
+	HarborEndpoints []string
+}
+
+// ImageConfigResolver fetches the config of an image from its registry
+type ImageConfigResolver interface {
+	ImageConfig(ctx context.Context, ref registry.Reference) (*registry.ImageConfig, error)
+}
+
+// AttachmentResolver discovers the SBOMs, attestations and signatures attached to an image in its registry
+type AttachmentResolver interface {
+	Attachments(ctx context.Context, ref registry.Reference) (*registry.Attachments, error)
+}
+
+// HarborResolver reads the project metadata of images in Harbor, it returns nil for images in other registries
+type HarborResolver interface {
+	HarborMetadata(ctx context.Context, ref registry.Reference) (*harbor.Metadata, error)
+}
+
+// Enrichers resolve the registry metadata of the images, nil resolvers are disabled
+type Enrichers struct {
+	ImageConfig ImageConfigResolver
+	Attachments AttachmentResolver
+	Harbor      HarborResolver
+}
+
+// EnrichImages adds the creation time and the OCI source labels of the image config, the attached SBOMs and
+// attestations and the Harbor project metadata to all images which are not skipped. Every image is resolved once,
+// failures are logged, leave the fields empty and are returned.
+func EnrichImages(ctx context.Context, images []CollectorImage, enrichers Enrichers, concurrency int) []RunError {
+	refs := map[string][]int{}
+	for i := range images {
+		if images[i].Skip {
+			continue
+		}
+		key := imageReference(&images[i])
+		refs[key] = append(refs[key], i)
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	// Every image index belongs to a single reference, so the goroutines never write the same image
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []RunError
+	failed := func(stage string, ref fmt.Stringer, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, RunError{Stage: stage, Image: ref.String(), Error: err.Error()})
+	}
+	for key, indexes := range refs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(key string, indexes []int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			ref, err := registry.ParseReference(key)
+			if err != nil {
+				log.Warn().Err(err).Str("image", key).Msg("Could not parse image reference")
+				return
+			}
+			// The image may run in several namespaces, the imagePullSecrets of all its pods are tried
+			ctx := registry.WithPullSecrets(ctx, pullSecrets(images, indexes))
+			if enrichers.ImageConfig != nil {
+				if err := enrichImageConfig(ctx, enrichers.ImageConfig, ref, images, indexes); err != nil {
+					failed(RunErrorStageImageConfig, ref, err)
+				}
+			}
+			if enrichers.Attachments != nil {
+				if err := enrichAttachments(ctx, enrichers.Attachments, ref, images, indexes); err != nil {
+					failed(RunErrorStageAttachments, ref, err)
+				}
+			}
+			if enrichers.Harbor != nil {
+				if err := enrichHarbor(ctx, enrichers.Harbor, ref, images, indexes); err != nil {
+					failed(RunErrorStageHarbor, ref, err)
+				}
+			}
+		}(key, indexes)
+	}
+	wg.Wait()
+
+	sortRunErrors(errs)
+	return errs
+}
+
+func enrichImageConfig(ctx context.Context, resolver ImageConfigResolver, ref registry.Reference, images []CollectorImage, indexes []int) error {
+	config, err := resolver.ImageConfig(ctx, ref)
+	if err != nil {
+		log.Warn().Err(err).Str("image", ref.String()).Msg("Could not read image config from registry")
+		return err
+	}
+
+	for _, i := range indexes {
+		if !config.Created.IsZero() {
+			images[i].ImageCreated = config.Created.UTC().Format(time.RFC3339)
+		}
+		images[i].ImageSource = config.Labels[LabelImageSource]
+		images[i].ImageRevision = config.Labels[LabelImageRevision]
+	}
+	return nil
+}
+
+func enrichAttachments(ctx context.Context, resolver AttachmentResolver, ref registry.Reference, images []CollectorImage, indexes []int) error {
+	attachments, err := resolver.Attachments(ctx, ref)
+	if err != nil {
+		log.Warn().Err(err).Str("image", ref.String()).Msg("Could not discover SBOMs and attestations in registry")
+		return err
+	}
+
+	for _, i := range indexes {
+		images[i].HasSbom = attachments.Sbom
+		images[i].SbomFormat = strings.Join(attachments.SbomFormats, ",")
+		images[i].HasAttestation = attachments.Attestation
+		images[i].HasSignature = attachments.Signature
+	}
+	return nil
+}
+
+func enrichHarbor(ctx context.Context, resolver HarborResolver, ref registry.Reference, images []CollectorImage, indexes []int) error {
+	metadata, err := resolver.HarborMetadata(ctx, ref)
+	if err != nil {
+		log.Warn().Err(err).Str("image", ref.String()).Msg("Could not read project metadata from Harbor")
+		return err
+	} else if metadata == nil {
+		return nil
+	}
+
+	for _, i := range indexes {
+		images[i].HarborProjectOwner = metadata.ProjectOwner
+		images[i].HarborSeverityThreshold = metadata.SeverityThreshold
+		images[i].HarborScanStatus = metadata.ScanStatus
+	}
+	return nil
+}
+
+// pullSecrets returns the distinct imagePullSecrets of the images at the indexes
+func pullSecrets(images []CollectorImage, indexes []int) []string {
+	var secrets []string
+	for _, i := range indexes {
+		for _, secret := range images[i].PullSecrets {
+			if !slices.Contains(secrets, secret) {
+				secrets = append(secrets, secret)
+			}
+		}
+	}
+	return secrets
+}
+
+// imageReference prefers the image id if it contains the repository digest, so the config of the running image is
+// read even if the tag moved
+func imageReference(ci *CollectorImage) string {
+	if strings.Contains(ci.ImageId, "@sha256:") {
+		return ci.ImageId
+	}
+	return ci.Image
+}
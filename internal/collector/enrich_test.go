@@ -0,0 +1,139 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/harbor"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockResolver struct {
+	mu       sync.Mutex
+	resolved []string
+}
+
+func (m *mockResolver) ImageConfig(ctx context.Context, ref registry.Reference) (*registry.ImageConfig, error) {
+	m.mu.Lock()
+	m.resolved = append(m.resolved, ref.String())
+	m.mu.Unlock()
+
+	if ref.Repository == "team/broken" {
+		return nil, errors.New("not found")
+	}
+	return &registry.ImageConfig{
+		Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Labels:  map[string]string{LabelImageSource: "https://github.com/" + ref.Repository, LabelImageRevision: "abc"},
+	}, nil
+}
+
+func (m *mockResolver) Attachments(ctx context.Context, ref registry.Reference) (*registry.Attachments, error) {
+	m.mu.Lock()
+	m.resolved = append(m.resolved, ref.String())
+	m.mu.Unlock()
+
+	if ref.Repository == "team/broken" {
+		return nil, errors.New("not found")
+	}
+	return &registry.Attachments{Sbom: true, SbomFormats: []string{"cyclonedx", "spdx"}, Attestation: true}, nil
+}
+
+func (m *mockResolver) HarborMetadata(ctx context.Context, ref registry.Reference) (*harbor.Metadata, error) {
+	if ref.Registry != "harbor.example.com" {
+		return nil, nil
+	}
+	return &harbor.Metadata{ProjectOwner: "admin", SeverityThreshold: "high", ScanStatus: "Success"}, nil
+}
+
+func TestEnrichImages(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "a", Image: "quay.io/team/app:1.0", ImageId: "quay.io/team/app@sha256:123"},
+		{Namespace: "b", Image: "quay.io/team/app:1.0", ImageId: "quay.io/team/app@sha256:123"},
+		{Namespace: "c", Image: "quay.io/team/broken:1.0"},
+		{Namespace: "d", Image: "quay.io/team/skipped:1.0", Skip: true},
+	}
+	resolver := &mockResolver{}
+
+	errs := EnrichImages(context.Background(), images, Enrichers{ImageConfig: resolver}, 2)
+
+	assert.Equal(t, []RunError{{Stage: RunErrorStageImageConfig, Image: "quay.io/team/broken:1.0", Error: "not found"}}, errs)
+	assert.ElementsMatch(t, []string{"quay.io/team/app@sha256:123", "quay.io/team/broken:1.0"}, resolver.resolved)
+	for _, image := range images[:2] {
+		assert.Equal(t, "2024-01-02T03:04:05Z", image.ImageCreated)
+		assert.Equal(t, "https://github.com/team/app", image.ImageSource)
+		assert.Equal(t, "abc", image.ImageRevision)
+	}
+	assert.Empty(t, images[2].ImageCreated)
+	assert.Empty(t, images[3].ImageSource)
+	assert.False(t, images[0].HasSbom)
+}
+
+// keychainResolver records the users the keychain returns for the images
+type keychainResolver struct {
+	keychain registry.Keychain
+	mu       sync.Mutex
+	users    map[string]string
+}
+
+func (k *keychainResolver) ImageConfig(ctx context.Context, ref registry.Reference) (*registry.ImageConfig, error) {
+	credentials, err := k.keychain.Resolve(ctx, ref.Registry)
+	k.mu.Lock()
+	k.users[ref.Repository] = credentials.Username
+	k.mu.Unlock()
+	return &registry.ImageConfig{}, err
+}
+
+func TestEnrichImagesPullSecrets(t *testing.T) {
+	teamA, err := registry.ParseDockerConfig([]byte(`{"auths":{"quay.io":{"username":"team-a","password":"a"}}}`))
+	assert.NoError(t, err)
+	teamB, err := registry.ParseDockerConfig([]byte(`{"auths":{"quay.io":{"username":"team-b","password":"b"}}}`))
+	assert.NoError(t, err)
+	keychain := &registry.PullSecretKeychain{}
+	keychain.Set(map[string]*registry.DockerConfigKeychain{"a/pull": teamA, "b/pull": teamB})
+
+	images := []CollectorImage{
+		{Namespace: "a", Image: "quay.io/team-a/app:1.0", PullSecrets: []string{"a/pull"}},
+		{Namespace: "b", Image: "quay.io/team-b/app:1.0", PullSecrets: []string{"b/pull"}},
+		{Namespace: "c", Image: "quay.io/team-c/app:1.0"},
+	}
+	resolver := &keychainResolver{keychain: keychain, users: map[string]string{}}
+
+	EnrichImages(context.Background(), images, Enrichers{ImageConfig: resolver}, 2)
+
+	assert.Equal(t, map[string]string{"team-a/app": "team-a", "team-b/app": "team-b", "team-c/app": ""}, resolver.users)
+}
+
+func TestEnrichImagesAttachments(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "a", Image: "quay.io/team/app:1.0"},
+		{Namespace: "b", Image: "quay.io/team/broken:1.0"},
+	}
+	resolver := &mockResolver{}
+
+	EnrichImages(context.Background(), images, Enrichers{Attachments: resolver}, 1)
+
+	assert.ElementsMatch(t, []string{"quay.io/team/app:1.0", "quay.io/team/broken:1.0"}, resolver.resolved)
+	assert.True(t, images[0].HasSbom)
+	assert.Equal(t, "cyclonedx,spdx", images[0].SbomFormat)
+	assert.True(t, images[0].HasAttestation)
+	assert.Empty(t, images[0].ImageCreated)
+	assert.False(t, images[1].HasSbom)
+}
+
+func TestEnrichImagesHarbor(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "a", Image: "harbor.example.com/team/app:1.0"},
+		{Namespace: "b", Image: "quay.io/team/app:1.0"},
+	}
+
+	EnrichImages(context.Background(), images, Enrichers{Harbor: &mockResolver{}}, 1)
+
+	assert.Equal(t, "admin", images[0].HarborProjectOwner)
+	assert.Equal(t, "high", images[0].HarborSeverityThreshold)
+	assert.Equal(t, "Success", images[0].HarborScanStatus)
+	assert.Empty(t, images[1].HarborProjectOwner)
+}
@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Actions of a filter rule
+const (
+	FilterActionAllow = "allow"
+	FilterActionDeny  = "deny"
+)
+
+// FilterRules is the content of the filter rules file, e.g.
+//
+//	rules:
+//	  - action: allow
+//	    namespace: ^team-x$
+//	    image: istio/proxyv2
+//	  - action: deny
+//	    image: istio/proxyv2
+type FilterRules struct {
+	Rules []FilterRule `yaml:"rules"`
+}
+
+// FilterRule matches images by regular expressions on all given conditions. Labels match the values of the merged
+// labels and annotations of pods and namespaces.
+type FilterRule struct {
+	Name          string            `yaml:"name"`
+	Action        string            `yaml:"action"`
+	Namespace     string            `yaml:"namespace"`
+	Image         string            `yaml:"image"`
+	ContainerType string            `yaml:"containerType"`
+	Labels        map[string]string `yaml:"labels"`
+}
+
+// LoadFilterRules reads and validates the rules file, an empty file name has no rules
+func LoadFilterRules(fileName string) ([]FilterRule, error) {
+	if fileName == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not read filter rules: %w", err)
+	}
+
+	var rules FilterRules
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not parse filter rules %s: %w", fileName, err)
+	}
+
+	for i, rule := range rules.Rules {
+		if err = rule.validate(); err != nil {
+			return nil, fmt.Errorf("filter rule %s in %s is invalid: %w", rule.name(i), fileName, err)
+		}
+	}
+	return rules.Rules, nil
+}
+
+func (r FilterRule) validate() error {
+	if r.Action != FilterActionAllow && r.Action != FilterActionDeny {
+		return fmt.Errorf("action %q has to be %s or %s", r.Action, FilterActionAllow, FilterActionDeny)
+	}
+
+	patterns := []string{r.Namespace, r.Image, r.ContainerType}
+	for _, value := range r.Labels {
+		patterns = append(patterns, value)
+	}
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%q is not a valid regular expression: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// name identifies the rule in skip decisions, rules without a name are numbered from 1
+func (r FilterRule) name(i int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return "#" + strconv.Itoa(i+1)
+}
+
+// matches reports whether all conditions of the rule match, missing labels never match
+func (r FilterRule) matches(ci *CollectorImage, tags map[string]string) bool {
+	if !matches(r.Namespace, ci.Namespace) || !matches(r.Image, ci.Image) || !matches(r.ContainerType, ci.ContainerType) {
+		return false
+	}
+	for name, pattern := range r.Labels {
+		value, ok := tags[name]
+		if !ok || !matches(pattern, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchingFilterRule returns the action and name of the first rule matching the image, the action is empty if no
+// rule matches
+func matchingFilterRule(ci *CollectorImage, tags map[string]string, rules []FilterRule) (string, string) {
+	for i, rule := range rules {
+		if rule.matches(ci, tags) {
+			return rule.Action, rule.name(i)
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFilterRules(t *testing.T, content string) string {
+	fileName := filepath.Join(t.TempDir(), "filter-rules.yaml")
+	assert.NoError(t, os.WriteFile(fileName, []byte(content), 0644))
+	return fileName
+}
+
+func TestFilterRules(t *testing.T) {
+	rules, err := LoadFilterRules(writeFilterRules(t, `
+rules:
+  - name: keep-team-x-sidecars
+    action: allow
+    namespace: ^team-x$
+    image: istio/proxyv2
+  - action: deny
+    image: istio/proxyv2
+  - action: deny
+    containerType: ^init$
+    labels:
+      app.kubernetes.io/part-of: ^migrations$
+`))
+	assert.NoError(t, err)
+
+	runConfig := &RunConfig{ImageFilter: []string{"istio/proxyv2"}, filterRules: rules}
+
+	testCases := []struct {
+		name           string
+		image          CollectorImage
+		tags           map[string]string
+		expectedReason string
+		expectedDetail string
+	}{
+		{
+			name:  "AllowRuleBypassesImageFilter",
+			image: CollectorImage{Namespace: "team-x", Image: "docker.io/istio/proxyv2:1.20.0"},
+		},
+		{
+			name:           "DenyRuleWithoutName",
+			image:          CollectorImage{Namespace: "team-y", Image: "docker.io/istio/proxyv2:1.20.0"},
+			expectedReason: SkipReasonFilterRule,
+			expectedDetail: "#2",
+		},
+		{
+			name:           "DenyRuleWithLabels",
+			image:          CollectorImage{Namespace: "team-y", Image: "app:1.0.0", ContainerType: "init"},
+			tags:           map[string]string{"app.kubernetes.io/part-of": "migrations"},
+			expectedReason: SkipReasonFilterRule,
+			expectedDetail: "#3",
+		},
+		{
+			name:  "MissingLabel",
+			image: CollectorImage{Namespace: "team-y", Image: "app:1.0.0", ContainerType: "init"},
+		},
+		{
+			name:           "SkipAnnotationBeforeRules",
+			image:          CollectorImage{Namespace: "team-x", Image: "docker.io/istio/proxyv2:1.20.0", Skip: true},
+			expectedReason: SkipReasonAnnotation,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, detail := skipReason(&tc.image, tc.tags, runConfig)
+			assert.Equal(t, tc.expectedReason, reason)
+			if tc.expectedDetail != "" {
+				assert.Equal(t, tc.expectedDetail, detail)
+			}
+		})
+	}
+}
+
+func TestLoadFilterRulesInvalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"UnknownAction": "rules:\n  - action: skip\n",
+		"InvalidRegex":  "rules:\n  - action: deny\n    image: '('\n",
+		"UnknownField":  "rules:\n  - action: deny\n    container: init\n",
+	} {
+		_, err := LoadFilterRules(writeFilterRules(t, content))
+		assert.Error(t, err, name)
+	}
+
+	rules, err := LoadFilterRules(writeFilterRules(t, ""))
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	_, err = LoadFilterRules(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
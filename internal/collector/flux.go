@@ -0,0 +1,27 @@
+package collector
+
+// Labels set by Flux on the resources applied by a Kustomization or HelmRelease
+const (
+	fluxKustomizationNameLabel      = "kustomize.toolkit.fluxcd.io/name"
+	fluxKustomizationNamespaceLabel = "kustomize.toolkit.fluxcd.io/namespace"
+	fluxHelmReleaseNameLabel        = "helm.toolkit.fluxcd.io/name"
+	fluxHelmReleaseNamespaceLabel   = "helm.toolkit.fluxcd.io/namespace"
+)
+
+// withFluxSource sets the Flux Kustomization and HelmRelease which deployed the image as '<namespace>/<name>'. The
+// labels are usually set on the namespace or the pod template, both are merged into the tags.
+func withFluxSource(ci *CollectorImage, tags map[string]string) {
+	ci.FluxKustomization = fluxObject(tags, fluxKustomizationNamespaceLabel, fluxKustomizationNameLabel)
+	ci.FluxHelmRelease = fluxObject(tags, fluxHelmReleaseNamespaceLabel, fluxHelmReleaseNameLabel)
+}
+
+func fluxObject(tags map[string]string, namespaceLabel, nameLabel string) string {
+	name := tags[nameLabel]
+	if name == "" {
+		return ""
+	}
+	if namespace := tags[namespaceLabel]; namespace != "" {
+		return namespace + "/" + name
+	}
+	return name
+}
@@ -0,0 +1,23 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithFluxSource(t *testing.T) {
+	var ci CollectorImage
+	withFluxSource(&ci, map[string]string{
+		"kustomize.toolkit.fluxcd.io/name":      "apps",
+		"kustomize.toolkit.fluxcd.io/namespace": "flux-system",
+		"helm.toolkit.fluxcd.io/name":           "app",
+	})
+	assert.Equal(t, "flux-system/apps", ci.FluxKustomization)
+	assert.Equal(t, "app", ci.FluxHelmRelease)
+
+	ci = CollectorImage{}
+	withFluxSource(&ci, map[string]string{"app.kubernetes.io/name": "app"})
+	assert.Empty(t, ci.FluxKustomization)
+	assert.Empty(t, ci.FluxHelmRelease)
+}
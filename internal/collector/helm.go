@@ -0,0 +1,35 @@
+package collector
+
+import (
+	"regexp"
+)
+
+// Labels and annotations set by Helm on the resources of a release
+const (
+	helmChartLabel         = "helm.sh/chart"
+	helmManagedByLabel     = "app.kubernetes.io/managed-by"
+	helmInstanceLabel      = "app.kubernetes.io/instance"
+	helmReleaseAnnotation  = "meta.helm.sh/release-name"
+	helmManagedByHelmValue = "Helm"
+)
+
+// helmChartVersion splits the chart label '<name>-<version>' at the first dash followed by a version, as both the
+// name and a pre-release version may contain dashes
+var helmChartVersion = regexp.MustCompile(`^(.+?)-(v?\d+\.\d+.*)$`)
+
+// withHelmRelease sets the chart name, chart version and release name of images deployed with Helm
+func withHelmRelease(ci *CollectorImage, tags map[string]string) {
+	chart := tags[helmChartLabel]
+	if chart == "" && tags[helmManagedByLabel] != helmManagedByHelmValue {
+		return
+	}
+
+	if match := helmChartVersion.FindStringSubmatch(chart); match != nil {
+		ci.HelmChart, ci.HelmChartVersion = match[1], match[2]
+	} else {
+		ci.HelmChart = chart
+	}
+
+	// Pods rarely carry the release annotation, the chart conventions set the instance label to the release name
+	ci.HelmRelease = GetOrDefaultString(tags, helmReleaseAnnotation, tags[helmInstanceLabel])
+}
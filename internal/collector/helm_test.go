@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithHelmRelease(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tags     map[string]string
+		expected CollectorImage
+	}{
+		{
+			name:     "NoHelm",
+			tags:     map[string]string{"app.kubernetes.io/name": "app"},
+			expected: CollectorImage{},
+		},
+		{
+			name:     "ChartAndReleaseAnnotation",
+			tags:     map[string]string{"helm.sh/chart": "my-app-1.2.3", "meta.helm.sh/release-name": "prod-app", "app.kubernetes.io/instance": "app"},
+			expected: CollectorImage{HelmChart: "my-app", HelmChartVersion: "1.2.3", HelmRelease: "prod-app"},
+		},
+		{
+			name:     "PreReleaseAndInstanceLabel",
+			tags:     map[string]string{"helm.sh/chart": "app-2.0.0-rc-1", "app.kubernetes.io/instance": "app"},
+			expected: CollectorImage{HelmChart: "app", HelmChartVersion: "2.0.0-rc-1", HelmRelease: "app"},
+		},
+		{
+			name:     "ManagedByHelmWithoutChart",
+			tags:     map[string]string{"app.kubernetes.io/managed-by": "Helm", "app.kubernetes.io/instance": "app"},
+			expected: CollectorImage{HelmRelease: "app"},
+		},
+		{
+			name:     "ChartWithoutVersion",
+			tags:     map[string]string{"helm.sh/chart": "app"},
+			expected: CollectorImage{HelmChart: "app"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ci CollectorImage
+			withHelmRelease(&ci, tc.tags)
+			assert.Equal(t, tc.expected, ci)
+		})
+	}
+}
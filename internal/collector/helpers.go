@@ -70,3 +70,7 @@ type JsonMarshal func(any) ([]byte, error)
 func JsonIndentMarshal(v any) ([]byte, error) {
 	return json.MarshalIndent(v, "", "\t")
 }
+
+func JsonCompactMarshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"path"
+	"strings"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/registry"
+)
+
+// ImageReference is an image name split into its parts, e.g. quay.io/sdase/app:1.0@sha256:abc
+type ImageReference = registry.Reference
+
+// ParseImageReference splits an image into registry, repository, tag and digest. The registry is only set if the
+// first path component looks like a host (contains a '.' or ':' or is localhost), see NormalizeImage for the defaults.
+func ParseImageReference(image string) ImageReference {
+	return registry.SplitReference(image)
+}
+
+// ImageDigest returns the digest of the image id (e.g. quay.io/sdase/app@sha256:abc or sha256:abc)
+func ImageDigest(imageId string) string {
+	if _, digest, found := strings.Cut(imageId, "@"); found {
+		return digest
+	}
+	if strings.HasPrefix(imageId, "sha256:") {
+		return imageId
+	}
+	return ""
+}
+
+// IsImageMismatch reports whether the repository of the image id of the container status differs from the image of
+// the pod spec. Image ids without a repository (e.g. sha256:abc) can't be compared and never mismatch.
+func IsImageMismatch(image, imageId string) bool {
+	imageId = strings.TrimPrefix(imageId, "docker-pullable://")
+	if image == "" || !strings.Contains(imageId, "@") {
+		return false
+	}
+	return normalizedRepository(image) != normalizedRepository(imageId)
+}
+
+// normalizedRepository returns the registry and repository of the normalized image, e.g. docker.io/library/nginx for
+// nginx:1.25
+func normalizedRepository(image string) string {
+	ref := ParseImageReference(NormalizeImage(strings.TrimPrefix(image, "docker-pullable://")))
+	return ref.Registry + "/" + ref.Repository
+}
+
+// NormalizeImage expands the image to its full reference with the defaults of the container runtimes and the
+// normalized registry, e.g. docker.io/library/nginx:latest for nginx and registry.example.com/app:1 for
+// registry.example.com:443/app:1, so the same image is named the same regardless of how it is referenced. Invalid
+// references are returned unchanged.
+func NormalizeImage(image string) string {
+	if image == "" {
+		return image
+	}
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return image
+	}
+	return ref.String()
+}
+
+// ImageRegistry returns the normalized registry host of the image, images without a registry are pulled from docker.io
+func ImageRegistry(image string) string {
+	return registry.NormalizeRegistry(ParseImageReference(strings.TrimPrefix(image, "docker-pullable://")).Registry)
+}
+
+// IsInternalRegistry reports whether the registry of the image matches one of the internal registries, which are
+// hostnames or patterns like '*.registry.example.com'
+func IsInternalRegistry(image string, internalRegistries []string) bool {
+	registry := ImageRegistry(image)
+	for _, pattern := range internalRegistries {
+		if matched, _ := path.Match(pattern, registry); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImageReference(t *testing.T) {
+	testCases := []struct {
+		name           string
+		image          string
+		expectedResult ImageReference
+	}{
+		{
+			name:           "DockerHubShortName",
+			image:          "mongo",
+			expectedResult: ImageReference{Repository: "mongo"},
+		},
+		{
+			name:           "DockerHubWithTag",
+			image:          "openpolicyagent/opa:0.50.0",
+			expectedResult: ImageReference{Repository: "openpolicyagent/opa", Tag: "0.50.0"},
+		},
+		{
+			name:           "RegistryWithTag",
+			image:          "quay.io/sdase/app:1.0",
+			expectedResult: ImageReference{Registry: "quay.io", Repository: "sdase/app", Tag: "1.0"},
+		},
+		{
+			name:           "RegistryWithPortTagAndDigest",
+			image:          "localhost:5000/app:1.0@sha256:1234",
+			expectedResult: ImageReference{Registry: "localhost:5000", Repository: "app", Tag: "1.0", Digest: "sha256:1234"},
+		},
+		{
+			name:           "RegistryWithPortNoTag",
+			image:          "registry.local:5000/team/app",
+			expectedResult: ImageReference{Registry: "registry.local:5000", Repository: "team/app"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedResult, ParseImageReference(tc.image))
+		})
+	}
+}
+
+func TestImageDigest(t *testing.T) {
+	assert.Equal(t, "sha256:1234", ImageDigest("quay.io/sdase/app@sha256:1234"))
+	assert.Equal(t, "sha256:1234", ImageDigest("sha256:1234"))
+	assert.Equal(t, "", ImageDigest("quay.io/sdase/app:1.0"))
+}
+
+func TestIsImageMismatch(t *testing.T) {
+	assert.False(t, IsImageMismatch("nginx:1.25", "docker.io/library/nginx@sha256:1234"))
+	assert.False(t, IsImageMismatch("quay.io/sdase/app:1.0", "docker-pullable://quay.io/sdase/app@sha256:1234"))
+	assert.False(t, IsImageMismatch("quay.io/sdase/app:1.0", "sha256:1234"))
+	assert.False(t, IsImageMismatch("quay.io/sdase/app:1.0", ""))
+	assert.True(t, IsImageMismatch("quay.io/sdase/app:1.0", "mirror.local/sdase/app@sha256:1234"))
+	assert.True(t, IsImageMismatch("nginx:1.25", "docker.io/library/httpd@sha256:1234"))
+}
+
+func TestNormalizeImage(t *testing.T) {
+	testCases := map[string]string{
+		"nginx":                                  "docker.io/library/nginx:latest",
+		"registry-1.docker.io/nginx":             "docker.io/library/nginx:latest",
+		"index.docker.io/bitnami/redis@sha256:1": "docker.io/bitnami/redis@sha256:1",
+		"nginx:1.25":                             "docker.io/library/nginx:1.25",
+		"docker.io/library/nginx:1.25":           "docker.io/library/nginx:1.25",
+		"index.docker.io/library/nginx:1.25":     "docker.io/library/nginx:1.25",
+		"bitnami/redis:7":                        "docker.io/bitnami/redis:7",
+		"nginx@sha256:1234":                      "docker.io/library/nginx@sha256:1234",
+		"registry.example.com:443/team/app:1.0":  "registry.example.com/team/app:1.0",
+		"registry.example.com:5000/team/app:1.0": "registry.example.com:5000/team/app:1.0",
+		"localhost/app:1.0@sha256:1234":          "localhost/app:1.0@sha256:1234",
+		"":                                       "",
+	}
+	for image, expected := range testCases {
+		assert.Equal(t, expected, NormalizeImage(image), image)
+	}
+}
+
+func TestIsInternalRegistry(t *testing.T) {
+	internalRegistries := []string{"registry.example.com", "*.azurecr.io", "localhost:5000"}
+
+	assert.True(t, IsInternalRegistry("registry.example.com/team/app:1.0", internalRegistries))
+	assert.True(t, IsInternalRegistry("sdase.azurecr.io/app@sha256:1234", internalRegistries))
+	assert.True(t, IsInternalRegistry("localhost:5000/app", internalRegistries))
+	assert.False(t, IsInternalRegistry("nginx:1.25", internalRegistries))
+	assert.False(t, IsInternalRegistry("docker.io/library/nginx:1.25", internalRegistries))
+	assert.False(t, IsInternalRegistry("quay.io/sdase/app:1.0", internalRegistries))
+	assert.True(t, IsInternalRegistry("nginx:1.25", []string{"docker.io"}))
+}
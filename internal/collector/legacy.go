@@ -0,0 +1,90 @@
+package collector
+
+// legacyImage is the image model of the previous ClusterImageScanner image collector, see
+// test_actions/imagecollector/expectedFiles for reports in this format
+type legacyImage struct {
+	Environment string `json:"environment"`
+	Namespace   string `json:"namespace"`
+	Image       string `json:"image"`
+	ImageId     string `json:"image_id"`
+
+	Team       string `json:"team"`
+	Slack      string `json:"slack"`
+	Rocketchat string `json:"rocketchat"`
+	Email      string `json:"email"`
+
+	AppKubernetesIoName    string `json:"app_kubernetes_io_name"`
+	AppKubernetesIoVersion string `json:"app_kubernetes_io_version"`
+	ContainerType          string `json:"container_type"`
+
+	IsScanBaseimageLifetime          bool `json:"is_scan_baseimage_lifetime"`
+	IsScanDependencyCheck            bool `json:"is_scan_dependency_check"`
+	IsScanDependencyTrack            bool `json:"is_scan_dependency_track"`
+	IsScanDistroless                 bool `json:"is_scan_distroless"`
+	IsScanLifetime                   bool `json:"is_scan_lifetime"`
+	IsScanMalware                    bool `json:"is_scan_malware"`
+	IsScanNewVersion                 bool `json:"is_scan_new_version"`
+	IsScanRunAsRoot                  bool `json:"is_scan_run_as_root"`
+	IsPotentiallyRunningAsRoot       bool `json:"is_potentially_running_as_root"`
+	IsScanRunAsPrivileged            bool `json:"is_scan_run_as_privileged"`
+	IsPotentiallyRunningAsPrivileged bool `json:"is_potentially_running_as_privileged"`
+
+	// The legacy collector wrote the lifetime in days under this name, consumers rely on it
+	ScanLifetimeMaxDays int64 `json:"is_scan_runasroot"`
+
+	Skip bool `json:"skip"`
+
+	// The legacy collector never resolved the source url and always wrote false
+	ScmSourceUrl bool `json:"scm_source_url"`
+
+	EngagementTags []string `json:"engagement_tags"`
+}
+
+func toLegacyImages(images []CollectorImage) []legacyImage {
+	legacyImages := make([]legacyImage, len(images))
+	for i, image := range images {
+		legacyImages[i] = legacyImage{
+			Environment: image.Environment,
+			Namespace:   image.Namespace,
+			Image:       image.Image,
+			ImageId:     image.ImageId,
+
+			Team:  image.Team,
+			Slack: image.Slack,
+			Email: image.Email,
+
+			AppKubernetesIoName:    image.AppKubernetesIoName,
+			AppKubernetesIoVersion: image.AppKubernetesIoVersion,
+			ContainerType:          image.ContainerType,
+
+			IsScanBaseimageLifetime:          image.IsScanBaseimageLifetime,
+			IsScanDependencyCheck:            image.IsScanDependencyCheck,
+			IsScanDependencyTrack:            image.IsScanDependencyTrack,
+			IsScanDistroless:                 image.IsScanDistroless,
+			IsScanLifetime:                   image.IsScanLifetime,
+			IsScanMalware:                    image.IsScanMalware,
+			IsScanNewVersion:                 image.IsScanNewVersion,
+			IsScanRunAsRoot:                  image.IsScanRunAsRoot,
+			IsPotentiallyRunningAsRoot:       image.IsPotentiallyRunningAsRoot,
+			IsScanRunAsPrivileged:            image.IsScanRunAsPrivileged,
+			IsPotentiallyRunningAsPrivileged: image.IsPotentiallyRunningAsPrivileged,
+			ScanLifetimeMaxDays:              image.ScanLifetimeMaxDays,
+
+			Skip:           image.Skip,
+			EngagementTags: image.EngagementTags,
+		}
+	}
+	return legacyImages
+}
+
+// legacyMarshal serializes the images with the legacy field names before handing them to marshal
+func legacyMarshal(marshal JsonMarshal) JsonMarshal {
+	return func(v any) ([]byte, error) {
+		images, err := imagesFromAny(v)
+		if err != nil {
+			return nil, err
+		}
+		legacyImages := toLegacyImages(images)
+		return marshal(&legacyImages)
+	}
+}
@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+)
+
+// Keys read from the annotations and labels with the scans and contact prefixes
+var (
+	knownScanKeys = []string{
+		"skip",
+		"skip-until",
+		"namespace-filter",
+		"negated_namespace_filter",
+		"is-scan-baseimage-lifetime",
+		"is-scan-dependency-check",
+		"is-scan-dependency-track",
+		"is-scan-distroless",
+		"is-scan-lifetime",
+		"is-scan-malware",
+		"is-scan-new-version",
+		"is-scan-runasroot",
+		"is-scan-potentially-running-as-root",
+		"is-scan-run-as-privileged",
+		"is-scan-potentially-running-as-privileged",
+		"scan-lifetime-max-days",
+	}
+	knownContactKeys = []string{"team", "slack", "email"}
+)
+
+// maxSuggestionDistance is the maximum edit distance of a known key suggested for an unknown one
+const maxSuggestionDistance = 3
+
+// AnnotationWarning lists an annotation or label with the scans or contact prefix which the collector doesn't know,
+// usually a typo which silently falls back to the default. Suggestion is the closest known annotation if any.
+type AnnotationWarning struct {
+	Namespace  string `json:"namespace"`
+	Annotation string `json:"annotation"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// LintAnnotations returns the unknown annotations and labels of the pods and namespaces, every annotation is listed
+// once per namespace
+func LintAnnotations(k8Images []kubeclient.Image, annotationNames *AnnotationNames) []AnnotationWarning {
+	var warnings []AnnotationWarning
+	seen := map[AnnotationWarning]bool{}
+	for _, k8Image := range k8Images {
+		for name := range imageTags(k8Image) {
+			suggestion, unknown := lintAnnotation(name, annotationNames)
+			if !unknown {
+				continue
+			}
+			warning := AnnotationWarning{Namespace: k8Image.NamespaceName, Annotation: name, Suggestion: suggestion}
+			if !seen[warning] {
+				seen[warning] = true
+				warnings = append(warnings, warning)
+			}
+		}
+	}
+
+	sort.Slice(warnings, func(i, j int) bool {
+		if warnings[i].Namespace != warnings[j].Namespace {
+			return warnings[i].Namespace < warnings[j].Namespace
+		}
+		return warnings[i].Annotation < warnings[j].Annotation
+	})
+	return warnings
+}
+
+// lintAnnotation reports whether the name has the scans or contact prefix but no known key and returns the closest
+// known annotation
+func lintAnnotation(name string, annotationNames *AnnotationNames) (string, bool) {
+	prefixes := []struct {
+		prefix string
+		keys   []string
+	}{
+		{annotationNames.Scans, knownScanKeys},
+		{annotationNames.Contact, knownContactKeys},
+	}
+
+	var unknown bool
+	var suggestion string
+	distance := maxSuggestionDistance + 1
+	for _, p := range prefixes {
+		key, ok := strings.CutPrefix(name, p.prefix)
+		if p.prefix == "" || !ok {
+			continue
+		}
+		for _, known := range p.keys {
+			if key == known {
+				return "", false
+			}
+			if d := editDistance(key, known); d < distance {
+				distance = d
+				suggestion = p.prefix + known
+			}
+		}
+		unknown = true
+	}
+	return suggestion, unknown
+}
+
+// editDistance is the Levenshtein distance of a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,38 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintAnnotations(t *testing.T) {
+	annotationNames := AnnotationNames{Base: "sdase.org/", Scans: "clusterscanner.sdase.org/", Contact: "contact.sdase.org/"}
+	k8Images := []kubeclient.Image{
+		{
+			NamespaceName: "team-a",
+			Labels:        map[string]string{"app.kubernetes.io/name": "app", "sdase.org/custom": "value"},
+			Annotations: map[string]string{
+				"clusterscanner.sdase.org/is-scan-run-as-root": "false",
+				"clusterscanner.sdase.org/skip-until":          "2025-12-31",
+				"contact.sdase.org/team":                       "a",
+			},
+		},
+		{
+			NamespaceName: "team-a",
+			Annotations:   map[string]string{"clusterscanner.sdase.org/is-scan-run-as-root": "false"},
+		},
+		{
+			NamespaceName: "team-b",
+			Annotations:   map[string]string{"contact.sdase.org/slak": "#b"},
+		},
+	}
+
+	warnings := LintAnnotations(k8Images, &annotationNames)
+
+	assert.Equal(t, []AnnotationWarning{
+		{Namespace: "team-a", Annotation: "clusterscanner.sdase.org/is-scan-run-as-root", Suggestion: "clusterscanner.sdase.org/is-scan-runasroot"},
+		{Namespace: "team-b", Annotation: "contact.sdase.org/slak", Suggestion: "contact.sdase.org/slack"},
+	}, warnings)
+}
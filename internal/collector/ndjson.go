@@ -0,0 +1,15 @@
+package collector
+
+import (
+	"bytes"
+)
+
+// NdjsonMarshal writes one JSON object per image, separated by newlines, so log pipelines can ingest the report
+// line by line
+func NdjsonMarshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NdjsonEncode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
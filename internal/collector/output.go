@@ -0,0 +1,235 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/rs/zerolog/log"
+)
+
+type OutputConfig struct {
+	OutputFormat string
+	CsvColumns   []string
+
+	// JsonCompact writes the json output without indentation, it is indented by default
+	JsonCompact bool
+
+	// OutputTemplateFile is a Go template rendering the images, it implies the template output format
+	OutputTemplateFile string
+
+	// OutputCompat serializes the report with the field names of an older model, only 'legacy' is supported
+	OutputCompat string
+
+	// SplitBy writes one report per distinct value of the field instead of a single report
+	SplitBy string
+
+	// GroupBy aggregates the images of the report, only 'workload' is supported, see GroupWorkloads
+	GroupBy string
+
+	// OutputFilter is a JMESPath expression evaluated on every image, only the images for which it is truthy are
+	// reported, see FilterImages
+	OutputFilter string
+
+	// PostProcessFile holds JSON Patch rules applied to the entries of the json or ndjson report before it is
+	// stored, see PostProcessRule. It is read on startup.
+	PostProcessFile string
+
+	// ValidateOutput validates JSON and NDJSON reports against the JSON Schema before they are stored
+	ValidateOutput bool
+
+	// AllowPartial reports the images of a run in which some namespaces or enrichments failed, together with the
+	// errors in the envelope '{"images": [...], "errors": [...]}'
+	AllowPartial bool
+
+	// ReportAnnotationWarnings adds the unknown annotations of the run to the envelope, see LintAnnotations
+	ReportAnnotationWarnings bool
+
+	// TrackSeen adds the first and last seen time to the images, the first seen time is read from the previous report
+	TrackSeen bool
+
+	// StaleRuns keeps the images of the previous report which were not collected for up to this number of runs as
+	// stale, 0 drops them at once
+	StaleRuns int
+}
+
+// OutputFormat describes how the images are serialized into the report
+type OutputFormat struct {
+	Marshal JsonMarshal
+	// Encode streams the images, it is only set for formats which can be written image by image. The envelope is only
+	// streamed by EncodeWithErrors.
+	Encode      JsonEncode
+	ContentType string
+	Extension   string
+
+	// Envelope wraps the images with the errors of the run, see MarshalWithErrors
+	Envelope           bool
+	annotationWarnings bool
+	indent             bool
+	filter             *jmespath.JMESPath
+}
+
+// NewOutputFormat returns the serialization for the configured output format, defaults to JSON
+func NewOutputFormat(cfg *OutputConfig) (*OutputFormat, error) {
+	outputFormat, err := newOutputFormat(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = SplitImages(nil, cfg.SplitBy); err != nil {
+		return nil, err
+	}
+	if outputFormat.filter, err = compileOutputFilter(cfg.OutputFilter); err != nil {
+		return nil, err
+	}
+
+	switch cfg.OutputCompat {
+	case "":
+	case "legacy":
+		if (cfg.OutputFormat != "json" && cfg.OutputFormat != "") || cfg.OutputTemplateFile != "" {
+			return nil, fmt.Errorf("Output compat legacy is only supported for the json output format")
+		}
+		outputFormat.Marshal = legacyMarshal(outputFormat.Marshal)
+		outputFormat.Encode = nil
+	default:
+		return nil, fmt.Errorf("Output compat %s is not supported", cfg.OutputCompat)
+	}
+
+	switch cfg.GroupBy {
+	case "":
+	case GroupByWorkload:
+		if (cfg.OutputFormat != "json" && cfg.OutputFormat != "ndjson" && cfg.OutputFormat != "") || cfg.OutputTemplateFile != "" || cfg.OutputCompat != "" {
+			return nil, fmt.Errorf("Group by workload is only supported for the json and ndjson output format")
+		}
+		if cfg.OutputFormat == "ndjson" {
+			outputFormat.Marshal = workloadNdjsonMarshal
+		} else {
+			outputFormat.Marshal = workloadMarshal(outputFormat.Marshal)
+		}
+		outputFormat.Encode = nil
+	default:
+		return nil, fmt.Errorf("Group by %s is not supported", cfg.GroupBy)
+	}
+
+	if cfg.PostProcessFile != "" {
+		if (cfg.OutputFormat != "json" && cfg.OutputFormat != "ndjson" && cfg.OutputFormat != "") || cfg.OutputTemplateFile != "" {
+			return nil, fmt.Errorf("Post-processing is only supported for the json and ndjson output format")
+		}
+		rules, err := LoadPostProcessRules(cfg.PostProcessFile)
+		if err != nil {
+			return nil, err
+		}
+		// The report is patched as a whole before it is validated, it can not be streamed
+		if cfg.OutputFormat == "ndjson" {
+			outputFormat.Marshal = postProcessNdjsonMarshal(outputFormat.Marshal, rules)
+		} else {
+			outputFormat.Marshal = postProcessMarshal(outputFormat.Marshal, rules, !cfg.JsonCompact)
+		}
+		outputFormat.Encode = nil
+	}
+
+	if cfg.ValidateOutput && cfg.OutputCompat != "" {
+		log.Warn().Str("outputCompat", cfg.OutputCompat).Msg("Output validation is not supported for the legacy output, ignoring it")
+	} else if cfg.ValidateOutput && cfg.GroupBy != "" {
+		log.Warn().Str("groupBy", cfg.GroupBy).Msg("Output validation is not supported for the grouped output, ignoring it")
+	} else if cfg.ValidateOutput {
+		format := cfg.OutputFormat
+		if cfg.OutputTemplateFile != "" {
+			format = "template"
+		}
+
+		// The report is validated as a whole, it can not be streamed
+		switch format {
+		case "json", "":
+			outputFormat.Marshal = validatingMarshal(outputFormat.Marshal, ValidateJson)
+			outputFormat.Encode = nil
+		case "ndjson":
+			outputFormat.Marshal = validatingMarshal(outputFormat.Marshal, ValidateNdjson)
+			outputFormat.Encode = nil
+		default:
+			log.Warn().Str("outputFormat", format).Msg("Output validation is only supported for json and ndjson, ignoring it")
+		}
+	}
+
+	if cfg.AllowPartial || cfg.ReportAnnotationWarnings {
+		if (cfg.OutputFormat != "json" && cfg.OutputFormat != "") || cfg.OutputTemplateFile != "" || cfg.OutputCompat != "" || cfg.GroupBy != "" {
+			return nil, fmt.Errorf("Allow partial and report annotation warnings are only supported for the json output format")
+		}
+		outputFormat.Envelope = true
+		outputFormat.annotationWarnings = cfg.ReportAnnotationWarnings
+		outputFormat.indent = !cfg.JsonCompact
+	}
+
+	return outputFormat, nil
+}
+
+func newOutputFormat(cfg *OutputConfig) (*OutputFormat, error) {
+	if cfg.OutputTemplateFile != "" && cfg.OutputFormat != "template" && cfg.OutputFormat != "json" && cfg.OutputFormat != "" {
+		return nil, fmt.Errorf("Output template file can not be combined with output format %s", cfg.OutputFormat)
+	}
+
+	if cfg.OutputTemplateFile != "" || cfg.OutputFormat == "template" {
+		if cfg.OutputTemplateFile == "" {
+			return nil, fmt.Errorf("Output format template requires an output template file")
+		}
+		marshal, err := NewTemplateMarshal(cfg.OutputTemplateFile)
+		if err != nil {
+			return nil, err
+		}
+		extension := templateExtension(cfg.OutputTemplateFile)
+		return &OutputFormat{Marshal: marshal, ContentType: templateContentType(extension), Extension: extension}, nil
+	}
+
+	switch cfg.OutputFormat {
+	case "json", "":
+		marshal, encode := JsonIndentMarshal, JsonIndentEncode
+		if cfg.JsonCompact {
+			marshal, encode = JsonCompactMarshal, JsonCompactEncode
+		}
+		return &OutputFormat{Marshal: marshal, Encode: encode, ContentType: "application/json", Extension: ".json"}, nil
+	case "spdx":
+		return &OutputFormat{Marshal: SpdxMarshal, ContentType: "application/spdx+json", Extension: ".spdx.json"}, nil
+	case "ndjson":
+		return &OutputFormat{Marshal: NdjsonMarshal, Encode: NdjsonEncode, ContentType: "application/x-ndjson", Extension: ".ndjson"}, nil
+	case "prometheus":
+		return &OutputFormat{Marshal: PrometheusMarshal, ContentType: "text/plain; version=0.0.4", Extension: ".prom"}, nil
+	case "protobuf":
+		return &OutputFormat{Marshal: ProtobufMarshal, ContentType: "application/x-protobuf", Extension: ".pb"}, nil
+	case "csv":
+		marshal, err := NewCsvMarshal(cfg.CsvColumns)
+		if err != nil {
+			return nil, err
+		}
+		return &OutputFormat{Marshal: marshal, ContentType: "text/csv", Extension: ".csv"}, nil
+	default:
+		return nil, fmt.Errorf("Output format %s is not supported", cfg.OutputFormat)
+	}
+}
+
+// imagesFromAny returns the images passed to a marshal function, which accept the same arguments as json.Marshal
+func imagesFromAny(v any) ([]CollectorImage, error) {
+	switch images := v.(type) {
+	case *[]CollectorImage:
+		if images == nil {
+			return nil, fmt.Errorf("cannot marshal nil")
+		}
+		return *images, nil
+	case []CollectorImage:
+		return images, nil
+	default:
+		return nil, fmt.Errorf("cannot marshal %T, expected collector images", v)
+	}
+}
+
+// validatingMarshal validates the serialized report, so serialization regressions are caught before anything is stored
+func validatingMarshal(marshal JsonMarshal, validate func([]byte) error) JsonMarshal {
+	return func(v any) ([]byte, error) {
+		data, err := marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if err = validate(data); err != nil {
+			return nil, fmt.Errorf("report does not match the JSON Schema: %w", err)
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,361 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/collector/schema"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestNewOutputFormat(t *testing.T) {
+	testCases := []struct {
+		name                string
+		format              string
+		expectedContentType string
+		expectError         bool
+	}{
+		{name: "DefaultExpectJson", format: "", expectedContentType: "application/json"},
+		{name: "Json", format: "json", expectedContentType: "application/json"},
+		{name: "Spdx", format: "spdx", expectedContentType: "application/spdx+json"},
+		{name: "Ndjson", format: "ndjson", expectedContentType: "application/x-ndjson"},
+		{name: "Csv", format: "csv", expectedContentType: "text/csv"},
+		{name: "Prometheus", format: "prometheus", expectedContentType: "text/plain; version=0.0.4"},
+		{name: "Protobuf", format: "protobuf", expectedContentType: "application/x-protobuf"},
+		{name: "UnknownExpectError", format: "xml", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			outputFormat, err := NewOutputFormat(&OutputConfig{OutputFormat: tc.format})
+			if tc.expectError {
+				assert.Error(t, err, "Expected error but got none")
+				return
+			}
+			assert.NoError(t, err, "Expected no error, got %v", err)
+			assert.Equal(t, tc.expectedContentType, outputFormat.ContentType)
+		})
+	}
+}
+
+func TestSpdxMarshal(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "ns", Image: "quay.io/sdase/app:1.0", ImageId: "quay.io/sdase/app@sha256:1234", Environment: "prod", Team: "team-a"},
+		{Namespace: "ns", Image: "mongo"},
+	}
+
+	data, err := SpdxMarshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	var document spdxDocument
+	assert.NoError(t, json.Unmarshal(data, &document))
+
+	assert.Equal(t, "SPDX-2.3", document.SpdxVersion)
+	assert.Equal(t, "prod-images", document.Name)
+	assert.Len(t, document.Packages, 2)
+	assert.Len(t, document.Relationships, 2)
+
+	pkg := document.Packages[0]
+	assert.Equal(t, "1.0", pkg.VersionInfo)
+	assert.Equal(t, "CONTAINER", pkg.PrimaryPackagePurpose)
+	assert.Equal(t, "Organization: team-a", pkg.Supplier)
+	assert.Equal(t, []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: "1234"}}, pkg.Checksums)
+	assert.Equal(t, "pkg:oci/app@sha256%3A1234?repository_url=quay.io%2Fsdase%2Fapp&tag=1.0", pkg.ExternalRefs[0].ReferenceLocator)
+
+	assert.Empty(t, document.Packages[1].Checksums)
+
+	_, err = SpdxMarshal(nil)
+	assert.Error(t, err, "Expected error but got none")
+}
+
+func TestCsvMarshal(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "ns", Image: "quay.io/sdase/app:1.0", Team: "team-a", Product: "p", Environment: "prod", Skip: true},
+		{Namespace: "ns", Image: "mongo", EngagementTags: []string{"a", "b"}, ScanLifetimeMaxDays: 14},
+	}
+
+	marshal, err := NewCsvMarshal(nil)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	data, err := marshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, "namespace,image,tag,team,product,environment,skip\n"+
+		"ns,quay.io/sdase/app:1.0,1.0,team-a,p,prod,true\n"+
+		"ns,mongo,,,,,false\n", string(data))
+
+	marshal, err = NewCsvMarshal([]string{"image", "engagement_tags", "scan_lifetime_max_days"})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	data, err = marshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, "image,engagement_tags,scan_lifetime_max_days\n"+
+		"quay.io/sdase/app:1.0,,0\n"+
+		"mongo,a;b,14\n", string(data))
+
+	_, err = NewCsvMarshal([]string{"unknown"})
+	assert.Error(t, err, "Expected error but got none")
+}
+
+func TestNdjsonMarshal(t *testing.T) {
+	images := []CollectorImage{{Namespace: "ns1", Image: "mongo"}, {Namespace: "ns2", Image: "nginx"}}
+
+	data, err := NdjsonMarshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	assert.Len(t, lines, 2)
+	for i, line := range lines {
+		var image CollectorImage
+		assert.NoError(t, json.Unmarshal([]byte(line), &image))
+		assert.Equal(t, images[i], image)
+	}
+
+	data, err = NdjsonMarshal(&[]CollectorImage{})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Empty(t, data)
+}
+
+func TestPrometheusMarshal(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "ns", Image: "nginx", ImageId: "sha256:1", Team: "team-\"a\""},
+		{Namespace: "ns", Image: "mongo", Environment: "prod", Skip: true},
+		{Namespace: "ns", Image: "nginx", ImageId: "sha256:1", Team: "team-\"a\""},
+	}
+
+	data, err := PrometheusMarshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, "# HELP collector_image_info Container image running in the cluster.\n"+
+		"# TYPE collector_image_info gauge\n"+
+		`collector_image_info{namespace="ns",image="mongo",image_id="",environment="prod",product="",team="",skip="true"} 1`+"\n"+
+		`collector_image_info{namespace="ns",image="nginx",image_id="sha256:1",environment="",product="",team="team-\"a\"",skip="false"} 1`+"\n",
+		string(data))
+}
+
+func TestProtobufMarshal(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "ns", Image: "mongo", Skip: true, EngagementTags: []string{"a", "b"}, ScanLifetimeMaxDays: 14},
+		{Image: "nginx"},
+	}
+
+	data, err := ProtobufMarshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	// Decode the Report message and collect the raw fields of every Image message
+	var decoded []map[protowire.Number][]any
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		assert.Equal(t, protowire.Number(1), num)
+		assert.Equal(t, protowire.BytesType, typ)
+		data = data[n:]
+
+		message, n := protowire.ConsumeBytes(data)
+		assert.Greater(t, n, 0)
+		data = data[n:]
+
+		fields := map[protowire.Number][]any{}
+		for len(message) > 0 {
+			num, typ, n := protowire.ConsumeTag(message)
+			message = message[n:]
+			switch typ {
+			case protowire.BytesType:
+				value, n := protowire.ConsumeString(message)
+				fields[num] = append(fields[num], value)
+				message = message[n:]
+			case protowire.VarintType:
+				value, n := protowire.ConsumeVarint(message)
+				fields[num] = append(fields[num], value)
+				message = message[n:]
+			default:
+				t.Fatalf("unexpected wire type %v", typ)
+			}
+		}
+		decoded = append(decoded, fields)
+	}
+
+	assert.Equal(t, []map[protowire.Number][]any{
+		{1: {"ns"}, 2: {"mongo"}, 10: {uint64(1)}, 13: {"a", "b"}, 28: {uint64(14)}},
+		{2: {"nginx"}},
+	}, decoded)
+}
+
+// protobufJsonNames maps the fields of report.proto to the keys of the JSON output which are spelled differently
+var protobufJsonNames = map[protoreflect.Name]string{"is_scan_malware": "is_scan_maleware"}
+
+func TestProtobufMatchesJson(t *testing.T) {
+	// Every field is set, so a field missing in the protobuf output or in the schema fails the comparison
+	full := CollectorImage{}
+	value := reflect.ValueOf(&full).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value.Type().Field(i).Name)
+		case reflect.Bool:
+			field.SetBool(true)
+		case reflect.Int, reflect.Int64:
+			field.SetInt(int64(i + 1))
+		case reflect.Slice:
+			field.Set(reflect.MakeSlice(field.Type(), 2, 2))
+			for j := 0; j < 2; j++ {
+				if field.Index(j).Kind() == reflect.String {
+					field.Index(j).SetString(fmt.Sprint(value.Type().Field(i).Name, j))
+				} else {
+					field.Index(j).SetInt(int64(i + j))
+				}
+			}
+		default:
+			t.Fatalf("unexpected kind %v of field %s", field.Kind(), value.Type().Field(i).Name)
+		}
+	}
+	images := []CollectorImage{full, {Image: "nginx"}}
+
+	outputFormat, err := NewOutputFormat(&OutputConfig{OutputFormat: "json"})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	jsonData, err := outputFormat.Marshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	var jsonImages []map[string]any
+	assert.NoError(t, json.Unmarshal(jsonData, &jsonImages))
+
+	files, err := schema.Files()
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	descriptor, err := files.FindDescriptorByName("sdase.imagemetadatacollector.v1.Report")
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	protobufData, err := ProtobufMarshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	report := dynamicpb.NewMessage(descriptor.(protoreflect.MessageDescriptor))
+	assert.NoError(t, proto.Unmarshal(protobufData, report))
+
+	protobufImages := report.Get(report.Descriptor().Fields().ByName("images")).List()
+	assert.Equal(t, len(jsonImages), protobufImages.Len())
+	for i := 0; i < protobufImages.Len(); i++ {
+		image := protobufImages.Get(i).Message()
+		assert.Empty(t, image.GetUnknown(), "Expected only fields of the schema in image %d", i)
+
+		fields := image.Descriptor().Fields()
+		protobufKeys := map[string]bool{}
+		for j := 0; j < fields.Len(); j++ {
+			field := fields.Get(j)
+			key, ok := protobufJsonNames[field.Name()]
+			if !ok {
+				key = string(field.Name())
+			}
+			protobufKeys[key] = true
+
+			jsonValue, ok := jsonImages[i][key]
+			if !ok && !image.Has(field) {
+				// Omitted from the JSON output and unset in the protobuf output
+				continue
+			}
+			assert.Equal(t, jsonValue, protobufJsonValue(image.Get(field), field), "Expected field %s of image %d to match", field.Name(), i)
+		}
+		for key := range jsonImages[i] {
+			assert.True(t, protobufKeys[key], "Expected the JSON field %s in report.proto", key)
+		}
+	}
+}
+
+// protobufJsonValue converts the value of a field to the type json.Unmarshal decodes the JSON output into
+func protobufJsonValue(value protoreflect.Value, field protoreflect.FieldDescriptor) any {
+	if field.IsList() {
+		list := value.List()
+		if list.Len() == 0 {
+			return nil
+		}
+		values := make([]any, list.Len())
+		for i := range values {
+			values[i] = protobufJsonScalar(list.Get(i))
+		}
+		return values
+	}
+	return protobufJsonScalar(value)
+}
+
+func protobufJsonScalar(value protoreflect.Value) any {
+	switch v := value.Interface().(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	default:
+		return v
+	}
+}
+
+func TestTemplateOutputFormat(t *testing.T) {
+	templateFile := filepath.Join(t.TempDir(), "inventory.md.tmpl")
+	template := "| Namespace | Image | Tag |\n{{ range . }}| {{ .Namespace }} | {{ .Image }} | {{ (imageReference .Image).Tag }} |\n{{ end }}"
+	assert.NoError(t, os.WriteFile(templateFile, []byte(template), 0644))
+	// The content type of the extension depends on the mime.types of the host otherwise
+	assert.NoError(t, mime.AddExtensionType(".md", "text/markdown; charset=utf-8"))
+
+	outputFormat, err := NewOutputFormat(&OutputConfig{OutputTemplateFile: templateFile, ValidateOutput: true})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, ".md", outputFormat.Extension)
+	assert.Equal(t, "text/markdown; charset=utf-8", outputFormat.ContentType)
+	assert.Equal(t, "text/plain; charset=utf-8", templateContentType(".inventory"))
+
+	data, err := outputFormat.Marshal(&[]CollectorImage{{Namespace: "ns", Image: "quay.io/sdase/app:1.0"}})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, "| Namespace | Image | Tag |\n| ns | quay.io/sdase/app:1.0 | 1.0 |\n", string(data))
+
+	_, err = NewOutputFormat(&OutputConfig{OutputFormat: "template"})
+	assert.Error(t, err, "Expected error but got none")
+
+	_, err = NewOutputFormat(&OutputConfig{OutputFormat: "csv", OutputTemplateFile: templateFile})
+	assert.Error(t, err, "Expected error but got none")
+
+	_, err = NewOutputFormat(&OutputConfig{OutputTemplateFile: filepath.Join(t.TempDir(), "missing.tmpl")})
+	assert.Error(t, err, "Expected error but got none")
+}
+
+func TestLegacyOutputCompat(t *testing.T) {
+	outputFormat, err := NewOutputFormat(&OutputConfig{OutputCompat: "legacy"})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	images := []CollectorImage{{Namespace: "ns", Image: "mongo", Team: "team-a", IsPotentiallyRunningAsRoot: true, ScanLifetimeMaxDays: 14}}
+	data, err := outputFormat.Marshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	var report []map[string]any
+	assert.NoError(t, json.Unmarshal(data, &report))
+	assert.Len(t, report, 1)
+	assert.Equal(t, "team-a", report[0]["team"])
+	assert.Equal(t, "", report[0]["rocketchat"])
+	assert.Equal(t, true, report[0]["is_potentially_running_as_root"])
+	assert.Equal(t, float64(14), report[0]["is_scan_runasroot"])
+	assert.Equal(t, false, report[0]["scm_source_url"])
+	assert.NotContains(t, report[0], "is_scan_potentially_running_as_root")
+	assert.NotContains(t, report[0], "product")
+
+	_, err = NewOutputFormat(&OutputConfig{OutputFormat: "csv", OutputCompat: "legacy"})
+	assert.Error(t, err, "Expected error but got none")
+
+	_, err = NewOutputFormat(&OutputConfig{OutputCompat: "unknown"})
+	assert.Error(t, err, "Expected error but got none")
+}
+
+func TestJsonCompact(t *testing.T) {
+	images := []CollectorImage{{Namespace: "ns", Image: "mongo"}}
+
+	outputFormat, err := NewOutputFormat(&OutputConfig{})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	indented, err := outputFormat.Marshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Contains(t, string(indented), "\n\t{\n\t\t\"namespace\": \"ns\",")
+
+	outputFormat, err = NewOutputFormat(&OutputConfig{JsonCompact: true})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	compact, err := outputFormat.Marshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.NotContains(t, string(compact), "\n")
+	assert.JSONEq(t, string(indented), string(compact))
+}
@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// compileOutputFilter compiles the JMESPath expression selecting the images of the report, nil without expression
+func compileOutputFilter(expression string) (*jmespath.JMESPath, error) {
+	if expression == "" {
+		return nil, nil
+	}
+	filter, err := jmespath.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("Output filter %q is not a valid JMESPath expression: %w", expression, err)
+	}
+	return filter, nil
+}
+
+// FilterImages returns the images for which the output filter is truthy, evaluated on their json fields, e.g.
+// container_type == 'application'. All images are returned without output filter. Unlike the skip flag, the images
+// which are filtered out are left out of the report.
+func (f *OutputFormat) FilterImages(images []CollectorImage) ([]CollectorImage, error) {
+	if f.filter == nil {
+		return images, nil
+	}
+
+	filtered := make([]CollectorImage, 0, len(images))
+	for i := range images {
+		data, err := json.Marshal(&images[i])
+		if err != nil {
+			return nil, err
+		}
+		var document any
+		if err = json.Unmarshal(data, &document); err != nil {
+			return nil, err
+		}
+		result, err := f.filter.Search(document)
+		if err != nil {
+			return nil, fmt.Errorf("output filter failed on image %s of namespace %s: %w", images[i].Image, images[i].Namespace, err)
+		}
+		if truthy(result) {
+			filtered = append(filtered, images[i])
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterImages(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "ns", Image: "app:1.0", ContainerType: "application"},
+		{Namespace: "ns", Image: "envoy:1.0", ContainerType: ContainerTypeSidecar},
+		{Namespace: "ns", Image: "job:1.0", ContainerType: "application", Skip: true},
+	}
+
+	outputFormat, err := NewOutputFormat(&OutputConfig{})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	filtered, err := outputFormat.FilterImages(images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Len(t, filtered, 3)
+
+	outputFormat, err = NewOutputFormat(&OutputConfig{OutputFilter: "container_type == 'application' && !skip"})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	filtered, err = outputFormat.FilterImages(images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, []CollectorImage{images[0]}, filtered)
+
+	// Fields which are left out of the json report are null
+	outputFormat, err = NewOutputFormat(&OutputConfig{OutputFilter: "exposure"})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	filtered, err = outputFormat.FilterImages(images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Empty(t, filtered)
+
+	outputFormat, err = NewOutputFormat(&OutputConfig{OutputFilter: "length(namespace) > `1` && abs(image)"})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	_, err = outputFormat.FilterImages(images)
+	assert.Error(t, err, "Expected error but got none")
+
+	_, err = NewOutputFormat(&OutputConfig{OutputFilter: "container_type =="})
+	assert.Error(t, err, "Expected error but got none")
+}
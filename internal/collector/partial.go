@@ -0,0 +1,192 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+)
+
+// Stages of a run which may fail without failing a partial report
+const (
+	RunErrorStageNamespace   = "namespace"
+	RunErrorStageImageConfig = "image-config"
+	RunErrorStageAttachments = "attachments"
+	RunErrorStageHarbor      = "harbor"
+)
+
+// RunError records a namespace or enrichment which failed, the images collected otherwise are still reported if
+// partial reports are allowed
+type RunError struct {
+	Stage     string `json:"stage"`
+	Namespace string `json:"namespace,omitempty"`
+	Image     string `json:"image,omitempty"`
+	Error     string `json:"error"`
+}
+
+// PartialError is returned with the collected images if some namespaces or enrichments failed
+type PartialError struct {
+	Errors []RunError
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%d namespaces or enrichments failed, first: %s", len(e.Errors), e.Errors[0].Error)
+}
+
+// sortRunErrors orders the errors independently of the order the goroutines failed in
+func sortRunErrors(errs []RunError) {
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].Stage != errs[j].Stage {
+			return errs[i].Stage < errs[j].Stage
+		}
+		if errs[i].Namespace != errs[j].Namespace {
+			return errs[i].Namespace < errs[j].Namespace
+		}
+		return errs[i].Image < errs[j].Image
+	})
+}
+
+// partialReport is the envelope of a report which may be incomplete, errors is an empty array if nothing failed. The
+// collector version names the release which wrote the report.
+// The pull warnings list the images of the report which can't be pulled, the annotation warnings are only part of it
+// if they are reported.
+type partialReport struct {
+	Images json.RawMessage `json:"images"`
+	reportErrors
+}
+
+// reportErrors follow the images in the envelope
+type reportErrors struct {
+	CollectorVersion   string               `json:"collector_version"`
+	Errors             []RunError           `json:"errors"`
+	PullWarnings       []PullWarning        `json:"pull_warnings"`
+	AnnotationWarnings *[]AnnotationWarning `json:"annotation_warnings,omitempty"`
+}
+
+func (f *OutputFormat) reportErrors(errs []RunError, warnings []AnnotationWarning) reportErrors {
+	if errs == nil {
+		errs = []RunError{}
+	}
+	report := reportErrors{CollectorVersion: version.Version, Errors: errs, PullWarnings: []PullWarning{}}
+	if f.annotationWarnings {
+		if warnings == nil {
+			warnings = []AnnotationWarning{}
+		}
+		report.AnnotationWarnings = &warnings
+	}
+	return report
+}
+
+// MarshalWithErrors returns the marshal function of the report, the images are wrapped into an envelope with the
+// errors of the run if partial reports are allowed and with the annotation warnings if they are reported
+func (f *OutputFormat) MarshalWithErrors(errs []RunError, warnings []AnnotationWarning) JsonMarshal {
+	if !f.Envelope {
+		return f.Marshal
+	}
+	reportErrors := f.reportErrors(errs, warnings)
+
+	return func(v any) ([]byte, error) {
+		images, err := f.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		report := partialReport{Images: images, reportErrors: reportErrors}
+		if report.PullWarnings, err = pullWarningsOf(v); err != nil {
+			return nil, err
+		}
+		if f.indent {
+			return json.MarshalIndent(report, "", "\t")
+		}
+		return json.Marshal(report)
+	}
+}
+
+// EncodeWithErrors returns the streaming encode function of the report, the output equals the one of
+// MarshalWithErrors. It is nil if the output format can't be streamed.
+func (f *OutputFormat) EncodeWithErrors(errs []RunError, warnings []AnnotationWarning) JsonEncode {
+	if !f.Envelope || f.Encode == nil {
+		return f.Encode
+	}
+	reportErrors := f.reportErrors(errs, warnings)
+
+	return func(w io.Writer, v any) error {
+		images, err := imagesFromAny(v)
+		if err != nil {
+			return err
+		}
+		reportErrors := reportErrors
+		if warnings := PullWarnings(images); warnings != nil {
+			reportErrors.PullWarnings = warnings
+		}
+		// The errors are small, they are marshalled as a whole and their object is continued after the images
+		var tail []byte
+		if f.indent {
+			tail, err = json.MarshalIndent(reportErrors, "", "\t")
+		} else {
+			tail, err = json.Marshal(reportErrors)
+		}
+		if err != nil {
+			return err
+		}
+
+		buf := bufio.NewWriter(w)
+		if f.indent {
+			buf.WriteString("{\n\t\"images\": ")
+		} else {
+			buf.WriteString(`{"images":`)
+		}
+		if err = encodeJsonArray(buf, images, f.indent, "\t"); err != nil {
+			return err
+		}
+		buf.WriteString(",")
+		buf.Write(tail[1:])
+		return buf.Flush()
+	}
+}
+
+// pullWarningsOf returns the pull warnings of the images of a report, an empty list if all can be pulled
+func pullWarningsOf(v any) ([]PullWarning, error) {
+	images, err := imagesFromAny(v)
+	if err != nil {
+		return nil, err
+	}
+	if warnings := PullWarnings(images); warnings != nil {
+		return warnings, nil
+	}
+	return []PullWarning{}, nil
+}
+
+// UnmarshalImages reads the images of a stored json or ndjson report, the images of an envelope are returned without
+// the errors
+func UnmarshalImages(content []byte) ([]CollectorImage, error) {
+	content = bytes.TrimSpace(content)
+	if bytes.HasPrefix(content, []byte("[")) {
+		var images []CollectorImage
+		if err := json.Unmarshal(content, &images); err != nil {
+			return nil, err
+		}
+		return images, nil
+	}
+
+	var envelope struct {
+		Images *[]CollectorImage `json:"images"`
+	}
+	if err := json.Unmarshal(content, &envelope); err == nil && envelope.Images != nil {
+		return *envelope.Images, nil
+	}
+
+	images := []CollectorImage{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for decoder.More() {
+		var image CollectorImage
+		if err := decoder.Decode(&image); err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalWithErrors(t *testing.T) {
+	images := []CollectorImage{{Namespace: "ns", Image: "app:1.0"}}
+
+	outputFormat, err := NewOutputFormat(&OutputConfig{AllowPartial: true, JsonCompact: true})
+	assert.NoError(t, err)
+	assert.NotNil(t, outputFormat.EncodeWithErrors(nil, nil), "Expected the envelope to be streamed")
+
+	data, err := outputFormat.MarshalWithErrors(nil, nil)(&images)
+	assert.NoError(t, err)
+	var report struct {
+		Images           []CollectorImage `json:"images"`
+		CollectorVersion string           `json:"collector_version"`
+		Errors           []RunError       `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, images, report.Images)
+	assert.Equal(t, version.Version, report.CollectorVersion)
+	assert.NotNil(t, report.Errors)
+	assert.Empty(t, report.Errors)
+
+	data, err = outputFormat.MarshalWithErrors([]RunError{{Stage: RunErrorStageNamespace, Namespace: "broken", Error: "forbidden"}}, nil)(&images)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"errors":[{"stage":"namespace","namespace":"broken","error":"forbidden"}]`)
+
+	outputFormat, err = NewOutputFormat(&OutputConfig{})
+	assert.NoError(t, err)
+	data, err = outputFormat.MarshalWithErrors([]RunError{{Stage: RunErrorStageNamespace, Error: "forbidden"}}, nil)(&images)
+	assert.NoError(t, err)
+	assert.Equal(t, byte('['), data[0], "the report has no envelope without allow partial")
+
+	_, err = NewOutputFormat(&OutputConfig{AllowPartial: true, OutputFormat: "csv"})
+	assert.Error(t, err)
+}
+
+func TestMarshalWithAnnotationWarnings(t *testing.T) {
+	images := []CollectorImage{{Namespace: "ns", Image: "app:1.0"}}
+
+	outputFormat, err := NewOutputFormat(&OutputConfig{AllowPartial: true})
+	assert.NoError(t, err)
+	data, err := outputFormat.MarshalWithErrors(nil, []AnnotationWarning{{Namespace: "ns", Annotation: "contact.sdase.org/teams"}})(&images)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "annotation_warnings")
+
+	outputFormat, err = NewOutputFormat(&OutputConfig{ReportAnnotationWarnings: true, JsonCompact: true})
+	assert.NoError(t, err)
+	data, err = outputFormat.MarshalWithErrors(nil, nil)(&images)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"errors":[],"pull_warnings":[],"annotation_warnings":[]`)
+
+	data, err = outputFormat.MarshalWithErrors(nil, []AnnotationWarning{{Namespace: "ns", Annotation: "contact.sdase.org/teams", Suggestion: "contact.sdase.org/team"}})(&images)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"annotation_warnings":[{"namespace":"ns","annotation":"contact.sdase.org/teams","suggestion":"contact.sdase.org/team"}]`)
+}
+
+func TestMarshalWithPullWarnings(t *testing.T) {
+	images := []CollectorImage{{Namespace: "ns", Image: "app:1.0"}, {Namespace: "ns", Image: "gone:1.0", PullError: "ImagePullBackOff"}}
+	expected := `"pull_warnings":[{"namespace":"ns","image":"gone:1.0","reason":"ImagePullBackOff"}]`
+
+	outputFormat, err := NewOutputFormat(&OutputConfig{AllowPartial: true, JsonCompact: true})
+	assert.NoError(t, err)
+	data, err := outputFormat.MarshalWithErrors(nil, nil)(&images)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), expected)
+
+	var buf bytes.Buffer
+	assert.NoError(t, outputFormat.EncodeWithErrors(nil, nil)(&buf, &images))
+	assert.Contains(t, buf.String(), expected, "the streamed envelope lists them as well")
+}
+
+func TestPartialError(t *testing.T) {
+	err := &PartialError{Errors: []RunError{{Stage: RunErrorStageNamespace, Namespace: "a", Error: "forbidden"}, {Stage: RunErrorStageHarbor, Error: "timeout"}}}
+	assert.EqualError(t, err, "2 namespaces or enrichments failed, first: forbidden")
+}
+
+func TestUnmarshalImages(t *testing.T) {
+	images := []CollectorImage{{Namespace: "ns", Image: "app:1.0"}, {Namespace: "ns", Image: "db:2.0"}}
+
+	for _, outputConfig := range []OutputConfig{{}, {OutputFormat: "ndjson"}, {AllowPartial: true}} {
+		outputFormat, err := NewOutputFormat(&outputConfig)
+		assert.NoError(t, err)
+		data, err := outputFormat.MarshalWithErrors(nil, nil)(&images)
+		assert.NoError(t, err)
+
+		read, err := UnmarshalImages(data)
+		assert.NoError(t, err)
+		assert.Equal(t, images, read, "output format %v", outputConfig)
+	}
+
+	_, err := UnmarshalImages([]byte("{"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,209 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/jmespath/go-jmespath"
+	"gopkg.in/yaml.v3"
+)
+
+// PostProcessRules is the content of the post-process file, e.g.
+//
+//	rules:
+//	  - name: payments-team
+//	    when: namespace == 'payments'
+//	    patch:
+//	      - op: replace
+//	        path: /team
+//	        value: payments
+//	  - patch:
+//	      - op: remove
+//	        path: /description
+type PostProcessRules struct {
+	Rules []PostProcessRule `yaml:"rules"`
+}
+
+// PostProcessRule patches the entries of the serialized report, e.g. the images, for which the JMESPath expression
+// When is truthy, all entries without When. A patch removing a path fails on entries without it, When can select the
+// entries having it.
+type PostProcessRule struct {
+	Name  string           `yaml:"name"`
+	When  string           `yaml:"when"`
+	Patch []map[string]any `yaml:"patch"`
+
+	when  *jmespath.JMESPath
+	patch jsonpatch.Patch
+}
+
+// LoadPostProcessRules reads and compiles the rules file, an empty file name has no rules
+func LoadPostProcessRules(fileName string) ([]PostProcessRule, error) {
+	if fileName == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not read post-process rules: %w", err)
+	}
+
+	var rules PostProcessRules
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not parse post-process rules %s: %w", fileName, err)
+	}
+
+	for i := range rules.Rules {
+		if err = rules.Rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("post-process rule %s in %s is invalid: %w", rules.Rules[i].name(i), fileName, err)
+		}
+	}
+	return rules.Rules, nil
+}
+
+func (r *PostProcessRule) compile() error {
+	if len(r.Patch) == 0 {
+		return fmt.Errorf("patch is empty")
+	}
+	if r.When != "" {
+		when, err := jmespath.Compile(r.When)
+		if err != nil {
+			return fmt.Errorf("when %q is not a valid JMESPath expression: %w", r.When, err)
+		}
+		r.when = when
+	}
+
+	operations, err := json.Marshal(r.Patch)
+	if err != nil {
+		return fmt.Errorf("patch can't be serialized: %w", err)
+	}
+	if r.patch, err = jsonpatch.DecodePatch(operations); err != nil {
+		return fmt.Errorf("patch is not a valid JSON Patch: %w", err)
+	}
+	// The operations are only checked when they are applied, an unknown one would fail the first report
+	for i, operation := range r.patch {
+		switch kind := operation.Kind(); kind {
+		case "add", "remove", "replace", "move", "copy", "test":
+		default:
+			return fmt.Errorf("operation %d of the patch has the unknown op %q", i+1, kind)
+		}
+		if _, err = operation.Path(); err != nil {
+			return fmt.Errorf("operation %d of the patch has no path: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// name identifies the rule in errors, rules without a name by their position
+func (r *PostProcessRule) name(i int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// apply patches a single entry of the report
+func (r *PostProcessRule) apply(entry []byte) ([]byte, error) {
+	if r.when != nil {
+		var document any
+		if err := json.Unmarshal(entry, &document); err != nil {
+			return nil, err
+		}
+		result, err := r.when.Search(document)
+		if err != nil {
+			return nil, err
+		}
+		if !truthy(result) {
+			return entry, nil
+		}
+	}
+	return r.patch.Apply(entry)
+}
+
+// truthy follows JMESPath, false, null and empty strings, arrays and objects are false
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	case map[string]any:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
+
+// postProcessEntries applies the rules in order to every entry
+func postProcessEntries(entries []json.RawMessage, rules []PostProcessRule) error {
+	for i := range entries {
+		for j := range rules {
+			patched, err := rules[j].apply(entries[i])
+			if err != nil {
+				return fmt.Errorf("post-process rule %s failed on entry %d: %w", rules[j].name(j), i+1, err)
+			}
+			entries[i] = patched
+		}
+	}
+	return nil
+}
+
+// postProcessMarshal applies the rules to the entries of the json array serialized by marshal
+func postProcessMarshal(marshal JsonMarshal, rules []PostProcessRule, indent bool) JsonMarshal {
+	return func(v any) ([]byte, error) {
+		data, err := marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var entries []json.RawMessage
+		if err = json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("could not post-process the report: %w", err)
+		}
+		if err = postProcessEntries(entries, rules); err != nil {
+			return nil, err
+		}
+		if indent {
+			return json.MarshalIndent(entries, "", "\t")
+		}
+		return json.Marshal(entries)
+	}
+}
+
+// postProcessNdjsonMarshal applies the rules to every line serialized by marshal
+func postProcessNdjsonMarshal(marshal JsonMarshal, rules []PostProcessRule) JsonMarshal {
+	return func(v any) ([]byte, error) {
+		data, err := marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var entries []json.RawMessage
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			if len(line) > 0 {
+				entries = append(entries, line)
+			}
+		}
+		if err = postProcessEntries(entries, rules); err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		for _, entry := range entries {
+			// The patched entries are compacted, so every entry stays on a single line
+			if err = json.Compact(&buf, entry); err != nil {
+				return nil, err
+			}
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), nil
+	}
+}
@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPostProcessRules = `
+rules:
+  - name: payments-team
+    when: namespace == 'payments'
+    patch:
+      - op: replace
+        path: /team
+        value: payments
+  - patch:
+      - op: remove
+        path: /description
+`
+
+func writePostProcessRules(t *testing.T, content string) string {
+	fileName := filepath.Join(t.TempDir(), "post-process.yaml")
+	assert.NoError(t, os.WriteFile(fileName, []byte(content), 0o600))
+	return fileName
+}
+
+func TestPostProcess(t *testing.T) {
+	fileName := writePostProcessRules(t, testPostProcessRules)
+	images := []CollectorImage{
+		{Namespace: "payments", Image: "app:1.0", Team: "unknown", Description: "secret"},
+		{Namespace: "shop", Image: "shop:1.0", Team: "shop"},
+	}
+
+	outputFormat, err := NewOutputFormat(&OutputConfig{PostProcessFile: fileName})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Nil(t, outputFormat.Encode)
+	data, err := outputFormat.Marshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.True(t, strings.HasPrefix(string(data), "[\n\t{"), "Expected the report to stay indented")
+
+	var report []map[string]any
+	assert.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, "payments", report[0]["team"])
+	assert.Equal(t, "shop", report[1]["team"])
+	assert.NotContains(t, report[0], "description")
+	assert.NotContains(t, report[1], "description")
+
+	outputFormat, err = NewOutputFormat(&OutputConfig{OutputFormat: "ndjson", PostProcessFile: fileName})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	data, err = outputFormat.Marshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"team":"payments"`)
+
+	outputFormat, err = NewOutputFormat(&OutputConfig{PostProcessFile: fileName, AllowPartial: true, JsonCompact: true})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	data, err = outputFormat.MarshalWithErrors(nil, nil)(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Contains(t, string(data), `"team":"payments"`)
+}
+
+func TestLoadPostProcessRulesInvalid(t *testing.T) {
+	for _, content := range []string{
+		"rules:\n  - name: empty\n",
+		"rules:\n  - when: \"namespace ==\"\n    patch:\n      - op: remove\n        path: /team\n",
+		"rules:\n  - patch:\n      - op: unknown\n        path: /team\n",
+		"rules:\n  - unknown: field\n",
+	} {
+		_, err := NewOutputFormat(&OutputConfig{PostProcessFile: writePostProcessRules(t, content)})
+		assert.Error(t, err, "Expected error for %q but got none", content)
+	}
+
+	_, err := NewOutputFormat(&OutputConfig{OutputFormat: "csv", PostProcessFile: writePostProcessRules(t, testPostProcessRules)})
+	assert.Error(t, err, "Expected error but got none")
+}
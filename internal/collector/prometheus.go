@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const prometheusInfoMetric = "collector_image_info"
+
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// PrometheusMarshal renders the images in the Prometheus text exposition format, every image becomes a
+// collector_image_info series with value 1 so dashboards can count images by any of the labels
+func PrometheusMarshal(v any) ([]byte, error) {
+	images, err := imagesFromAny(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Identical images in the same namespace, e.g. several replicas, would result in duplicate series
+	series := map[string]struct{}{}
+	for _, image := range images {
+		series[prometheusLabels(image)] = struct{}{}
+	}
+
+	lines := make([]string, 0, len(series))
+	for labels := range series {
+		lines = append(lines, prometheusInfoMetric+labels+" 1\n")
+	}
+	sort.Strings(lines)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# HELP %s Container image running in the cluster.\n", prometheusInfoMetric)
+	fmt.Fprintf(&buf, "# TYPE %s gauge\n", prometheusInfoMetric)
+	for _, line := range lines {
+		buf.WriteString(line)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func prometheusLabels(image CollectorImage) string {
+	labels := []struct{ name, value string }{
+		{"namespace", image.Namespace},
+		{"image", image.Image},
+		{"image_id", image.ImageId},
+		{"environment", image.Environment},
+		{"product", image.Product},
+		{"team", image.Team},
+		{"skip", fmt.Sprint(image.Skip)},
+	}
+
+	pairs := make([]string, len(labels))
+	for i, label := range labels {
+		pairs[i] = label.name + `="` + prometheusLabelEscaper.Replace(label.value) + `"`
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,137 @@
+package collector
+
+import (
+	_ "embed"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ProtobufSchema is the published schema of the protobuf output format
+//
+//go:embed schema/report.proto
+var ProtobufSchema string
+
+// ProtobufMarshal encodes the images as the Report message defined in schema/report.proto, which is considerably
+// smaller than JSON for large clusters
+func ProtobufMarshal(v any) ([]byte, error) {
+	images, err := imagesFromAny(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	for i := range images {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, appendProtobufImage(nil, &images[i]))
+	}
+
+	return b, nil
+}
+
+// appendProtobufImage encodes the fields of the Image message, default values are omitted as in proto3
+func appendProtobufImage(b []byte, image *CollectorImage) []byte {
+	b = appendProtobufString(b, 1, image.Namespace)
+	b = appendProtobufString(b, 2, image.Image)
+	b = appendProtobufString(b, 3, image.ImageId)
+
+	b = appendProtobufString(b, 4, image.Environment)
+	b = appendProtobufString(b, 5, image.Product)
+	b = appendProtobufString(b, 6, image.Description)
+	b = appendProtobufString(b, 7, image.AppKubernetesIoName)
+	b = appendProtobufString(b, 8, image.AppKubernetesIoVersion)
+	b = appendProtobufString(b, 9, image.ContainerType)
+	b = appendProtobufBool(b, 10, image.Skip)
+	b = appendProtobufString(b, 11, image.NamespaceFilter)
+	b = appendProtobufString(b, 12, image.NamespaceFilterNegated)
+	for _, tag := range image.EngagementTags {
+		b = protowire.AppendTag(b, 13, protowire.BytesType)
+		b = protowire.AppendString(b, tag)
+	}
+
+	b = appendProtobufString(b, 14, image.Team)
+	b = appendProtobufString(b, 15, image.Slack)
+	b = appendProtobufString(b, 16, image.Email)
+
+	b = appendProtobufBool(b, 17, image.IsScanBaseimageLifetime)
+	b = appendProtobufBool(b, 18, image.IsScanDependencyCheck)
+	b = appendProtobufBool(b, 19, image.IsScanDependencyTrack)
+	b = appendProtobufBool(b, 20, image.IsScanDistroless)
+	b = appendProtobufBool(b, 21, image.IsScanLifetime)
+	b = appendProtobufBool(b, 22, image.IsScanMalware)
+	b = appendProtobufBool(b, 23, image.IsScanNewVersion)
+	b = appendProtobufBool(b, 24, image.IsScanRunAsRoot)
+	b = appendProtobufBool(b, 25, image.IsPotentiallyRunningAsRoot)
+	b = appendProtobufBool(b, 26, image.IsScanRunAsPrivileged)
+	b = appendProtobufBool(b, 27, image.IsPotentiallyRunningAsPrivileged)
+	if image.ScanLifetimeMaxDays != 0 {
+		b = protowire.AppendTag(b, 28, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(image.ScanLifetimeMaxDays))
+	}
+
+	b = appendProtobufString(b, 29, image.ImageCreated)
+	b = appendProtobufString(b, 30, image.ImageSource)
+	b = appendProtobufString(b, 31, image.ImageRevision)
+	b = appendProtobufBool(b, 32, image.HasSbom)
+	b = appendProtobufString(b, 33, image.SbomFormat)
+	b = appendProtobufBool(b, 34, image.HasAttestation)
+	b = appendProtobufString(b, 35, image.HarborProjectOwner)
+	b = appendProtobufString(b, 36, image.HarborSeverityThreshold)
+	b = appendProtobufString(b, 37, image.HarborScanStatus)
+	b = appendProtobufBool(b, 38, image.ImageMismatch)
+	b = appendProtobufString(b, 39, image.StatusImage)
+	b = appendProtobufBool(b, 40, image.IsInternalRegistry)
+	b = appendProtobufString(b, 41, image.PullError)
+	b = appendProtobufString(b, 42, image.HelmChart)
+	b = appendProtobufString(b, 43, image.HelmChartVersion)
+	b = appendProtobufString(b, 44, image.HelmRelease)
+	b = appendProtobufString(b, 45, image.FluxKustomization)
+	b = appendProtobufString(b, 46, image.FluxHelmRelease)
+	b = appendProtobufString(b, 47, image.FirstSeen)
+	b = appendProtobufString(b, 48, image.LastSeen)
+	b = appendProtobufBool(b, 49, image.Stale)
+	if image.MissedRuns != 0 {
+		b = protowire.AppendTag(b, 50, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(image.MissedRuns))
+	}
+	b = appendProtobufBool(b, 51, image.IsWindows)
+	b = appendProtobufBool(b, 52, image.Terminated)
+	b = appendProtobufBool(b, 53, image.Suspended)
+	if image.Replicas != 0 {
+		b = protowire.AppendTag(b, 54, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(image.Replicas))
+	}
+	b = appendProtobufString(b, 55, image.WorkloadKind)
+	b = appendProtobufString(b, 56, image.Workload)
+	for _, port := range image.ContainerPorts {
+		b = protowire.AppendTag(b, 57, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(port))
+	}
+	b = appendProtobufString(b, 58, image.Exposure)
+	if image.RiskScore != 0 {
+		b = protowire.AppendTag(b, 59, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(image.RiskScore))
+	}
+	for _, factor := range image.RiskFactors {
+		b = protowire.AppendTag(b, 60, protowire.BytesType)
+		b = protowire.AppendString(b, factor)
+	}
+	b = appendProtobufBool(b, 61, image.HasSignature)
+
+	return b
+}
+
+func appendProtobufString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendProtobufBool(b []byte, num protowire.Number, value bool) []byte {
+	if !value {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, protowire.EncodeBool(value))
+}
@@ -0,0 +1,114 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Factors of the risk score, the weight of a factor is added to the score of the images it applies to
+const (
+	// RiskFactorExposed applies to images of pods exposed by a LoadBalancer or an Ingress, see --service-exposure
+	RiskFactorExposed = "exposed"
+	// RiskFactorPrivileged applies to images potentially running privileged
+	RiskFactorPrivileged = "privileged"
+	// RiskFactorRoot applies to images potentially running as root
+	RiskFactorRoot = "root"
+	// RiskFactorMutableTag applies to images referenced by a tag instead of a digest
+	RiskFactorMutableTag = "mutable-tag"
+	// RiskFactorUnsigned applies to images without a cosign or Notary signature, it is only evaluated with the SBOM
+	// enrichment which discovers them
+	RiskFactorUnsigned = "unsigned"
+	// RiskFactorReplicas grows with the number of pods running the image up to RiskReplicasSaturation
+	RiskFactorReplicas = "replicas"
+)
+
+// RiskReplicasSaturation is the number of pods at which the replicas factor adds its full weight
+const RiskReplicasSaturation = 100
+
+// DefaultRiskWeights sum up to a maximum score of 100
+var DefaultRiskWeights = map[string]int{
+	RiskFactorExposed:    30,
+	RiskFactorPrivileged: 20,
+	RiskFactorRoot:       15,
+	RiskFactorMutableTag: 10,
+	RiskFactorUnsigned:   10,
+	RiskFactorReplicas:   15,
+}
+
+type RiskConfig struct {
+	// ScoreRisk adds a risk score and the factors contributing to it to the images
+	ScoreRisk bool
+	// RiskWeights override the DefaultRiskWeights, in the format '<factor>=<weight>', a weight of 0 disables a factor
+	RiskWeights []string
+}
+
+// ParseRiskWeights returns the DefaultRiskWeights with the given weights applied
+func ParseRiskWeights(values []string) (map[string]int, error) {
+	weights := map[string]int{}
+	for factor, weight := range DefaultRiskWeights {
+		weights[factor] = weight
+	}
+	for _, value := range values {
+		factor, weight, ok := strings.Cut(value, "=")
+		if _, known := DefaultRiskWeights[factor]; !ok || !known {
+			return nil, fmt.Errorf("invalid risk weight %s, expected '<factor>=<weight>' with a factor of %s", value, strings.Join(riskFactors(), ", "))
+		}
+		parsed, err := strconv.Atoi(weight)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid risk weight %s, the weight must be a non-negative integer", value)
+		}
+		weights[factor] = parsed
+	}
+	return weights, nil
+}
+
+func riskFactors() []string {
+	factors := make([]string, 0, len(DefaultRiskWeights))
+	for factor := range DefaultRiskWeights {
+		factors = append(factors, factor)
+	}
+	sort.Strings(factors)
+	return factors
+}
+
+// ScoreImages sets the risk score of the images to the sum of the weights of the factors applying to them, the
+// factors with a positive weight are listed in the order of their contribution
+func ScoreImages(images []CollectorImage, weights map[string]int) {
+	for i := range images {
+		image := &images[i]
+		contributions := map[string]int{}
+		add := func(factor string, applies bool) {
+			if applies && weights[factor] > 0 {
+				contributions[factor] = weights[factor]
+			}
+		}
+		add(RiskFactorExposed, image.Exposure != "")
+		add(RiskFactorPrivileged, image.IsPotentiallyRunningAsPrivileged)
+		add(RiskFactorRoot, image.IsPotentiallyRunningAsRoot)
+		add(RiskFactorMutableTag, !strings.Contains(image.Image, "@"))
+		add(RiskFactorUnsigned, !image.HasSignature)
+		if replicas := min(image.Replicas, RiskReplicasSaturation); replicas > 0 && weights[RiskFactorReplicas] > 0 {
+			contributions[RiskFactorReplicas] = int(math.Round(float64(weights[RiskFactorReplicas]*replicas) / RiskReplicasSaturation))
+		}
+
+		image.RiskScore = 0
+		image.RiskFactors = nil
+		for factor, contribution := range contributions {
+			if contribution == 0 {
+				continue
+			}
+			image.RiskScore += contribution
+			image.RiskFactors = append(image.RiskFactors, factor)
+		}
+		sort.Slice(image.RiskFactors, func(a, b int) bool {
+			fa, fb := image.RiskFactors[a], image.RiskFactors[b]
+			if contributions[fa] != contributions[fb] {
+				return contributions[fa] > contributions[fb]
+			}
+			return fa < fb
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRiskWeights(t *testing.T) {
+	weights, err := ParseRiskWeights([]string{"exposed=50", "replicas=0"})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, 50, weights[RiskFactorExposed])
+	assert.Equal(t, 0, weights[RiskFactorReplicas])
+	assert.Equal(t, DefaultRiskWeights[RiskFactorRoot], weights[RiskFactorRoot])
+	assert.Equal(t, 30, DefaultRiskWeights[RiskFactorExposed], "Expected the defaults not to be modified")
+
+	for _, value := range []string{"exposed", "unknown=10", "root=-1", "root=high"} {
+		_, err = ParseRiskWeights([]string{value})
+		assert.Error(t, err, "Expected error for %s but got none", value)
+	}
+}
+
+func TestScoreImages(t *testing.T) {
+	images := []CollectorImage{
+		{Image: "app:1.0", Exposure: "Ingress", IsPotentiallyRunningAsRoot: true, Replicas: 200},
+		{Image: "app@sha256:1234", HasAttestation: true, HasSignature: true, Replicas: 10},
+		{Image: "app@sha256:1234", HasSignature: true, RiskScore: 99, RiskFactors: []string{"stale"}},
+	}
+
+	ScoreImages(images, DefaultRiskWeights)
+
+	assert.Equal(t, 30+15+10+10+15, images[0].RiskScore)
+	assert.Equal(t, []string{RiskFactorExposed, RiskFactorReplicas, RiskFactorRoot, RiskFactorMutableTag, RiskFactorUnsigned}, images[0].RiskFactors)
+	assert.Equal(t, 2, images[1].RiskScore, "Expected 10 of 100 pods to add a tenth of the replicas weight")
+	assert.Equal(t, []string{RiskFactorReplicas}, images[1].RiskFactors)
+	assert.Equal(t, 0, images[2].RiskScore)
+	assert.Nil(t, images[2].RiskFactors)
+}
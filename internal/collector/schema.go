@@ -0,0 +1,173 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JsonSchema returns the JSON Schema of the report, it is generated from the CollectorImage struct so it can not
+// drift from the serialized format
+func JsonSchema() map[string]any {
+	return map[string]any{
+		"$schema":     jsonSchemaDraft,
+		"$id":         "https://github.com/SDA-SE/image-metadata-collector/report.schema.json",
+		"title":       "Image metadata collector report",
+		"description": "Container images running in a cluster enriched with team and scan information",
+		"type":        "array",
+		"items":       jsonSchemaFor(reflect.TypeOf(CollectorImage{})),
+	}
+}
+
+// JsonSchemaMarshal returns the indented JSON Schema of the report
+func JsonSchemaMarshal() ([]byte, error) {
+	return json.MarshalIndent(JsonSchema(), "", "  ")
+}
+
+func jsonSchemaFor(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Slice:
+		// nil slices are serialized as null
+		return map[string]any{"type": []any{"array", "null"}, "items": jsonSchemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []any{}
+		for i := 0; i < t.NumField(); i++ {
+			name, options, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			properties[name] = jsonSchemaFor(t.Field(i).Type)
+			if !strings.Contains(options, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	default:
+		panic(fmt.Sprintf("no JSON Schema for %s", t))
+	}
+}
+
+// ValidateJson validates a serialized report against the JSON Schema, only the keywords used by JsonSchema are
+// supported
+func ValidateJson(data []byte) error {
+	var document any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Errorf("report is not valid JSON: %w", err)
+	}
+	return validateJsonSchema(JsonSchema(), document, "$")
+}
+
+// ValidateNdjson validates every line of a NDJSON report against the JSON Schema of a single image
+func ValidateNdjson(data []byte) error {
+	schema := JsonSchema()["items"].(map[string]any)
+	for i, line := range bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var document any
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil {
+			return fmt.Errorf("line %d is not valid JSON: %w", i+1, err)
+		}
+		if err := validateJsonSchema(schema, document, fmt.Sprintf("$[%d]", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateJsonSchema(schema map[string]any, value any, path string) error {
+	var types []any
+	switch t := schema["type"].(type) {
+	case string:
+		types = []any{t}
+	case []any:
+		types = t
+	}
+	if len(types) > 0 && !slices.Contains(types, any(jsonTypeOf(value))) {
+		return fmt.Errorf("%s: expected %v, got %s", path, schema["type"], jsonTypeOf(value))
+	}
+
+	switch v := value.(type) {
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateJsonSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := v[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing required property %s", path, name)
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s: unexpected property %s", path, name)
+				}
+				continue
+			}
+			if err := validateJsonSchema(property, v[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func jsonTypeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
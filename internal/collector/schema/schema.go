@@ -0,0 +1,351 @@
+// Package schema holds the protobuf schema of the report and of the ReportIngestion service. The collector encodes the
+// messages by hand and has no generated types, Files parses the schema into descriptors so the encoded messages can
+// be checked against it with the protobuf runtime, e.g. with dynamicpb.
+package schema
+
+import (
+	"embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+//go:embed report.proto ingest.proto
+var protoFiles embed.FS
+
+// fileNames are ordered by their imports
+var fileNames = []string{"report.proto", "ingest.proto"}
+
+var scalarTypes = map[string]descriptorpb.FieldDescriptorProto_Type{
+	"double":   descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	"float":    descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+	"int32":    descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	"int64":    descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	"uint32":   descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+	"uint64":   descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+	"sint32":   descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+	"sint64":   descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+	"fixed32":  descriptorpb.FieldDescriptorProto_TYPE_FIXED32,
+	"fixed64":  descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+	"sfixed32": descriptorpb.FieldDescriptorProto_TYPE_SFIXED32,
+	"sfixed64": descriptorpb.FieldDescriptorProto_TYPE_SFIXED64,
+	"bool":     descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	"string":   descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	"bytes":    descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+}
+
+// Files returns the descriptors of report.proto and ingest.proto
+func Files() (*protoregistry.Files, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, name := range fileNames {
+		content, err := protoFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		file, err := parse(name, string(content))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", name, err)
+		}
+		set.File = append(set.File, file)
+	}
+	return protodesc.NewFiles(set)
+}
+
+// parser reads the subset of the proto3 language used by the schema: messages with scalar, message, repeated and map
+// fields, oneofs and services. Nested messages, enums and field options are not supported.
+type parser struct {
+	tokens []string
+	pkg    string
+}
+
+func parse(name, content string) (*descriptorpb.FileDescriptorProto, error) {
+	p := &parser{tokens: tokenize(content)}
+	file := &descriptorpb.FileDescriptorProto{Name: proto.String(name)}
+
+	for len(p.tokens) > 0 {
+		var err error
+		switch keyword := p.next(); keyword {
+		case "syntax":
+			var syntax string
+			if syntax, err = p.statement(); err == nil && syntax != `= "proto3"` {
+				err = fmt.Errorf("unsupported syntax %s", syntax)
+			}
+			file.Syntax = proto.String("proto3")
+		case "package":
+			p.pkg, err = p.statement()
+			file.Package = proto.String(p.pkg)
+		case "import":
+			var dependency string
+			dependency, err = p.statement()
+			file.Dependency = append(file.Dependency, strings.Trim(dependency, `"`))
+		case "option":
+			_, err = p.statement()
+		case "message":
+			var message *descriptorpb.DescriptorProto
+			message, err = p.message()
+			file.MessageType = append(file.MessageType, message)
+		case "service":
+			var service *descriptorpb.ServiceDescriptorProto
+			service, err = p.service()
+			file.Service = append(file.Service, service)
+		default:
+			err = fmt.Errorf("unexpected %q", keyword)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// tokenize splits the content into identifiers, literals and punctuation, comments are dropped
+func tokenize(content string) []string {
+	var tokens []string
+	for _, line := range strings.Split(content, "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return unicode.IsSpace(r)
+		})
+		for _, field := range fields {
+			start := 0
+			for i, r := range field {
+				if strings.ContainsRune("{}();=<>,", r) {
+					if i > start {
+						tokens = append(tokens, field[start:i])
+					}
+					tokens = append(tokens, string(r))
+					start = i + 1
+				}
+			}
+			if start < len(field) {
+				tokens = append(tokens, field[start:])
+			}
+		}
+	}
+	return tokens
+}
+
+func (p *parser) next() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	token := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return token
+}
+
+func (p *parser) expect(token string) error {
+	if next := p.next(); next != token {
+		return fmt.Errorf("expected %q, got %q", token, next)
+	}
+	return nil
+}
+
+// statement returns the tokens up to the next semicolon joined by spaces
+func (p *parser) statement() (string, error) {
+	var tokens []string
+	for token := p.next(); token != ";"; token = p.next() {
+		if token == "" {
+			return "", fmt.Errorf("missing ;")
+		}
+		tokens = append(tokens, token)
+	}
+	return strings.Join(tokens, " "), nil
+}
+
+func (p *parser) message() (*descriptorpb.DescriptorProto, error) {
+	message := &descriptorpb.DescriptorProto{Name: proto.String(p.next())}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	for token := p.next(); token != "}"; token = p.next() {
+		switch token {
+		case "":
+			return nil, fmt.Errorf("message %s is not closed", message.GetName())
+		case "oneof":
+			index := int32(len(message.OneofDecl))
+			message.OneofDecl = append(message.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String(p.next())})
+			if err := p.expect("{"); err != nil {
+				return nil, err
+			}
+			for token = p.next(); token != "}"; token = p.next() {
+				field, err := p.field(token, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL)
+				if err != nil {
+					return nil, err
+				}
+				field.OneofIndex = proto.Int32(index)
+				message.Field = append(message.Field, field)
+			}
+		case "map":
+			field, entry, err := p.mapField(message.GetName())
+			if err != nil {
+				return nil, err
+			}
+			message.Field = append(message.Field, field)
+			message.NestedType = append(message.NestedType, entry)
+		case "repeated":
+			field, err := p.field(p.next(), descriptorpb.FieldDescriptorProto_LABEL_REPEATED)
+			if err != nil {
+				return nil, err
+			}
+			message.Field = append(message.Field, field)
+		default:
+			field, err := p.field(token, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL)
+			if err != nil {
+				return nil, err
+			}
+			message.Field = append(message.Field, field)
+		}
+	}
+	return message, nil
+}
+
+// field parses '<type> <name> = <number>;' after the label
+func (p *parser) field(typeName string, label descriptorpb.FieldDescriptorProto_Label) (*descriptorpb.FieldDescriptorProto, error) {
+	name := p.next()
+	if err := p.expect("="); err != nil {
+		return nil, err
+	}
+	number, err := strconv.Atoi(p.next())
+	if err != nil {
+		return nil, fmt.Errorf("invalid number of field %s: %w", name, err)
+	}
+	if err = p.expect(";"); err != nil {
+		return nil, err
+	}
+
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(int32(number)),
+		Label:    label.Enum(),
+		JsonName: proto.String(jsonName(name)),
+	}
+	if scalar, ok := scalarTypes[typeName]; ok {
+		field.Type = scalar.Enum()
+	} else {
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		field.TypeName = proto.String(p.typeName(typeName))
+	}
+	return field, nil
+}
+
+// mapField parses 'map<key, value> name = number;' into a repeated field of an entry message nested in the message
+func (p *parser) mapField(messageName string) (*descriptorpb.FieldDescriptorProto, *descriptorpb.DescriptorProto, error) {
+	if err := p.expect("<"); err != nil {
+		return nil, nil, err
+	}
+	keyType := p.next()
+	if err := p.expect(","); err != nil {
+		return nil, nil, err
+	}
+	valueType := p.next()
+	if err := p.expect(">"); err != nil {
+		return nil, nil, err
+	}
+
+	field, err := p.field("", descriptorpb.FieldDescriptorProto_LABEL_REPEATED)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := (&parser{tokens: []string{"key", "=", "1", ";"}, pkg: p.pkg}).field(keyType, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL)
+	if err != nil {
+		return nil, nil, err
+	}
+	value, err := (&parser{tokens: []string{"value", "=", "2", ";"}, pkg: p.pkg}).field(valueType, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	camelName := jsonName(field.GetName())
+	entryName := strings.ToUpper(camelName[:1]) + camelName[1:] + "Entry"
+	entry := &descriptorpb.DescriptorProto{
+		Name:    proto.String(entryName),
+		Field:   []*descriptorpb.FieldDescriptorProto{key, value},
+		Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+	field.TypeName = proto.String(p.typeName(messageName) + "." + entryName)
+	return field, entry, nil
+}
+
+func (p *parser) service() (*descriptorpb.ServiceDescriptorProto, error) {
+	service := &descriptorpb.ServiceDescriptorProto{Name: proto.String(p.next())}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	for token := p.next(); token != "}"; token = p.next() {
+		if token != "rpc" {
+			return nil, fmt.Errorf("unexpected %q in service %s", token, service.GetName())
+		}
+		method := &descriptorpb.MethodDescriptorProto{Name: proto.String(p.next())}
+		input, clientStreaming, err := p.methodType()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("returns"); err != nil {
+			return nil, err
+		}
+		output, serverStreaming, err := p.methodType()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(";"); err != nil {
+			return nil, err
+		}
+		method.InputType, method.ClientStreaming = proto.String(input), proto.Bool(clientStreaming)
+		method.OutputType, method.ServerStreaming = proto.String(output), proto.Bool(serverStreaming)
+		service.Method = append(service.Method, method)
+	}
+	return service, nil
+}
+
+// methodType parses '(stream <type>)' or '(<type>)'
+func (p *parser) methodType() (string, bool, error) {
+	if err := p.expect("("); err != nil {
+		return "", false, err
+	}
+	typeName, streaming := p.next(), false
+	if typeName == "stream" {
+		typeName, streaming = p.next(), true
+	}
+	if err := p.expect(")"); err != nil {
+		return "", false, err
+	}
+	return p.typeName(typeName), streaming, nil
+}
+
+// typeName returns the fully qualified name of a message, unqualified names refer to the package of the file
+func (p *parser) typeName(name string) string {
+	if name == "" {
+		return ""
+	}
+	if strings.Contains(name, ".") {
+		return "." + strings.TrimPrefix(name, ".")
+	}
+	return "." + p.pkg + "." + name
+}
+
+// jsonName converts the field name to lower camel case as protoc does
+func jsonName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package schema
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestFiles(t *testing.T) {
+	files, err := Files()
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	descriptor, err := files.FindDescriptorByName("sdase.imagemetadatacollector.v1.ReportIngestion")
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	method := descriptor.(protoreflect.ServiceDescriptor).Methods().ByName("StreamReport")
+	if method == nil || !method.IsStreamingClient() || method.IsStreamingServer() {
+		t.Fatalf("Expected the client streaming method StreamReport, got %v\n", method)
+	}
+	if method.Input().Fields().ByName("image").Message().FullName() != "sdase.imagemetadatacollector.v1.Image" {
+		t.Errorf("Expected the image of the request to be the Image message of the report")
+	}
+
+	metadata := method.Input().Fields().ByName("metadata").Message().Fields().ByName("metadata")
+	if !metadata.IsMap() || metadata.MapValue().Kind() != protoreflect.StringKind {
+		t.Errorf("Expected the metadata to be a map of strings, got %v\n", metadata)
+	}
+
+	descriptor, err = files.FindDescriptorByName("sdase.imagemetadatacollector.v1.Image")
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	ports := descriptor.(protoreflect.MessageDescriptor).Fields().ByNumber(57)
+	if ports.Name() != "container_ports" || ports.Kind() != protoreflect.Int32Kind || !ports.IsList() {
+		t.Errorf("Expected the repeated int32 container_ports as field 57, got %v\n", ports)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, content := range []string{
+		`syntax = "proto2";`,
+		`message Image { string name = one; }`,
+		`message Image { string name = 1;`,
+		`enum Kind { UNKNOWN = 0; }`,
+		`service Ingestion { rpc Send(Image) returns Image; }`,
+	} {
+		if _, err := parse("test.proto", content); err == nil {
+			t.Errorf("Expected an error for %s", content)
+		}
+	}
+}
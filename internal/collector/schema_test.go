@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonSchema(t *testing.T) {
+	schema := JsonSchema()
+	items := schema["items"].(map[string]any)
+	properties := items["properties"].(map[string]any)
+
+	assert.Equal(t, "array", schema["type"])
+	assert.Equal(t, map[string]any{"type": "string"}, properties["namespace"])
+	assert.Equal(t, map[string]any{"type": "boolean"}, properties["is_scan_maleware"])
+	assert.Equal(t, map[string]any{"type": "integer"}, properties["scan_lifetime_max_days"])
+	// The fields of the registry, SBOM and Harbor enrichment, the image mismatch, the registry classification, the
+	// pull error, the Helm and Flux attribution, the seen times, the stale flag, the Windows, terminated and
+	// suspended flags, the replicas, the workload, the ports, the exposure and the risk score are optional
+	assert.Len(t, items["required"], len(properties)-33)
+	assert.NotContains(t, items["required"], "image_created")
+	assert.NotContains(t, items["required"], "has_sbom")
+	assert.Equal(t, map[string]any{"type": "string"}, properties["image_created"])
+}
+
+func TestValidateJson(t *testing.T) {
+	images := []CollectorImage{{Namespace: "ns", Image: "mongo"}, {Image: "nginx", EngagementTags: []string{"a"}}}
+
+	data, err := JsonIndentMarshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.NoError(t, ValidateJson(data))
+
+	data, err = NdjsonMarshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.NoError(t, ValidateNdjson(data))
+
+	testCases := []struct {
+		name          string
+		data          string
+		expectedError string
+	}{
+		{name: "NotAnArray", data: `{}`, expectedError: "$: expected array, got object"},
+		{name: "MissingProperty", data: `[{"namespace": "ns"}]`, expectedError: "$[0]: missing required property"},
+		{name: "InvalidJson", data: `[`, expectedError: "report is not valid JSON"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateJson([]byte(tc.data))
+			assert.ErrorContains(t, err, tc.expectedError)
+		})
+	}
+
+	data, err = JsonIndentMarshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	data = []byte(strings.Replace(string(data), `"skip": false`, `"skip": "false"`, 1))
+	assert.ErrorContains(t, ValidateJson(data), "$[0].skip: expected boolean, got string")
+
+	data = []byte(strings.Replace(string(data), `"skip": "false"`, `"skip": false, "unknown": 1`, 1))
+	assert.ErrorContains(t, ValidateJson(data), "$[0]: unexpected property unknown")
+}
@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"slices"
+	"time"
+)
+
+// TrackSeen sets the last seen time of the images to now and keeps the first seen time of the previous report. An
+// image is identified by its namespace and image, it is seen for the first time again if its digest changed, e.g.
+// after the tag was moved. Images of a previous report stored without the times are seen for the first time.
+func TrackSeen(images []CollectorImage, previous []CollectorImage, now time.Time) []CollectorImage {
+	type seen struct {
+		firstSeen string
+		digest    string
+	}
+	previousImages := make(map[string]seen, len(previous))
+	for _, image := range previous {
+		firstSeen := image.FirstSeen
+		if firstSeen == "" {
+			firstSeen = image.LastSeen
+		}
+		if firstSeen == "" {
+			continue
+		}
+		key := image.Namespace + "/" + image.Image
+		// The same image may run with several digests during a rollout, the earliest time wins
+		if existing, ok := previousImages[key]; !ok || firstSeen < existing.firstSeen {
+			previousImages[key] = seen{firstSeen: firstSeen, digest: ImageDigest(image.ImageId)}
+		}
+	}
+
+	lastSeen := now.UTC().Format(time.RFC3339)
+	tracked := make([]CollectorImage, len(images))
+	for i, image := range images {
+		image.FirstSeen, image.LastSeen = lastSeen, lastSeen
+		if previousImage, ok := previousImages[image.Namespace+"/"+image.Image]; ok {
+			digest := ImageDigest(image.ImageId)
+			if previousImage.digest == "" || digest == "" || previousImage.digest == digest {
+				image.FirstSeen = previousImage.firstSeen
+			}
+		}
+		tracked[i] = image
+	}
+	return tracked
+}
+
+// KeepStale adds the images of the previous report which are missing from the images, e.g. of a paused CronJob, until
+// they were missing for more than runs runs. The kept images are marked as stale, so downstream engagements are not
+// closed and reopened whenever an image disappears for a short time.
+func KeepStale(images []CollectorImage, previous []CollectorImage, runs int) []CollectorImage {
+	current := make(map[string]bool, len(images))
+	for _, image := range images {
+		current[image.Namespace+"/"+image.Image] = true
+	}
+
+	kept := slices.Clone(images)
+	for _, image := range previous {
+		if current[image.Namespace+"/"+image.Image] || image.MissedRuns >= runs {
+			continue
+		}
+		image.Stale = true
+		image.MissedRuns++
+		kept = append(kept, image)
+	}
+	return kept
+}
@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackSeen(t *testing.T) {
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	previous := []CollectorImage{
+		{Namespace: "ns", Image: "app:1.0", ImageId: "app@sha256:aaa", FirstSeen: "2024-05-01T10:00:00Z", LastSeen: "2024-05-01T12:00:00Z"},
+		{Namespace: "ns", Image: "app:1.0", ImageId: "app@sha256:aaa", FirstSeen: "2024-04-30T10:00:00Z", LastSeen: "2024-05-01T12:00:00Z"},
+		{Namespace: "ns", Image: "db:2.0", ImageId: "db@sha256:bbb", FirstSeen: "2024-04-01T10:00:00Z"},
+		{Namespace: "ns", Image: "legacy:1.0"},
+		{Namespace: "other", Image: "cache:3.0", LastSeen: "2024-04-15T10:00:00Z"},
+	}
+	images := []CollectorImage{
+		{Namespace: "ns", Image: "app:1.0", ImageId: "app@sha256:aaa"},
+		{Namespace: "ns", Image: "db:2.0", ImageId: "db@sha256:ccc"},
+		{Namespace: "ns", Image: "legacy:1.0"},
+		{Namespace: "other", Image: "cache:3.0"},
+		{Namespace: "other", Image: "app:1.0", ImageId: "app@sha256:aaa"},
+	}
+
+	tracked := TrackSeen(images, previous, now)
+	assert.Len(t, tracked, len(images))
+	assert.Empty(t, images[0].FirstSeen, "Expected the images to be copied")
+
+	for _, image := range tracked {
+		assert.Equal(t, "2024-05-02T10:00:00Z", image.LastSeen)
+	}
+	assert.Equal(t, "2024-04-30T10:00:00Z", tracked[0].FirstSeen, "Expected the earliest first seen time")
+	assert.Equal(t, "2024-05-02T10:00:00Z", tracked[1].FirstSeen, "Expected a changed digest to be seen for the first time")
+	assert.Equal(t, "2024-05-02T10:00:00Z", tracked[2].FirstSeen, "Expected an image stored without times to be seen for the first time")
+	assert.Equal(t, "2024-04-15T10:00:00Z", tracked[3].FirstSeen, "Expected the last seen time without first seen time")
+	assert.Equal(t, "2024-05-02T10:00:00Z", tracked[4].FirstSeen, "Expected the namespace to be part of the identity")
+
+	assert.Empty(t, TrackSeen(nil, previous, now))
+}
+
+func TestKeepStale(t *testing.T) {
+	images := []CollectorImage{{Namespace: "ns", Image: "app:1.0"}}
+	previous := []CollectorImage{
+		{Namespace: "ns", Image: "app:1.0", Stale: true, MissedRuns: 1},
+		{Namespace: "ns", Image: "job:1.0", LastSeen: "2024-05-01T10:00:00Z"},
+		{Namespace: "ns", Image: "cron:1.0", Stale: true, MissedRuns: 1},
+		{Namespace: "ns", Image: "old:1.0", Stale: true, MissedRuns: 2},
+		{Namespace: "other", Image: "app:1.0"},
+	}
+
+	kept := KeepStale(images, previous, 2)
+	assert.Equal(t, []CollectorImage{
+		{Namespace: "ns", Image: "app:1.0"},
+		{Namespace: "ns", Image: "job:1.0", LastSeen: "2024-05-01T10:00:00Z", Stale: true, MissedRuns: 1},
+		{Namespace: "ns", Image: "cron:1.0", Stale: true, MissedRuns: 2},
+		{Namespace: "other", Image: "app:1.0", Stale: true, MissedRuns: 1},
+	}, kept)
+	assert.Len(t, images, 1, "Expected the images to be copied")
+
+	assert.Equal(t, images, KeepStale(images, previous, 0))
+}
@@ -0,0 +1,39 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// skipUntilDateLayout is the layout of a skip-until date, the exemption lasts until the end of the day in UTC
+const skipUntilDateLayout = "2006-01-02"
+
+// withSkipUntil skips the image until the time of the skip-until annotation, e.g. 'skip-until=2025-12-31', so a
+// temporary exemption lapses without another change of the deployment. The annotation takes precedence over the
+// skip annotation, invalid values are ignored.
+func withSkipUntil(ci *CollectorImage, metadata map[string]string, name string, collectedAt time.Time) {
+	value, ok := metadata[name]
+	if !ok || value == "" {
+		return
+	}
+
+	until, err := parseSkipUntil(value)
+	if err != nil {
+		log.Warn().Str("namespace", ci.Namespace).Str("image", ci.Image).Str("skipUntil", value).Msg("Ignoring invalid skip-until annotation, expected a date like 2025-12-31 or an RFC 3339 time")
+		return
+	}
+
+	ci.Skip = collectedAt.Before(until)
+	if !ci.Skip {
+		log.Info().Str("namespace", ci.Namespace).Str("image", ci.Image).Str("skipUntil", value).Msg("Skip exemption expired")
+	}
+}
+
+// parseSkipUntil returns the end of the exemption, a date without time lasts until the end of that day
+func parseSkipUntil(value string) (time.Time, error) {
+	if date, err := time.Parse(skipUntilDateLayout, value); err == nil {
+		return date.AddDate(0, 0, 1), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,145 @@
+package collector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const spdxNamespacePrefix = "https://sda.se/spdx/image-metadata-collector/"
+
+type spdxDocument struct {
+	SpdxVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name                  string            `json:"name"`
+	SPDXID                string            `json:"SPDXID"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	Supplier              string            `json:"supplier,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose"`
+	Checksums             []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+	Comment               string            `json:"comment,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SpdxElementId      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+// SpdxMarshal renders the images as SPDX 2.3 JSON document with one container package per image
+func SpdxMarshal(v any) ([]byte, error) {
+	images, err := imagesFromAny(v)
+	if err != nil {
+		return nil, err
+	}
+
+	name := "images"
+	if len(images) > 0 && images[0].Environment != "" {
+		name = images[0].Environment + "-images"
+	}
+
+	created := time.Now().UTC()
+	// The namespace has to be unique per document, the content and creation time identify it
+	hash := sha256.New()
+	for _, image := range images {
+		hash.Write([]byte(image.Namespace + "/" + image.Image + "@" + image.ImageId + "\n"))
+	}
+	hash.Write([]byte(created.String()))
+
+	document := spdxDocument{
+		SpdxVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: spdxNamespacePrefix + url.PathEscape(name) + "-" + hex.EncodeToString(hash.Sum(nil))[:16],
+		CreationInfo: spdxCreationInfo{
+			Created:  created.Format(time.RFC3339),
+			Creators: []string{"Tool: image-metadata-collector"},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+
+	for i, image := range images {
+		spdxId := fmt.Sprintf("SPDXRef-Package-%d", i+1)
+		document.Packages = append(document.Packages, newSpdxPackage(spdxId, &image))
+		document.Relationships = append(document.Relationships, spdxRelationship{
+			SpdxElementId:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSpdxElement: spdxId,
+		})
+	}
+
+	return json.MarshalIndent(document, "", "\t")
+}
+
+func newSpdxPackage(spdxId string, image *CollectorImage) spdxPackage {
+	ref := ParseImageReference(image.Image)
+	digest := ImageDigest(image.ImageId)
+	if digest == "" {
+		digest = ref.Digest
+	}
+
+	pkg := spdxPackage{
+		Name:                  image.Image,
+		SPDXID:                spdxId,
+		VersionInfo:           ref.Tag,
+		DownloadLocation:      "NOASSERTION",
+		FilesAnalyzed:         false,
+		PrimaryPackagePurpose: "CONTAINER",
+		Comment:               fmt.Sprintf("namespace=%s team=%s product=%s", image.Namespace, image.Team, image.Product),
+	}
+
+	if image.Team != "" {
+		pkg.Supplier = "Organization: " + image.Team
+	}
+
+	if algorithm, value, found := strings.Cut(digest, ":"); found && algorithm == "sha256" {
+		pkg.Checksums = []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: value}}
+
+		repository := ref.Repository
+		if ref.Registry != "" {
+			repository = ref.Registry + "/" + ref.Repository
+		}
+		lastSlash := strings.LastIndex(ref.Repository, "/")
+		purl := fmt.Sprintf("pkg:oci/%s@%s?repository_url=%s", ref.Repository[lastSlash+1:], url.QueryEscape(digest), url.QueryEscape(repository))
+		if ref.Tag != "" {
+			purl += "&tag=" + url.QueryEscape(ref.Tag)
+		}
+		pkg.ExternalRefs = []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: purl}}
+	}
+
+	return pkg
+}
@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// UnassignedTeam is the group of images without a team when splitting by team
+const UnassignedTeam = "unassigned"
+
+// unsafeKeyChars are replaced in group keys as they end up in file and object names
+var unsafeKeyChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ImageGroup is a subset of the collected images which is stored as a separate report
+type ImageGroup struct {
+	Key    string
+	Images []CollectorImage
+}
+
+// SplitImages groups the images by the given field, an empty splitBy returns all images in a single group. Teams
+// whose names end up with the same key, e.g. 'team a' and 'team/a' or a team named like UnassignedTeam, are an error
+// as their reports would overwrite each other.
+func SplitImages(images []CollectorImage, splitBy string) ([]ImageGroup, error) {
+	var fieldOf func(image *CollectorImage) string
+	keyOf := func(value string) string { return value }
+	switch splitBy {
+	case "":
+		return []ImageGroup{{Images: images}}, nil
+	case "namespace":
+		fieldOf = func(image *CollectorImage) string { return image.Namespace }
+	case "team":
+		fieldOf = func(image *CollectorImage) string { return image.Team }
+		keyOf = func(team string) string {
+			if team == "" {
+				return UnassignedTeam
+			}
+			return unsafeKeyChars.ReplaceAllString(team, "-")
+		}
+	default:
+		return nil, fmt.Errorf("Split by %s is not supported", splitBy)
+	}
+
+	groups := map[string][]CollectorImage{}
+	values := map[string]string{}
+	for i := range images {
+		value := fieldOf(&images[i])
+		key := keyOf(value)
+		if other, found := values[key]; found && other != value {
+			return nil, fmt.Errorf("Split by %s maps %q and %q to the same report %s", splitBy, other, value, key)
+		}
+		values[key] = value
+		groups[key] = append(groups[key], images[i])
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]ImageGroup, len(keys))
+	for i, key := range keys {
+		result[i] = ImageGroup{Key: key, Images: groups[key]}
+	}
+
+	return result, nil
+}
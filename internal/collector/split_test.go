@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitImages(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "ns-b", Image: "mongo", Team: "team a/b"},
+		{Namespace: "ns-a", Image: "nginx"},
+		{Namespace: "ns-b", Image: "redis", Team: "team a/b"},
+	}
+
+	groups, err := SplitImages(images, "")
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, []ImageGroup{{Images: images}}, groups)
+
+	groups, err = SplitImages(images, "namespace")
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, []ImageGroup{
+		{Key: "ns-a", Images: []CollectorImage{images[1]}},
+		{Key: "ns-b", Images: []CollectorImage{images[0], images[2]}},
+	}, groups)
+
+	groups, err = SplitImages(images, "team")
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, []ImageGroup{
+		{Key: "team-a-b", Images: []CollectorImage{images[0], images[2]}},
+		{Key: UnassignedTeam, Images: []CollectorImage{images[1]}},
+	}, groups)
+
+	_, err = SplitImages(images, "unknown")
+	assert.Error(t, err, "Expected error but got none")
+
+	_, err = SplitImages(append(images, CollectorImage{Image: "httpd", Team: "team a-b"}), "team")
+	assert.ErrorContains(t, err, "team-a-b", "Expected an error for teams with the same key")
+
+	_, err = SplitImages(append(images, CollectorImage{Image: "httpd", Team: UnassignedTeam}), "team")
+	assert.ErrorContains(t, err, UnassignedTeam, "Expected an error for a team named like the unassigned group")
+}
@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+)
+
+// JsonEncode writes the serialized images to w image by image, the output equals the one of the matching JsonMarshal
+type JsonEncode func(io.Writer, any) error
+
+func JsonIndentEncode(w io.Writer, v any) error {
+	return encodeJson(w, v, true, "")
+}
+
+func JsonCompactEncode(w io.Writer, v any) error {
+	return encodeJson(w, v, false, "")
+}
+
+// NdjsonEncode writes one JSON object per image like NdjsonMarshal
+func NdjsonEncode(w io.Writer, v any) error {
+	images, err := imagesFromAny(v)
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buf)
+	for i := range images {
+		// Encode terminates every value with a newline
+		if err = encoder.Encode(&images[i]); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// encodeJson writes the images through a buffered writer, so only a single serialized image is held in memory
+func encodeJson(w io.Writer, v any, indent bool, prefix string) error {
+	images, err := imagesFromAny(v)
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(w)
+	if err = encodeJsonArray(buf, images, indent, prefix); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// encodeJsonArray encodes one image at a time, the elements are laid out like json.MarshalIndent with a tab after
+// the prefix
+func encodeJsonArray(buf *bufio.Writer, images []CollectorImage, indent bool, prefix string) error {
+	switch {
+	case images == nil:
+		buf.WriteString("null")
+		return nil
+	case len(images) == 0:
+		buf.WriteString("[]")
+		return nil
+	}
+
+	// The encoder writes into a buffer reused for every image, its trailing newline is dropped
+	var element bytes.Buffer
+	encoder := json.NewEncoder(&element)
+	separator, end := ",", "]"
+	buf.WriteString("[")
+	if indent {
+		encoder.SetIndent(prefix+"\t", "\t")
+		separator, end = ",\n"+prefix+"\t", "\n"+prefix+"]"
+		buf.WriteString("\n" + prefix + "\t")
+	}
+	for i := range images {
+		element.Reset()
+		if err := encoder.Encode(&images[i]); err != nil {
+			return err
+		}
+		if i > 0 {
+			buf.WriteString(separator)
+		}
+		buf.Write(element.Bytes()[:element.Len()-1])
+	}
+	buf.WriteString(end)
+	return nil
+}
+
+// StoreReportStream encodes the images while the storager writes them, so storagers supporting streams never hold
+// the whole report in memory
+func StoreReportStream(ctx context.Context, images *[]CollectorImage, storager backend.Storager, report backend.Report, encode JsonEncode) (backend.StoreResult, error) {
+	if images == nil {
+		return backend.StoreResult{}, errors.New("cannot marshal nil")
+	}
+
+	if report.ContentType == "" {
+		report.ContentType = "application/json"
+	}
+	if report.Metadata == nil {
+		report.Metadata = map[string]string{}
+	}
+	report.Metadata["images"] = strconv.Itoa(len(*images))
+	report.Metadata["collectorVersion"] = version.Version
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(encode(pw, images))
+	}()
+
+	result, err := backend.StoreStream(ctx, storager, report, pr)
+	// Unblocks the encoder if the storager stopped reading early
+	pr.Close()
+	return result, err
+}
@@ -0,0 +1,172 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeEqualsMarshal(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "ns-a", Image: "quay.io/a:1", EngagementTags: []string{"<tag>"}},
+		{Namespace: "ns-b", Image: "quay.io/b:1"},
+	}
+
+	testCases := []struct {
+		name    string
+		marshal JsonMarshal
+		encode  JsonEncode
+	}{
+		{name: "Indent", marshal: JsonIndentMarshal, encode: JsonIndentEncode},
+		{name: "Compact", marshal: JsonCompactMarshal, encode: JsonCompactEncode},
+		{name: "Ndjson", marshal: NdjsonMarshal, encode: NdjsonEncode},
+	}
+	for _, tc := range testCases {
+		for _, v := range []*[]CollectorImage{&images, {}, new([]CollectorImage)} {
+			expected, err := tc.marshal(v)
+			assert.NoError(t, err, "Expected no error, got %v", err)
+
+			var buf bytes.Buffer
+			err = tc.encode(&buf, v)
+			assert.NoError(t, err, "Expected no error, got %v", err)
+			assert.Equal(t, string(expected), buf.String(), tc.name)
+		}
+	}
+}
+
+func TestEncodeWithErrorsEqualsMarshal(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "ns-a", Image: "quay.io/a:1", EngagementTags: []string{"<tag>"}},
+		{Namespace: "ns-b", Image: "quay.io/b:1"},
+	}
+	errs := []RunError{{Stage: RunErrorStageNamespace, Namespace: "broken", Error: "forbidden"}}
+	warnings := []AnnotationWarning{{Namespace: "ns-a", Annotation: "contact.sdase.org/teams"}}
+
+	for _, cfg := range []OutputConfig{
+		{AllowPartial: true},
+		{AllowPartial: true, JsonCompact: true},
+		{ReportAnnotationWarnings: true},
+		{ReportAnnotationWarnings: true, JsonCompact: true},
+	} {
+		outputFormat, err := NewOutputFormat(&cfg)
+		assert.NoError(t, err, "Expected no error, got %v", err)
+
+		for _, v := range []*[]CollectorImage{&images, {}, new([]CollectorImage)} {
+			for _, errs := range [][]RunError{nil, errs} {
+				expected, err := outputFormat.MarshalWithErrors(errs, warnings)(v)
+				assert.NoError(t, err, "Expected no error, got %v", err)
+
+				var buf bytes.Buffer
+				err = outputFormat.EncodeWithErrors(errs, warnings)(&buf, v)
+				assert.NoError(t, err, "Expected no error, got %v", err)
+				assert.Equal(t, string(expected), buf.String())
+			}
+		}
+	}
+
+	outputFormat, err := NewOutputFormat(&OutputConfig{AllowPartial: true, ValidateOutput: true})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Nil(t, outputFormat.EncodeWithErrors(nil, nil), "Expected the validated report not to be streamed")
+}
+
+// heapWriter discards the output and samples the live heap every sampleSize bytes
+type heapWriter struct {
+	written, nextSample int
+	maxHeap             uint64
+}
+
+const sampleSize = 128 << 20
+
+func (w *heapWriter) Write(p []byte) (int, error) {
+	w.written += len(p)
+	if w.written >= w.nextSample {
+		w.nextSample += sampleSize
+		runtime.GC()
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		w.maxHeap = max(w.maxHeap, stats.HeapAlloc)
+	}
+	return len(p), nil
+}
+
+func TestEncodeMemoryIsFlat(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Encoding 1M images takes a while")
+	}
+
+	images := make([]CollectorImage, 1_000_000)
+	for i := range images {
+		images[i] = CollectorImage{
+			Namespace:   "namespace-" + strconv.Itoa(i%1000),
+			Image:       "registry.example.com/team/app-" + strconv.Itoa(i) + ":1.0.0",
+			ImageId:     "registry.example.com/team/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			Environment: "prod",
+			Team:        "team",
+		}
+	}
+	envelope, err := NewOutputFormat(&OutputConfig{AllowPartial: true})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	compactEnvelope, err := NewOutputFormat(&OutputConfig{AllowPartial: true, JsonCompact: true})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	testCases := []struct {
+		name   string
+		encode JsonEncode
+	}{
+		{name: "Compact", encode: JsonCompactEncode},
+		{name: "Ndjson", encode: NdjsonEncode},
+		{name: "Envelope", encode: envelope.EncodeWithErrors(nil, nil)},
+		{name: "CompactEnvelope", encode: compactEnvelope.EncodeWithErrors(nil, nil)},
+	}
+	for _, tc := range testCases {
+		runtime.GC()
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+
+		w := &heapWriter{}
+		assert.NoError(t, tc.encode(w, &images), tc.name)
+		// The report is several hundred MiB, the heap grows by a few buffers at most
+		assert.Greater(t, w.written, 200<<20, tc.name)
+		assert.Less(t, int64(w.maxHeap)-int64(stats.HeapAlloc), int64(8<<20), tc.name)
+	}
+}
+
+type streamStorager struct {
+	content []byte
+	report  backend.Report
+}
+
+func (s *streamStorager) Store(ctx context.Context, report backend.Report) (backend.StoreResult, error) {
+	return backend.StoreResult{}, errors.New("expected a stream")
+}
+
+func (s *streamStorager) StoreStream(ctx context.Context, report backend.Report, content io.Reader) (backend.StoreResult, error) {
+	var err error
+	s.report = report
+	s.content, err = io.ReadAll(content)
+	return backend.StoreResult{Size: len(s.content)}, err
+}
+
+func TestStoreReportStream(t *testing.T) {
+	images := []CollectorImage{{Namespace: "ns-a", Image: "quay.io/a:1"}}
+	storager := &streamStorager{}
+
+	result, err := StoreReportStream(context.Background(), &images, storager, backend.Report{FileName: "env-output.json"}, JsonCompactEncode)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	expected, _ := JsonCompactMarshal(&images)
+	assert.Equal(t, string(expected), string(storager.content))
+	assert.Equal(t, len(expected), result.Size)
+	assert.Equal(t, "1", storager.report.Metadata["images"])
+	assert.Equal(t, "application/json", storager.report.ContentType)
+
+	_, err = StoreReportStream(context.Background(), nil, storager, backend.Report{}, JsonCompactEncode)
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are available in addition to the builtin template functions
+var templateFuncs = template.FuncMap{
+	"join":           strings.Join,
+	"lower":          strings.ToLower,
+	"upper":          strings.ToUpper,
+	"imageReference": ParseImageReference,
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// NewTemplateMarshal parses the Go template in fileName, the returned marshal function executes it with the
+// collected images as data
+func NewTemplateMarshal(fileName string) (JsonMarshal, error) {
+	tmpl, err := template.New(filepath.Base(fileName)).Funcs(templateFuncs).ParseFiles(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse output template: %w", err)
+	}
+
+	return func(v any) ([]byte, error) {
+		images, err := imagesFromAny(v)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err = tmpl.Execute(&buf, images); err != nil {
+			return nil, fmt.Errorf("could not render output template: %w", err)
+		}
+		return buf.Bytes(), nil
+	}, nil
+}
+
+// templateExtension derives the report extension from the template file name, e.g. 'inventory.md.tmpl' results in '.md'
+func templateExtension(fileName string) string {
+	base := filepath.Base(fileName)
+	for _, suffix := range []string{".tmpl", ".gotmpl", ".tpl"} {
+		base = strings.TrimSuffix(base, suffix)
+	}
+	return filepath.Ext(base)
+}
+
+// templateContentType guesses the content type from the report extension, defaults to plain text
+func templateContentType(extension string) string {
+	if contentType := mime.TypeByExtension(extension); contentType != "" {
+		return contentType
+	}
+	if extension == ".md" {
+		return "text/markdown; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
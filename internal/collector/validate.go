@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Validate checks the regular expressions and annotation names of the configuration, all problems are returned
+// joined into a single error
+func Validate(annotationNames *AnnotationNames, defaults *CollectorImage, runConfig *RunConfig) error {
+	var errs []error
+
+	for _, imageFilter := range runConfig.ImageFilter {
+		if _, err := regexp.Compile(imageFilter); err != nil {
+			errs = append(errs, fmt.Errorf("image filter %q is not a valid regular expression: %w", imageFilter, err))
+		}
+	}
+
+	for _, filter := range []struct{ name, value string }{
+		{"namespace filter", defaults.NamespaceFilter},
+		{"negated namespace filter", defaults.NamespaceFilterNegated},
+	} {
+		if _, err := regexp.Compile(filter.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s %q is not a valid regular expression: %w", filter.name, filter.value, err))
+		}
+	}
+
+	for _, prefix := range []struct{ name, value string }{
+		{"base", annotationNames.Base},
+		{"scans", annotationNames.Scans},
+		{"contact", annotationNames.Contact},
+		{"defect dojo", annotationNames.DefectDojo},
+	} {
+		// The prefixes are concatenated with the annotation name, e.g. 'contact.sdase.org/' + 'team'
+		for _, msg := range validation.IsQualifiedName(prefix.value + "name") {
+			errs = append(errs, fmt.Errorf("%s annotation name %q is not a valid annotation prefix: %s", prefix.name, prefix.value, msg))
+		}
+	}
+
+	errs = append(errs, validateBackstageMapping(annotationNames.BackstageMapping)...)
+
+	if _, err := LoadFilterRules(runConfig.FilterRulesFile); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch runConfig.MetadataPrecedence {
+	case "", MetadataPrecedenceMerged, MetadataPrecedenceNamespaceOnly:
+	default:
+		errs = append(errs, fmt.Errorf("metadata precedence %q is not supported, use %s or %s", runConfig.MetadataPrecedence, MetadataPrecedenceMerged, MetadataPrecedenceNamespaceOnly))
+	}
+
+	return errors.Join(errs...)
+}
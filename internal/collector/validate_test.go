@@ -0,0 +1,32 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	annotationNames := &AnnotationNames{
+		Base:       "sdase.org/",
+		Scans:      "clusterscanner.sdase.org/",
+		Contact:    "contact.sdase.org/",
+		DefectDojo: "defectdojo.sdase.org/",
+	}
+
+	err := Validate(annotationNames, &CollectorImage{NamespaceFilter: "^kube-"}, &RunConfig{ImageFilter: []string{"mongo", "/istio/"}})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	invalidAnnotationNames := *annotationNames
+	invalidAnnotationNames.Contact = "contact sdase/org/"
+	invalidAnnotationNames.BackstageMapping = []string{"backstage.io/owner=team", "backstage.io/owner", "backstage.io/system=system"}
+
+	err = Validate(&invalidAnnotationNames, &CollectorImage{NamespaceFilterNegated: "("}, &RunConfig{ImageFilter: []string{"mongo", "["}, MetadataPrecedence: "pod"})
+	assert.ErrorContains(t, err, `image filter "["`)
+	assert.ErrorContains(t, err, `metadata precedence "pod" is not supported`)
+	assert.ErrorContains(t, err, `negated namespace filter "("`)
+	assert.ErrorContains(t, err, `contact annotation name "contact sdase/org/"`)
+	assert.ErrorContains(t, err, `Backstage mapping "backstage.io/owner" has to be in the format`)
+	assert.ErrorContains(t, err, `Backstage mapping "backstage.io/system=system" has an unknown field`)
+	assert.NotContains(t, err.Error(), `"backstage.io/owner=team"`)
+}
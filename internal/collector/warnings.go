@@ -0,0 +1,26 @@
+package collector
+
+// PullWarning lists an image which currently can't be pulled, which often means its tag was deleted and rescanning
+// it will fail as well
+type PullWarning struct {
+	Namespace string `json:"namespace"`
+	Image     string `json:"image"`
+	Reason    string `json:"reason"`
+}
+
+// PullWarnings returns the images with a pull error, every image is listed once per namespace
+func PullWarnings(images []CollectorImage) []PullWarning {
+	var warnings []PullWarning
+	seen := map[PullWarning]bool{}
+	for _, image := range images {
+		if image.PullError == "" {
+			continue
+		}
+		warning := PullWarning{Namespace: image.Namespace, Image: image.Image, Reason: image.PullError}
+		if !seen[warning] {
+			seen[warning] = true
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings
+}
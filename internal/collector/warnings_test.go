@@ -0,0 +1,22 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullWarnings(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "ns", Image: "app:1.0"},
+		{Namespace: "ns", Image: "app:deleted", PullError: "ImagePullBackOff"},
+		{Namespace: "ns", Image: "app:deleted", PullError: "ImagePullBackOff"},
+		{Namespace: "other", Image: "app:deleted", PullError: "ErrImagePull"},
+	}
+
+	assert.Equal(t, []PullWarning{
+		{Namespace: "ns", Image: "app:deleted", Reason: "ImagePullBackOff"},
+		{Namespace: "other", Image: "app:deleted", Reason: "ErrImagePull"},
+	}, PullWarnings(images))
+	assert.Empty(t, PullWarnings(images[:1]))
+}
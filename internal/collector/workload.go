@@ -0,0 +1,119 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// GroupByWorkload aggregates the images per owning workload instead of reporting every container instance
+const GroupByWorkload = "workload"
+
+// Workload is an entry of the report grouped by workload, e.g. a Deployment with the images of its containers in
+// every namespace it is deployed to. Images without a known workload, e.g. of an input file, are grouped in an entry
+// without kind and name. Identically named workloads of different teams or products are separate entries.
+type Workload struct {
+	Kind       string   `json:"kind"`
+	Name       string   `json:"name"`
+	Namespaces []string `json:"namespaces"`
+	Images     []string `json:"images"`
+
+	// Environment is the one of the first image which sets it
+	Environment string `json:"environment,omitempty"`
+	Product     string `json:"product,omitempty"`
+	Team        string `json:"team,omitempty"`
+}
+
+// GroupWorkloads aggregates the images which are not skipped by the kind and name of their workload and by their team
+// and product, sorted by these
+func GroupWorkloads(images []CollectorImage) []Workload {
+	type workloadSets struct {
+		workload   *Workload
+		namespaces map[string]bool
+		images     map[string]bool
+	}
+
+	byKey := map[string]*workloadSets{}
+	var keys []string
+	for i := range images {
+		image := &images[i]
+		if image.Skip {
+			continue
+		}
+		key := image.WorkloadKind + "/" + image.Workload + "/" + image.Team + "/" + image.Product
+		sets, ok := byKey[key]
+		if !ok {
+			sets = &workloadSets{
+				workload:   &Workload{Kind: image.WorkloadKind, Name: image.Workload, Product: image.Product, Team: image.Team},
+				namespaces: map[string]bool{},
+				images:     map[string]bool{},
+			}
+			byKey[key] = sets
+			keys = append(keys, key)
+		}
+
+		workload := sets.workload
+		if !sets.namespaces[image.Namespace] {
+			sets.namespaces[image.Namespace] = true
+			workload.Namespaces = append(workload.Namespaces, image.Namespace)
+		}
+		if !sets.images[image.Image] {
+			sets.images[image.Image] = true
+			workload.Images = append(workload.Images, image.Image)
+		}
+		if workload.Environment == "" {
+			workload.Environment = image.Environment
+		}
+	}
+
+	workloads := make([]Workload, len(keys))
+	for i, key := range keys {
+		workloads[i] = *byKey[key].workload
+		sort.Strings(workloads[i].Namespaces)
+		sort.Strings(workloads[i].Images)
+	}
+	sort.Slice(workloads, func(i, j int) bool {
+		a, b := workloads[i], workloads[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Team != b.Team {
+			return a.Team < b.Team
+		}
+		return a.Product < b.Product
+	})
+	return workloads
+}
+
+// workloadMarshal serializes the workloads of the images with marshal, which has to accept any value like
+// json.Marshal
+func workloadMarshal(marshal JsonMarshal) JsonMarshal {
+	return func(v any) ([]byte, error) {
+		images, err := imagesFromAny(v)
+		if err != nil {
+			return nil, err
+		}
+		workloads := GroupWorkloads(images)
+		return marshal(&workloads)
+	}
+}
+
+// workloadNdjsonMarshal writes one JSON object per workload like NdjsonMarshal
+func workloadNdjsonMarshal(v any) ([]byte, error) {
+	images, err := imagesFromAny(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, workload := range GroupWorkloads(images) {
+		if err = encoder.Encode(&workload); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
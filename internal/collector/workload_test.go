@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupWorkloads(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "prod", Image: "app:1.0", WorkloadKind: "Deployment", Workload: "app", Product: "shop", Team: "team-a", Environment: "prod"},
+		{Namespace: "prod", Image: "app:1.0", WorkloadKind: "Deployment", Workload: "app", Product: "shop", Team: "team-a"},
+		{Namespace: "prod", Image: "envoy:1.0", WorkloadKind: "Deployment", Workload: "app", Product: "shop", Team: "team-a"},
+		{Namespace: "dev", Image: "app:1.1", WorkloadKind: "Deployment", Workload: "app", Product: "shop", Team: "team-a"},
+		{Namespace: "other", Image: "other-app:1.0", WorkloadKind: "Deployment", Workload: "app", Team: "team-b"},
+		{Namespace: "prod", Image: "backup:1.0", WorkloadKind: "CronJob", Workload: "backup"},
+		{Namespace: "prod", Image: "debug:1.0"},
+		{Namespace: "prod", Image: "skipped:1.0", WorkloadKind: "CronJob", Workload: "backup", Skip: true},
+	}
+
+	workloads := GroupWorkloads(images)
+
+	assert.Equal(t, []Workload{
+		{Namespaces: []string{"prod"}, Images: []string{"debug:1.0"}},
+		{Kind: "CronJob", Name: "backup", Namespaces: []string{"prod"}, Images: []string{"backup:1.0"}},
+		{Kind: "Deployment", Name: "app", Namespaces: []string{"dev", "prod"}, Images: []string{"app:1.0", "app:1.1", "envoy:1.0"}, Environment: "prod", Product: "shop", Team: "team-a"},
+		{Kind: "Deployment", Name: "app", Namespaces: []string{"other"}, Images: []string{"other-app:1.0"}, Team: "team-b"},
+	}, workloads)
+}
+
+func TestGroupByWorkloadOutput(t *testing.T) {
+	images := []CollectorImage{
+		{Namespace: "prod", Image: "app:1.0", WorkloadKind: "Deployment", Workload: "app"},
+		{Namespace: "prod", Image: "db:1.0", WorkloadKind: "StatefulSet", Workload: "db"},
+	}
+
+	outputFormat, err := NewOutputFormat(&OutputConfig{GroupBy: GroupByWorkload})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Nil(t, outputFormat.Encode)
+	data, err := outputFormat.Marshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	var workloads []Workload
+	assert.NoError(t, json.Unmarshal(data, &workloads))
+	assert.Len(t, workloads, 2)
+	assert.Equal(t, "app", workloads[0].Name)
+
+	outputFormat, err = NewOutputFormat(&OutputConfig{OutputFormat: "ndjson", GroupBy: GroupByWorkload})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	data, err = outputFormat.Marshal(&images)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[1], `"kind":"StatefulSet"`)
+
+	_, err = NewOutputFormat(&OutputConfig{OutputFormat: "csv", GroupBy: GroupByWorkload})
+	assert.Error(t, err, "Expected error but got none")
+
+	_, err = NewOutputFormat(&OutputConfig{GroupBy: GroupByWorkload, AllowPartial: true})
+	assert.Error(t, err, "Expected error but got none")
+
+	_, err = NewOutputFormat(&OutputConfig{GroupBy: "team"})
+	assert.Error(t, err, "Expected error but got none")
+}
@@ -1,9 +1,21 @@
 package config
 
 import (
+	"time"
+
 	"github.com/SDA-SE/image-metadata-collector/internal/collector"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/admission"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/heartbeat"
 	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/leader"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/metrics"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/notify"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/profile"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/server"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/status"
 	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/tlsconfig"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/vault"
 )
 
 type Config struct {
@@ -12,6 +24,44 @@ type Config struct {
 	kubeclient.KubeConfig
 	storage.StorageConfig
 	collector.RunConfig
+	collector.OutputConfig
+	collector.SkipAuditConfig
+	collector.EnrichConfig
+	collector.RiskConfig
+	server.ServerConfig
+	metrics.PushConfig
+	leader.LeaderElectionConfig
+	leader.RunLockConfig
+	profile.ProfileConfig
+	heartbeat.HeartbeatConfig
+	notify.NotifyConfig
+	status.StatusConfig
+	admission.AdmissionConfig
+	admission.WebhookConfig
+	vault.VaultConfig
+	tlsconfig.TLSConfig
+
+	ConfigFile   string
+	StrictConfig bool
+	Debug        bool
+	LogLevel     string
+	LogFormat    string
+
+	// Interval runs the collector continuously, every run is delayed by up to IntervalJitter * Interval
+	Interval       time.Duration
+	IntervalJitter float64
+
+	// Operator updates the reports of the namespaces whose pods changed, changes are collected for OperatorDebounce
+	Operator         bool
+	OperatorDebounce time.Duration
+
+	// Timeout bounds a single run, including waiting for the run lock
+	Timeout time.Duration
+
+	// ShutdownTimeout is the grace period of an in-flight run after a termination signal
+	ShutdownTimeout time.Duration
 
-	Debug bool
+	// MaxRunRetries repeats a run failing with a retryable error after RunRetryBackoff, which doubles with every retry
+	MaxRunRetries   int
+	RunRetryBackoff time.Duration
 }
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"reflect"
+)
+
+// Change is a single field which differs between two configurations
+type Change struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// Diff compares the exported fields of two values of the same struct type, fields of nested structs are prefixed
+// with the struct field name e.g. 'CollectorImage.Team'
+func Diff(old, new any) []Change {
+	return diff("", reflect.ValueOf(old), reflect.ValueOf(new))
+}
+
+func diff(prefix string, old, new reflect.Value) []Change {
+	if old.Kind() == reflect.Pointer {
+		old, new = old.Elem(), new.Elem()
+	}
+
+	var changes []Change
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			changes = append(changes, diff(prefix+field.Name+".", old.Field(i), new.Field(i))...)
+			continue
+		}
+
+		if !reflect.DeepEqual(old.Field(i).Interface(), new.Field(i).Interface()) {
+			changes = append(changes, Change{Field: prefix + field.Name, Old: old.Field(i).Interface(), New: new.Field(i).Interface()})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/collector"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Config{
+		CollectorImage: collector.CollectorImage{Team: "team-a", EngagementTags: []string{"a"}},
+		RunConfig:      collector.RunConfig{ImageFilter: []string{"mongo"}},
+	}
+	new := &Config{
+		CollectorImage: collector.CollectorImage{Team: "team-b", EngagementTags: []string{"a"}},
+		RunConfig:      collector.RunConfig{ImageFilter: []string{"mongo", "nginx"}},
+	}
+
+	assert.Empty(t, Diff(old, old))
+	assert.Equal(t, []Change{
+		{Field: "CollectorImage.Team", Old: "team-a", New: "team-b"},
+		{Field: "RunConfig.ImageFilter", Old: []string{"mongo"}, New: []string{"mongo", "nginx"}},
+	}, Diff(old, new))
+}
@@ -0,0 +1,324 @@
+// Package admission records the images of pods the moment they are created with a validating admission webhook, so
+// the images of short-lived pods, e.g. of jobs which terminate between two runs, are still reported. The webhook
+// keeps the images in a ConfigMap which the collector merges into the images of the cluster.
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+
+	"github.com/rs/zerolog/log"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// DefaultRetention is used if the retention is not set
+const DefaultRetention = 24 * time.Hour
+
+// maxConfigMapSize stays below the size limit of a ConfigMap, the oldest images are dropped beyond it
+const maxConfigMapSize = 900 * 1024
+
+type AdmissionConfig struct {
+	// AdmissionConfigMap keeps the admitted images, the webhook writes it and the collector merges it, disabled if
+	// empty
+	AdmissionConfigMap string
+	// AdmissionNamespace of the ConfigMap, defaults to the namespace of the pod
+	AdmissionNamespace string
+	// AdmissionRetention drops images which were not admitted again for this duration, defaults to DefaultRetention
+	AdmissionRetention time.Duration
+}
+
+func (cfg *AdmissionConfig) retention() time.Duration {
+	if cfg.AdmissionRetention <= 0 {
+		return DefaultRetention
+	}
+	return cfg.AdmissionRetention
+}
+
+// Enabled reports whether admitted images are recorded or merged
+func (cfg *AdmissionConfig) Enabled() bool {
+	return cfg.AdmissionConfigMap != ""
+}
+
+// Record is an image of an admitted pod, the ConfigMap holds the records of a namespace as JSON array under the name
+// of the namespace
+type Record struct {
+	Image       string            `json:"image"`
+	Pod         string            `json:"pod"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	PullSecrets []string          `json:"pullSecrets,omitempty"`
+	Admitted    time.Time         `json:"admitted"`
+}
+
+// Records returns the images of the pod including its init containers, pods created by a controller are named after
+// their generateName until the API server assigns the name
+func Records(pod *corev1.Pod, admitted time.Time) []Record {
+	name := pod.Name
+	if name == "" {
+		name = pod.GenerateName
+	}
+	var pullSecrets []string
+	for _, secret := range pod.Spec.ImagePullSecrets {
+		pullSecrets = append(pullSecrets, secret.Name)
+	}
+
+	var records []Record
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, container := range containers {
+			if container.Image == "" {
+				continue
+			}
+			records = append(records, Record{
+				Image:       container.Image,
+				Pod:         name,
+				Labels:      pod.Labels,
+				Annotations: pod.Annotations,
+				PullSecrets: pullSecrets,
+				Admitted:    admitted.UTC(),
+			})
+		}
+	}
+	return records
+}
+
+// K8Image converts the record like the images of running pods, the metadata of the namespace takes precedence
+func (r Record) K8Image(namespace kubeclient.Namespace) kubeclient.Image {
+	labels := maps.Clone(r.Labels)
+	if labels == nil {
+		labels = namespace.Labels
+	} else {
+		maps.Copy(labels, namespace.Labels)
+	}
+	annotations := maps.Clone(r.Annotations)
+	if annotations == nil {
+		annotations = namespace.Annotations
+	} else {
+		maps.Copy(annotations, namespace.Annotations)
+	}
+	return kubeclient.Image{
+		Image:                r.Image,
+		NamespaceName:        namespace.Name,
+		Labels:               labels,
+		Annotations:          annotations,
+		PullSecrets:          r.PullSecrets,
+		NamespaceLabels:      namespace.Labels,
+		NamespaceAnnotations: namespace.Annotations,
+	}
+}
+
+// Recorder collects the admitted images in memory and merges them into the ConfigMap on Flush, so a burst of pods
+// costs one update instead of one per pod
+type Recorder struct {
+	configMaps typedcorev1.ConfigMapInterface
+	name       string
+	namespace  string
+	retention  time.Duration
+
+	mu      sync.Mutex
+	pending map[string]map[string]Record
+}
+
+// NewRecorder resolves the namespace of the ConfigMap
+func NewRecorder(clientset kubernetes.Interface, cfg *AdmissionConfig) (*Recorder, error) {
+	namespace, err := kubeclient.PodNamespace(cfg.AdmissionNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine the namespace of the admitted images, set --admission-namespace: %w", err)
+	}
+	return &Recorder{
+		configMaps: clientset.CoreV1().ConfigMaps(namespace),
+		name:       cfg.AdmissionConfigMap,
+		namespace:  namespace,
+		retention:  cfg.retention(),
+		pending:    map[string]map[string]Record{},
+	}, nil
+}
+
+// Record adds the images of an admitted pod, the latest pod of an image is kept
+func (r *Recorder) Record(namespace string, records []Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending[namespace] == nil {
+		r.pending[namespace] = map[string]Record{}
+	}
+	for _, record := range records {
+		if existing, ok := r.pending[namespace][record.Image]; !ok || !existing.Admitted.After(record.Admitted) {
+			r.pending[namespace][record.Image] = record
+		}
+	}
+}
+
+// Flush merges the recorded images into the ConfigMap and drops the expired ones. The images are recorded again if
+// the update fails, so the next flush retries them.
+func (r *Recorder) Flush(ctx context.Context, now time.Time) error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = map[string]map[string]Record{}
+	r.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := r.configMaps.Get(ctx, r.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			configMap = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: r.name, Namespace: r.namespace}}
+			if configMap.Data, err = mergeRecords(nil, pending, now.Add(-r.retention)); err != nil {
+				return err
+			}
+			_, err = r.configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		if configMap.Data, err = mergeRecords(configMap.Data, pending, now.Add(-r.retention)); err != nil {
+			return err
+		}
+		_, err = r.configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		for namespace, records := range pending {
+			r.Record(namespace, sortedRecords(records))
+		}
+		return fmt.Errorf("could not update ConfigMap %s/%s of the admitted images: %w", r.namespace, r.name, err)
+	}
+	return nil
+}
+
+// Run flushes the recorded images every interval and once more when the context is done
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// The final flush must not be cancelled with the webhook
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			if err := r.Flush(flushCtx, time.Now()); err != nil {
+				log.Error().Err(err).Msg("Could not record admitted images")
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := r.Flush(ctx, time.Now()); err != nil {
+				log.Warn().Err(err).Msg("Could not record admitted images, retrying")
+			}
+		}
+	}
+}
+
+// Load returns the images admitted within the retention by namespace, without ConfigMap there are none
+func Load(ctx context.Context, clientset kubernetes.Interface, cfg *AdmissionConfig, now time.Time) (map[string][]Record, error) {
+	namespace, err := kubeclient.PodNamespace(cfg.AdmissionNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine the namespace of the admitted images, set --admission-namespace: %w", err)
+	}
+	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, cfg.AdmissionConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		log.Debug().Str("configMap", cfg.AdmissionConfigMap).Msg("No images admitted yet")
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read ConfigMap %s/%s of the admitted images: %w", namespace, cfg.AdmissionConfigMap, err)
+	}
+
+	since := now.Add(-cfg.retention())
+	admitted := map[string][]Record{}
+	for name, value := range configMap.Data {
+		var records []Record
+		if err = json.Unmarshal([]byte(value), &records); err != nil {
+			return nil, fmt.Errorf("invalid admitted images of namespace %s: %w", name, err)
+		}
+		for _, record := range records {
+			if record.Admitted.After(since) {
+				admitted[name] = append(admitted[name], record)
+			}
+		}
+	}
+	return admitted, nil
+}
+
+// mergeRecords adds the pending records to the data of the ConfigMap and drops the records admitted before expiry.
+// If the data grows too large, the oldest records are dropped.
+func mergeRecords(data map[string]string, pending map[string]map[string]Record, expiry time.Time) (map[string]string, error) {
+	namespaces := map[string]map[string]Record{}
+	for namespace, value := range data {
+		var records []Record
+		if err := json.Unmarshal([]byte(value), &records); err != nil {
+			log.Warn().Err(err).Str("namespace", namespace).Msg("Dropping invalid admitted images")
+			continue
+		}
+		namespaces[namespace] = map[string]Record{}
+		for _, record := range records {
+			namespaces[namespace][record.Image] = record
+		}
+	}
+	for namespace, records := range pending {
+		if namespaces[namespace] == nil {
+			namespaces[namespace] = map[string]Record{}
+		}
+		maps.Copy(namespaces[namespace], records)
+	}
+
+	type admitted struct {
+		namespace string
+		record    Record
+	}
+	var all []admitted
+	for namespace, records := range namespaces {
+		for _, record := range records {
+			if record.Admitted.After(expiry) {
+				all = append(all, admitted{namespace, record})
+			}
+		}
+	}
+	// The newest records come first, so the oldest are dropped once the size is exceeded
+	sort.Slice(all, func(i, j int) bool { return all[i].record.Admitted.After(all[j].record.Admitted) })
+
+	kept := map[string][]Record{}
+	size := 0
+	for i, a := range all {
+		value, err := json.Marshal(a.record)
+		if err != nil {
+			return nil, err
+		}
+		if size += len(value) + len(a.namespace); size > maxConfigMapSize {
+			log.Warn().Int("dropped", len(all)-i).Msg("Dropping the oldest admitted images, the ConfigMap is full")
+			break
+		}
+		kept[a.namespace] = append(kept[a.namespace], a.record)
+	}
+
+	merged := map[string]string{}
+	for namespace, records := range kept {
+		sort.Slice(records, func(i, j int) bool { return records[i].Image < records[j].Image })
+		value, err := json.Marshal(records)
+		if err != nil {
+			return nil, err
+		}
+		merged[namespace] = string(value)
+	}
+	return merged, nil
+}
+
+func sortedRecords(records map[string]Record) []Record {
+	sorted := make([]Record, 0, len(records))
+	for _, record := range records {
+		sorted = append(sorted, record)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Image < sorted[j].Image })
+	return sorted
+}
@@ -0,0 +1,137 @@
+package admission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func review(t *testing.T, operation admissionv1.Operation, namespace string, pod *corev1.Pod) []byte {
+	raw, err := json.Marshal(pod)
+	assert.NoError(t, err)
+	body, err := json.Marshal(admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "uid-1",
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Operation: operation,
+			Namespace: namespace,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	})
+	assert.NoError(t, err)
+	return body
+}
+
+func TestHandler(t *testing.T) {
+	clientset := testclient.NewSimpleClientset()
+	recorder, err := NewRecorder(clientset, &AdmissionConfig{AdmissionConfigMap: "admitted-images", AdmissionNamespace: "scanner"})
+	assert.NoError(t, err)
+	handler := Handler(recorder)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "job-", Labels: map[string]string{"app": "job"}},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init", Image: "busybox"}},
+			Containers:     []corev1.Container{{Name: "app", Image: "migrate:1.0"}},
+		},
+	}
+	for _, operation := range []admissionv1.Operation{admissionv1.Create, admissionv1.Update} {
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(review(t, operation, "jobs", pod))))
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var result admissionv1.AdmissionReview
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+		assert.True(t, result.Response.Allowed)
+		assert.Equal(t, "uid-1", string(result.Response.UID))
+	}
+
+	now := time.Now()
+	assert.NoError(t, recorder.Flush(context.Background(), now))
+	admitted, err := Load(context.Background(), clientset, &AdmissionConfig{AdmissionConfigMap: "admitted-images", AdmissionNamespace: "scanner"}, now)
+	assert.NoError(t, err)
+	assert.Len(t, admitted["jobs"], 2)
+	assert.Equal(t, "busybox", admitted["jobs"][0].Image)
+	assert.Equal(t, "job-", admitted["jobs"][1].Pod)
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader([]byte("{"))))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestFlush(t *testing.T) {
+	clientset := testclient.NewSimpleClientset()
+	cfg := &AdmissionConfig{AdmissionConfigMap: "admitted-images", AdmissionNamespace: "scanner", AdmissionRetention: time.Hour}
+	recorder, err := NewRecorder(clientset, cfg)
+	assert.NoError(t, err)
+	ctx := context.Background()
+	start := time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+
+	// Flushing without recorded images does not create the ConfigMap
+	assert.NoError(t, recorder.Flush(ctx, start))
+	admitted, err := Load(ctx, clientset, cfg, start)
+	assert.NoError(t, err)
+	assert.Empty(t, admitted)
+
+	recorder.Record("ns-a", []Record{{Image: "old", Pod: "pod-1", Admitted: start}})
+	recorder.Record("ns-a", []Record{{Image: "app", Pod: "pod-1", Admitted: start}})
+	assert.NoError(t, recorder.Flush(ctx, start))
+
+	// The image is kept with its latest pod, the expired image is dropped
+	recorder.Record("ns-a", []Record{{Image: "app", Pod: "pod-2", Admitted: start.Add(50 * time.Minute)}})
+	recorder.Record("ns-b", []Record{{Image: "job", Pod: "pod-3", Admitted: start.Add(70 * time.Minute)}})
+	assert.NoError(t, recorder.Flush(ctx, start.Add(70*time.Minute)))
+
+	configMap, err := clientset.CoreV1().ConfigMaps("scanner").Get(ctx, "admitted-images", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, configMap.Data, 2)
+
+	admitted, err = Load(ctx, clientset, cfg, start.Add(70*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]Record{
+		"ns-a": {{Image: "app", Pod: "pod-2", Admitted: start.Add(50 * time.Minute)}},
+		"ns-b": {{Image: "job", Pod: "pod-3", Admitted: start.Add(70 * time.Minute)}},
+	}, admitted)
+
+	// Images which expired since the last flush are not loaded
+	admitted, err = Load(ctx, clientset, cfg, start.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ns-b"}, keys(admitted))
+}
+
+func keys(admitted map[string][]Record) []string {
+	var names []string
+	for name := range admitted {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestK8Image(t *testing.T) {
+	record := Record{Image: "app", Labels: map[string]string{"team": "pod"}, Annotations: map[string]string{"a": "pod"}, PullSecrets: []string{"pull"}}
+	image := record.K8Image(kubeclient.Namespace{Name: "ns", Labels: map[string]string{"team": "namespace"}})
+
+	assert.Equal(t, kubeclient.Image{
+		Image:           "app",
+		NamespaceName:   "ns",
+		Labels:          map[string]string{"team": "namespace"},
+		Annotations:     map[string]string{"a": "pod"},
+		PullSecrets:     []string{"pull"},
+		NamespaceLabels: map[string]string{"team": "namespace"},
+	}, image)
+	assert.Equal(t, "pod", record.Labels["team"], "Expected the labels of the record to be unchanged")
+}
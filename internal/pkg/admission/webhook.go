@@ -0,0 +1,79 @@
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxReviewSize limits the AdmissionReview read into memory, the API server sends at most a few MiB
+const maxReviewSize = 8 << 20
+
+type WebhookConfig struct {
+	// WebhookListenAddress of the HTTPS server receiving the AdmissionReviews
+	WebhookListenAddress string
+	// WebhookTlsCertFile and WebhookTlsKeyFile are the serving certificate the API server trusts with the caBundle of
+	// the ValidatingWebhookConfiguration
+	WebhookTlsCertFile string
+	WebhookTlsKeyFile  string
+	// WebhookFlushInterval merges the recorded images into the ConfigMap at this interval
+	WebhookFlushInterval time.Duration
+}
+
+// Handler records the images of created pods and allows every request, the webhook never blocks pods. Requests which
+// can't be decoded are allowed as well and only logged.
+func Handler(recorder *Recorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxReviewSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var review admissionv1.AdmissionReview
+		if err = json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(w, fmt.Sprintf("invalid AdmissionReview: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err = record(recorder, review.Request, time.Now()); err != nil {
+			log.Warn().Err(err).Str("namespace", review.Request.Namespace).Str("uid", string(review.Request.UID)).Msg("Could not record admitted pod")
+		}
+
+		review.Response = &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+		review.Request = nil
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(review)
+	})
+}
+
+// record adds the images of a created pod, other requests and dry runs are ignored
+func record(recorder *Recorder, request *admissionv1.AdmissionRequest, now time.Time) error {
+	if request.Operation != admissionv1.Create || request.Kind != (metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}) || request.SubResource != "" {
+		return nil
+	}
+	if request.DryRun != nil && *request.DryRun {
+		return nil
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal(request.Object.Raw, &pod); err != nil {
+		return fmt.Errorf("invalid pod: %w", err)
+	}
+	// The namespace of the request is set even if the pod does not name one
+	records := Records(&pod, now)
+	recorder.Record(request.Namespace, records)
+	log.Debug().Str("namespace", request.Namespace).Str("pod", request.Name).Int("images", len(records)).Msg("Recorded admitted pod")
+	return nil
+}
@@ -0,0 +1,65 @@
+// Package atomicfile replaces files atomically, readers see either the previous or the new content but never a
+// partially written file
+package atomicfile
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Write writes content to a temporary file next to fileName and renames it to fileName, beforeRename is called right
+// before the existing file is replaced. It returns the number of bytes written.
+func Write(fileName string, content io.Reader, beforeRename func() error) (int, error) {
+	tmpFile, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	tmpName := tmpFile.Name()
+
+	// Remove the temporary file if anything goes wrong before the rename
+	defer func() {
+		if _, statErr := os.Stat(tmpName); statErr == nil {
+			_ = os.Remove(tmpName)
+		}
+	}()
+
+	written, err := io.Copy(tmpFile, content)
+	n := int(written)
+	if err != nil {
+		tmpFile.Close()
+		return n, err
+	}
+
+	if err = tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return n, err
+	}
+
+	if err = tmpFile.Close(); err != nil {
+		return n, err
+	}
+
+	if err = os.Chmod(tmpName, 0644); err != nil {
+		return n, err
+	}
+
+	if beforeRename != nil {
+		if err = beforeRename(); err != nil {
+			return n, err
+		}
+	}
+
+	if err = os.Rename(tmpName, fileName); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
+
+// WriteFile replaces the file with the content
+func WriteFile(fileName string, content []byte) error {
+	_, err := Write(fileName, bytes.NewReader(content), nil)
+	return err
+}
@@ -0,0 +1,44 @@
+package atomicfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "report.json")
+
+	if err := WriteFile(fileName, []byte("old")); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	n, err := Write(fileName, strings.NewReader("new"), func() error { return errors.New("rotation failed") })
+	if err == nil {
+		t.Fatalf("Expected the error of beforeRename but got none\n")
+	}
+	if n != 3 {
+		t.Fatalf("Expected 3 bytes written but got %d\n", n)
+	}
+	if content, _ := os.ReadFile(fileName); string(content) != "old" {
+		t.Fatalf("Expected the previous content to be kept but got %s\n", content)
+	}
+
+	if _, err = Write(fileName, strings.NewReader("new"), nil); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if content, _ := os.ReadFile(fileName); string(content) != "new" {
+		t.Fatalf("Expected the new content but got %s\n", content)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected the temporary files to be removed but got %v\n", entries)
+	}
+}
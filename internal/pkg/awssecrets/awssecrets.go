@@ -0,0 +1,119 @@
+// Package awssecrets resolves references to AWS Secrets Manager secrets and SSM Parameter Store parameters
+package awssecrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+const (
+	// SecretsManagerScheme references a secret by name or ARN, e.g. 'awssm://collector/api-key'
+	SecretsManagerScheme = "awssm://"
+	// ParameterStoreScheme references a parameter by path, e.g. 'ssm:///collector/api-key'
+	ParameterStoreScheme = "ssm://"
+)
+
+// IsReference reports whether the value references a secret or parameter instead of holding the credential itself
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, SecretsManagerScheme) || strings.HasPrefix(value, ParameterStoreScheme)
+}
+
+// Resolver reads the referenced values, the AWS clients are created with the first reference
+type Resolver struct {
+	secretsManager secretsmanageriface.SecretsManagerAPI
+	parameterStore ssmiface.SSMAPI
+}
+
+func NewResolver() *Resolver {
+	return &Resolver{}
+}
+
+func (r *Resolver) init() error {
+	if r.secretsManager != nil && r.parameterStore != nil {
+		return nil
+	}
+	// Region and credentials are read from the environment, the shared config or the web identity of IRSA
+	sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return fmt.Errorf("could not create AWS session: %w", err)
+	}
+	r.secretsManager = secretsmanager.New(sess)
+	r.parameterStore = ssm.New(sess)
+	return nil
+}
+
+// Resolve returns the value of a reference. A secret holding a JSON object can be narrowed to one of its keys with
+// 'awssm://<name>#<key>', parameters of type SecureString are decrypted.
+func (r *Resolver) Resolve(ctx context.Context, reference string) (string, error) {
+	if !IsReference(reference) {
+		return reference, nil
+	}
+	if err := r.init(); err != nil {
+		return "", err
+	}
+
+	if name, ok := strings.CutPrefix(reference, SecretsManagerScheme); ok {
+		name, key, _ := strings.Cut(name, "#")
+		if name == "" {
+			return "", fmt.Errorf("AWS secret reference %s has to be in the format %s<name>[#<key>]", reference, SecretsManagerScheme)
+		}
+		return r.secret(ctx, name, key)
+	}
+
+	path := strings.TrimPrefix(reference, ParameterStoreScheme)
+	if path == "" {
+		return "", fmt.Errorf("AWS parameter reference %s has to be in the format %s<path>", reference, ParameterStoreScheme)
+	}
+	return r.parameter(ctx, path)
+}
+
+func (r *Resolver) secret(ctx context.Context, name, key string) (string, error) {
+	output, err := r.secretsManager.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(name)})
+	if err != nil {
+		return "", fmt.Errorf("could not read AWS secret %s: %w", name, err)
+	}
+
+	value := aws.StringValue(output.SecretString)
+	if output.SecretString == nil {
+		value = string(output.SecretBinary)
+	}
+	if key == "" {
+		return value, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("AWS secret %s is no JSON object: %w", name, err)
+	}
+	field, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("AWS secret %s has no key %s", name, key)
+	}
+	if s, ok := field.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(field), nil
+}
+
+func (r *Resolver) parameter(ctx context.Context, path string) (string, error) {
+	output, err := r.parameterStore.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(path),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not read AWS parameter %s: %w", path, err)
+	}
+	if output.Parameter == nil {
+		return "", fmt.Errorf("AWS parameter %s has no value", path)
+	}
+	return aws.StringValue(output.Parameter.Value), nil
+}
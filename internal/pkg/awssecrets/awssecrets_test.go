@@ -0,0 +1,83 @@
+package awssecrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+	secrets map[string]string
+}
+
+func (f *fakeSecretsManager) GetSecretValueWithContext(_ aws.Context, input *secretsmanager.GetSecretValueInput, _ ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+	value, ok := f.secrets[*input.SecretId]
+	if !ok {
+		return nil, errors.New("ResourceNotFoundException")
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(value)}, nil
+}
+
+type fakeParameterStore struct {
+	ssmiface.SSMAPI
+	parameters map[string]string
+}
+
+func (f *fakeParameterStore) GetParameterWithContext(_ aws.Context, input *ssm.GetParameterInput, _ ...request.Option) (*ssm.GetParameterOutput, error) {
+	value, ok := f.parameters[*input.Name]
+	if !ok || !aws.BoolValue(input.WithDecryption) {
+		return nil, errors.New("ParameterNotFound")
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String(value)}}, nil
+}
+
+func TestResolve(t *testing.T) {
+	r := &Resolver{
+		secretsManager: &fakeSecretsManager{secrets: map[string]string{
+			"collector/api-key": "plain-secret",
+			"collector/git":     `{"username":"bot","password":"git-secret","port":22}`,
+		}},
+		parameterStore: &fakeParameterStore{parameters: map[string]string{"/collector/api-key": "parameter-secret"}},
+	}
+	ctx := context.Background()
+
+	for reference, expected := range map[string]string{
+		"literal-value":                  "literal-value",
+		"awssm://collector/api-key":      "plain-secret",
+		"awssm://collector/git#password": "git-secret",
+		"awssm://collector/git#port":     "22",
+		"ssm:///collector/api-key":       "parameter-secret",
+	} {
+		value, err := r.Resolve(ctx, reference)
+		assert.NoError(t, err, reference)
+		assert.Equal(t, expected, value, reference)
+	}
+
+	for _, reference := range []string{
+		"awssm://",
+		"ssm://",
+		"awssm://collector/missing",
+		"awssm://collector/api-key#password",
+		"awssm://collector/git#missing",
+		"ssm:///collector/missing",
+	} {
+		_, err := r.Resolve(ctx, reference)
+		assert.Error(t, err, reference)
+	}
+}
+
+func TestIsReference(t *testing.T) {
+	assert.True(t, IsReference("awssm://name"))
+	assert.True(t, IsReference("ssm:///path"))
+	assert.False(t, IsReference("secret"))
+	assert.False(t, IsReference("s3://bucket"))
+}
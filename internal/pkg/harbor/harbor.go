@@ -0,0 +1,159 @@
+// Package harbor reads project metadata and scan results of images hosted in Harbor registries
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/registry"
+)
+
+// maxResponseSize limits the API responses read into memory
+const maxResponseSize = 4 << 20
+
+// projectCacheTTL bounds how long a project is cached, so changes of the project are seen by later runs of a daemon
+const projectCacheTTL = 10 * time.Minute
+
+// Metadata of the Harbor project and artifact of an image
+type Metadata struct {
+	ProjectOwner string
+	// SeverityThreshold is the severity from which the project prevents pulling vulnerable images
+	SeverityThreshold string
+	ScanStatus        string
+}
+
+// Client queries the Harbor API of the registries it has an endpoint for
+type Client struct {
+	httpClient *http.Client
+	keychain   registry.Keychain
+	endpoints  map[string]string
+
+	mu       sync.Mutex
+	projects map[string]cachedProject
+	now      func() time.Time
+}
+
+type cachedProject struct {
+	project   *project
+	fetchedAt time.Time
+}
+
+type project struct {
+	OwnerName string `json:"owner_name"`
+	Metadata  struct {
+		Severity   string `json:"severity"`
+		PreventVul string `json:"prevent_vul"`
+	} `json:"metadata"`
+}
+
+// NewClient creates a client for endpoints in the format '<registry host>=<harbor url>', the credentials of the
+// registry in the keychain are used for the API
+func NewClient(httpClient *http.Client, keychain registry.Keychain, endpoints []string) (*Client, error) {
+	c := &Client{httpClient: httpClient, keychain: keychain, endpoints: map[string]string{}, projects: map[string]cachedProject{}, now: time.Now}
+	for _, endpoint := range endpoints {
+		host, base, ok := strings.Cut(endpoint, "=")
+		if !ok || host == "" || base == "" {
+			return nil, fmt.Errorf("Harbor endpoint %s has to be in the format registry=url", endpoint)
+		}
+		if _, err := url.Parse(base); err != nil {
+			return nil, fmt.Errorf("invalid Harbor URL of %s: %w", host, err)
+		}
+		c.endpoints[host] = strings.TrimSuffix(base, "/")
+	}
+	return c, nil
+}
+
+// HarborMetadata returns the metadata of the image or nil if its registry is no configured Harbor
+func (c *Client) HarborMetadata(ctx context.Context, ref registry.Reference) (*Metadata, error) {
+	base, ok := c.endpoints[ref.Registry]
+	if !ok {
+		return nil, nil
+	}
+	projectName, repository, ok := strings.Cut(ref.Repository, "/")
+	if !ok {
+		return nil, fmt.Errorf("repository %s of %s has no Harbor project", ref.Repository, ref)
+	}
+
+	credentials, err := c.keychain.Resolve(ctx, ref.Registry)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve credentials for %s: %w", ref.Registry, err)
+	}
+
+	p, err := c.project(ctx, base, projectName, credentials)
+	if err != nil {
+		return nil, err
+	}
+	metadata := &Metadata{ProjectOwner: p.OwnerName}
+	if p.Metadata.PreventVul == "true" {
+		metadata.SeverityThreshold = p.Metadata.Severity
+	}
+
+	var artifact struct {
+		ScanOverview map[string]struct {
+			ScanStatus string `json:"scan_status"`
+		} `json:"scan_overview"`
+	}
+	path := fmt.Sprintf("/api/v2.0/projects/%s/repositories/%s/artifacts/%s?with_scan_overview=true",
+		url.PathEscape(projectName), url.PathEscape(url.PathEscape(repository)), url.PathEscape(ref.Identifier()))
+	if err = c.get(ctx, base+path, credentials, &artifact); err != nil {
+		return nil, err
+	}
+	// Harbor reports one overview per report mime type, they share the status of the scan
+	for _, overview := range artifact.ScanOverview {
+		metadata.ScanStatus = overview.ScanStatus
+		break
+	}
+	return metadata, nil
+}
+
+// project returns the project, they are cached for projectCacheTTL because many images share a project
+func (c *Client) project(ctx context.Context, base, name string, credentials registry.Credentials) (*project, error) {
+	key := base + "/" + name
+	c.mu.Lock()
+	cached, ok := c.projects[key]
+	c.mu.Unlock()
+	if ok && c.now().Sub(cached.fetchedAt) < projectCacheTTL {
+		return cached.project, nil
+	}
+
+	p := &project{}
+	if err := c.get(ctx, base+"/api/v2.0/projects/"+url.PathEscape(name), credentials, p); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.projects[key] = cachedProject{project: p, fetchedAt: c.now()}
+	c.mu.Unlock()
+	return p, nil
+}
+
+func (c *Client) get(ctx context.Context, address string, credentials registry.Credentials, v any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	// Project names consisting of digits would be treated as ids otherwise
+	request.Header.Set("X-Is-Resource-Name", "true")
+	if credentials != (registry.Credentials{}) {
+		request.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+
+	res, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if err = registry.CheckStatus(res, http.StatusOK, "GET "+request.URL.Path); err != nil {
+		return err
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(v)
+}
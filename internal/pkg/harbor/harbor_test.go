@@ -0,0 +1,74 @@
+package harbor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/registry"
+)
+
+func TestHarborMetadata(t *testing.T) {
+	projectCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, _ := r.BasicAuth(); username != "robot" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/api/v2.0/projects/team":
+			projectCalls++
+			_, _ = w.Write([]byte(`{"owner_name":"admin","metadata":{"severity":"high","prevent_vul":"true"}}`))
+		case "/api/v2.0/projects/team/repositories/group%252Fapp/artifacts/1.0":
+			if r.URL.Query().Get("with_scan_overview") != "true" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"scan_overview":{"application/vnd.security.vulnerability.report; version=1.1":{"scan_status":"Success"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	keychain, err := registry.ParseDockerConfig([]byte(`{"auths":{"harbor.example.com":{"username":"robot","password":"secret"}}}`))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	client, err := NewClient(server.Client(), keychain, []string{"harbor.example.com=" + server.URL + "/"})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		metadata, err := client.HarborMetadata(context.Background(), registry.Reference{Registry: "harbor.example.com", Repository: "team/group/app", Tag: "1.0"})
+		if err != nil {
+			t.Fatalf("Got an error=%v\n", err)
+		}
+		if *metadata != (Metadata{ProjectOwner: "admin", SeverityThreshold: "high", ScanStatus: "Success"}) {
+			t.Fatalf("Expected the project metadata but got %+v\n", *metadata)
+		}
+	}
+	if projectCalls != 1 {
+		t.Fatalf("Expected the project to be cached but it was fetched %d times\n", projectCalls)
+	}
+
+	client.now = func() time.Time { return time.Now().Add(projectCacheTTL) }
+	if _, err = client.HarborMetadata(context.Background(), registry.Reference{Registry: "harbor.example.com", Repository: "team/group/app", Tag: "1.0"}); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if projectCalls != 2 {
+		t.Fatalf("Expected the expired project to be fetched again but it was fetched %d times\n", projectCalls)
+	}
+
+	metadata, err := client.HarborMetadata(context.Background(), registry.Reference{Registry: "quay.io", Repository: "team/app", Tag: "1.0"})
+	if err != nil || metadata != nil {
+		t.Fatalf("Expected no metadata for other registries but got %v, %v\n", metadata, err)
+	}
+
+	if _, err = NewClient(server.Client(), keychain, []string{"harbor.example.com"}); err == nil {
+		t.Fatalf("Expected an error for an endpoint without url\n")
+	}
+}
@@ -0,0 +1,40 @@
+package heartbeat
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/atomicfile"
+)
+
+type HeartbeatConfig struct {
+	// HeartbeatFile is updated after every successful run, a liveness probe checks its age
+	HeartbeatFile string
+	// HeartbeatMaxAge is the age after which the heartbeat is considered stale
+	HeartbeatMaxAge time.Duration
+}
+
+// Write stores the time in the file, it is replaced atomically so a probe never reads a partial timestamp
+func Write(fileName string, now time.Time) error {
+	return atomicfile.WriteFile(fileName, []byte(now.UTC().Format(time.RFC3339)+"\n"))
+}
+
+// Check fails if the file is missing or the stored time is older than maxAge
+func Check(fileName string, maxAge time.Duration, now time.Time) error {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("could not read heartbeat: %w", err)
+	}
+
+	last, err := time.Parse(time.RFC3339, strings.TrimSpace(string(content)))
+	if err != nil {
+		return fmt.Errorf("invalid heartbeat in %s: %w", fileName, err)
+	}
+
+	if age := now.Sub(last); age > maxAge {
+		return fmt.Errorf("last heartbeat %s is older than %s", last.Format(time.RFC3339), maxAge)
+	}
+	return nil
+}
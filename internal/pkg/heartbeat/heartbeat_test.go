@@ -0,0 +1,26 @@
+package heartbeat
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeat(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "heartbeat")
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	assert.Error(t, Check(fileName, time.Minute, now))
+
+	err := Write(fileName, now)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	assert.NoError(t, Check(fileName, time.Minute, now.Add(time.Minute)))
+	assert.ErrorContains(t, Check(fileName, time.Minute, now.Add(2*time.Minute)), "older than 1m0s")
+
+	err = Write(fileName, now.Add(2*time.Minute))
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.NoError(t, Check(fileName, time.Minute, now.Add(2*time.Minute)))
+}
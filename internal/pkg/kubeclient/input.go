@@ -0,0 +1,36 @@
+package kubeclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// StdinInputFile reads the images from stdin
+const StdinInputFile = "-"
+
+// ReadImagesFromFile reads a JSON array of images, e.g. a dump of a previous run, instead of listing the pods of
+// the cluster. The field names match the Image struct case-insensitively, e.g. 'image', 'namespaceName'.
+func ReadImagesFromFile(fileName string) (*[]Image, error) {
+	if fileName == StdinInputFile {
+		return ReadImages(os.Stdin)
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadImages(file)
+}
+
+// ReadImages reads a JSON array of images
+func ReadImages(r io.Reader) (*[]Image, error) {
+	var images []Image
+	if err := json.NewDecoder(r).Decode(&images); err != nil {
+		return nil, fmt.Errorf("could not decode images: %w", err)
+	}
+	return &images, nil
+}
@@ -2,12 +2,21 @@ package kubeclient
 
 import (
 	"context"
+	"fmt"
 	"maps"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -18,13 +27,87 @@ type KubeConfig struct {
 	ConfigFile string
 	Context    string
 	MasterUrl  string
+
+	// InputFile reads the images from a file ('-' for stdin) instead of the cluster
+	InputFile string
+
+	// ProgressInterval logs the progress of listing the pods, 0 disables it
+	ProgressInterval time.Duration
+
+	// NodeOperatingSystem reads the operating system of pods which don't name one from the label of their node
+	NodeOperatingSystem bool
+
+	// TerminatedLookback only reports succeeded and failed pods created within this duration, 0 reports all
+	TerminatedLookback time.Duration
+
+	// SuspendedCronJobs marks the images of the Jobs of suspended CronJobs
+	SuspendedCronJobs bool
+
+	// ServiceExposure marks the images of pods exposed by a LoadBalancer Service or an Ingress
+	ServiceExposure bool
 }
 
 type Client struct {
 	Clientset kubernetes.Interface
+
+	// Observer is notified about the list calls to the API server, it is optional
+	Observer Observer
+
+	// ProgressInterval logs the progress of listing the pods, 0 disables it
+	ProgressInterval time.Duration
+
+	// NodeOperatingSystem lists the nodes to read the operating system of pods which don't name one
+	NodeOperatingSystem bool
+
+	// TerminatedLookback leaves out succeeded and failed pods created before this duration, 0 keeps all
+	TerminatedLookback time.Duration
+
+	// SuspendedCronJobs lists the CronJobs and Jobs to mark the images of suspended CronJobs
+	SuspendedCronJobs bool
+
+	// ServiceExposure lists the Services and Ingresses to mark the images of exposed pods
+	ServiceExposure bool
+
+	// OnNamespaceError skips namespaces whose pods can't be listed instead of failing, it is optional
+	OnNamespaceError func(namespace string, err error)
+}
+
+// Observer records the list calls to the API server, e.g. as metrics
+type Observer interface {
+	ObserveList(resource string, items int, duration time.Duration, err error)
 }
 
-func NewClient(cfg *KubeConfig) *Client {
+// observeList reports a list call which started at start
+func (c *Client) observeList(resource string, start time.Time, items int, err error) {
+	if c.Observer != nil {
+		c.Observer.ObserveList(resource, items, time.Since(start), err)
+	}
+}
+
+// NewClient creates a client from the kubeconfig or the in-cluster config
+func NewClient(cfg *KubeConfig) (*Client, error) {
+	config, err := RestConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		Clientset:           clientset,
+		ProgressInterval:    cfg.ProgressInterval,
+		NodeOperatingSystem: cfg.NodeOperatingSystem,
+		TerminatedLookback:  cfg.TerminatedLookback,
+		SuspendedCronJobs:   cfg.SuspendedCronJobs,
+		ServiceExposure:     cfg.ServiceExposure,
+	}, nil
+}
+
+// RestConfig returns the kubeconfig or the in-cluster config, e.g. to create clients of other API groups
+func RestConfig(cfg *KubeConfig) (*rest.Config, error) {
 	kubeconfig := cfg.ConfigFile
 
 	if kubeconfig == "" {
@@ -45,14 +128,32 @@ func NewClient(cfg *KubeConfig) *Client {
 		log.Info().Msg("Using kubeconfig")
 		config, err = buildConfigFromFlags(cfg.MasterUrl, kubeconfig, cfg.Context)
 	}
-	if err != nil {
-		log.Fatal().Stack().Err(err).Msg("Couldn't build config from flags")
-	}
+	return config, err
+}
 
-	client := &Client{Clientset: kubernetes.NewForConfigOrDie(config)}
+// serviceAccountNamespaceFile contains the namespace of the pod when running in a cluster
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
 
-	return client
+// PodNamespace returns the namespace if it is set and the namespace of the pod otherwise, which is read from
+// POD_NAMESPACE or the service account
+func PodNamespace(namespace string) (string, error) {
+	if namespace != "" {
+		return namespace, nil
+	}
+	if namespace = os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace, nil
+	}
+	content, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
 
+// Check verifies connectivity and permissions with a cheap namespace list
+func (c *Client) Check(ctx context.Context) error {
+	_, err := c.Clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{Limit: 1})
+	return err
 }
 
 // TODO: Move this into the NewClient function
@@ -77,11 +178,14 @@ type Namespace struct {
 	Annotations map[string]string
 }
 
-func (c *Client) GetNamespaces() (*[]Namespace, error) {
-	k8Namespaces, err := c.Clientset.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+func (c *Client) GetNamespaces(ctx context.Context) (*[]Namespace, error) {
+	start := time.Now()
+	k8Namespaces, err := c.Clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
+		c.observeList("namespaces", start, 0, err)
 		return nil, err
 	}
+	c.observeList("namespaces", start, len(k8Namespaces.Items), nil)
 	var namespaces []Namespace
 	for _, k8Namespace := range k8Namespaces.Items {
 		namespace := Namespace{
@@ -100,20 +204,321 @@ type Image struct {
 	NamespaceName string
 	Labels        map[string]string
 	Annotations   map[string]string
+
+	// PullSecrets are the names of the imagePullSecrets of the pod
+	PullSecrets []string `json:",omitempty"`
+
+	// PullError is the waiting reason of a container whose image can't be pulled, e.g. ImagePullBackOff
+	PullError string `json:",omitempty"`
+
+	// NamespaceLabels and NamespaceAnnotations are the metadata of the namespace alone, Labels and Annotations are
+	// merged with the metadata of the pod
+	NamespaceLabels      map[string]string `json:",omitempty"`
+	NamespaceAnnotations map[string]string `json:",omitempty"`
+
+	// OperatingSystem of the pod, e.g. windows, empty if it is unknown
+	OperatingSystem string `json:",omitempty"`
+
+	// Terminated is set for the images of succeeded and failed pods, e.g. of completed Jobs
+	Terminated bool `json:",omitempty"`
+
+	// Suspended is set for the images of Jobs whose CronJob is suspended, the workload is dormant
+	Suspended bool `json:",omitempty"`
+
+	// Replicas is the number of pods in the namespace running the image
+	Replicas int `json:",omitempty"`
+
+	// WorkloadKind and Workload name the workload owning the pod, e.g. Deployment and its name, see podWorkload
+	WorkloadKind string `json:",omitempty"`
+	Workload     string `json:",omitempty"`
+
+	// Container is the name of the container running the image in the pod
+	Container string `json:",omitempty"`
+
+	// Sidecar is set for the images of native sidecars, init containers with restartPolicy Always
+	Sidecar bool `json:",omitempty"`
+
+	// ContainerPorts are the ports declared by the container
+	ContainerPorts []int32 `json:",omitempty"`
+
+	// Exposure is ExposureLoadBalancer or ExposureIngress if a Service of this kind selects the pod, empty if it is
+	// not exposed or ServiceExposure is not set
+	Exposure string `json:",omitempty"`
+}
+
+const (
+	// ExposureLoadBalancer is the exposure of pods selected by a Service of type LoadBalancer
+	ExposureLoadBalancer = "LoadBalancer"
+	// ExposureIngress is the exposure of pods selected by a Service which is the backend of an Ingress
+	ExposureIngress = "Ingress"
+)
+
+// exposedService selects the pods of a Service exposed outside the cluster
+type exposedService struct {
+	selector labels.Selector
+	exposure string
+}
+
+// exposedServices returns the Services of the namespace exposed by their type or an Ingress, nil unless
+// ServiceExposure is set
+func (c *Client) exposedServices(ctx context.Context, namespace string) ([]exposedService, error) {
+	if !c.ServiceExposure {
+		return nil, nil
+	}
+	start := time.Now()
+	services, err := c.Clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		c.observeList("services", start, 0, err)
+		return nil, fmt.Errorf("failed to list Services of namespace %s: %w", namespace, err)
+	}
+	c.observeList("services", start, len(services.Items), nil)
+	if len(services.Items) == 0 {
+		return nil, nil
+	}
+
+	start = time.Now()
+	ingresses, err := c.Clientset.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		c.observeList("ingresses", start, 0, err)
+		return nil, fmt.Errorf("failed to list Ingresses of namespace %s: %w", namespace, err)
+	}
+	c.observeList("ingresses", start, len(ingresses.Items), nil)
+
+	backends := map[string]bool{}
+	addBackend := func(backend *networkingv1.IngressBackend) {
+		if backend != nil && backend.Service != nil {
+			backends[backend.Service.Name] = true
+		}
+	}
+	for _, ingress := range ingresses.Items {
+		addBackend(ingress.Spec.DefaultBackend)
+		for _, rule := range ingress.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				addBackend(&path.Backend)
+			}
+		}
+	}
+
+	var exposed []exposedService
+	for _, service := range services.Items {
+		// Services without selector don't select pods, their endpoints are managed otherwise
+		if len(service.Spec.Selector) == 0 {
+			continue
+		}
+		selector := labels.SelectorFromSet(service.Spec.Selector)
+		if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
+			exposed = append(exposed, exposedService{selector: selector, exposure: ExposureLoadBalancer})
+		} else if backends[service.Name] {
+			exposed = append(exposed, exposedService{selector: selector, exposure: ExposureIngress})
+		}
+	}
+	return exposed, nil
+}
+
+// podExposure returns the exposure of the pod with the given labels, a LoadBalancer takes precedence over an Ingress
+func podExposure(podLabels map[string]string, services []exposedService) string {
+	exposure := ""
+	for _, service := range services {
+		if !service.selector.Matches(labels.Set(podLabels)) {
+			continue
+		}
+		if service.exposure == ExposureLoadBalancer {
+			return ExposureLoadBalancer
+		}
+		exposure = service.exposure
+	}
+	return exposure
+}
+
+// suspendedJobs returns the names of the Jobs of the suspended CronJobs in the namespace, nil unless
+// SuspendedCronJobs is set
+func (c *Client) suspendedJobs(ctx context.Context, namespace string) (map[string]bool, error) {
+	if !c.SuspendedCronJobs {
+		return nil, nil
+	}
+	start := time.Now()
+	cronJobs, err := c.Clientset.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		c.observeList("cronjobs", start, 0, err)
+		return nil, fmt.Errorf("failed to list CronJobs of namespace %s: %w", namespace, err)
+	}
+	c.observeList("cronjobs", start, len(cronJobs.Items), nil)
+
+	suspended := map[string]bool{}
+	for _, cronJob := range cronJobs.Items {
+		if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+			suspended[cronJob.Name] = true
+		}
+	}
+	if len(suspended) == 0 {
+		return nil, nil
+	}
+
+	start = time.Now()
+	jobs, err := c.Clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		c.observeList("jobs", start, 0, err)
+		return nil, fmt.Errorf("failed to list Jobs of namespace %s: %w", namespace, err)
+	}
+	c.observeList("jobs", start, len(jobs.Items), nil)
+
+	names := map[string]bool{}
+	for _, job := range jobs.Items {
+		if owner := metav1.GetControllerOf(&job); owner != nil && owner.Kind == "CronJob" && suspended[owner.Name] {
+			names[job.Name] = true
+		}
+	}
+	return names, nil
+}
+
+// podJob returns the name of the Job which created the pod, empty for other pods
+func podJob(pod *corev1.Pod) string {
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "Job" {
+		return owner.Name
+	}
+	return ""
+}
+
+// cronJobSuffix is appended by the CronJob controller to the names of its Jobs, the scheduled time in minutes
+var cronJobSuffix = regexp.MustCompile(`-[0-9]{8,}$`)
+
+// podWorkload returns the kind and name of the workload owning the pod. The Deployment of a ReplicaSet and the
+// CronJob of a Job are derived from the names their controllers generate, so no further resources have to be
+// listed. Pods without controller are their own workload.
+func podWorkload(pod *corev1.Pod) (string, string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+	switch owner.Kind {
+	case "ReplicaSet":
+		if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+			return "Deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	case "Job":
+		if suffix := cronJobSuffix.FindString(owner.Name); suffix != "" {
+			return "CronJob", strings.TrimSuffix(owner.Name, suffix)
+		}
+	}
+	return owner.Kind, owner.Name
+}
+
+// ports returns the declared ports of the container
+func ports(container *corev1.Container) []int32 {
+	var ports []int32
+	for _, port := range container.Ports {
+		ports = append(ports, port.ContainerPort)
+	}
+	return ports
+}
+
+// isSidecar reports whether the init container is a native sidecar which runs as long as the pod
+func isSidecar(container *corev1.Container) bool {
+	return container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways
+}
+
+// isTerminated reports whether all containers of the pod terminated and won't be restarted
+func isTerminated(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// OperatingSystemWindows is the operating system of the pods running on Windows nodes
+const OperatingSystemWindows = "windows"
+
+// podOperatingSystem returns the operating system of the pod from its spec, its node selector or the label of its
+// node in this order
+func podOperatingSystem(pod *corev1.Pod, nodes map[string]string) string {
+	if pod.Spec.OS != nil && pod.Spec.OS.Name != "" {
+		return string(pod.Spec.OS.Name)
+	}
+	if selected := pod.Spec.NodeSelector[corev1.LabelOSStable]; selected != "" {
+		return selected
+	}
+	return nodes[pod.Spec.NodeName]
+}
+
+// nodeOperatingSystems returns the operating system of the nodes by name, nil unless NodeOperatingSystem is set
+func (c *Client) nodeOperatingSystems(ctx context.Context) (map[string]string, error) {
+	if !c.NodeOperatingSystem {
+		return nil, nil
+	}
+	start := time.Now()
+	nodes, err := c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		c.observeList("nodes", start, 0, err)
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	c.observeList("nodes", start, len(nodes.Items), nil)
+
+	operatingSystems := make(map[string]string, len(nodes.Items))
+	for _, node := range nodes.Items {
+		operatingSystems[node.Name] = node.Labels[corev1.LabelOSStable]
+	}
+	return operatingSystems, nil
+}
+
+// pullErrorReasons are the waiting reasons of containers whose image can't be pulled
+var pullErrorReasons = map[string]bool{"ErrImagePull": true, "ImagePullBackOff": true}
+
+// pullError returns the waiting reason if the image of the container can't be pulled
+func pullError(status corev1.ContainerStatus) string {
+	if waiting := status.State.Waiting; waiting != nil && pullErrorReasons[waiting.Reason] {
+		return waiting.Reason
+	}
+	return ""
 }
 
 // GetImages returns all images of all pods in the given namespaces
 // The Labels & Annotations of Pods and Namespaces are merged
-func (c *Client) GetImages(namespaces *[]Namespace) (*[]Image, error) {
+func (c *Client) GetImages(ctx context.Context, namespaces *[]Namespace) (*[]Image, error) {
 	var images []Image
+	progress := newProgress(c.ProgressInterval, len(*namespaces))
+	nodes, err := c.nodeOperatingSystems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var terminatedSince time.Time
+	if c.TerminatedLookback > 0 {
+		terminatedSince = time.Now().Add(-c.TerminatedLookback)
+	}
 
-	for _, namespace := range *namespaces {
-		pods, err := c.Clientset.CoreV1().Pods(namespace.Name).List(context.Background(), metav1.ListOptions{})
+	for i, namespace := range *namespaces {
+		namespaceImages := len(images)
+		start := time.Now()
+		pods, err := c.Clientset.CoreV1().Pods(namespace.Name).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			c.observeList("pods", start, 0, err)
+			if c.OnNamespaceError != nil {
+				log.Warn().Err(err).Str("namespace", namespace.Name).Msg("Skipping namespace whose pods can't be listed")
+				c.OnNamespaceError(namespace.Name, err)
+				progress.update(i+1, len(images))
+				continue
+			}
+			return nil, err
+		}
+		c.observeList("pods", start, len(pods.Items), nil)
+		suspendedJobs, err := c.suspendedJobs(ctx, namespace.Name)
+		if err != nil {
+			return nil, err
+		}
+		exposedServices, err := c.exposedServices(ctx, namespace.Name)
 		if err != nil {
 			return nil, err
 		}
 
+		// The pods of an image are counted once, even if several of their containers run it
+		replicas := map[string]int{}
 		for _, pod := range pods.Items {
+			terminated := isTerminated(&pod)
+			if terminated && pod.CreationTimestamp.Time.Before(terminatedSince) {
+				continue
+			}
+			podImages := len(images)
+			// The exposure is matched before the labels of the pod are merged with the ones of the namespace
+			exposure := podExposure(pod.Labels, exposedServices)
 
 			// Merge Pod and Namespace Labels & Annotations
 			labels := pod.GetLabels()
@@ -129,10 +534,32 @@ func (c *Client) GetImages(namespaces *[]Namespace) (*[]Image, error) {
 				maps.Copy(annotations, namespace.Annotations)
 			}
 
+			var pullSecrets []string
+			for _, secret := range pod.Spec.ImagePullSecrets {
+				pullSecrets = append(pullSecrets, secret.Name)
+			}
+
+			// The fields shared by the images of all containers of the pod
+			podImage := Image{
+				Exposure:             exposure,
+				NamespaceName:        namespace.Name,
+				Labels:               labels,
+				Annotations:          annotations,
+				PullSecrets:          pullSecrets,
+				NamespaceLabels:      namespace.Labels,
+				NamespaceAnnotations: namespace.Annotations,
+				OperatingSystem:      podOperatingSystem(&pod, nodes),
+				Terminated:           terminated,
+				Suspended:            suspendedJobs[podJob(&pod)],
+			}
+			podImage.WorkloadKind, podImage.Workload = podWorkload(&pod)
+
 			// Get all container images
 			containerImageMap := map[string]string{}
+			containerPorts := map[string][]int32{}
 			for _, container := range pod.Spec.Containers {
 				containerImageMap[container.Name] = container.Image
+				containerPorts[container.Name] = ports(&container)
 			}
 
 			// Create images for all containers with status
@@ -150,44 +577,135 @@ func (c *Client) GetImages(namespaces *[]Namespace) (*[]Image, error) {
 					imageName = containerImage
 				}
 
-				image := Image{
-					Image:         imageName,
-					ImageId:       status.ImageID,
-					NamespaceName: namespace.Name,
-					Labels:        labels,
-					Annotations:   annotations,
-				}
+				image := podImage
+				image.Image = imageName
+				image.Container = status.Name
+				image.ImageId = status.ImageID
+				image.PullError = pullError(status)
+				image.ContainerPorts = containerPorts[status.Name]
 				images = append(images, image)
 			}
 
 			// Add all remaining container images for which no status exists
-			for _, imageName := range containerImageMap {
+			for name, imageName := range containerImageMap {
+				image := podImage
+				image.Image = imageName
+				image.Container = name
+				image.ContainerPorts = containerPorts[name]
+				images = append(images, image)
+			}
 
-				image := Image{
-					Image:         imageName,
-					NamespaceName: namespace.Name,
-					Labels:        labels,
-					Annotations:   annotations,
+			// Native sidecars are init containers which keep running next to the containers, the other init
+			// containers are not collected
+			initStatuses := map[string]corev1.ContainerStatus{}
+			for _, status := range pod.Status.InitContainerStatuses {
+				initStatuses[status.Name] = status
+			}
+			for _, container := range pod.Spec.InitContainers {
+				if !isSidecar(&container) || container.Image == "" {
+					continue
 				}
+				status := initStatuses[container.Name]
+				image := podImage
+				image.Image = container.Image
+				image.Container = container.Name
+				image.ImageId = status.ImageID
+				image.PullError = pullError(status)
+				image.ContainerPorts = ports(&container)
+				image.Sidecar = true
 				images = append(images, image)
 			}
+
+			counted := map[string]bool{}
+			for _, image := range images[podImages:] {
+				if !counted[image.Image] {
+					counted[image.Image] = true
+					replicas[image.Image]++
+				}
+			}
+		}
+		for j := namespaceImages; j < len(images); j++ {
+			images[j].Replicas = replicas[images[j].Image]
 		}
+		log.Info().
+			Str("namespace", namespace.Name).
+			Int("pods", len(pods.Items)).
+			Int("images", len(images)-namespaceImages).
+			Msg("Collected images of namespace")
+		progress.update(i+1, len(images))
 	}
 
 	return &images, nil
 }
 
-func (c *Client) GetAllImagesForAllNamespaces() (*[]Image, error) {
-	namespaces, err := c.GetNamespaces()
+func (c *Client) GetAllImagesForAllNamespaces(ctx context.Context) (*[]Image, error) {
+	namespaces, err := c.GetNamespaces(ctx)
 	if err != nil {
-		log.Fatal().Stack().Err(err).Msg("failed to get namespaces")
-		return nil, err
+		return nil, fmt.Errorf("failed to get namespaces: %w", err)
 	}
-	k8Images, err := c.GetImages(namespaces)
+	k8Images, err := c.GetImages(ctx, namespaces)
 	if err != nil {
-		log.Fatal().Stack().Err(err).Msg("failed to get images")
-		return nil, err
+		return nil, fmt.Errorf("failed to get images: %w", err)
 	}
 
 	return k8Images, nil
 }
+
+// PullSecret is the docker config of an imagePullSecret
+type PullSecret struct {
+	Namespace string
+	Name      string
+	Type      corev1.SecretType
+	Data      []byte
+}
+
+// GetPullSecrets returns the docker configs of the imagePullSecrets of the images, secrets which are missing or not
+// readable are skipped with a warning
+func (c *Client) GetPullSecrets(ctx context.Context, images *[]Image) ([]PullSecret, error) {
+	var pullSecrets []PullSecret
+	seen := map[string]bool{}
+
+	for _, image := range *images {
+		for _, name := range image.PullSecrets {
+			key := image.NamespaceName + "/" + name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			secret, err := c.Clientset.CoreV1().Secrets(image.NamespaceName).Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+				log.Warn().Err(err).Str("namespace", image.NamespaceName).Str("secret", name).Msg("Skipping imagePullSecret")
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to get imagePullSecret %s: %w", key, err)
+			}
+
+			var data []byte
+			switch secret.Type {
+			case corev1.SecretTypeDockerConfigJson:
+				data = secret.Data[corev1.DockerConfigJsonKey]
+			case corev1.SecretTypeDockercfg:
+				data = secret.Data[corev1.DockerConfigKey]
+			default:
+				log.Warn().Str("namespace", image.NamespaceName).Str("secret", name).Str("type", string(secret.Type)).Msg("Skipping imagePullSecret of unsupported type")
+				continue
+			}
+			pullSecrets = append(pullSecrets, PullSecret{Namespace: image.NamespaceName, Name: name, Type: secret.Type, Data: data})
+		}
+	}
+	return pullSecrets, nil
+}
+
+// GetSecretValue returns the value of a key of a Secret, e.g. a credential referenced by --api-key-secret-ref
+func (c *Client) GetSecretValue(ctx context.Context, namespace, name, key string) (string, error) {
+	secret, err := c.Clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s/%s: %w", namespace, name, err)
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no key %s", namespace, name, key)
+	}
+	return string(value), nil
+}
@@ -1,10 +1,22 @@
 package kubeclient
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 	"sort"
 	"strings"
 	"testing"
@@ -124,7 +136,7 @@ func TestGetNamespaces(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			client.Clientset = testclient.NewSimpleClientset(tc.namespaces...)
-			namespaces, err := client.GetNamespaces()
+			namespaces, err := client.GetNamespaces(context.Background())
 
 			if tc.expectSuccess && err != nil {
 				t.Fatalf("Got an error=%v\n", err)
@@ -477,7 +489,7 @@ func TestGetImages(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			client.Clientset = testclient.NewSimpleClientset(tc.pods...)
-			images, err := client.GetImages(&tc.targetNamespaces)
+			images, err := client.GetImages(context.Background(), &tc.targetNamespaces)
 
 			sort.Slice(*images, func(i, j int) bool {
 				return strings.ToLower((*images)[i].Image) < strings.ToLower((*images)[j].Image)
@@ -826,7 +838,7 @@ func TestGetAllImages(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			client.Clientset = testclient.NewSimpleClientset(tc.pods...)
-			images, err := client.GetAllImagesForAllNamespaces()
+			images, err := client.GetAllImagesForAllNamespaces(context.Background())
 
 			sort.Slice(*images, func(i, j int) bool {
 				return strings.ToLower((*images)[i].Image) < strings.ToLower((*images)[j].Image)
@@ -881,3 +893,465 @@ func TestGetAllImages(t *testing.T) {
 		})
 	}
 }
+
+func TestCheck(t *testing.T) {
+	client := Client{Clientset: testclient.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test_ns_1"}})}
+
+	if err := client.Check(context.Background()); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+}
+
+func TestGetPullSecrets(t *testing.T) {
+	client := Client{Clientset: testclient.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: "ns"},
+			Type:       corev1.SecretTypeOpaque,
+		},
+	)}
+
+	images := []Image{
+		{Image: "a", NamespaceName: "ns", PullSecrets: []string{"registry", "missing", "opaque"}},
+		{Image: "b", NamespaceName: "ns", PullSecrets: []string{"registry"}},
+	}
+	pullSecrets, err := client.GetPullSecrets(context.Background(), &images)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if len(pullSecrets) != 1 || pullSecrets[0].Name != "registry" || string(pullSecrets[0].Data) != `{"auths":{}}` {
+		t.Fatalf("Expected only the docker config secret once but got %v\n", pullSecrets)
+	}
+}
+
+func TestGetImagesPullError(t *testing.T) {
+	client := Client{Clientset: testclient.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:deleted"}}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "app",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+		}}},
+	})}
+
+	images, err := client.GetImages(context.Background(), &[]Namespace{{Name: "ns"}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if len(*images) != 1 || (*images)[0].PullError != "ImagePullBackOff" {
+		t.Fatalf("Expected the pull error of the image but got %v\n", *images)
+	}
+}
+
+func TestGetImagesOperatingSystem(t *testing.T) {
+	pod := func(name string, spec corev1.PodSpec) *corev1.Pod {
+		spec.Containers = []corev1.Container{{Name: "app", Image: name + ":1.0"}}
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}, Spec: spec}
+	}
+	clientset := testclient.NewSimpleClientset(
+		pod("spec", corev1.PodSpec{OS: &corev1.PodOS{Name: corev1.Windows}, NodeSelector: map[string]string{corev1.LabelOSStable: "linux"}}),
+		pod("selector", corev1.PodSpec{NodeSelector: map[string]string{corev1.LabelOSStable: "windows"}}),
+		pod("node", corev1.PodSpec{NodeName: "win-1"}),
+		pod("unscheduled", corev1.PodSpec{}),
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "win-1", Labels: map[string]string{corev1.LabelOSStable: "windows"}}},
+	)
+	expected := map[string]string{"spec:1.0": "windows", "selector:1.0": "windows", "node:1.0": "windows", "unscheduled:1.0": ""}
+
+	client := Client{Clientset: clientset, NodeOperatingSystem: true}
+	images, err := client.GetImages(context.Background(), &[]Namespace{{Name: "ns"}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	for _, image := range *images {
+		if image.OperatingSystem != expected[image.Image] {
+			t.Fatalf("Expected operating system %s of %s but got %s\n", expected[image.Image], image.Image, image.OperatingSystem)
+		}
+	}
+
+	// The nodes are not listed without NodeOperatingSystem
+	client.NodeOperatingSystem = false
+	images, err = client.GetImages(context.Background(), &[]Namespace{{Name: "ns"}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	for _, image := range *images {
+		if image.Image == "node:1.0" && image.OperatingSystem != "" {
+			t.Fatalf("Expected no operating system of the node but got %s\n", image.OperatingSystem)
+		}
+	}
+}
+
+func TestGetImagesTerminatedLookback(t *testing.T) {
+	pod := func(name string, phase corev1.PodPhase, age time.Duration) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", CreationTimestamp: metav1.NewTime(time.Now().Add(-age))},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: name + ":1.0"}}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	clientset := testclient.NewSimpleClientset(
+		pod("running", corev1.PodRunning, 48*time.Hour),
+		pod("nightly", corev1.PodSucceeded, 2*time.Hour),
+		pod("failed", corev1.PodFailed, 3*time.Hour),
+		pod("old", corev1.PodSucceeded, 30*time.Hour),
+	)
+
+	client := Client{Clientset: clientset, TerminatedLookback: 24 * time.Hour}
+	images, err := client.GetImages(context.Background(), &[]Namespace{{Name: "ns"}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	terminated := map[string]bool{}
+	for _, image := range *images {
+		terminated[image.Image] = image.Terminated
+	}
+	expected := map[string]bool{"running:1.0": false, "nightly:1.0": true, "failed:1.0": true}
+	if len(terminated) != len(expected) {
+		t.Fatalf("Expected the images %v but got %v\n", expected, terminated)
+	}
+	for image, isTerminated := range expected {
+		if terminated[image] != isTerminated {
+			t.Fatalf("Expected the images %v but got %v\n", expected, terminated)
+		}
+	}
+
+	client.TerminatedLookback = 0
+	images, err = client.GetImages(context.Background(), &[]Namespace{{Name: "ns"}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if len(*images) != 4 {
+		t.Fatalf("Expected all images without lookback but got %v\n", *images)
+	}
+}
+
+func TestGetImagesSuspendedCronJobs(t *testing.T) {
+	suspend := true
+	jobPod := func(name, job string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: job, Controller: &suspend}}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: name + ":1.0"}}},
+		}
+	}
+	job := func(name, cronJob string) *batchv1.Job {
+		return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: cronJob, Controller: &suspend}}}}
+	}
+	clientset := testclient.NewSimpleClientset(
+		jobPod("dormant", "dormant-28000000"),
+		jobPod("nightly", "nightly-28000000"),
+		jobPod("oneshot", "oneshot"),
+		job("dormant-28000000", "dormant"),
+		job("nightly-28000000", "nightly"),
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "oneshot", Namespace: "ns"}},
+		&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "dormant", Namespace: "ns"}, Spec: batchv1.CronJobSpec{Suspend: &suspend}},
+		&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "ns"}},
+	)
+
+	client := Client{Clientset: clientset, SuspendedCronJobs: true}
+	images, err := client.GetImages(context.Background(), &[]Namespace{{Name: "ns"}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if len(*images) != 3 {
+		t.Fatalf("Expected 3 images but got %v\n", *images)
+	}
+	for _, image := range *images {
+		if image.Suspended != (image.Image == "dormant:1.0") {
+			t.Fatalf("Expected only the image of the suspended CronJob to be suspended but got %v\n", image)
+		}
+	}
+
+	client.SuspendedCronJobs = false
+	images, err = client.GetImages(context.Background(), &[]Namespace{{Name: "ns"}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	for _, image := range *images {
+		if image.Suspended {
+			t.Fatalf("Expected no suspended images without SuspendedCronJobs but got %v\n", image)
+		}
+	}
+}
+
+func TestGetImagesReplicas(t *testing.T) {
+	pod := func(name string, images ...string) *corev1.Pod {
+		var containers []corev1.Container
+		for i, image := range images {
+			containers = append(containers, corev1.Container{Name: fmt.Sprintf("c%d", i), Image: image})
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+			Spec:       corev1.PodSpec{Containers: containers},
+		}
+	}
+	clientset := testclient.NewSimpleClientset(
+		pod("web-1", "web:1.0"),
+		pod("web-2", "web:1.0"),
+		pod("web-3", "web:1.0", "web:1.0", "proxy:1.0"),
+		pod("debug", "debug:1.0"),
+	)
+
+	client := Client{Clientset: clientset}
+	images, err := client.GetImages(context.Background(), &[]Namespace{{Name: "ns"}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	expected := map[string]int{"web:1.0": 3, "proxy:1.0": 1, "debug:1.0": 1}
+	for _, image := range *images {
+		if image.Replicas != expected[image.Image] {
+			t.Fatalf("Expected %d replicas of %s but got %d\n", expected[image.Image], image.Image, image.Replicas)
+		}
+	}
+}
+
+func TestGetImagesWorkload(t *testing.T) {
+	controller := true
+	pod := func(name, ownerKind, owner string, labels map[string]string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: labels},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: name + ":1.0"}}},
+		}
+		if owner != "" {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: owner, Controller: &controller}}
+		}
+		return pod
+	}
+	clientset := testclient.NewSimpleClientset(
+		pod("web", "ReplicaSet", "web-5d8f7c9b6", map[string]string{"pod-template-hash": "5d8f7c9b6"}),
+		pod("legacy", "ReplicaSet", "legacy", nil),
+		pod("db", "StatefulSet", "db", nil),
+		pod("backup", "Job", "backup-28000000", nil),
+		pod("migrate", "Job", "migrate", nil),
+		pod("debug", "", "", nil),
+	)
+
+	client := Client{Clientset: clientset}
+	images, err := client.GetImages(context.Background(), &[]Namespace{{Name: "ns"}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	workloads := map[string]string{}
+	for _, image := range *images {
+		workloads[image.Image] = image.WorkloadKind + "/" + image.Workload
+	}
+	expected := map[string]string{
+		"web:1.0":     "Deployment/web",
+		"legacy:1.0":  "ReplicaSet/legacy",
+		"db:1.0":      "StatefulSet/db",
+		"backup:1.0":  "CronJob/backup",
+		"migrate:1.0": "Job/migrate",
+		"debug:1.0":   "Pod/debug",
+	}
+	for image, workload := range expected {
+		if workloads[image] != workload {
+			t.Fatalf("Expected the workloads %v but got %v\n", expected, workloads)
+		}
+	}
+}
+
+func TestGetImagesNativeSidecars(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	clientset := testclient.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: "migrate", Image: "migrate:1.0"},
+				{Name: "envoy", Image: "envoy:1.0", RestartPolicy: &always},
+			},
+			Containers: []corev1.Container{{Name: "app", Image: "app:1.0"}},
+		},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{{Name: "envoy", Image: "envoy:1.0", ImageID: "envoy@sha256:1234"}},
+		},
+	})
+
+	client := Client{Clientset: clientset}
+	images, err := client.GetImages(context.Background(), &[]Namespace{{Name: "ns"}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if len(*images) != 2 {
+		t.Fatalf("Expected the images of the container and the native sidecar but got %v\n", *images)
+	}
+	sidecar := (*images)[1]
+	if sidecar.Image != "envoy:1.0" || !sidecar.Sidecar || sidecar.ImageId != "envoy@sha256:1234" || sidecar.Container != "envoy" {
+		t.Fatalf("Expected the native sidecar envoy:1.0 but got %v\n", sidecar)
+	}
+	if (*images)[0].Sidecar || (*images)[0].Container != "app" {
+		t.Fatalf("Expected the container app not to be a sidecar but got %v\n", (*images)[0])
+	}
+}
+
+func TestGetImagesServiceExposure(t *testing.T) {
+	pod := func(name string, port int32) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: map[string]string{"app": name}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: name + ":1.0", Ports: []corev1.ContainerPort{{ContainerPort: port}}}}},
+		}
+	}
+	service := func(name string, serviceType corev1.ServiceType) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+			Spec:       corev1.ServiceSpec{Type: serviceType, Selector: map[string]string{"app": name}},
+		}
+	}
+	clientset := testclient.NewSimpleClientset(
+		pod("gateway", 8443),
+		pod("web", 8080),
+		pod("db", 5432),
+		service("gateway", corev1.ServiceTypeLoadBalancer),
+		service("web", corev1.ServiceTypeClusterIP),
+		service("db", corev1.ServiceTypeClusterIP),
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"},
+			Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{{Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "web"}}}},
+			}}}}},
+		},
+	)
+
+	client := Client{Clientset: clientset, ServiceExposure: true}
+	images, err := client.GetImages(context.Background(), &[]Namespace{{Name: "ns", Labels: map[string]string{"team": "a"}}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	exposures := map[string]string{}
+	for _, image := range *images {
+		exposures[image.Image] = image.Exposure
+		if len(image.ContainerPorts) != 1 {
+			t.Fatalf("Expected the container port of %s but got %v\n", image.Image, image.ContainerPorts)
+		}
+	}
+	expected := map[string]string{"gateway:1.0": ExposureLoadBalancer, "web:1.0": ExposureIngress, "db:1.0": ""}
+	for image, exposure := range expected {
+		if exposures[image] != exposure {
+			t.Fatalf("Expected the exposures %v but got %v\n", expected, exposures)
+		}
+	}
+
+	client.ServiceExposure = false
+	images, err = client.GetImages(context.Background(), &[]Namespace{{Name: "ns"}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	for _, image := range *images {
+		if image.Exposure != "" {
+			t.Fatalf("Expected no exposure without ServiceExposure but got %v\n", image)
+		}
+	}
+}
+
+func TestGetImagesSkipsFailedNamespaces(t *testing.T) {
+	clientset := testclient.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ok"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:1.0"}}},
+	})
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "broken" {
+			return true, nil, errors.New("forbidden")
+		}
+		return false, nil, nil
+	})
+	namespaces := []Namespace{{Name: "broken"}, {Name: "ok"}}
+
+	client := Client{Clientset: clientset}
+	if _, err := client.GetImages(context.Background(), &namespaces); err == nil {
+		t.Fatalf("Expected an error without OnNamespaceError\n")
+	}
+
+	var failed []string
+	client.OnNamespaceError = func(namespace string, err error) { failed = append(failed, namespace) }
+	images, err := client.GetImages(context.Background(), &namespaces)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if len(*images) != 1 || len(failed) != 1 || failed[0] != "broken" {
+		t.Fatalf("Expected the images of the other namespaces and the failed namespace but got %v and %v\n", *images, failed)
+	}
+}
+
+func TestGetSecretValue(t *testing.T) {
+	client := Client{Clientset: testclient.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "collector", Namespace: "ns"},
+		Data:       map[string][]byte{"api-key": []byte("secret")},
+	})}
+
+	value, err := client.GetSecretValue(context.Background(), "ns", "collector", "api-key")
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if value != "secret" {
+		t.Fatalf("Expected the value of the key but got %s\n", value)
+	}
+
+	if _, err := client.GetSecretValue(context.Background(), "ns", "collector", "missing"); err == nil {
+		t.Fatalf("Expected an error for a missing key\n")
+	}
+	if _, err := client.GetSecretValue(context.Background(), "ns", "missing", "api-key"); err == nil {
+		t.Fatalf("Expected an error for a missing secret\n")
+	}
+}
+
+func TestReadImages(t *testing.T) {
+	images, err := ReadImages(strings.NewReader(`[{"image":"nginx:1","imageId":"sha256:1","namespaceName":"ns","annotations":{"contact.sdase.org/team":"a"}}]`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []Image{{Image: "nginx:1", ImageId: "sha256:1", NamespaceName: "ns", Annotations: map[string]string{"contact.sdase.org/team": "a"}}}
+	if len(*images) != 1 || (*images)[0].Image != expected[0].Image || (*images)[0].NamespaceName != expected[0].NamespaceName || (*images)[0].Annotations["contact.sdase.org/team"] != "a" {
+		t.Fatalf("Expected %v, got %v", expected, *images)
+	}
+
+	if _, err = ReadImages(strings.NewReader(`{"image":"nginx"}`)); err == nil {
+		t.Fatalf("Expected an error for a JSON object")
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.Logger
+	log.Logger = zerolog.New(&buf)
+	defer func() { log.Logger = logger }()
+
+	start := time.Unix(0, 0)
+	now := start
+	p := &progress{interval: time.Minute, namespaces: 4, start: start, last: start, now: func() time.Time { return now }}
+
+	now = start.Add(30 * time.Second)
+	p.update(1, 10)
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no progress before the interval, got %s", buf.String())
+	}
+
+	now = start.Add(time.Minute)
+	p.update(2, 25)
+	expected := `"processedNamespaces":2,"namespaces":4,"images":25,"elapsed":60000,"eta":60000,"message":"Processed 2/4 namespaces, 25 images so far"`
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("Expected %s, got %s", expected, buf.String())
+	}
+
+	buf.Reset()
+	now = start.Add(3 * time.Minute)
+	p.update(4, 50)
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no progress once all namespaces are processed, got %s", buf.String())
+	}
+}
+
+func TestPodNamespace(t *testing.T) {
+	namespace, err := PodNamespace("configured")
+	if err != nil || namespace != "configured" {
+		t.Fatalf("Expected the configured namespace but got %s, %v\n", namespace, err)
+	}
+
+	t.Setenv("POD_NAMESPACE", "from-env")
+	namespace, err = PodNamespace("")
+	if err != nil || namespace != "from-env" {
+		t.Fatalf("Expected the namespace of the environment but got %s, %v\n", namespace, err)
+	}
+}
@@ -0,0 +1,46 @@
+package kubeclient
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// progress logs how many namespaces were processed at most once per interval, so long runs on large clusters do not
+// look stuck
+type progress struct {
+	interval   time.Duration
+	namespaces int
+	start      time.Time
+	last       time.Time
+
+	now func() time.Time
+}
+
+func newProgress(interval time.Duration, namespaces int) *progress {
+	now := time.Now()
+	return &progress{interval: interval, namespaces: namespaces, start: now, last: now, now: time.Now}
+}
+
+// update is called after each processed namespace, the ETA assumes the remaining namespaces take as long as the
+// processed ones on average
+func (p *progress) update(processed, images int) {
+	if p.interval <= 0 || processed >= p.namespaces {
+		return
+	}
+	now := p.now()
+	if now.Sub(p.last) < p.interval {
+		return
+	}
+	p.last = now
+
+	elapsed := now.Sub(p.start)
+	eta := time.Duration(float64(elapsed) / float64(processed) * float64(p.namespaces-processed))
+	log.Info().
+		Int("processedNamespaces", processed).
+		Int("namespaces", p.namespaces).
+		Int("images", images).
+		Dur("elapsed", elapsed.Round(time.Second)).
+		Dur("eta", eta.Round(time.Second)).
+		Msgf("Processed %d/%d namespaces, %d images so far", processed, p.namespaces, images)
+}
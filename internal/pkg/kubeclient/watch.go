@@ -0,0 +1,97 @@
+package kubeclient
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// GetNamespacesByName returns the given namespaces, namespaces which don't exist anymore are left out
+func (c *Client) GetNamespacesByName(ctx context.Context, names []string) (*[]Namespace, error) {
+	var namespaces []Namespace
+	for _, name := range names {
+		k8Namespace, err := c.Clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			log.Debug().Str("namespace", name).Msg("Namespace was deleted")
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get namespace %s: %w", name, err)
+		}
+		namespaces = append(namespaces, Namespace{
+			Name:        k8Namespace.GetName(),
+			Labels:      k8Namespace.GetLabels(),
+			Annotations: k8Namespace.GetAnnotations(),
+		})
+	}
+	return &namespaces, nil
+}
+
+// WatchPods calls onChange with the namespace of every pod which is added or deleted or whose images, labels or
+// annotations changed, until the context is done. Changes of the namespaces themselves are not watched. The pods existing at the start are reported as added once the cache is synced.
+func (c *Client) WatchPods(ctx context.Context, onChange func(namespace string)) error {
+	factory := informers.NewSharedInformerFactory(c.Clientset, 0)
+	informer := factory.Core().V1().Pods().Informer()
+
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				onChange(pod.Namespace)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, oldOk := oldObj.(*corev1.Pod)
+			newPod, newOk := newObj.(*corev1.Pod)
+			if oldOk && newOk && podChanged(oldPod, newPod) {
+				onChange(newPod.Namespace)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pod, ok := obj.(*corev1.Pod); ok {
+				onChange(pod.Namespace)
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch pods: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return ctx.Err()
+	}
+	log.Info().Msg("Watching pods")
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// podChanged reports whether the images, labels or annotations of the pod changed
+func podChanged(oldPod, newPod *corev1.Pod) bool {
+	return !slices.Equal(podImages(oldPod), podImages(newPod)) ||
+		!maps.Equal(oldPod.Labels, newPod.Labels) ||
+		!maps.Equal(oldPod.Annotations, newPod.Annotations)
+}
+
+// podImages lists what a report of the pod depends on, status updates like probes don't change it
+func podImages(pod *corev1.Pod) []string {
+	var images []string
+	for _, container := range pod.Spec.Containers {
+		images = append(images, container.Name+"="+container.Image)
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		images = append(images, status.Name+"="+status.ImageID+"="+pullError(status))
+	}
+	return images
+}
@@ -0,0 +1,70 @@
+package kubeclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWatchPods(t *testing.T) {
+	clientset := testclient.NewSimpleClientset(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "a"}})
+	client := Client{Clientset: clientset}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan string, 10)
+	go func() {
+		_ = client.WatchPods(ctx, func(namespace string) { changes <- namespace })
+	}()
+
+	expectChange := func(expected string) {
+		t.Helper()
+		select {
+		case namespace := <-changes:
+			if namespace != expected {
+				t.Fatalf("Expected a change of namespace %s, got %s\n", expected, namespace)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected a change of namespace %s\n", expected)
+		}
+	}
+	expectChange("a")
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "b"}, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:1.0"}}}}
+	if _, err := clientset.CoreV1().Pods("b").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	expectChange("b")
+
+	// Changes which don't affect the report are ignored
+	pod.Status.Phase = corev1.PodRunning
+	if _, err := clientset.CoreV1().Pods("b").Update(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	pod.Spec.Containers[0].Image = "app:1.1"
+	if _, err := clientset.CoreV1().Pods("b").Update(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	expectChange("b")
+	select {
+	case namespace := <-changes:
+		t.Fatalf("Expected no further change, got %s\n", namespace)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestGetNamespacesByName(t *testing.T) {
+	client := Client{Clientset: testclient.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{"team": "x"}}})}
+
+	namespaces, err := client.GetNamespacesByName(context.Background(), []string{"a", "deleted"})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if len(*namespaces) != 1 || (*namespaces)[0].Name != "a" || (*namespaces)[0].Labels["team"] != "x" {
+		t.Fatalf("Expected only namespace a, got %v\n", *namespaces)
+	}
+}
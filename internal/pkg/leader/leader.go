@@ -0,0 +1,166 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/rs/zerolog/log"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+type LeaderElectionConfig struct {
+	LeaderElect              bool
+	LeaderElectLeaseName     string
+	LeaderElectNamespace     string
+	LeaderElectLeaseDuration time.Duration
+	LeaderElectRenewDeadline time.Duration
+	LeaderElectRetryPeriod   time.Duration
+}
+
+// Run campaigns for the lease and calls lead while this replica holds it. If the leadership is lost, the context of
+// lead is cancelled and the replica campaigns again once lead returned. Run returns the result of lead once it
+// returned on its own, or when ctx is done or stopping is closed while not leading.
+func Run(ctx context.Context, clientset kubernetes.Interface, cfg *LeaderElectionConfig, stopping <-chan struct{}, onLeading func(bool), lead func(ctx context.Context) error) error {
+	namespace, err := kubeclient.PodNamespace(cfg.LeaderElectNamespace)
+	if err != nil {
+		return fmt.Errorf("could not determine the namespace of the lease, set --leader-elect-namespace: %w", err)
+	}
+
+	identity, err := identity()
+	if err != nil {
+		return fmt.Errorf("could not determine leader election identity: %w", err)
+	}
+
+	return elect(ctx, clientset, election{
+		name:          cfg.LeaderElectLeaseName,
+		namespace:     namespace,
+		identity:      identity,
+		leaseDuration: cfg.LeaderElectLeaseDuration,
+		renewDeadline: cfg.LeaderElectRenewDeadline,
+		retryPeriod:   cfg.LeaderElectRetryPeriod,
+	}, stopping, onLeading, lead)
+}
+
+type election struct {
+	name          string
+	namespace     string
+	identity      string
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+}
+
+func elect(ctx context.Context, clientset kubernetes.Interface, e election, stopping <-chan struct{}, onLeading func(bool), lead func(ctx context.Context) error) error {
+	identity, namespace := e.identity, e.namespace
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: e.name, Namespace: namespace},
+		Client:     clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	type result struct {
+		err      error
+		finished bool
+	}
+
+	for {
+		electionCtx, cancel := context.WithCancel(ctx)
+		results := make(chan result, 1)
+
+		// started is checked under the lock, the elector starts the callback asynchronously and cancels its context
+		// before Run returns, so either the callback or Run sees the election as over
+		var mu sync.Mutex
+		started := false
+		isStarted := func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return started
+		}
+
+		elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			LeaseDuration:   e.leaseDuration,
+			RenewDeadline:   e.renewDeadline,
+			RetryPeriod:     e.retryPeriod,
+			ReleaseOnCancel: true,
+			Name:            e.name,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(leaderCtx context.Context) {
+					mu.Lock()
+					if leaderCtx.Err() != nil {
+						mu.Unlock()
+						return
+					}
+					started = true
+					mu.Unlock()
+
+					log.Info().Str("identity", identity).Str("lease", namespace+"/"+e.name).Msg("Acquired lease")
+					onLeading(true)
+
+					err := lead(leaderCtx)
+					// lead returned on its own, e.g. after a termination signal, release the lease
+					finished := leaderCtx.Err() == nil
+					cancel()
+					results <- result{err: err, finished: finished}
+				},
+				OnStoppedLeading: func() {
+					onLeading(false)
+				},
+				OnNewLeader: func(current string) {
+					if current != identity {
+						log.Info().Str("holder", current).Str("lease", namespace+"/"+e.name).Msg("Waiting for lease held by another replica")
+					}
+				},
+			},
+		})
+		if err != nil {
+			cancel()
+			return err
+		}
+
+		// A replica waiting for the lease has nothing to finish, it stops right away
+		go func() {
+			select {
+			case <-stopping:
+				if !isStarted() {
+					cancel()
+				}
+			case <-electionCtx.Done():
+			}
+		}()
+
+		elector.Run(electionCtx)
+		cancel()
+
+		if !isStarted() {
+			// Stopped or cancelled before the lease was acquired
+			return ctx.Err()
+		}
+
+		r := <-results
+		if r.finished || ctx.Err() != nil {
+			return r.err
+		}
+		if r.err != nil && !errors.Is(r.err, context.Canceled) {
+			log.Error().Err(r.err).Msg("Run failed after the lease was lost")
+		}
+		log.Warn().Str("identity", identity).Str("lease", namespace+"/"+e.name).Msg("Lost lease, campaigning again")
+	}
+}
+
+// identity is the pod name, which equals the hostname unless the pod sets POD_NAME
+func identity() (string, error) {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name, nil
+	}
+	return os.Hostname()
+}
@@ -0,0 +1,71 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func testConfig() *LeaderElectionConfig {
+	return &LeaderElectionConfig{
+		LeaderElect:              true,
+		LeaderElectLeaseName:     "collector",
+		LeaderElectNamespace:     "default",
+		LeaderElectLeaseDuration: 2 * time.Second,
+		LeaderElectRenewDeadline: time.Second,
+		LeaderElectRetryPeriod:   100 * time.Millisecond,
+	}
+}
+
+func TestRunLeads(t *testing.T) {
+	t.Setenv("POD_NAME", "replica-a")
+	clientset := testclient.NewSimpleClientset()
+
+	var leading []bool
+	errDone := errors.New("done")
+	err := Run(context.Background(), clientset, testConfig(), nil, func(leader bool) { leading = append(leading, leader) }, func(ctx context.Context) error {
+		lease, err := clientset.CoordinationV1().Leases("default").Get(ctx, "collector", metav1.GetOptions{})
+		assert.NoError(t, err, "Expected no error, got %v", err)
+		assert.Equal(t, "replica-a", *lease.Spec.HolderIdentity)
+		return errDone
+	})
+
+	assert.ErrorIs(t, err, errDone)
+	assert.Equal(t, []bool{true, false}, leading)
+
+	// The lease is released when lead returned, so a standby replica takes over right away
+	lease, err := clientset.CoordinationV1().Leases("default").Get(context.Background(), "collector", metav1.GetOptions{})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Empty(t, lease.Spec.HolderIdentity)
+}
+
+func TestRunStopsWhileWaiting(t *testing.T) {
+	t.Setenv("POD_NAME", "replica-b")
+	holder := "replica-a"
+	duration := int32(60)
+	now := metav1.NewMicroTime(time.Now())
+	clientset := testclient.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "collector", Namespace: "default"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &duration,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	})
+
+	stopping := make(chan struct{})
+	time.AfterFunc(300*time.Millisecond, func() { close(stopping) })
+
+	err := Run(context.Background(), clientset, testConfig(), stopping, func(bool) {}, func(ctx context.Context) error {
+		t.Errorf("Expected replica-b to never lead")
+		return nil
+	})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+}
@@ -0,0 +1,87 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrLocked is returned if the run lock is still held by another run after the timeout
+var ErrLocked = errors.New("run lock is held by another run")
+
+// The run lock uses the client-go defaults, a crashed run blocks the next one for at most the lease duration
+const (
+	runLockLeaseDuration = 15 * time.Second
+	runLockRenewDeadline = 10 * time.Second
+	runLockRetryPeriod   = 2 * time.Second
+)
+
+type RunLockConfig struct {
+	RunLock          bool
+	RunLockName      string
+	RunLockNamespace string
+	RunLockTimeout   time.Duration
+}
+
+// WithLock calls fn while holding the run lock lease, so overlapping runs, e.g. a slow CronJob run and the next
+// schedule, can't interleave their writes to the same target. It waits up to the timeout for another run to release
+// the lock, at least one retry period. Returns nil without calling fn if stopping is closed while waiting.
+func WithLock(ctx context.Context, clientset kubernetes.Interface, cfg *RunLockConfig, stopping <-chan struct{}, fn func(ctx context.Context) error) error {
+	namespace, err := kubeclient.PodNamespace(cfg.RunLockNamespace)
+	if err != nil {
+		return fmt.Errorf("could not determine the namespace of the run lock, set --run-lock-namespace: %w", err)
+	}
+
+	// Several runs may share a host name outside of kubernetes, the process id tells them apart
+	identity, err := identity()
+	if err != nil {
+		return fmt.Errorf("could not determine run lock identity: %w", err)
+	}
+	identity += "-" + strconv.Itoa(os.Getpid())
+
+	timeout := time.NewTimer(max(cfg.RunLockTimeout, runLockRetryPeriod))
+	defer timeout.Stop()
+
+	timedOut := make(chan struct{})
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-stopping:
+		case <-timeout.C:
+			close(timedOut)
+		case <-ctx.Done():
+			return
+		}
+		close(stop)
+	}()
+
+	acquired := false
+	err = elect(ctx, clientset, election{
+		name:          cfg.RunLockName,
+		namespace:     namespace,
+		identity:      identity,
+		leaseDuration: runLockLeaseDuration,
+		renewDeadline: runLockRenewDeadline,
+		retryPeriod:   runLockRetryPeriod,
+	}, stop, func(leading bool) {
+		if leading {
+			acquired = true
+		}
+	}, fn)
+
+	if !acquired && err == nil {
+		select {
+		case <-timedOut:
+			return fmt.Errorf("%w, waited %s for %s/%s", ErrLocked, cfg.RunLockTimeout, namespace, cfg.RunLockName)
+		default:
+		}
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWithLock(t *testing.T) {
+	t.Setenv("POD_NAME", "run-a")
+	clientset := testclient.NewSimpleClientset()
+	cfg := &RunLockConfig{RunLock: true, RunLockName: "run", RunLockNamespace: "default"}
+
+	called := false
+	err := WithLock(context.Background(), clientset, cfg, nil, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.True(t, called)
+
+	// The released lock is acquired by the next run right away
+	called = false
+	err = WithLock(context.Background(), clientset, cfg, nil, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.True(t, called)
+}
+
+func TestWithLockHeld(t *testing.T) {
+	t.Setenv("POD_NAME", "run-b")
+	holder := "run-a"
+	duration := int32(60)
+	now := metav1.NewMicroTime(time.Now())
+	clientset := testclient.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "run", Namespace: "default"},
+		Spec:       coordinationv1.LeaseSpec{HolderIdentity: &holder, LeaseDurationSeconds: &duration, AcquireTime: &now, RenewTime: &now},
+	})
+	cfg := &RunLockConfig{RunLock: true, RunLockName: "run", RunLockNamespace: "default", RunLockTimeout: time.Millisecond}
+
+	err := WithLock(context.Background(), clientset, cfg, nil, func(ctx context.Context) error {
+		t.Errorf("Expected the run to wait for the lock")
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrLocked)
+}
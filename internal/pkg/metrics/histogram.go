@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"strconv"
+)
+
+// defaultBuckets are the upper bounds in seconds used for durations, the same as the Prometheus client defaults
+var defaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// histogram counts observations in cumulative buckets like a Prometheus histogram, it is guarded by the lock of Metrics
+type histogram struct {
+	buckets []float64
+	counts  []int
+	count   int
+	sum     float64
+}
+
+func newHistogram() *histogram {
+	return &histogram{buckets: defaultBuckets, counts: make([]int, len(defaultBuckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// samples returns the _bucket, _sum and _count samples with the given labels
+func (h *histogram) samples(labels [][2]string) []sample {
+	samples := make([]sample, 0, len(h.buckets)+3)
+	for i, bound := range h.buckets {
+		samples = append(samples, sample{suffix: "_bucket", labels: withLabel(labels, "le", strconv.FormatFloat(bound, 'g', -1, 64)), value: float64(h.counts[i])})
+	}
+	samples = append(samples,
+		sample{suffix: "_bucket", labels: withLabel(labels, "le", "+Inf"), value: float64(h.count)},
+		sample{suffix: "_sum", labels: labels, value: h.sum},
+		sample{suffix: "_count", labels: labels, value: float64(h.count)},
+	)
+	return samples
+}
+
+func withLabel(labels [][2]string, name, value string) [][2]string {
+	return append(append([][2]string{}, labels...), [2]string{name, value})
+}
@@ -0,0 +1,251 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+)
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Metrics collects statistics about the collection runs and renders them in the Prometheus text exposition format
+type Metrics struct {
+	mu sync.Mutex
+
+	runs                 map[string]int
+	lastRunTimestamp     time.Time
+	lastSuccessTimestamp time.Time
+	lastRunDuration      time.Duration
+	lastRunSucceeded     bool
+	images               int
+
+	namespacesListed int
+	podsListed       int
+	imagesCollected  int
+	imagesSkipped    map[string]int
+	storageBytes     int
+	storageDuration  *histogram
+	kubeAPIDurations map[string]*histogram
+
+	// leaderElection is set once the leadership is reported, only then the leader metric is exposed
+	leaderElection bool
+	leader         bool
+}
+
+func New() *Metrics {
+	return &Metrics{
+		runs:             map[string]int{ResultSuccess: 0, ResultFailure: 0},
+		imagesSkipped:    map[string]int{},
+		storageDuration:  newHistogram(),
+		kubeAPIDurations: map[string]*histogram{},
+	}
+}
+
+// ObserveList records a list call to the kubernetes API, items is the number of listed namespaces or pods
+func (m *Metrics) ObserveList(resource string, items int, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.kubeAPIDurations[resource]
+	if !ok {
+		h = newHistogram()
+		m.kubeAPIDurations[resource] = h
+	}
+	h.observe(duration.Seconds())
+
+	switch resource {
+	case "namespaces":
+		m.namespacesListed += items
+	case "pods":
+		m.podsListed += items
+	}
+}
+
+// ObserveImages records the converted images of a run and the number of skipped images by reason
+func (m *Metrics) ObserveImages(images int, skipped map[string]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.imagesCollected += images
+	for reason, count := range skipped {
+		m.imagesSkipped[reason] += count
+	}
+}
+
+// ObserveStore records a report written to the storage, failed writes only count towards the duration
+func (m *Metrics) ObserveStore(duration time.Duration, size int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.storageDuration.observe(duration.Seconds())
+	if err == nil {
+		m.storageBytes += size
+	}
+}
+
+// ObserveRun records a finished collection run which started at start, images is ignored for failed runs
+func (m *Metrics) ObserveRun(start time.Time, images int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.lastRunTimestamp = now
+	m.lastRunDuration = now.Sub(start)
+	m.lastRunSucceeded = err == nil
+
+	if err != nil {
+		m.runs[ResultFailure]++
+		return
+	}
+
+	m.runs[ResultSuccess]++
+	m.lastSuccessTimestamp = now
+	m.images = images
+}
+
+// LastRunSucceeded reports whether a run finished and the latest one was successful
+func (m *Metrics) LastRunSucceeded() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lastRunSucceeded
+}
+
+// SetLeader records whether this replica holds the leader lease
+func (m *Metrics) SetLeader(leader bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.leaderElection = true
+	m.leader = leader
+}
+
+// Ready reports whether the last run succeeded, standby replicas of a leader election are always ready
+func (m *Metrics) Ready() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.leaderElection && !m.leader {
+		return true
+	}
+	return m.lastRunSucceeded
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var buf bytes.Buffer
+
+	info := version.Get()
+	writeMetric(&buf, "collector_build_info", "gauge", "Build information of the collector.",
+		sample{labels: [][2]string{{"version", info.Version}, {"commit", info.Commit}, {"go_version", info.GoVersion}}, value: 1})
+
+	results := make([]string, 0, len(m.runs))
+	for result := range m.runs {
+		results = append(results, result)
+	}
+	sort.Strings(results)
+	runs := make([]sample, len(results))
+	for i, result := range results {
+		runs[i] = sample{labels: [][2]string{{"result", result}}, value: float64(m.runs[result])}
+	}
+	writeMetric(&buf, "collector_runs_total", "counter", "Number of finished collection runs by result.", runs...)
+
+	writeMetric(&buf, "collector_last_run_timestamp_seconds", "gauge", "Unix time the last collection run finished.",
+		sample{value: unixSeconds(m.lastRunTimestamp)})
+	writeMetric(&buf, "collector_last_success_timestamp_seconds", "gauge", "Unix time the last successful collection run finished.",
+		sample{value: unixSeconds(m.lastSuccessTimestamp)})
+	writeMetric(&buf, "collector_last_run_duration_seconds", "gauge", "Duration of the last collection run.",
+		sample{value: m.lastRunDuration.Seconds()})
+	writeMetric(&buf, "collector_images", "gauge", "Number of images collected by the last successful run.",
+		sample{value: float64(m.images)})
+
+	writeMetric(&buf, "collector_namespaces_listed_total", "counter", "Number of namespaces listed from the kubernetes API.",
+		sample{value: float64(m.namespacesListed)})
+	writeMetric(&buf, "collector_pods_listed_total", "counter", "Number of pods listed from the kubernetes API.",
+		sample{value: float64(m.podsListed)})
+	writeMetric(&buf, "collector_images_collected_total", "counter", "Number of images collected, including skipped images.",
+		sample{value: float64(m.imagesCollected)})
+
+	reasons := make([]string, 0, len(m.imagesSkipped))
+	for reason := range m.imagesSkipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	skipped := make([]sample, len(reasons))
+	for i, reason := range reasons {
+		skipped[i] = sample{labels: [][2]string{{"reason", reason}}, value: float64(m.imagesSkipped[reason])}
+	}
+	writeMetric(&buf, "collector_images_skipped_total", "counter", "Number of images skipped by reason.", skipped...)
+
+	writeMetric(&buf, "collector_storage_write_duration_seconds", "histogram", "Duration of the writes to the storage.",
+		m.storageDuration.samples(nil)...)
+	writeMetric(&buf, "collector_storage_written_bytes_total", "counter", "Number of bytes written to the storage.",
+		sample{value: float64(m.storageBytes)})
+
+	resources := make([]string, 0, len(m.kubeAPIDurations))
+	for resource := range m.kubeAPIDurations {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	var durations []sample
+	for _, resource := range resources {
+		durations = append(durations, m.kubeAPIDurations[resource].samples([][2]string{{"resource", resource}})...)
+	}
+	writeMetric(&buf, "collector_kube_api_request_duration_seconds", "histogram", "Duration of the list calls to the kubernetes API by resource.",
+		durations...)
+
+	if m.leaderElection {
+		leader := 0.0
+		if m.leader {
+			leader = 1
+		}
+		writeMetric(&buf, "collector_leader", "gauge", "Whether this replica holds the leader lease and collects images.",
+			sample{value: leader})
+	}
+
+	return buf.WriteTo(w)
+}
+
+type sample struct {
+	// suffix is appended to the metric name, e.g. _bucket for histograms
+	suffix string
+	labels [][2]string
+	value  float64
+}
+
+func writeMetric(buf *bytes.Buffer, name, metricType, help string, samples ...sample) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, metricType)
+	for _, s := range samples {
+		buf.WriteString(name + s.suffix)
+		if len(s.labels) > 0 {
+			pairs := make([]string, len(s.labels))
+			for i, label := range s.labels {
+				pairs[i] = label[0] + `="` + labelEscaper.Replace(label[1]) + `"`
+			}
+			buf.WriteString("{" + strings.Join(pairs, ",") + "}")
+		}
+		fmt.Fprintf(buf, " %g\n", s.value)
+	}
+}
+
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixMilli()) / 1000
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	assert.False(t, m.LastRunSucceeded())
+
+	m.ObserveRun(time.Now(), 42, nil)
+	assert.True(t, m.LastRunSucceeded())
+
+	m.ObserveRun(time.Now(), 0, errors.New("failed"))
+	assert.False(t, m.LastRunSucceeded())
+
+	var buf bytes.Buffer
+	_, err := m.WriteTo(&buf)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	output := buf.String()
+	assert.Contains(t, output, "# TYPE collector_runs_total counter\n")
+	assert.Contains(t, output, "collector_runs_total{result=\"failure\"} 1\n")
+	assert.Contains(t, output, "collector_runs_total{result=\"success\"} 1\n")
+	assert.Contains(t, output, "collector_images 42\n")
+	assert.Contains(t, output, "collector_build_info{version=\"dev\"")
+	assert.NotContains(t, output, "collector_last_success_timestamp_seconds 0\n")
+}
+
+func TestLeaderMetrics(t *testing.T) {
+	m := New()
+	assert.False(t, m.Ready())
+
+	var buf bytes.Buffer
+	_, _ = m.WriteTo(&buf)
+	assert.NotContains(t, buf.String(), "collector_leader")
+
+	// Standby replicas never run, they must not be reported as unready
+	m.SetLeader(false)
+	assert.True(t, m.Ready())
+
+	m.SetLeader(true)
+	assert.False(t, m.Ready())
+	m.ObserveRun(time.Now(), 1, nil)
+	assert.True(t, m.Ready())
+
+	buf.Reset()
+	_, _ = m.WriteTo(&buf)
+	assert.Contains(t, buf.String(), "collector_leader 1\n")
+}
+
+func TestCollectionMetrics(t *testing.T) {
+	m := New()
+	m.ObserveList("namespaces", 2, 20*time.Millisecond, nil)
+	m.ObserveList("pods", 3, 200*time.Millisecond, nil)
+	m.ObserveList("pods", 0, time.Second, errors.New("forbidden"))
+	m.ObserveImages(5, map[string]int{"annotation": 1, "image-filter": 2})
+	m.ObserveImages(5, map[string]int{"annotation": 1})
+	m.ObserveStore(30*time.Millisecond, 1024, nil)
+	m.ObserveStore(time.Second, 0, errors.New("denied"))
+
+	var buf bytes.Buffer
+	_, err := m.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	output := buf.String()
+	assert.Contains(t, output, "collector_namespaces_listed_total 2\n")
+	assert.Contains(t, output, "collector_pods_listed_total 3\n")
+	assert.Contains(t, output, "collector_images_collected_total 10\n")
+	assert.Contains(t, output, "collector_images_skipped_total{reason=\"annotation\"} 2\n")
+	assert.Contains(t, output, "collector_images_skipped_total{reason=\"image-filter\"} 2\n")
+	assert.Contains(t, output, "collector_storage_written_bytes_total 1024\n")
+	assert.Contains(t, output, "# TYPE collector_storage_write_duration_seconds histogram\n")
+	assert.Contains(t, output, "collector_storage_write_duration_seconds_bucket{le=\"0.05\"} 1\n")
+	assert.Contains(t, output, "collector_storage_write_duration_seconds_bucket{le=\"+Inf\"} 2\n")
+	assert.Contains(t, output, "collector_storage_write_duration_seconds_count 2\n")
+	assert.Contains(t, output, "collector_kube_api_request_duration_seconds_bucket{resource=\"pods\",le=\"0.25\"} 1\n")
+	assert.Contains(t, output, "collector_kube_api_request_duration_seconds_count{resource=\"pods\"} 2\n")
+	assert.Contains(t, output, "collector_kube_api_request_duration_seconds_sum{resource=\"namespaces\"} 0.02\n")
+}
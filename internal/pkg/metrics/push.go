@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+)
+
+type PushConfig struct {
+	PushgatewayUrl      string
+	PushgatewayJob      string
+	PushgatewayGrouping []string
+	PushgatewayTimeout  time.Duration
+}
+
+// Push replaces the metrics of the job and grouping labels on the Pushgateway, so a CronJob run exposes its metrics
+// after it exited. Basic auth credentials can be given in the URL.
+func (m *Metrics) Push(ctx context.Context, cfg *PushConfig) error {
+	pushUrl, err := pushgatewayUrl(cfg)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	if _, err = m.WriteTo(&body); err != nil {
+		return err
+	}
+
+	if cfg.PushgatewayTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.PushgatewayTimeout)
+		defer cancel()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, pushUrl, &body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	res, err := (&http.Client{Transport: version.Transport(nil)}).Do(request)
+	if err != nil {
+		return fmt.Errorf("could not push metrics: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("Pushgateway responded with %s: %s", res.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// pushgatewayUrl returns '<url>/metrics/job/<job>/<label>/<value>...', values containing a slash are base64 encoded
+// as the Pushgateway requires
+func pushgatewayUrl(cfg *PushConfig) (string, error) {
+	if cfg.PushgatewayJob == "" {
+		return "", fmt.Errorf("Pushgateway job must not be empty")
+	}
+
+	path := "/metrics/" + pathSegment("job", cfg.PushgatewayJob)
+	for _, grouping := range cfg.PushgatewayGrouping {
+		name, value, ok := strings.Cut(grouping, "=")
+		if !ok || name == "" {
+			return "", fmt.Errorf("Pushgateway grouping %s has to be in the format name=value", grouping)
+		}
+		path += "/" + pathSegment(name, value)
+	}
+
+	base, err := url.Parse(strings.TrimSuffix(cfg.PushgatewayUrl, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid Pushgateway URL: %w", err)
+	}
+	return base.String() + path, nil
+}
+
+func pathSegment(name, value string) string {
+	if value == "" {
+		return name + "@base64/="
+	}
+	if strings.Contains(value, "/") {
+		return name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return name + "/" + url.PathEscape(value)
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPush(t *testing.T) {
+	var method, path, body, user string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.EscapedPath(), string(content)
+		user, _, _ = r.BasicAuth()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	m := New()
+	m.ObserveRun(time.Now(), 3, nil)
+
+	cfg := &PushConfig{
+		PushgatewayUrl:      "http://collector@" + server.Listener.Addr().String() + "/",
+		PushgatewayJob:      "image-metadata-collector",
+		PushgatewayGrouping: []string{"environment=prod", "cluster=eu/west"},
+	}
+	assert.NoError(t, m.Push(context.Background(), cfg))
+
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/metrics/job/image-metadata-collector/environment/prod/cluster@base64/ZXUvd2VzdA", path)
+	assert.Contains(t, body, "collector_images 3\n")
+	assert.Equal(t, "collector", user)
+}
+
+func TestPushErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "pushed metrics are invalid", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := New().Push(context.Background(), &PushConfig{PushgatewayUrl: server.URL, PushgatewayJob: "job"})
+	assert.ErrorContains(t, err, "pushed metrics are invalid")
+
+	err = New().Push(context.Background(), &PushConfig{PushgatewayUrl: server.URL, PushgatewayJob: "job", PushgatewayGrouping: []string{"invalid"}})
+	assert.ErrorContains(t, err, "name=value")
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// MSTeams posts the summaries as Adaptive Card to a Teams incoming webhook or Workflows webhook
+type MSTeams struct {
+	httpClient *http.Client
+	webhookUrl string
+}
+
+func NewMSTeams(httpClient *http.Client, webhookUrl string) *MSTeams {
+	return &MSTeams{httpClient: httpClient, webhookUrl: webhookUrl}
+}
+
+func (m *MSTeams) Notify(ctx context.Context, summary Summary) error {
+	return postJson(ctx, m.httpClient, "MS Teams", m.webhookUrl, adaptiveCardMessage(summary))
+}
+
+type fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// adaptiveCardMessage wraps a card with a headline and the details of the run as facts
+func adaptiveCardMessage(summary Summary) map[string]any {
+	headline, color := "Image collection finished", "Good"
+	if summary.Err != nil {
+		headline, color = "Image collection failed", "Attention"
+	}
+
+	environment := summary.Environment
+	if environment == "" {
+		environment = "unknown"
+	}
+	facts := []fact{
+		{Title: "Environment", Value: environment},
+		{Title: "Duration", Value: summary.Duration.Round(durationPrecision).String()},
+		{Title: "Images", Value: strconv.Itoa(summary.Images)},
+	}
+	if summary.NewImages >= 0 {
+		facts = append(facts, fact{Title: "New images", Value: strconv.Itoa(summary.NewImages)})
+	}
+	if summary.Err != nil {
+		facts = append(facts, fact{Title: "Error", Value: summary.Err.Error()})
+	}
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []any{
+			map[string]any{"type": "TextBlock", "text": headline, "weight": "Bolder", "size": "Medium", "color": color, "wrap": true},
+			map[string]any{"type": "FactSet", "facts": facts},
+		},
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []any{
+			map[string]any{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+}
@@ -0,0 +1,176 @@
+// Package notify posts summaries of the collector runs to chat webhooks
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/atomicfile"
+)
+
+type NotifyConfig struct {
+	// SlackWebhookUrl receives a summary after every run, empty disables the notification
+	SlackWebhookUrl string
+	// MSTeamsWebhookUrl receives the summary as Adaptive Card, empty disables the notification
+	MSTeamsWebhookUrl string
+	// NotifyStateFile keeps the images of the last run to count new images across processes, e.g. CronJob runs
+	NotifyStateFile string
+	// NotifyStateFromStorage counts the new images against the last report read from the storage instead
+	NotifyStateFromStorage bool
+}
+
+// durationPrecision rounds the durations in the messages
+const durationPrecision = 100 * time.Millisecond
+
+// Notifier posts the summary of a run
+type Notifier interface {
+	Notify(ctx context.Context, summary Summary) error
+}
+
+// Summary describes a single run
+type Summary struct {
+	Environment string
+	Duration    time.Duration
+	Images      int
+	// NewImages is the number of images which were not part of the last run, it is negative if unknown
+	NewImages int
+	Err       error
+}
+
+// Text formats the summary as Slack mrkdwn message
+func (s Summary) Text() string {
+	environment := s.Environment
+	if environment == "" {
+		environment = "unknown"
+	}
+	duration := s.Duration.Round(durationPrecision)
+
+	if s.Err != nil {
+		return fmt.Sprintf(":x: Image collection in *%s* failed after %s: %s", environment, duration, s.Err)
+	}
+	text := fmt.Sprintf(":white_check_mark: Image collection in *%s* finished in %s: %d images", environment, duration, s.Images)
+	if s.NewImages >= 0 {
+		text += fmt.Sprintf(", %d new", s.NewImages)
+	}
+	return text
+}
+
+// Slack posts the summaries to an incoming webhook
+type Slack struct {
+	httpClient *http.Client
+	webhookUrl string
+}
+
+func NewSlack(httpClient *http.Client, webhookUrl string) *Slack {
+	return &Slack{httpClient: httpClient, webhookUrl: webhookUrl}
+}
+
+func (s *Slack) Notify(ctx context.Context, summary Summary) error {
+	return postJson(ctx, s.httpClient, "Slack", s.webhookUrl, map[string]string{"text": summary.Text()})
+}
+
+// postJson posts the message to the webhook, any 2xx status is a success as some webhooks accept asynchronously
+func postJson(ctx context.Context, httpClient *http.Client, name, webhookUrl string, message any) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	res, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s webhook returned status %s", name, res.Status)
+	}
+	return nil
+}
+
+// Tracker counts the images which were not part of the last run, it starts from the state file if one is given
+type Tracker struct {
+	stateFile string
+	last      map[string]bool
+}
+
+func NewTracker(stateFile string) (*Tracker, error) {
+	t := &Tracker{stateFile: stateFile}
+	if stateFile == "" {
+		return t, nil
+	}
+
+	content, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return t, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	if err = json.Unmarshal(content, &keys); err != nil {
+		return nil, fmt.Errorf("invalid notify state file %s: %w", stateFile, err)
+	}
+	t.last = toSet(keys)
+	return t, nil
+}
+
+// NewTrackerFromKeys starts from the keys of the last run, e.g. of the previous report, nil keys mean there was no
+// last run. The state is only kept in memory.
+func NewTrackerFromKeys(keys []string) *Tracker {
+	t := &Tracker{}
+	if keys != nil {
+		t.last = toSet(keys)
+	}
+	return t
+}
+
+// Update replaces the last run with the keys of the images and returns the number of new keys, or -1 for the first
+// run without state
+func (t *Tracker) Update(keys []string) (int, error) {
+	current := toSet(keys)
+	newImages := -1
+	if t.last != nil {
+		newImages = 0
+		for key := range current {
+			if !t.last[key] {
+				newImages++
+			}
+		}
+	}
+	t.last = current
+
+	if t.stateFile == "" {
+		return newImages, nil
+	}
+	sorted := make([]string, 0, len(current))
+	for key := range current {
+		sorted = append(sorted, key)
+	}
+	slices.Sort(sorted)
+	content, err := json.Marshal(sorted)
+	if err != nil {
+		return newImages, err
+	}
+	return newImages, atomicfile.WriteFile(t.stateFile, content)
+}
+
+func toSet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
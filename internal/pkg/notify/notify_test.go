@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlackNotify(t *testing.T) {
+	var message map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+	}))
+	defer server.Close()
+
+	slack := NewSlack(server.Client(), server.URL)
+	err := slack.Notify(context.Background(), Summary{Environment: "prod", Duration: 1500 * time.Millisecond, Images: 12, NewImages: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, ":white_check_mark: Image collection in *prod* finished in 1.5s: 12 images, 3 new", message["text"])
+
+	err = slack.Notify(context.Background(), Summary{Environment: "prod", Duration: time.Second, NewImages: -1, Err: errors.New("storage error")})
+	assert.NoError(t, err)
+	assert.Equal(t, ":x: Image collection in *prod* failed after 1s: storage error", message["text"])
+
+	err = NewSlack(server.Client(), server.URL+"/missing\x7f").Notify(context.Background(), Summary{})
+	assert.Error(t, err)
+}
+
+func TestSlackNotifyStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewSlack(server.Client(), server.URL).Notify(context.Background(), Summary{})
+	assert.ErrorContains(t, err, "403")
+}
+
+func TestSummaryText(t *testing.T) {
+	assert.Equal(t, ":white_check_mark: Image collection in *unknown* finished in 0s: 2 images", Summary{Images: 2, NewImages: -1}.Text())
+}
+
+func TestTracker(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+
+	tracker, err := NewTracker(stateFile)
+	assert.NoError(t, err)
+	newImages, err := tracker.Update([]string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, -1, newImages)
+
+	// A new process continues from the state file
+	tracker, err = NewTracker(stateFile)
+	assert.NoError(t, err)
+	newImages, err = tracker.Update([]string{"a", "c", "d", "d"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, newImages)
+
+	tracker, err = NewTracker("")
+	assert.NoError(t, err)
+	_, _ = tracker.Update([]string{"a"})
+	newImages, _ = tracker.Update([]string{"a", "b"})
+	assert.Equal(t, 1, newImages)
+
+	// The previous report may be empty, which is not the same as no previous report
+	newImages, _ = NewTrackerFromKeys([]string{}).Update([]string{"a"})
+	assert.Equal(t, 1, newImages)
+	newImages, _ = NewTrackerFromKeys(nil).Update([]string{"a"})
+	assert.Equal(t, -1, newImages)
+}
+
+func TestMSTeamsNotify(t *testing.T) {
+	var message struct {
+		Type        string `json:"type"`
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+			Content     struct {
+				Type string `json:"type"`
+				Body []struct {
+					Type  string `json:"type"`
+					Text  string `json:"text"`
+					Facts []fact `json:"facts"`
+				} `json:"body"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+		// Workflows webhooks accept the message asynchronously
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	err := NewMSTeams(server.Client(), server.URL).Notify(context.Background(), Summary{Environment: "prod", Duration: time.Second, Images: 5, NewImages: -1, Err: errors.New("storage error")})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "message", message.Type)
+	assert.Len(t, message.Attachments, 1)
+	card := message.Attachments[0]
+	assert.Equal(t, "application/vnd.microsoft.card.adaptive", card.ContentType)
+	assert.Equal(t, "AdaptiveCard", card.Content.Type)
+	assert.Equal(t, "Image collection failed", card.Content.Body[0].Text)
+	assert.Equal(t, []fact{
+		{Title: "Environment", Value: "prod"},
+		{Title: "Duration", Value: "1s"},
+		{Title: "Images", Value: "5"},
+		{Title: "Error", Value: "storage error"},
+	}, card.Content.Body[1].Facts)
+}
@@ -0,0 +1,65 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/rs/zerolog/log"
+)
+
+type ProfileConfig struct {
+	// CpuProfile is the file the CPU profile of the whole process is written to
+	CpuProfile string
+	// HeapProfile is the file a heap profile is written to when the process exits
+	HeapProfile string
+}
+
+// Start starts the CPU profiling if configured, stop writes the profiles and has to be called before the process exits
+func Start(cfg *ProfileConfig) (stop func(), err error) {
+	var cpuFile *os.File
+	if cfg.CpuProfile != "" {
+		if cpuFile, err = os.Create(cfg.CpuProfile); err != nil {
+			return nil, fmt.Errorf("could not create CPU profile: %w", err)
+		}
+		if err = pprof.StartCPUProfile(cpuFile); err != nil {
+			cpuFile.Close()
+			return nil, fmt.Errorf("could not start CPU profile: %w", err)
+		}
+	}
+
+	return func() {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			if err := cpuFile.Close(); err != nil {
+				log.Warn().Err(err).Str("fileName", cfg.CpuProfile).Msg("Could not write CPU profile")
+			} else {
+				log.Info().Str("fileName", cfg.CpuProfile).Msg("Wrote CPU profile")
+			}
+		}
+
+		if cfg.HeapProfile != "" {
+			if err := writeHeapProfile(cfg.HeapProfile); err != nil {
+				log.Warn().Err(err).Str("fileName", cfg.HeapProfile).Msg("Could not write heap profile")
+			} else {
+				log.Info().Str("fileName", cfg.HeapProfile).Msg("Wrote heap profile")
+			}
+		}
+	}, nil
+}
+
+func writeHeapProfile(fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+
+	// Collect garbage first, so the profile reflects the live heap
+	runtime.GC()
+	if err = pprof.WriteHeapProfile(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,27 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStart(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &ProfileConfig{CpuProfile: filepath.Join(dir, "cpu.pprof"), HeapProfile: filepath.Join(dir, "heap.pprof")}
+
+	stop, err := Start(cfg)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	stop()
+
+	for _, fileName := range []string{cfg.CpuProfile, cfg.HeapProfile} {
+		info, err := os.Stat(fileName)
+		assert.NoError(t, err, "Expected no error, got %v", err)
+		assert.NotZero(t, info.Size())
+	}
+
+	_, err = Start(&ProfileConfig{CpuProfile: filepath.Join(dir, "missing", "cpu.pprof")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+// Package redact masks secrets in the log output, both configured secret values and the values of sensitive HTTP
+// headers and parameters, e.g. in the debug logs of the AWS SDK
+package redact
+
+import (
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Mask replaces the redacted values
+const Mask = "******"
+
+// minSecretLength avoids masking short values like 'true' or a single character in every log line
+const minSecretLength = 6
+
+// sensitive matches a sensitive header or parameter name followed by its value, the value ends at a quote, a comma,
+// a line break, an escaped line break of JSON output or an ampersand of query strings
+var sensitive = regexp.MustCompile(`(?i)((?:x-api-key|x-api-signature|authorization|proxy-authorization|cookie|set-cookie|x-amz-security-token|x-amz-signature|x-amz-credential|private-token|access_token|refresh_token|client_secret|password)"?\s*[:=]\s*"?)([^"',&\r\n\\]+)`)
+
+var (
+	mu      sync.RWMutex
+	secrets []string
+)
+
+// Register adds secret values which are masked in the log output from now on
+func Register(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, value := range values {
+		if len(value) >= minSecretLength && !slices.Contains(secrets, value) {
+			secrets = append(secrets, value)
+		}
+	}
+	// Longer secrets first, so a secret containing another one is masked completely
+	slices.SortFunc(secrets, func(a, b string) int { return len(b) - len(a) })
+}
+
+// String masks the registered secrets and the values of sensitive headers and parameters
+func String(s string) string {
+	mu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	mu.RUnlock()
+	return sensitive.ReplaceAllString(s, "${1}"+Mask)
+}
+
+// Writer masks secrets in everything written to the underlying writer, zerolog writes one event per call
+type Writer struct {
+	Out io.Writer
+}
+
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{Out: out}
+}
+
+// Write returns the length of p on success, as callers do not expect the masked length
+func (w *Writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.Out, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
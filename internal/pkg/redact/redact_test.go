@@ -0,0 +1,45 @@
+package redact
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestString(t *testing.T) {
+	Register("s3cr3t-api-key", "short", "s3cr3t-api-key-extended")
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "RegisteredSecret", input: `{"message":"key s3cr3t-api-key"}`, expected: `{"message":"key ******"}`},
+		{name: "LongerSecretFirst", input: "s3cr3t-api-key-extended", expected: "******"},
+		{name: "ShortValuesAreKept", input: "short", expected: "short"},
+		{name: "Header", input: "X-Api-Key: abc\r\nContent-Type: application/json", expected: "X-Api-Key: ******\r\nContent-Type: application/json"},
+		{name: "EscapedHeaderInJson", input: `{"message":"Authorization: AWS4-HMAC-SHA256 Credential=AKIA/eu\r\nHost: s3"}`, expected: `{"message":"Authorization: ******\r\nHost: s3"}`},
+		{name: "JsonField", input: `{"authorization":"Bearer token","level":"debug"}`, expected: `{"authorization":"******","level":"debug"}`},
+		{name: "QueryParameter", input: "GET /?X-Amz-Signature=abcdef&X-Amz-Date=20240101", expected: "GET /?X-Amz-Signature=******&X-Amz-Date=20240101"},
+		{name: "NoSecrets", input: `{"message":"Run finished","images":12}`, expected: `{"message":"Run finished","images":12}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, String(tc.input))
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	Register("writer-secret")
+
+	var out bytes.Buffer
+	input := []byte(`{"message":"writer-secret"}` + "\n")
+	n, err := NewWriter(&out).Write(input)
+
+	assert.NoError(t, err)
+	assert.Equal(t, len(input), n)
+	assert.Equal(t, `{"message":"******"}`+"\n", out.String())
+}
@@ -0,0 +1,201 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Formats of the SBOMs recognized by their media type or predicate type
+const (
+	SbomFormatSpdx      = "spdx"
+	SbomFormatCycloneDx = "cyclonedx"
+	SbomFormatSyft      = "syft"
+)
+
+const (
+	ociIndexMediaType = "application/vnd.oci.image.index.v1+json"
+
+	// Artifact types of signatures stored as referrers by cosign and Notary
+	cosignSignatureArtifactType = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	notarySignatureArtifactType = "application/vnd.cncf.notary.signature"
+
+	// Annotations with the predicate type of attestations stored as referrers
+	sigstoreBundlePredicateType = "dev.sigstore.bundle.predicateType"
+	// sigstoreBundleContent is message-signature for sigstore bundles signing the image instead of an attestation
+	sigstoreBundleContent = "dev.sigstore.bundle.content"
+	inTotoPredicateType   = "in-toto.io/predicate-type"
+	// cosignPredicateType is the annotation of the layers of cosign attestation tags
+	cosignPredicateType = "predicateType"
+)
+
+// Attachments are the SBOMs, attestations and signatures attached to an image in its registry
+type Attachments struct {
+	Sbom bool
+	// SbomFormats are the sorted formats of the SBOMs, e.g. spdx and cyclonedx
+	SbomFormats []string
+	Attestation bool
+	// Signature is set for cosign signatures and Notary signatures, an attestation alone does not sign the image
+	Signature bool
+}
+
+// attachedManifest is the part of referrers indexes and cosign attachment manifests describing the attachments
+type attachedManifest struct {
+	Manifests []attachedDescriptor `json:"manifests"`
+	Layers    []attachedDescriptor `json:"layers"`
+}
+
+type attachedDescriptor struct {
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType"`
+	Annotations  map[string]string `json:"annotations"`
+}
+
+// Attachments discovers the SBOMs, attestations and signatures of the image with the OCI referrers API and the .sbom,
+// .att and .sig tags cosign creates on registries without it
+func (r *Resolver) Attachments(ctx context.Context, ref Reference) (*Attachments, error) {
+	client, err := r.client(ctx, ref.Registry)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := ref.Digest
+	if digest == "" {
+		if digest, err = r.digest(ctx, client, ref); err != nil {
+			return nil, err
+		}
+	}
+
+	attachments := &Attachments{}
+	res, err := r.do(ctx, client, ref, http.MethodGet, "referrers/"+digest, ociIndexMediaType)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusOK {
+		var index attachedManifest
+		if err = json.NewDecoder(io.LimitReader(res.Body, maxDocumentSize)).Decode(&index); err != nil {
+			return nil, fmt.Errorf("invalid referrers of %s: %w", ref, err)
+		}
+		for _, descriptor := range index.Manifests {
+			attachments.add(descriptor.ArtifactType, descriptor.Annotations)
+		}
+	} else if !referrersUnsupported(res.StatusCode) {
+		return nil, CheckStatus(res, http.StatusOK, "GET referrers of "+ref.String())
+	}
+
+	if err = r.cosignAttachments(ctx, client, ref, digest, attachments); err != nil {
+		return nil, err
+	}
+
+	slices.Sort(attachments.SbomFormats)
+	attachments.SbomFormats = slices.Compact(attachments.SbomFormats)
+	return attachments, nil
+}
+
+// referrersUnsupported are the status codes of registries without the referrers API, which treat it as a tag or an
+// unknown route
+func referrersUnsupported(status int) bool {
+	return status == http.StatusNotFound || status == http.StatusBadRequest || status == http.StatusMethodNotAllowed
+}
+
+// cosignAttachments checks the sha256-<digest>.sbom, .att and .sig tags cosign pushes next to the image
+func (r *Resolver) cosignAttachments(ctx context.Context, client *Client, ref Reference, digest string, attachments *Attachments) error {
+	tag := strings.Replace(digest, ":", "-", 1)
+	for _, suffix := range []string{".sbom", ".att", ".sig"} {
+		res, err := r.do(ctx, client, ref, http.MethodGet, "manifests/"+tag+suffix, strings.Join(manifestMediaTypes, ","))
+		if err != nil {
+			return err
+		}
+
+		if res.StatusCode == http.StatusNotFound {
+			res.Body.Close()
+			continue
+		}
+		if err = CheckStatus(res, http.StatusOK, "GET "+tag+suffix+" of "+ref.String()); err != nil {
+			res.Body.Close()
+			return err
+		}
+
+		var manifest attachedManifest
+		err = json.NewDecoder(io.LimitReader(res.Body, maxDocumentSize)).Decode(&manifest)
+		res.Body.Close()
+		if err != nil {
+			return fmt.Errorf("invalid %s manifest of %s: %w", suffix, ref, err)
+		}
+
+		for _, layer := range manifest.Layers {
+			switch suffix {
+			case ".sbom":
+				attachments.Sbom = true
+				if format := sbomFormat(layer.MediaType); format != "" {
+					attachments.SbomFormats = append(attachments.SbomFormats, format)
+				}
+			case ".att":
+				attachments.add("application/vnd.in-toto+json", layer.Annotations)
+			case ".sig":
+				attachments.Signature = true
+			}
+		}
+	}
+	return nil
+}
+
+// add records an attachment by its artifact type, attestations are SBOMs too if their predicate is an SBOM
+func (a *Attachments) add(artifactType string, annotations map[string]string) {
+	if artifactType == cosignSignatureArtifactType || artifactType == notarySignatureArtifactType || annotations[sigstoreBundleContent] == "message-signature" {
+		a.Signature = true
+		return
+	}
+	if format := sbomFormat(artifactType); format != "" {
+		a.Sbom = true
+		a.SbomFormats = append(a.SbomFormats, format)
+		return
+	}
+	if !strings.Contains(artifactType, "in-toto") && !strings.Contains(artifactType, "sigstore.bundle") {
+		return
+	}
+
+	a.Attestation = true
+	for _, key := range []string{sigstoreBundlePredicateType, inTotoPredicateType, cosignPredicateType} {
+		if format := sbomFormat(annotations[key]); format != "" {
+			a.Sbom = true
+			a.SbomFormats = append(a.SbomFormats, format)
+		}
+	}
+}
+
+// sbomFormat returns the SBOM format of a media type like application/spdx+json or a predicate type like
+// https://cyclonedx.org/bom, or an empty string
+func sbomFormat(mediaType string) string {
+	mediaType = strings.ToLower(mediaType)
+	for _, format := range []string{SbomFormatSpdx, SbomFormatCycloneDx, SbomFormatSyft} {
+		if strings.Contains(mediaType, format) {
+			return format
+		}
+	}
+	return ""
+}
+
+// digest returns the digest of the manifest the tag of the reference points to
+func (r *Resolver) digest(ctx context.Context, client *Client, ref Reference) (string, error) {
+	res, err := r.do(ctx, client, ref, http.MethodHead, "manifests/"+ref.Identifier(), strings.Join(manifestMediaTypes, ","))
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+
+	if err = CheckStatus(res, http.StatusOK, "HEAD manifest of "+ref.String()); err != nil {
+		return "", err
+	}
+	digest := res.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry returned no digest for %s", ref)
+	}
+	return digest, nil
+}
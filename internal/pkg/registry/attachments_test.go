@@ -0,0 +1,83 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAttachments(t *testing.T) {
+	documents := map[string]string{
+		"/v2/team/app/referrers/sha256:app": `{"manifests":[` +
+			`{"artifactType":"application/spdx+json"},` +
+			`{"artifactType":"application/vnd.dev.sigstore.bundle.v0.3+json","annotations":{"dev.sigstore.bundle.predicateType":"https://cyclonedx.org/bom"}}]}`,
+		"/v2/team/legacy/manifests/sha256-legacy.sbom": `{"layers":[{"mediaType":"text/spdx+json"}]}`,
+		"/v2/team/legacy/manifests/sha256-legacy.att":  `{"layers":[{"mediaType":"application/vnd.dsse.envelope.v1+json","annotations":{"predicateType":"https://slsa.dev/provenance/v0.2"}}]}`,
+		"/v2/team/signed/manifests/sha256-signed.sig":  `{"layers":[{"mediaType":"application/vnd.dev.cosign.simplesigning.v1+json"}]}`,
+		"/v2/team/notary/referrers/sha256:notary":      `{"manifests":[{"artifactType":"application/vnd.cncf.notary.signature"}]}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && r.URL.Path == "/v2/team/app/manifests/1.0" {
+			w.Header().Set("Docker-Content-Digest", "sha256:app")
+			return
+		}
+		document, ok := documents[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(document))
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	resolver := NewResolver(server.Client(), Anonymous, []string{host})
+
+	testCases := []struct {
+		name     string
+		ref      Reference
+		expected Attachments
+	}{
+		{
+			name:     "Referrers",
+			ref:      Reference{Registry: host, Repository: "team/app", Tag: "1.0"},
+			expected: Attachments{Sbom: true, SbomFormats: []string{SbomFormatCycloneDx, SbomFormatSpdx}, Attestation: true},
+		},
+		{
+			name:     "CosignTags",
+			ref:      Reference{Registry: host, Repository: "team/legacy", Digest: "sha256:legacy"},
+			expected: Attachments{Sbom: true, SbomFormats: []string{SbomFormatSpdx}, Attestation: true},
+		},
+		{
+			name:     "CosignSignature",
+			ref:      Reference{Registry: host, Repository: "team/signed", Digest: "sha256:signed"},
+			expected: Attachments{Signature: true},
+		},
+		{
+			name:     "NotarySignature",
+			ref:      Reference{Registry: host, Repository: "team/notary", Digest: "sha256:notary"},
+			expected: Attachments{Signature: true},
+		},
+		{
+			name:     "NoAttachments",
+			ref:      Reference{Registry: host, Repository: "team/plain", Digest: "sha256:plain"},
+			expected: Attachments{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attachments, err := resolver.Attachments(context.Background(), tc.ref)
+			if err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+			if !reflect.DeepEqual(*attachments, tc.expected) {
+				t.Fatalf("Expected %+v but got %+v\n", tc.expected, *attachments)
+			}
+		})
+	}
+}
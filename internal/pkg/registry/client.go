@@ -0,0 +1,163 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Client talks to the registry HTTP API (OCI distribution spec) and handles basic and bearer token auth
+type Client struct {
+	client   *http.Client
+	username string
+	password string
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewClient creates a client authenticating with the given credentials, anonymous if username is empty
+func NewClient(httpClient *http.Client, username, password string) *Client {
+	return &Client{client: httpClient, username: username, password: password}
+}
+
+// Do sends the request created by newRequest, on a bearer challenge a token is fetched and the request is sent again.
+// newRequest is called for every attempt so the body can be read again.
+func (c *Client) Do(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		request, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		request = request.WithContext(ctx)
+		c.authorize(request)
+
+		res, err := c.client.Do(request)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return res, nil
+		}
+
+		challenge := res.Header.Get("WWW-Authenticate")
+		res.Body.Close()
+
+		if err = c.handleChallenge(ctx, challenge); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("registry rejected the credentials")
+}
+
+func (c *Client) authorize(request *http.Request) {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	} else if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+}
+
+// handleChallenge fetches a bearer token from the realm given in the WWW-Authenticate header
+func (c *Client) handleChallenge(ctx context.Context, challenge string) error {
+	scheme, params := parseChallenge(challenge)
+	if !strings.EqualFold(scheme, "bearer") {
+		return fmt.Errorf("registry requires unsupported authentication '%s'", challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("registry sent invalid bearer realm '%s'", params["realm"])
+	}
+
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if scope := params["scope"]; scope != "" {
+		query.Set("scope", scope)
+	}
+	realm.RawQuery = query.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+
+	res, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Got a Status '%s' instead of an '200 OK' response for registry token request", res.Status)
+	}
+
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&tokenResponse); err != nil {
+		return err
+	}
+
+	token := tokenResponse.Token
+	if token == "" {
+		token = tokenResponse.AccessToken
+	}
+	if token == "" {
+		return fmt.Errorf("registry token response did not contain a token")
+	}
+
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+
+	return nil
+}
+
+// parseChallenge splits a WWW-Authenticate header like 'Bearer realm="...",service="..."' into scheme and params
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key != "" {
+			params[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+
+	return scheme, params
+}
+
+// CheckStatus returns an error containing the (truncated) body if the status is not the expected one
+func CheckStatus(res *http.Response, expected int, action string) error {
+	if res.StatusCode == expected {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	return fmt.Errorf("Got a Status '%s' instead of '%d' for %s: %s", res.Status, expected, action, strings.TrimSpace(string(body)))
+}
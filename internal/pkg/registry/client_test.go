@@ -0,0 +1,16 @@
+package registry
+
+import (
+	"testing"
+)
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull,push"`)
+
+	if scheme != "Bearer" {
+		t.Fatalf("Expected scheme Bearer but got %s\n", scheme)
+	}
+	if params["realm"] != "https://auth.example.com/token" || params["service"] != "registry.example.com" || params["scope"] != "repository:a/b:pull,push" {
+		t.Fatalf("Got unexpected params %v\n", params)
+	}
+}
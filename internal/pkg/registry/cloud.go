@@ -0,0 +1,255 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+)
+
+// Names of the credential helpers of the cloud registries
+const (
+	HelperECR    = "ecr"
+	HelperGoogle = "google"
+	HelperAzure  = "azure"
+)
+
+// refreshBefore renews cached tokens this long before they expire
+const refreshBefore = 5 * time.Minute
+
+// NewCredentialHelper returns the keychain of a cloud registry by name, it only resolves credentials for the hosts of
+// that registry
+func NewCredentialHelper(name string, httpClient *http.Client) (Keychain, error) {
+	switch name {
+	case HelperECR:
+		return NewECRKeychain(), nil
+	case HelperGoogle:
+		return NewGoogleKeychain(httpClient), nil
+	case HelperAzure:
+		return NewAzureKeychain(httpClient), nil
+	default:
+		return nil, fmt.Errorf("registry credential helper %s is not supported [%s, %s, %s]", name, HelperECR, HelperGoogle, HelperAzure)
+	}
+}
+
+// MultiKeychain returns the first non-empty credentials of its keychains
+type MultiKeychain []Keychain
+
+func (m MultiKeychain) Resolve(ctx context.Context, registry string) (Credentials, error) {
+	for _, keychain := range m {
+		credentials, err := keychain.Resolve(ctx, registry)
+		if err != nil {
+			return Credentials{}, err
+		}
+		if credentials != (Credentials{}) {
+			return credentials, nil
+		}
+	}
+	return Credentials{}, nil
+}
+
+// tokenCache keeps short-lived registry credentials until shortly before they expire
+type tokenCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedCredentials
+}
+
+type cachedCredentials struct {
+	credentials Credentials
+	expires     time.Time
+}
+
+// get returns the cached credentials of key or fetches new ones
+func (c *tokenCache) get(key string, fetch func() (Credentials, time.Time, error)) (Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok && time.Until(entry.expires) > refreshBefore {
+		return entry.credentials, nil
+	}
+
+	credentials, expires, err := fetch()
+	if err != nil {
+		return Credentials{}, err
+	}
+	if c.entries == nil {
+		c.entries = map[string]cachedCredentials{}
+	}
+	c.entries[key] = cachedCredentials{credentials: credentials, expires: expires}
+	return credentials, nil
+}
+
+var ecrHost = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(-fips)?\.([a-z0-9-]+)\.amazonaws\.com(\.cn)?$`)
+
+// ECRKeychain fetches authorization tokens for Amazon ECR with the AWS credentials of the environment, e.g. IRSA
+type ECRKeychain struct {
+	cache    tokenCache
+	getToken func(ctx context.Context, region, registryId string) (Credentials, time.Time, error)
+}
+
+func NewECRKeychain() *ECRKeychain {
+	return &ECRKeychain{getToken: ecrToken}
+}
+
+func (k *ECRKeychain) Resolve(ctx context.Context, registry string) (Credentials, error) {
+	match := ecrHost.FindStringSubmatch(registry)
+	if match == nil {
+		return Credentials{}, nil
+	}
+	registryId, region := match[1], match[3]
+
+	return k.cache.get(registry, func() (Credentials, time.Time, error) {
+		return k.getToken(ctx, region, registryId)
+	})
+}
+
+func ecrToken(ctx context.Context, region, registryId string) (Credentials, time.Time, error) {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return Credentials{}, time.Time{}, err
+	}
+
+	output, err := ecr.New(sess).GetAuthorizationTokenWithContext(ctx, &ecr.GetAuthorizationTokenInput{RegistryIds: []*string{aws.String(registryId)}})
+	if err != nil {
+		return Credentials{}, time.Time{}, fmt.Errorf("could not get ECR authorization token: %w", err)
+	}
+	if len(output.AuthorizationData) == 0 || output.AuthorizationData[0].AuthorizationToken == nil {
+		return Credentials{}, time.Time{}, fmt.Errorf("ECR returned no authorization token")
+	}
+
+	data := output.AuthorizationData[0]
+	decoded, err := base64.StdEncoding.DecodeString(*data.AuthorizationToken)
+	if err != nil {
+		return Credentials{}, time.Time{}, fmt.Errorf("invalid ECR authorization token: %w", err)
+	}
+	username, password, _ := strings.Cut(string(decoded), ":")
+	return Credentials{Username: username, Password: password}, aws.TimeValue(data.ExpiresAt), nil
+}
+
+// GoogleKeychain uses the access token of the service account of the GCE metadata server, e.g. with GKE workload
+// identity, for Artifact Registry and Container Registry
+type GoogleKeychain struct {
+	cache       tokenCache
+	httpClient  *http.Client
+	metadataUrl string
+}
+
+func NewGoogleKeychain(httpClient *http.Client) *GoogleKeychain {
+	return &GoogleKeychain{
+		httpClient:  httpClient,
+		metadataUrl: "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token",
+	}
+}
+
+func isGoogleRegistry(registry string) bool {
+	return registry == "gcr.io" || strings.HasSuffix(registry, ".gcr.io") || strings.HasSuffix(registry, "-docker.pkg.dev")
+}
+
+func (k *GoogleKeychain) Resolve(ctx context.Context, registry string) (Credentials, error) {
+	if !isGoogleRegistry(registry) {
+		return Credentials{}, nil
+	}
+
+	// The access token is the same for all registries
+	return k.cache.get("", func() (Credentials, time.Time, error) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, k.metadataUrl, nil)
+		if err != nil {
+			return Credentials{}, time.Time{}, err
+		}
+		request.Header.Set("Metadata-Flavor", "Google")
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int    `json:"expires_in"`
+		}
+		if err = k.fetch(request, &token); err != nil {
+			return Credentials{}, time.Time{}, fmt.Errorf("could not get access token from the GCE metadata server: %w", err)
+		}
+		return Credentials{Username: "oauth2accesstoken", Password: token.AccessToken}, time.Now().Add(time.Duration(token.ExpiresIn) * time.Second), nil
+	})
+}
+
+func (k *GoogleKeychain) fetch(request *http.Request, v any) error {
+	return fetchJson(k.httpClient, request, v)
+}
+
+// acrUsername is the fixed user name for ACR refresh tokens
+const acrUsername = "00000000-0000-0000-0000-000000000000"
+
+// acrTokenLifetime is the time ACR refresh tokens are cached, they are valid for three hours
+const acrTokenLifetime = time.Hour
+
+// AzureKeychain exchanges the managed identity token of the Azure instance metadata service for an ACR refresh token
+type AzureKeychain struct {
+	cache      tokenCache
+	httpClient *http.Client
+	imdsUrl    string
+	scheme     string
+}
+
+func NewAzureKeychain(httpClient *http.Client) *AzureKeychain {
+	return &AzureKeychain{
+		httpClient: httpClient,
+		imdsUrl:    "http://169.254.169.254/metadata/identity/oauth2/token?api-version=2018-02-01&resource=https%3A%2F%2Fmanagement.azure.com%2F",
+		scheme:     "https",
+	}
+}
+
+func (k *AzureKeychain) Resolve(ctx context.Context, registry string) (Credentials, error) {
+	if !strings.HasSuffix(registry, ".azurecr.io") {
+		return Credentials{}, nil
+	}
+
+	return k.cache.get(registry, func() (Credentials, time.Time, error) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, k.imdsUrl, nil)
+		if err != nil {
+			return Credentials{}, time.Time{}, err
+		}
+		request.Header.Set("Metadata", "true")
+
+		var aadToken struct {
+			AccessToken string `json:"access_token"`
+		}
+		if err = fetchJson(k.httpClient, request, &aadToken); err != nil {
+			return Credentials{}, time.Time{}, fmt.Errorf("could not get managed identity token: %w", err)
+		}
+
+		form := url.Values{"grant_type": {"access_token"}, "service": {registry}, "access_token": {aadToken.AccessToken}}
+		request, err = http.NewRequestWithContext(ctx, http.MethodPost, k.scheme+"://"+registry+"/oauth2/exchange", strings.NewReader(form.Encode()))
+		if err != nil {
+			return Credentials{}, time.Time{}, err
+		}
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var refreshToken struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err = fetchJson(k.httpClient, request, &refreshToken); err != nil {
+			return Credentials{}, time.Time{}, fmt.Errorf("could not exchange managed identity token for ACR refresh token: %w", err)
+		}
+		return Credentials{Username: acrUsername, Password: refreshToken.RefreshToken}, time.Now().Add(acrTokenLifetime), nil
+	})
+}
+
+func fetchJson(httpClient *http.Client, request *http.Request, v any) error {
+	res, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if err = CheckStatus(res, http.StatusOK, request.Method+" "+request.URL.Host+request.URL.Path); err != nil {
+		return err
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,165 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestECRKeychain(t *testing.T) {
+	calls := 0
+	keychain := &ECRKeychain{getToken: func(ctx context.Context, region, registryId string) (Credentials, time.Time, error) {
+		calls++
+		if region != "eu-central-1" || registryId != "123456789012" {
+			t.Fatalf("Expected region and registry id of the host but got %s %s\n", region, registryId)
+		}
+		return Credentials{Username: "AWS", Password: "token"}, time.Now().Add(12 * time.Hour), nil
+	}}
+
+	for i := 0; i < 2; i++ {
+		credentials, err := keychain.Resolve(context.Background(), "123456789012.dkr.ecr.eu-central-1.amazonaws.com")
+		if err != nil {
+			t.Fatalf("Got an error=%v\n", err)
+		}
+		if credentials != (Credentials{Username: "AWS", Password: "token"}) {
+			t.Fatalf("Expected the ECR token but got %v\n", credentials)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected the token to be cached but it was fetched %d times\n", calls)
+	}
+
+	credentials, err := keychain.Resolve(context.Background(), "docker.io")
+	if err != nil || credentials != (Credentials{}) {
+		t.Fatalf("Expected no credentials for other registries but got %v, %v\n", credentials, err)
+	}
+}
+
+func TestGoogleKeychain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"gcp-token","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	keychain := NewGoogleKeychain(server.Client())
+	keychain.metadataUrl = server.URL
+
+	credentials, err := keychain.Resolve(context.Background(), "europe-west3-docker.pkg.dev")
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if credentials != (Credentials{Username: "oauth2accesstoken", Password: "gcp-token"}) {
+		t.Fatalf("Expected the access token but got %v\n", credentials)
+	}
+
+	credentials, err = keychain.Resolve(context.Background(), "quay.io")
+	if err != nil || credentials != (Credentials{}) {
+		t.Fatalf("Expected no credentials for other registries but got %v, %v\n", credentials, err)
+	}
+}
+
+// rewriteTransport sends all requests to the test server
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestAzureKeychain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metadata/identity/oauth2/token":
+			_, _ = w.Write([]byte(`{"access_token":"aad-token"}`))
+		case "/oauth2/exchange":
+			if r.FormValue("access_token") != "aad-token" || r.FormValue("service") != "team.azurecr.io" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"refresh_token":"acr-token"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	keychain := NewAzureKeychain(&http.Client{Transport: rewriteTransport{target: target}})
+
+	credentials, err := keychain.Resolve(context.Background(), "team.azurecr.io")
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if credentials != (Credentials{Username: acrUsername, Password: "acr-token"}) {
+		t.Fatalf("Expected the ACR refresh token but got %v\n", credentials)
+	}
+}
+
+func TestMultiKeychain(t *testing.T) {
+	dockerConfig, err := ParseDockerConfig([]byte(`{"auths":{"registry.example.com":{"username":"user","password":"secret"}}}`))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	pullSecrets := &PullSecretKeychain{}
+	pullSecret, err := ParseDockercfg([]byte(`{"https://other.example.com":{"username":"pod","password":"pull"}}`))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	pullSecrets.Set(map[string]*DockerConfigKeychain{"team-a/pull": pullSecret})
+	ctx := WithPullSecrets(context.Background(), []string{"team-a/pull"})
+
+	keychain := MultiKeychain{dockerConfig, pullSecrets, Anonymous}
+	for registry, expected := range map[string]Credentials{
+		"registry.example.com": {Username: "user", Password: "secret"},
+		"other.example.com":    {Username: "pod", Password: "pull"},
+		"docker.io":            {},
+	} {
+		credentials, err := keychain.Resolve(ctx, registry)
+		if err != nil {
+			t.Fatalf("Got an error=%v\n", err)
+		}
+		if credentials != expected {
+			t.Fatalf("Expected %v for %s but got %v\n", expected, registry, credentials)
+		}
+	}
+}
+
+func TestPullSecretKeychain(t *testing.T) {
+	teamA, err := ParseDockerConfig([]byte(`{"auths":{"registry.example.com":{"username":"team-a","password":"a"}}}`))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	teamB, err := ParseDockerConfig([]byte(`{"auths":{"registry.example.com":{"username":"team-b","password":"b"}}}`))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	keychain := &PullSecretKeychain{}
+	keychain.Set(map[string]*DockerConfigKeychain{"team-a/pull": teamA, "team-b/pull": teamB})
+
+	for secrets, expected := range map[string]Credentials{
+		"team-a/pull":             {Username: "team-a", Password: "a"},
+		"team-b/pull":             {Username: "team-b", Password: "b"},
+		"team-c/pull,team-b/pull": {Username: "team-b", Password: "b"},
+		"":                        {},
+	} {
+		credentials, err := keychain.Resolve(WithPullSecrets(context.Background(), strings.Split(secrets, ",")), "registry.example.com")
+		if err != nil {
+			t.Fatalf("Got an error=%v\n", err)
+		}
+		if credentials != expected {
+			t.Fatalf("Expected %v for %s but got %v\n", expected, secrets, credentials)
+		}
+	}
+}
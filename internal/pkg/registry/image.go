@@ -0,0 +1,176 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// manifestMediaTypes are accepted when fetching manifests, indexes are resolved to the manifest of defaultPlatform
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+const defaultPlatform = "linux/amd64"
+
+// maxDocumentSize limits manifests and image configs read into memory
+const maxDocumentSize = 4 << 20
+
+// ImageConfig contains the parts of the image config blob the collector records
+type ImageConfig struct {
+	Created time.Time
+	Labels  map[string]string
+}
+
+// Resolver fetches metadata from the registries of the images, it keeps one authenticated client per registry
+type Resolver struct {
+	httpClient *http.Client
+	keychain   Keychain
+	plainHttp  map[string]bool
+
+	mu      sync.Mutex
+	clients map[string]registryClient
+}
+
+// registryClient is a client with the credentials it was created with
+type registryClient struct {
+	credentials Credentials
+	client      *Client
+}
+
+// clientKey distinguishes the clients of a registry by credentials, the images of different imagePullSecrets are
+// pulled with different credentials
+func clientKey(registry string, credentials Credentials) string {
+	if credentials == (Credentials{}) {
+		return registry
+	}
+	return registry + "#" + credentials.Username
+}
+
+// NewResolver creates a resolver using the keychain for the credentials, the registries in plainHttp are accessed
+// without TLS
+func NewResolver(httpClient *http.Client, keychain Keychain, plainHttp []string) *Resolver {
+	r := &Resolver{httpClient: httpClient, keychain: keychain, plainHttp: map[string]bool{}, clients: map[string]registryClient{}}
+	for _, registry := range plainHttp {
+		r.plainHttp[registry] = true
+	}
+	return r
+}
+
+type imageManifest struct {
+	MediaType string `json:"mediaType"`
+	Config    *struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform *struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// ImageConfig fetches the manifest and the config blob of the image, for multi platform images the config of
+// linux/amd64 or else the first platform is returned
+func (r *Resolver) ImageConfig(ctx context.Context, ref Reference) (*ImageConfig, error) {
+	client, err := r.client(ctx, ref.Registry)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest imageManifest
+	if err = r.get(ctx, client, ref, "manifests/"+ref.Identifier(), strings.Join(manifestMediaTypes, ","), &manifest); err != nil {
+		return nil, err
+	}
+
+	if manifest.Config == nil && len(manifest.Manifests) > 0 {
+		digest := manifest.Manifests[0].Digest
+		for _, m := range manifest.Manifests {
+			if m.Platform != nil && m.Platform.OS+"/"+m.Platform.Architecture == defaultPlatform {
+				digest = m.Digest
+				break
+			}
+		}
+		manifest = imageManifest{}
+		if err = r.get(ctx, client, ref, "manifests/"+digest, strings.Join(manifestMediaTypes, ","), &manifest); err != nil {
+			return nil, err
+		}
+	}
+	if manifest.Config == nil || manifest.Config.Digest == "" {
+		return nil, fmt.Errorf("manifest of %s has no config", ref)
+	}
+
+	var config struct {
+		Created time.Time `json:"created"`
+		Config  struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"config"`
+	}
+	if err = r.get(ctx, client, ref, "blobs/"+manifest.Config.Digest, "", &config); err != nil {
+		return nil, err
+	}
+
+	return &ImageConfig{Created: config.Created, Labels: config.Config.Labels}, nil
+}
+
+// get decodes the JSON document at /v2/<repository>/<path>
+func (r *Resolver) get(ctx context.Context, client *Client, ref Reference, path, accept string, v any) error {
+	res, err := r.do(ctx, client, ref, http.MethodGet, path, accept)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if err = CheckStatus(res, http.StatusOK, "GET "+path+" of "+ref.String()); err != nil {
+		return err
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, maxDocumentSize)).Decode(v)
+}
+
+func (r *Resolver) scheme(registry string) string {
+	if r.plainHttp[registry] {
+		return "http"
+	}
+	return "https"
+}
+
+// client returns the client of the registry and user, it is recreated when the keychain returns another password,
+// e.g. after a short-lived cloud registry token was renewed
+func (r *Resolver) client(ctx context.Context, registry string) (*Client, error) {
+	credentials, err := r.keychain.Resolve(ctx, registry)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve credentials for %s: %w", registry, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := clientKey(registry, credentials)
+	if cached, ok := r.clients[key]; ok && cached.credentials == credentials {
+		return cached.client, nil
+	}
+	client := NewClient(r.httpClient, credentials.Username, credentials.Password)
+	r.clients[key] = registryClient{credentials: credentials, client: client}
+	return client, nil
+}
+
+// do sends a request to /v2/<repository>/<path>, the caller closes the body
+func (r *Resolver) do(ctx context.Context, client *Client, ref Reference, method, path, accept string) (*http.Response, error) {
+	url := fmt.Sprintf("%s://%s/v2/%s/%s", r.scheme(ref.Registry), ref.Host(), ref.Repository, path)
+	return client.Do(ctx, func() (*http.Request, error) {
+		request, err := http.NewRequest(method, url, nil)
+		if err == nil && accept != "" {
+			request.Header.Set("Accept", accept)
+		}
+		return request, err
+	})
+}
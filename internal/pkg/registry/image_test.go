@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImageConfig(t *testing.T) {
+	documents := map[string]string{
+		"/v2/team/app/manifests/1.0": `{"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[` +
+			`{"digest":"sha256:arm","platform":{"os":"linux","architecture":"arm64"}},` +
+			`{"digest":"sha256:amd","platform":{"os":"linux","architecture":"amd64"}}]}`,
+		"/v2/team/app/manifests/sha256:amd": `{"mediaType":"application/vnd.oci.image.manifest.v1+json","config":{"digest":"sha256:config"}}`,
+		"/v2/team/app/blobs/sha256:config":  `{"created":"2024-01-02T03:04:05Z","config":{"Labels":{"org.opencontainers.image.source":"https://github.com/team/app"}}}`,
+	}
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if strings.Contains(r.URL.Path, "/manifests/") && !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		document, ok := documents[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(document))
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	keychain, err := ParseDockerConfig([]byte(`{"auths":{"` + host + `":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("user:secret")) + `"}}}`))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	resolver := NewResolver(server.Client(), keychain, []string{host})
+
+	config, err := resolver.ImageConfig(context.Background(), Reference{Registry: host, Repository: "team/app", Tag: "1.0"})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if !config.Created.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("Expected the creation time of the config but got %s\n", config.Created)
+	}
+	if config.Labels["org.opencontainers.image.source"] != "https://github.com/team/app" {
+		t.Fatalf("Expected the source label but got %v\n", config.Labels)
+	}
+	if authorization != "Basic "+base64.StdEncoding.EncodeToString([]byte("user:secret")) {
+		t.Fatalf("Expected basic auth with the docker config credentials but got %s\n", authorization)
+	}
+
+	_, err = resolver.ImageConfig(context.Background(), Reference{Registry: host, Repository: "team/missing", Tag: "1.0"})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("Expected a not found error but got %v\n", err)
+	}
+}
+
+func TestDockerConfigKeychain(t *testing.T) {
+	keychain, err := ParseDockerConfig([]byte(`{"auths":{"https://index.docker.io/v1/":{"username":"user","password":"secret"}}}`))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	credentials, _ := keychain.Resolve(context.Background(), "docker.io")
+	if credentials != (Credentials{Username: "user", Password: "secret"}) {
+		t.Fatalf("Expected the Docker Hub credentials but got %+v\n", credentials)
+	}
+	credentials, _ = keychain.Resolve(context.Background(), "quay.io")
+	if credentials != (Credentials{}) {
+		t.Fatalf("Expected no credentials but got %+v\n", credentials)
+	}
+}
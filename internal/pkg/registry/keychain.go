@@ -0,0 +1,140 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Credentials authenticate at a registry, empty credentials pull anonymously
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Keychain returns the credentials for a registry, e.g. registry.example.com or docker.io
+type Keychain interface {
+	Resolve(ctx context.Context, registry string) (Credentials, error)
+}
+
+// Anonymous pulls without credentials
+var Anonymous Keychain = anonymous{}
+
+type anonymous struct{}
+
+func (anonymous) Resolve(ctx context.Context, registry string) (Credentials, error) {
+	return Credentials{}, nil
+}
+
+// dockerAuth is an entry of the auths of a docker config
+type dockerAuth struct {
+	Auth     string `json:"auth"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// dockerConfig is the format of ~/.docker/config.json and of kubernetes.io/dockerconfigjson secrets
+type dockerConfig struct {
+	Auths map[string]dockerAuth `json:"auths"`
+}
+
+// DockerConfigKeychain resolves the credentials from the auths of a docker config.json
+type DockerConfigKeychain struct {
+	auths map[string]Credentials
+}
+
+// NewDockerConfigKeychain reads the auths of a docker config.json file
+func NewDockerConfigKeychain(fileName string) (*DockerConfigKeychain, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDockerConfig(content)
+}
+
+// ParseDockerConfig parses the auths of a docker config.json
+func ParseDockerConfig(content []byte) (*DockerConfigKeychain, error) {
+	var cfg dockerConfig
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid docker config: %w", err)
+	}
+	return newDockerConfigKeychain(cfg.Auths)
+}
+
+// ParseDockercfg parses the legacy ~/.dockercfg format of kubernetes.io/dockercfg secrets, which contains the auths
+// at the top level
+func ParseDockercfg(content []byte) (*DockerConfigKeychain, error) {
+	var auths map[string]dockerAuth
+	if err := json.Unmarshal(content, &auths); err != nil {
+		return nil, fmt.Errorf("invalid dockercfg: %w", err)
+	}
+	return newDockerConfigKeychain(auths)
+}
+
+func newDockerConfigKeychain(auths map[string]dockerAuth) (*DockerConfigKeychain, error) {
+	keychain := &DockerConfigKeychain{auths: map[string]Credentials{}}
+	for server, auth := range auths {
+		credentials := Credentials{Username: auth.Username, Password: auth.Password}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auth of %s in docker config: %w", server, err)
+			}
+			credentials.Username, credentials.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		keychain.auths[normalizeRegistry(server)] = credentials
+	}
+	return keychain, nil
+}
+
+func (k *DockerConfigKeychain) Resolve(ctx context.Context, registry string) (Credentials, error) {
+	return k.auths[normalizeRegistry(registry)], nil
+}
+
+// normalizeRegistry strips the scheme and path of docker config keys like https://index.docker.io/v1/
+func normalizeRegistry(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	server, _, _ = strings.Cut(server, "/")
+	return NormalizeRegistry(server)
+}
+
+// pullSecretsKey is the context key of the imagePullSecrets of the image being resolved
+type pullSecretsKey struct{}
+
+// WithPullSecrets returns a context resolving the credentials of the PullSecretKeychain from the imagePullSecrets of
+// the image, in the format '<namespace>/<name>'
+func WithPullSecrets(ctx context.Context, secrets []string) context.Context {
+	return context.WithValue(ctx, pullSecretsKey{}, secrets)
+}
+
+// PullSecretKeychain resolves the credentials from the imagePullSecrets of the pods running the image, like the
+// kubelet the first secret with credentials for the registry is used. They are replaced on every run.
+type PullSecretKeychain struct {
+	mu      sync.RWMutex
+	secrets map[string]*DockerConfigKeychain
+}
+
+// Set replaces the credentials of the keychain by the parsed secrets by '<namespace>/<name>'
+func (k *PullSecretKeychain) Set(secrets map[string]*DockerConfigKeychain) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.secrets = secrets
+}
+
+func (k *PullSecretKeychain) Resolve(ctx context.Context, registry string) (Credentials, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	names, _ := ctx.Value(pullSecretsKey{}).([]string)
+	for _, name := range names {
+		if secret, ok := k.secrets[name]; ok {
+			if credentials, ok := secret.auths[normalizeRegistry(registry)]; ok {
+				return credentials, nil
+			}
+		}
+	}
+	return Credentials{}, nil
+}
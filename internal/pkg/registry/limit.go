@@ -0,0 +1,133 @@
+package registry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// AnyHost configures the rate limit of all hosts without a limit of their own
+const AnyHost = "*"
+
+// HostLimits bound the requests sent to every registry host, so the enrichment of large clusters is not throttled by
+// the registries
+type HostLimits struct {
+	// Concurrency is the maximum number of requests in flight per host, 0 is unlimited
+	Concurrency int
+	// RateLimits are the requests per second by host, AnyHost applies to the other hosts
+	RateLimits map[string]float64
+}
+
+// ParseRateLimits parses rate limits in the format '<registry>=<requests per second>', e.g. 'docker.io=10' or '*=50'
+func ParseRateLimits(values []string) (map[string]float64, error) {
+	limits := map[string]float64{}
+	for _, value := range values {
+		host, limit, ok := strings.Cut(value, "=")
+		if !ok || host == "" {
+			return nil, fmt.Errorf("invalid rate limit %s, expected '<registry>=<requests per second>'", value)
+		}
+		perSecond, err := strconv.ParseFloat(limit, 64)
+		if err != nil || perSecond <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %s, the requests per second must be a positive number", value)
+		}
+		// The images name Docker Hub, its API is served by another host
+		if host == dockerHub {
+			host = dockerHubHost
+		}
+		limits[host] = perSecond
+	}
+	return limits, nil
+}
+
+// LimitTransport applies the limits to the requests of base, a request waits until its host has capacity or the
+// context of the request is done
+func LimitTransport(base http.RoundTripper, limits HostLimits) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if limits.Concurrency <= 0 && len(limits.RateLimits) == 0 {
+		return base
+	}
+	return &limitTransport{base: base, limits: limits, hosts: map[string]*hostLimiter{}}
+}
+
+type limitTransport struct {
+	base   http.RoundTripper
+	limits HostLimits
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter
+}
+
+// hostLimiter limits a single host, nil fields are unlimited
+type hostLimiter struct {
+	inFlight chan struct{}
+	rate     *rate.Limiter
+}
+
+func (t *limitTransport) host(host string) *hostLimiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if limiter, ok := t.hosts[host]; ok {
+		return limiter
+	}
+	limiter := &hostLimiter{}
+	if t.limits.Concurrency > 0 {
+		limiter.inFlight = make(chan struct{}, t.limits.Concurrency)
+	}
+	perSecond, ok := t.limits.RateLimits[host]
+	if !ok {
+		perSecond, ok = t.limits.RateLimits[AnyHost]
+	}
+	if ok {
+		// A burst of one request spreads the requests evenly
+		limiter.rate = rate.NewLimiter(rate.Limit(perSecond), 1)
+	}
+	t.hosts[host] = limiter
+	return limiter
+}
+
+func (t *limitTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	limiter := t.host(request.URL.Host)
+	ctx := request.Context()
+
+	if limiter.rate != nil {
+		if err := limiter.rate.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if limiter.inFlight == nil {
+		return t.base.RoundTrip(request)
+	}
+
+	select {
+	case limiter.inFlight <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := sync.OnceFunc(func() { <-limiter.inFlight })
+	res, err := t.base.RoundTrip(request)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// The request is in flight until its body was read
+	res.Body = &releaseBody{ReadCloser: res.Body, release: release}
+	return res, nil
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
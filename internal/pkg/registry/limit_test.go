@@ -0,0 +1,105 @@
+package registry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits([]string{"docker.io=5", "registry.example.com:5000=0.5", "*=20"})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	expected := map[string]float64{dockerHubHost: 5, "registry.example.com:5000": 0.5, AnyHost: 20}
+	if len(limits) != len(expected) {
+		t.Fatalf("Expected %v but got %v\n", expected, limits)
+	}
+	for host, limit := range expected {
+		if limits[host] != limit {
+			t.Fatalf("Expected %v but got %v\n", expected, limits)
+		}
+	}
+
+	for _, value := range []string{"docker.io", "=5", "docker.io=fast", "docker.io=0", "docker.io=-1"} {
+		if _, err = ParseRateLimits([]string{value}); err == nil {
+			t.Fatalf("Expected an error for %s\n", value)
+		}
+	}
+}
+
+func TestLimitTransportConcurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			observed := maxInFlight.Load()
+			if current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: LimitTransport(nil, HostLimits{Concurrency: 2})}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Get(server.URL)
+			if err != nil {
+				t.Errorf("Got an error=%v\n", err)
+				return
+			}
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight.Load() > 2 {
+		t.Fatalf("Expected at most 2 requests in flight but got %d\n", maxInFlight.Load())
+	}
+}
+
+func TestLimitTransportRate(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	transport := LimitTransport(nil, HostLimits{RateLimits: map[string]float64{serverURL.Host: 1, AnyHost: 1000}})
+	client := &http.Client{Transport: transport}
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	res.Body.Close()
+
+	// The next request has to wait a second, longer than the context allows
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err = client.Do(request); err == nil {
+		t.Fatalf("Expected the request to be rate limited\n")
+	}
+	if requests.Load() != 1 {
+		t.Fatalf("Expected 1 request but got %d\n", requests.Load())
+	}
+
+	if transport := LimitTransport(http.DefaultTransport, HostLimits{}); transport != http.DefaultTransport {
+		t.Fatalf("Expected no limits to return the base transport\n")
+	}
+}
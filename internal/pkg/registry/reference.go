@@ -0,0 +1,103 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	dockerHub     = "docker.io"
+	dockerHubHost = "registry-1.docker.io"
+)
+
+// Reference is a parsed image reference like quay.io/team/app:1.0 or nginx@sha256:...
+type Reference struct {
+	// Registry is the host of the registry, docker.io for images without registry
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// SplitReference splits an image into registry, repository, tag and digest without applying any defaults. The
+// registry is only set if the first path component looks like a host, i.e. contains a '.' or ':' or is localhost.
+func SplitReference(image string) Reference {
+	var ref Reference
+
+	name, digest, hasDigest := strings.Cut(image, "@")
+	if hasDigest {
+		ref.Digest = digest
+	}
+
+	// A colon after the last slash separates the tag, a colon before is the port of the registry
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+
+	if first, rest, found := strings.Cut(name, "/"); found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry, name = first, rest
+	}
+	ref.Repository = name
+	return ref
+}
+
+// ParseReference parses an image name as used in pod specs and container statuses with the defaults of the container
+// runtimes: images without registry are pulled from docker.io, official images from its library/ repositories and
+// images without tag and digest with the tag latest. The registry is normalized, see NormalizeRegistry.
+func ParseReference(image string) (Reference, error) {
+	ref := SplitReference(image)
+	if ref.Digest != "" && !strings.Contains(ref.Digest, ":") {
+		return ref, fmt.Errorf("invalid digest in image reference %s", image)
+	}
+
+	ref.Registry = NormalizeRegistry(ref.Registry)
+	if ref.Registry == dockerHub && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+
+	if ref.Repository == "" || strings.HasSuffix(ref.Repository, "/") {
+		return ref, fmt.Errorf("invalid image reference %s", image)
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref, nil
+}
+
+// NormalizeRegistry returns docker.io for an empty registry and the hosts of Docker Hub, the default port 443 is
+// stripped, so the same registry is named the same regardless of how images reference it
+func NormalizeRegistry(registry string) string {
+	registry = strings.TrimSuffix(registry, ":443")
+	switch registry {
+	case "", "index.docker.io", dockerHubHost:
+		return dockerHub
+	}
+	return registry
+}
+
+// Host returns the host serving the registry API
+func (r Reference) Host() string {
+	if r.Registry == dockerHub {
+		return dockerHubHost
+	}
+	return r.Registry
+}
+
+// Identifier returns the digest if known, the tag otherwise
+func (r Reference) Identifier() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
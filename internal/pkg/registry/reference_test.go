@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	testCases := []struct {
+		image    string
+		expected Reference
+	}{
+		{image: "nginx", expected: Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{image: "bitnami/redis:7", expected: Reference{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7"}},
+		{image: "quay.io/team/app:1.0", expected: Reference{Registry: "quay.io", Repository: "team/app", Tag: "1.0"}},
+		{image: "localhost:5000/app", expected: Reference{Registry: "localhost:5000", Repository: "app", Tag: "latest"}},
+		{image: "registry.example.com/app@sha256:abc", expected: Reference{Registry: "registry.example.com", Repository: "app", Digest: "sha256:abc"}},
+		{image: "docker.io/library/nginx:1.25@sha256:abc", expected: Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25", Digest: "sha256:abc"}},
+		{image: "index.docker.io/nginx:1.25", expected: Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25"}},
+		{image: "registry-1.docker.io/bitnami/redis", expected: Reference{Registry: "docker.io", Repository: "bitnami/redis", Tag: "latest"}},
+		{image: "registry.example.com:443/app:1", expected: Reference{Registry: "registry.example.com", Repository: "app", Tag: "1"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.image, func(t *testing.T) {
+			ref, err := ParseReference(tc.image)
+			if err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+			if ref != tc.expected {
+				t.Fatalf("Expected %+v but got %+v\n", tc.expected, ref)
+			}
+		})
+	}
+
+	for _, image := range []string{"quay.io/", "app@abc"} {
+		if _, err := ParseReference(image); err == nil {
+			t.Fatalf("Expected an error for %s but got none\n", image)
+		}
+	}
+
+	ref, _ := ParseReference("nginx")
+	if ref.Host() != "registry-1.docker.io" || ref.Identifier() != "latest" {
+		t.Fatalf("Expected Docker Hub host and tag latest but got %s %s\n", ref.Host(), ref.Identifier())
+	}
+}
+
+func TestSplitReference(t *testing.T) {
+	testCases := map[string]Reference{
+		"nginx":                                {Repository: "nginx"},
+		"bitnami/redis:7":                      {Repository: "bitnami/redis", Tag: "7"},
+		"localhost/app":                        {Registry: "localhost", Repository: "app"},
+		"registry.example.com:443/team/app:1":  {Registry: "registry.example.com:443", Repository: "team/app", Tag: "1"},
+		"quay.io/team/app:1.0@sha256:abc":      {Registry: "quay.io", Repository: "team/app", Tag: "1.0", Digest: "sha256:abc"},
+		"index.docker.io/library/nginx@sha256": {Registry: "index.docker.io", Repository: "library/nginx", Digest: "sha256"},
+	}
+	for image, expected := range testCases {
+		if ref := SplitReference(image); ref != expected {
+			t.Fatalf("Expected %+v for %s but got %+v\n", expected, image, ref)
+		}
+	}
+}
+
+func TestNormalizeRegistry(t *testing.T) {
+	testCases := map[string]string{
+		"":                          "docker.io",
+		"docker.io":                 "docker.io",
+		"index.docker.io":           "docker.io",
+		"registry-1.docker.io":      "docker.io",
+		"registry.example.com:443":  "registry.example.com",
+		"registry.example.com:5000": "registry.example.com:5000",
+	}
+	for registry, expected := range testCases {
+		if normalized := NormalizeRegistry(registry); normalized != expected {
+			t.Fatalf("Expected %s for %q but got %s\n", expected, registry, normalized)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	m := metrics.New()
+	handler := New(&ServerConfig{}, m).Handler
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusOK, get("/healthz").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/readyz").Code)
+
+	m.ObserveRun(time.Now(), 1, nil)
+	assert.Equal(t, http.StatusOK, get("/readyz").Code)
+
+	res := get("/metrics")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.True(t, strings.HasPrefix(res.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+	assert.Contains(t, res.Body.String(), "collector_images 1\n")
+}
+
+func TestServerPprof(t *testing.T) {
+	get := func(cfg *ServerConfig, path string) int {
+		recorder := httptest.NewRecorder()
+		New(cfg, metrics.New()).Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, get(&ServerConfig{}, "/debug/pprof/"))
+	assert.Equal(t, http.StatusOK, get(&ServerConfig{Pprof: true}, "/debug/pprof/"))
+	assert.Equal(t, http.StatusOK, get(&ServerConfig{Pprof: true}, "/debug/pprof/heap"))
+}
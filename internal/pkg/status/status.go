@@ -0,0 +1,181 @@
+// Package status publishes the outcome of every run as Kubernetes Event and ConfigMap in the namespace of the
+// collector, so operators see its health with kubectl and alerting that watches events
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Reasons of the events
+const (
+	ReasonSucceeded = "CollectionSucceeded"
+	ReasonFailed    = "CollectionFailed"
+)
+
+// Keys of the status ConfigMap, lastSuccess is kept from the previous status after a failed run
+const (
+	keyResult      = "result"
+	keyLastRun     = "lastRun"
+	keyLastSuccess = "lastSuccess"
+	keyDuration    = "duration"
+	keyImages      = "images"
+	keySkipped     = "skipped"
+	keyErrors      = "errors"
+	keyError       = "error"
+)
+
+type StatusConfig struct {
+	// StatusEvents creates an Event for every run
+	StatusEvents bool
+	// StatusConfigMap is the name of a ConfigMap updated with the outcome of the last run, disabled if empty
+	StatusConfigMap string
+	// StatusNamespace of the events and the ConfigMap, defaults to the namespace of the pod
+	StatusNamespace string
+}
+
+// Enabled reports whether events or the ConfigMap are published
+func (cfg *StatusConfig) Enabled() bool {
+	return cfg.StatusEvents || cfg.StatusConfigMap != ""
+}
+
+// Outcome summarizes a run, Errors counts the failed namespaces and enrichments of a partial report
+type Outcome struct {
+	Start    time.Time
+	Duration time.Duration
+	Images   int
+	Skipped  int
+	Errors   int
+	Err      error
+}
+
+// Reporter publishes the outcomes, the events refer to the pod of the collector
+type Reporter struct {
+	clientset kubernetes.Interface
+	cfg       StatusConfig
+	namespace string
+	pod       string
+	component string
+}
+
+// NewReporter resolves the namespace and the pod of the collector, component is the source of the events
+func NewReporter(clientset kubernetes.Interface, cfg *StatusConfig, component string) (*Reporter, error) {
+	namespace, err := kubeclient.PodNamespace(cfg.StatusNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine the namespace of the status, set --status-namespace: %w", err)
+	}
+
+	pod := os.Getenv("POD_NAME")
+	if pod == "" {
+		if pod, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("could not determine the pod of the status events: %w", err)
+		}
+	}
+	return &Reporter{clientset: clientset, cfg: *cfg, namespace: namespace, pod: pod, component: component}, nil
+}
+
+// Report creates the event and updates the ConfigMap, both are attempted even if one fails
+func (r *Reporter) Report(ctx context.Context, outcome Outcome) error {
+	var errs []error
+	if r.cfg.StatusEvents {
+		if err := r.createEvent(ctx, outcome); err != nil {
+			errs = append(errs, fmt.Errorf("could not create status event: %w", err))
+		}
+	}
+	if r.cfg.StatusConfigMap != "" {
+		if err := r.updateConfigMap(ctx, outcome); err != nil {
+			errs = append(errs, fmt.Errorf("could not update status ConfigMap %s/%s: %w", r.namespace, r.cfg.StatusConfigMap, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Reporter) createEvent(ctx context.Context, outcome Outcome) error {
+	eventType, reason := corev1.EventTypeNormal, ReasonSucceeded
+	if outcome.Err != nil {
+		eventType, reason = corev1.EventTypeWarning, ReasonFailed
+	}
+
+	now := metav1.NewTime(outcome.Start.Add(outcome.Duration))
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			// Events are named like the ones of client-go, the suffix keeps them unique per run
+			Name:      fmt.Sprintf("%s.%x", r.pod, now.UnixNano()),
+			Namespace: r.namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", APIVersion: "v1", Name: r.pod, Namespace: r.namespace},
+		Reason:         reason,
+		Message:        message(outcome),
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: r.component},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	_, err := r.clientset.CoreV1().Events(r.namespace).Create(ctx, event, metav1.CreateOptions{})
+	return err
+}
+
+func (r *Reporter) updateConfigMap(ctx context.Context, outcome Outcome) error {
+	configMaps := r.clientset.CoreV1().ConfigMaps(r.namespace)
+	configMap, err := configMaps.Get(ctx, r.cfg.StatusConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: r.cfg.StatusConfigMap, Namespace: r.namespace}}
+		configMap.Data = statusData(nil, outcome)
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	configMap.Data = statusData(configMap.Data, outcome)
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	return err
+}
+
+// statusData returns the ConfigMap data of the outcome, the last success is taken from the previous data after a
+// failed run
+func statusData(previous map[string]string, outcome Outcome) map[string]string {
+	finished := outcome.Start.Add(outcome.Duration).UTC().Format(time.RFC3339)
+	data := map[string]string{
+		keyResult:   "success",
+		keyLastRun:  finished,
+		keyDuration: outcome.Duration.Round(time.Millisecond).String(),
+		keyImages:   strconv.Itoa(outcome.Images),
+		keySkipped:  strconv.Itoa(outcome.Skipped),
+		keyErrors:   strconv.Itoa(outcome.Errors),
+	}
+	if outcome.Err != nil {
+		data[keyResult] = "failure"
+		data[keyError] = outcome.Err.Error()
+		if lastSuccess := previous[keyLastSuccess]; lastSuccess != "" {
+			data[keyLastSuccess] = lastSuccess
+		}
+	} else {
+		data[keyLastSuccess] = finished
+	}
+	return data
+}
+
+func message(outcome Outcome) string {
+	duration := outcome.Duration.Round(time.Millisecond)
+	if outcome.Err != nil {
+		return fmt.Sprintf("Collection failed after %s: %s", duration, outcome.Err)
+	}
+	msg := fmt.Sprintf("Collected %d images, %d skipped, in %s", outcome.Images, outcome.Skipped, duration)
+	if outcome.Errors > 0 {
+		msg += fmt.Sprintf(", %d namespaces or enrichments failed", outcome.Errors)
+	}
+	return msg
+}
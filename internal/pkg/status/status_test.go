@@ -0,0 +1,57 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReport(t *testing.T) {
+	t.Setenv("POD_NAME", "collector-abc")
+	clientset := testclient.NewSimpleClientset()
+	reporter, err := NewReporter(clientset, &StatusConfig{StatusEvents: true, StatusConfigMap: "collector-status", StatusNamespace: "scanner"}, "image-metadata-collector")
+	assert.NoError(t, err)
+	ctx := context.Background()
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	err = reporter.Report(ctx, Outcome{Start: start, Duration: 2 * time.Second, Images: 10, Skipped: 3})
+	assert.NoError(t, err)
+
+	configMap, err := clientset.CoreV1().ConfigMaps("scanner").Get(ctx, "collector-status", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "success", configMap.Data["result"])
+	assert.Equal(t, "2025-01-02T03:04:07Z", configMap.Data["lastSuccess"])
+	assert.Equal(t, "10", configMap.Data["images"])
+	assert.Equal(t, "3", configMap.Data["skipped"])
+
+	err = reporter.Report(ctx, Outcome{Start: start.Add(time.Hour), Duration: time.Second, Err: errors.New("forbidden")})
+	assert.NoError(t, err)
+
+	configMap, err = clientset.CoreV1().ConfigMaps("scanner").Get(ctx, "collector-status", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "failure", configMap.Data["result"])
+	assert.Equal(t, "forbidden", configMap.Data["error"])
+	assert.Equal(t, "2025-01-02T04:04:06Z", configMap.Data["lastRun"])
+	assert.Equal(t, "2025-01-02T03:04:07Z", configMap.Data["lastSuccess"], "the last success is kept after a failure")
+
+	events, err := clientset.CoreV1().Events("scanner").List(ctx, metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, events.Items, 2)
+	reasons := map[string]string{}
+	for _, event := range events.Items {
+		reasons[event.Reason] = event.Type
+		assert.Equal(t, "collector-abc", event.InvolvedObject.Name)
+	}
+	assert.Equal(t, map[string]string{ReasonSucceeded: corev1.EventTypeNormal, ReasonFailed: corev1.EventTypeWarning}, reasons)
+}
+
+func TestMessage(t *testing.T) {
+	assert.Equal(t, "Collected 5 images, 1 skipped, in 1.5s, 2 namespaces or enrichments failed", message(Outcome{Duration: 1500 * time.Millisecond, Images: 5, Skipped: 1, Errors: 2}))
+	assert.Equal(t, "Collection failed after 1s: timeout", message(Outcome{Duration: time.Second, Err: errors.New("timeout")}))
+}
@@ -2,48 +2,289 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
 	"github.com/rs/zerolog/log"
+	"net"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 )
 
 type ApiConfig struct {
-	ApiKey       string
-	ApiSignature string
-	ApiEndpoint  string
+	ApiKey           string
+	ApiKeyFile       string
+	ApiSignature     string
+	ApiSignatureFile string
+	// ApiEndpoint may contain the placeholders $ACCOUNT, $CLUSTER and $ENVIRONMENT or environment variables
+	ApiEndpoint string
+	ApiAccount  string
+	ApiCluster  string
+
+	ApiOAuthTokenUrl         string
+	ApiOAuthClientId         string
+	ApiOAuthClientSecret     string
+	ApiOAuthClientSecretFile string
+	ApiOAuthScopes           []string
+
+	ApiClientCertFile string
+	ApiClientKeyFile  string
+	ApiCaFile         string
+
+	ApiTimeout     time.Duration
+	ApiDialTimeout time.Duration
+
+	ApiMethod             string
+	ApiSuccessStatusCodes []int
+
+	ApiRequestsPerSecond float64
+	ApiRetryAttempts     int
 }
 
-// Write content to API Endpoint added to config
-func (api ApiConfig) Write(content []byte) (int, error) {
-	client := &http.Client{}
+type api struct {
+	endpoint      string
+	account       string
+	cluster       string
+	method        string
+	successCodes  []int
+	key           string
+	signature     string
+	client        *http.Client
+	tokens        *tokenSource
+	limiter       *rateLimiter
+	retryAttempts int
+}
 
-	request, err := http.NewRequest(http.MethodPut, api.ApiEndpoint, bytes.NewBuffer(content))
+// NewApi creates a new api storage for the endpoint given in the config
+func NewApi(cfg *ApiConfig) (*api, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig, err := newTLSConfig(cfg, transport.TLSClientConfig)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	if cfg.ApiDialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   cfg.ApiDialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+		transport.TLSHandshakeTimeout = cfg.ApiDialTimeout
+	}
+
+	client := &http.Client{
+		Transport: version.Transport(transport),
+		Timeout:   cfg.ApiTimeout,
 	}
 
-	hashedKey := sha256.Sum256([]byte(api.ApiKey))
-	hashedKeyStr := hex.EncodeToString(hashedKey[:])
-	log.Debug().Str("ApiKeySha256", hashedKeyStr).Msgf("ApiKey sha256")
-	log.Debug().Msgf("ApiSignature: %s", api.ApiSignature)
+	method := strings.ToUpper(cfg.ApiMethod)
+	if method == "" {
+		method = http.MethodPut
+	}
+	if method != http.MethodPut && method != http.MethodPost && method != http.MethodPatch {
+		return nil, fmt.Errorf("API method %s is not supported", cfg.ApiMethod)
+	}
 
-	request.Header.Set("x-api-key", api.ApiKey)
-	request.Header.Set("x-api-signature", api.ApiSignature)
-	request.Header.Set("Content-Type", "application/json")
+	successCodes := cfg.ApiSuccessStatusCodes
+	if len(successCodes) == 0 {
+		successCodes = []int{http.StatusOK}
+	}
 
-	res, err := client.Do(request)
+	a := &api{
+		endpoint:     cfg.ApiEndpoint,
+		account:      cfg.ApiAccount,
+		cluster:      cfg.ApiCluster,
+		method:       method,
+		successCodes: successCodes,
+		key:          cfg.ApiKey,
+		signature:    cfg.ApiSignature,
+		client:       client,
 
+		limiter:       newRateLimiter(cfg.ApiRequestsPerSecond),
+		retryAttempts: cfg.ApiRetryAttempts,
+	}
+
+	if cfg.ApiOAuthTokenUrl != "" {
+		if cfg.ApiOAuthClientId == "" || cfg.ApiOAuthClientSecret == "" {
+			return nil, fmt.Errorf("OAuth2 client id and secret are required when an OAuth2 token url is set")
+		}
+		a.tokens = newTokenSource(cfg, client)
+	}
+
+	return a, nil
+}
+
+// Store sends the report to the API Endpoint added to config, all requests are bound to ctx
+func (api *api) Store(ctx context.Context, report backend.Report) (backend.StoreResult, error) {
+	if api.key != "" {
+		hashedKey := sha256.Sum256([]byte(api.key))
+		hashedKeyStr := hex.EncodeToString(hashedKey[:])
+		log.Debug().Str("ApiKeySha256", hashedKeyStr).Msgf("ApiKey sha256")
+	}
+	log.Debug().Msgf("ApiSignature: %s", api.signature)
+
+	endpoint, err := api.endpointFor(report.Environment)
+	if err != nil {
+		return backend.StoreResult{}, err
+	}
+
+	res, err := api.do(ctx, endpoint, &report)
 	if err != nil {
 		log.Error().Msgf("Error sending request: %s", err)
-		return 0, err
+		return backend.StoreResult{}, err
+	}
+	defer res.Body.Close()
+
+	if !slices.Contains(api.successCodes, res.StatusCode) {
+		statusError := newStatusError(res, api.successCodes)
+		log.Error().Str("requestId", statusError.RequestId).Str("body", statusError.Body).Msgf("Error sending request, got StatusCode: %s", res.Status)
+		return backend.StoreResult{}, statusError
+	}
+
+	if report.Checksum != "" {
+		if err := verifyChecksum(res, report.Checksum); err != nil {
+			log.Error().Err(err).Msg("Checksum verification failed")
+			return backend.StoreResult{}, err
+		}
+	}
+
+	result := backend.StoreResult{
+		Location: endpoint,
+		Size:     len(report.Content),
+		Metadata: map[string]string{"status": res.Status},
+	}
+	for _, header := range requestIdHeaders {
+		if value := res.Header.Get(header); value != "" {
+			result.Metadata["requestId"] = value
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// Check fetches an OAuth2 token if configured and sends a HEAD request to the endpoint, only a rejected
+// authentication or a connection error fails the check as the endpoint may not support HEAD requests
+func (api *api) Check(ctx context.Context) error {
+	endpoint, err := api.endpointFor("")
+	if err != nil {
+		// $ENVIRONMENT is only known for a report, the credentials can be checked anyway
+		log.Debug().Err(err).Msg("Skipping the API connectivity check")
+		if api.tokens != nil {
+			if _, err = api.tokens.Token(ctx); err != nil {
+				return fmt.Errorf("could not get OAuth2 token: %w", err)
+			}
+		}
+		return nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if err = api.authorize(ctx, request); err != nil {
+		return err
+	}
+
+	res, err := api.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("could not connect to API: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return newStatusError(res, api.successCodes)
+	}
+
+	return nil
+}
+
+// do sends the content, refreshes a rejected OAuth2 token once and retries throttled requests after the requested delay
+func (api *api) do(ctx context.Context, endpoint string, report *backend.Report) (*http.Response, error) {
+	tokenRefreshed := false
+	throttled := 0
+
+	for {
+		if err := api.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		res, err := api.send(ctx, endpoint, report)
+		if err != nil {
+			return nil, err
+		}
+
+		// The cached token may have been revoked before its expiry, fetch a new one and retry once
+		if res.StatusCode == http.StatusUnauthorized && api.tokens != nil && !tokenRefreshed {
+			res.Body.Close()
+			log.Info().Msg("API rejected the OAuth2 token, refreshing token and retrying")
+			api.tokens.Invalidate()
+			tokenRefreshed = true
+			continue
+		}
+
+		if isThrottled(res) && throttled < api.retryAttempts {
+			wait := retryAfter(res)
+			res.Body.Close()
+			throttled++
+			log.Warn().Str("status", res.Status).Dur("retryAfter", wait).Int("attempt", throttled).Msg("API throttled the request, retrying")
+			if err := sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		return res, nil
+	}
+}
+
+func (api *api) send(ctx context.Context, endpoint string, report *backend.Report) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, api.method, endpoint, bytes.NewBuffer(report.Content))
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := report.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	request.Header.Set("Content-Type", contentType)
+	if report.ContentEncoding != "" {
+		request.Header.Set("Content-Encoding", report.ContentEncoding)
+	}
+	if report.Checksum != "" {
+		setChecksumHeaders(request, report.Checksum)
+	}
+
+	if err = api.authorize(ctx, request); err != nil {
+		return nil, err
+	}
+
+	return api.client.Do(request)
+}
+
+// authorize adds the API key, signature and OAuth2 token headers to the request
+func (api *api) authorize(ctx context.Context, request *http.Request) error {
+	if api.key != "" {
+		request.Header.Set("x-api-key", api.key)
+	}
+	if api.signature != "" {
+		request.Header.Set("x-api-signature", api.signature)
 	}
 
-	if res.StatusCode != 200 {
-		log.Error().Msgf("Error sending request, got StatusCode: %s", res.Status)
-		return 0, fmt.Errorf("Got a Status '%s' instead of an '200 OK' response for API request", res.Status)
+	if api.tokens != nil {
+		token, err := api.tokens.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("could not get OAuth2 token: %w", err)
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
 	}
 
-	return len(content), nil
+	return nil
 }
@@ -0,0 +1,474 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+)
+
+func TestWriteWithOAuth(t *testing.T) {
+	testCases := []struct {
+		name                string
+		rejectFirstToken    bool
+		expectedTokenCalls  int
+		expectedUploadCalls int
+		expectSuccess       bool
+	}{
+		{
+			name:                "ValidTokenExpectSingleUpload",
+			rejectFirstToken:    false,
+			expectedTokenCalls:  1,
+			expectedUploadCalls: 1,
+			expectSuccess:       true,
+		},
+		{
+			name:                "RejectedTokenExpectRefreshAndRetry",
+			rejectFirstToken:    true,
+			expectedTokenCalls:  2,
+			expectedUploadCalls: 2,
+			expectSuccess:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenCalls := 0
+			uploadCalls := 0
+
+			tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tokenCalls++
+				if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "a b" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				_ = json.NewEncoder(w).Encode(oauthTokenResponse{AccessToken: fmt.Sprintf("token-%d", tokenCalls), ExpiresIn: 3600})
+			}))
+			defer tokenServer.Close()
+
+			apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				uploadCalls++
+				if tc.rejectFirstToken && r.Header.Get("Authorization") == "Bearer token-1" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer apiServer.Close()
+
+			api, err := NewApi(&ApiConfig{
+				ApiEndpoint:          apiServer.URL,
+				ApiOAuthTokenUrl:     tokenServer.URL,
+				ApiOAuthClientId:     "client",
+				ApiOAuthClientSecret: "secret",
+				ApiOAuthScopes:       []string{"a", "b"},
+			})
+			if err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+
+			_, err = api.Store(context.Background(), backend.Report{Content: []byte("[]")})
+			if tc.expectSuccess && err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			} else if !tc.expectSuccess && err == nil {
+				t.Fatalf("Expected an error but got none\n")
+			}
+
+			if tokenCalls != tc.expectedTokenCalls {
+				t.Fatalf("Expected %d token requests but got %d\n", tc.expectedTokenCalls, tokenCalls)
+			}
+			if uploadCalls != tc.expectedUploadCalls {
+				t.Fatalf("Expected %d upload requests but got %d\n", tc.expectedUploadCalls, uploadCalls)
+			}
+		})
+	}
+}
+
+func TestShortLivedToken(t *testing.T) {
+	testCases := []struct {
+		name               string
+		expiresIn          int64
+		wait               time.Duration
+		expectedTokenCalls int
+	}{
+		{name: "ExpiresWithinDeltaExpectReuse", expiresIn: 20, expectedTokenCalls: 1},
+		{name: "ExpiresInOneSecondExpectReuse", expiresIn: 1, expectedTokenCalls: 1},
+		{name: "HalfLifetimePassedExpectRefresh", expiresIn: 1, wait: 600 * time.Millisecond, expectedTokenCalls: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenCalls := 0
+			tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tokenCalls++
+				_ = json.NewEncoder(w).Encode(oauthTokenResponse{AccessToken: fmt.Sprintf("token-%d", tokenCalls), ExpiresIn: tc.expiresIn})
+			}))
+			defer tokenServer.Close()
+
+			ts := newTokenSource(&ApiConfig{ApiOAuthTokenUrl: tokenServer.URL, ApiOAuthClientId: "client", ApiOAuthClientSecret: "secret"}, tokenServer.Client())
+			if _, err := ts.Token(context.Background()); err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+			time.Sleep(tc.wait)
+			if _, err := ts.Token(context.Background()); err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+
+			if tokenCalls != tc.expectedTokenCalls {
+				t.Fatalf("Expected %d token requests but got %d\n", tc.expectedTokenCalls, tokenCalls)
+			}
+		})
+	}
+}
+
+func TestNewApiMissingOAuthCredentials(t *testing.T) {
+	_, err := NewApi(&ApiConfig{ApiEndpoint: "http://localhost", ApiOAuthTokenUrl: "http://localhost/token"})
+	if err == nil {
+		t.Fatalf("Expected an error but got none\n")
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		cfg           ApiConfig
+		expectConfig  bool
+		expectSuccess bool
+	}{
+		{
+			name:          "NothingSetExpectDefaultTransport",
+			cfg:           ApiConfig{},
+			expectConfig:  false,
+			expectSuccess: true,
+		},
+		{
+			name:          "CertWithoutKeyExpectError",
+			cfg:           ApiConfig{ApiClientCertFile: "client.pem"},
+			expectConfig:  false,
+			expectSuccess: false,
+		},
+		{
+			name:          "MissingCaFileExpectError",
+			cfg:           ApiConfig{ApiCaFile: "does-not-exist.pem"},
+			expectConfig:  false,
+			expectSuccess: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tlsConfig, err := newTLSConfig(&tc.cfg, nil)
+			if tc.expectSuccess && err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			} else if !tc.expectSuccess && err == nil {
+				t.Fatalf("Expected an error but got none\n")
+			}
+
+			if tc.expectConfig != (tlsConfig != nil) {
+				t.Fatalf("Expected tls config to be set=%v but got %v\n", tc.expectConfig, tlsConfig)
+			}
+		})
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiServer.Close()
+
+	api, err := NewApi(&ApiConfig{ApiEndpoint: apiServer.URL, ApiTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	if _, err = api.Store(context.Background(), backend.Report{Content: []byte("[]")}); err == nil {
+		t.Fatalf("Expected a timeout error but got none\n")
+	}
+}
+
+func TestWriteMethodAndStatusCodes(t *testing.T) {
+	testCases := []struct {
+		name          string
+		method        string
+		successCodes  []int
+		responseCode  int
+		expectSuccess bool
+	}{
+		{
+			name:          "DefaultsExpectPutAnd200",
+			method:        "",
+			successCodes:  nil,
+			responseCode:  http.StatusOK,
+			expectSuccess: true,
+		},
+		{
+			name:          "DefaultsWith201ExpectError",
+			method:        "",
+			successCodes:  nil,
+			responseCode:  http.StatusCreated,
+			expectSuccess: false,
+		},
+		{
+			name:          "PostWithAccepted202ExpectSuccess",
+			method:        "post",
+			successCodes:  []int{201, 202},
+			responseCode:  http.StatusAccepted,
+			expectSuccess: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expectedMethod := strings.ToUpper(tc.method)
+			if expectedMethod == "" {
+				expectedMethod = http.MethodPut
+			}
+
+			apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != expectedMethod {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				w.WriteHeader(tc.responseCode)
+			}))
+			defer apiServer.Close()
+
+			api, err := NewApi(&ApiConfig{ApiEndpoint: apiServer.URL, ApiMethod: tc.method, ApiSuccessStatusCodes: tc.successCodes})
+			if err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+
+			_, err = api.Store(context.Background(), backend.Report{Content: []byte("[]")})
+			if tc.expectSuccess && err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			} else if !tc.expectSuccess && err == nil {
+				t.Fatalf("Expected an error but got none\n")
+			}
+		})
+	}
+}
+
+func TestWriteErrorContainsBodyAndRequestId(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid report"}` + strings.Repeat("x", 2*maxErrorBodySize)))
+	}))
+	defer apiServer.Close()
+
+	api, err := NewApi(&ApiConfig{ApiEndpoint: apiServer.URL})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	_, err = api.Store(context.Background(), backend.Report{Content: []byte("[]")})
+	statusError, ok := err.(*StatusError)
+	if !ok {
+		t.Fatalf("Expected a StatusError but got %v\n", err)
+	}
+
+	if statusError.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d but got %d\n", http.StatusBadRequest, statusError.StatusCode)
+	}
+	if statusError.RequestId != "req-123" {
+		t.Fatalf("Expected request id req-123 but got %s\n", statusError.RequestId)
+	}
+	if !strings.HasPrefix(statusError.Body, `{"error":"invalid report"}`) || !strings.HasSuffix(statusError.Body, "...(truncated)") {
+		t.Fatalf("Expected truncated body but got %s\n", statusError.Body)
+	}
+}
+
+func TestWriteRetryAfterThrottling(t *testing.T) {
+	testCases := []struct {
+		name          string
+		throttleCount int
+		retryAttempts int
+		expectedCalls int
+		expectSuccess bool
+	}{
+		{
+			name:          "ThrottledOnceExpectRetry",
+			throttleCount: 1,
+			retryAttempts: 3,
+			expectedCalls: 2,
+			expectSuccess: true,
+		},
+		{
+			name:          "ThrottledMoreThanAttemptsExpectError",
+			throttleCount: 5,
+			retryAttempts: 2,
+			expectedCalls: 3,
+			expectSuccess: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls <= tc.throttleCount {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer apiServer.Close()
+
+			api, err := NewApi(&ApiConfig{ApiEndpoint: apiServer.URL, ApiRetryAttempts: tc.retryAttempts, ApiRequestsPerSecond: 100})
+			if err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+
+			_, err = api.Store(context.Background(), backend.Report{Content: []byte("[]")})
+			if tc.expectSuccess && err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			} else if !tc.expectSuccess && err == nil {
+				t.Fatalf("Expected an error but got none\n")
+			}
+
+			if calls != tc.expectedCalls {
+				t.Fatalf("Expected %d requests but got %d\n", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestWriteChecksumVerification(t *testing.T) {
+	content := []byte("[]")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	testCases := []struct {
+		name             string
+		receivedChecksum string
+		expectSuccess    bool
+	}{
+		{
+			name:             "NoChecksumInResponseExpectSuccess",
+			receivedChecksum: "",
+			expectSuccess:    true,
+		},
+		{
+			name:             "MatchingChecksumExpectSuccess",
+			receivedChecksum: checksum,
+			expectSuccess:    true,
+		},
+		{
+			name:             "MismatchingChecksumExpectError",
+			receivedChecksum: strings.Repeat("0", 64),
+			expectSuccess:    false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get(checksumHeader) != checksum {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if tc.receivedChecksum != "" {
+					w.Header().Set(checksumHeader, tc.receivedChecksum)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer apiServer.Close()
+
+			api, err := NewApi(&ApiConfig{ApiEndpoint: apiServer.URL})
+			if err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+
+			_, err = api.Store(context.Background(), backend.Report{Content: content, Checksum: checksum})
+			if tc.expectSuccess && err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			} else if !tc.expectSuccess && err == nil {
+				t.Fatalf("Expected an error but got none\n")
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		name          string
+		responseCode  int
+		expectSuccess bool
+	}{
+		{
+			name:          "HeadNotAllowedExpectSuccess",
+			responseCode:  http.StatusMethodNotAllowed,
+			expectSuccess: true,
+		},
+		{
+			name:          "ForbiddenExpectError",
+			responseCode:  http.StatusForbidden,
+			expectSuccess: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.responseCode)
+			}))
+			defer apiServer.Close()
+
+			api, err := NewApi(&ApiConfig{ApiEndpoint: apiServer.URL, ApiKey: "key"})
+			if err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+
+			err = api.Check(context.Background())
+			if tc.expectSuccess && err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			} else if !tc.expectSuccess && err == nil {
+				t.Fatalf("Expected an error but got none\n")
+			}
+		})
+	}
+}
+
+func TestWriteResolvesEndpointPlaceholders(t *testing.T) {
+	var path string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiServer.Close()
+
+	t.Setenv("COLLECTOR_TEST_TENANT", "tenant")
+	endpoint := apiServer.URL + "/v1/account/$ACCOUNT/cluster/${CLUSTER}/$ENVIRONMENT/$COLLECTOR_TEST_TENANT"
+	api, err := NewApi(&ApiConfig{ApiEndpoint: endpoint, ApiAccount: "team a/b", ApiCluster: "cluster"})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	result, err := api.Store(context.Background(), backend.Report{Content: []byte("[]"), Environment: "prod"})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if path != "/v1/account/team%20a%2Fb/cluster/cluster/prod/$COLLECTOR_TEST_TENANT" || result.Location != apiServer.URL+path {
+		t.Fatalf("Expected only the known placeholders to be resolved but got path %s and location %s\n", path, result.Location)
+	}
+
+	if _, err = api.Store(context.Background(), backend.Report{Content: []byte("[]")}); err == nil || !strings.Contains(err.Error(), "ENVIRONMENT") {
+		t.Fatalf("Expected an error for the unresolved placeholder but got %v\n", err)
+	}
+}
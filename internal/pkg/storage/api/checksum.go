@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// checksumHeader carries the hex encoded SHA-256 of the request body, APIs may echo it in the response
+const checksumHeader = "X-Content-Sha256"
+
+// setChecksumHeaders adds the checksum as plain header and as Content-Digest (RFC 9530)
+func setChecksumHeaders(request *http.Request, checksum string) {
+	request.Header.Set(checksumHeader, checksum)
+	if sum, err := hex.DecodeString(checksum); err == nil {
+		request.Header.Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum)+":")
+	}
+}
+
+// verifyChecksum compares the checksum the API reports for the received body, if any, with the sent checksum
+func verifyChecksum(res *http.Response, checksum string) error {
+	if received := res.Header.Get(checksumHeader); received != "" {
+		if !strings.EqualFold(received, checksum) {
+			return fmt.Errorf("API received content with sha256 %s but %s was sent", received, checksum)
+		}
+		return nil
+	}
+
+	for _, digest := range strings.Split(res.Header.Get("Content-Digest"), ",") {
+		algorithm, value, found := strings.Cut(strings.TrimSpace(digest), "=")
+		if !found || algorithm != "sha-256" {
+			continue
+		}
+
+		sum, err := base64.StdEncoding.DecodeString(strings.Trim(value, ":"))
+		if err != nil {
+			return fmt.Errorf("could not decode Content-Digest of API response: %w", err)
+		}
+		if received := hex.EncodeToString(sum); received != checksum {
+			return fmt.Errorf("API received content with sha256 %s but %s was sent", received, checksum)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// placeholderPattern matches placeholders like $ACCOUNT or ${CLUSTER}
+var placeholderPattern = regexp.MustCompile(`\$(?:\{(\w+)\}|(\w+))`)
+
+// resolveEndpoint substitutes the placeholders of the endpoint like $ACCOUNT or ${CLUSTER} with the path escaped
+// values of the given variables. Other placeholders are kept as they are, e.g. query options like $filter. A
+// placeholder whose variable is empty is an error as it would silently send the report to a wrong URL.
+func resolveEndpoint(endpoint string, variables map[string]string) (string, error) {
+	var missing []string
+	resolved := placeholderPattern.ReplaceAllStringFunc(endpoint, func(placeholder string) string {
+		match := placeholderPattern.FindStringSubmatch(placeholder)
+		name := match[1] + match[2]
+		value, known := variables[name]
+		if !known {
+			return placeholder
+		}
+		if value == "" {
+			missing = append(missing, name)
+			return ""
+		}
+		return url.PathEscape(value)
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("API endpoint %s has unresolved placeholders %s", endpoint, strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
+// endpointFor resolves the endpoint for a report of the environment
+func (api *api) endpointFor(environment string) (string, error) {
+	return resolveEndpoint(api.endpoint, map[string]string{
+		"ACCOUNT":     api.account,
+		"CLUSTER":     api.cluster,
+		"ENVIRONMENT": environment,
+	})
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodySize limits how much of an error response body is kept in the returned error
+const maxErrorBodySize = 1024
+
+// requestIdHeaders are checked in order to find the id the API assigned to a failed request
+var requestIdHeaders = []string{"X-Request-Id", "X-Correlation-Id", "X-Amzn-Requestid"}
+
+// StatusError is returned if the API responded with a status code that is not configured as success
+type StatusError struct {
+	Status       string
+	StatusCode   int
+	SuccessCodes []int
+	RequestId    string
+	Body         string
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("Got a Status '%s' instead of one of %v response for API request", e.Status, e.SuccessCodes)
+	if e.RequestId != "" {
+		msg += fmt.Sprintf(" (request id: %s)", e.RequestId)
+	}
+	if e.Body != "" {
+		msg += fmt.Sprintf(": %s", e.Body)
+	}
+	return msg
+}
+
+// newStatusError reads the (truncated) body and request id of the response into a StatusError
+func newStatusError(res *http.Response, successCodes []int) *StatusError {
+	statusError := &StatusError{
+		Status:       res.Status,
+		StatusCode:   res.StatusCode,
+		SuccessCodes: successCodes,
+	}
+
+	for _, header := range requestIdHeaders {
+		if value := res.Header.Get(header); value != "" {
+			statusError.RequestId = value
+			break
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize+1))
+	if err == nil {
+		bodyStr := strings.TrimSpace(string(body))
+		if len(bodyStr) > maxErrorBodySize {
+			bodyStr = bodyStr[:maxErrorBodySize] + "...(truncated)"
+		}
+		statusError.Body = bodyStr
+	}
+
+	return statusError
+}
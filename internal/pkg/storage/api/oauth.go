@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// tokenExpiryDelta is subtracted from the token lifetime so a token is refreshed before it actually expires. Tokens
+// living shorter than twice the delta are refreshed after half their lifetime instead, so they are still reused.
+const tokenExpiryDelta = 30 * time.Second
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// tokenSource fetches bearer tokens via the OAuth2 client-credentials grant and caches them until they expire
+type tokenSource struct {
+	tokenUrl     string
+	clientId     string
+	clientSecret string
+	scopes       []string
+	client       *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+func newTokenSource(cfg *ApiConfig, client *http.Client) *tokenSource {
+	return &tokenSource{
+		tokenUrl:     cfg.ApiOAuthTokenUrl,
+		clientId:     cfg.ApiOAuthClientId,
+		clientSecret: cfg.ApiOAuthClientSecret,
+		scopes:       cfg.ApiOAuthScopes,
+		client:       client,
+	}
+}
+
+// Token returns the cached access token or fetches a new one if none is cached or it is about to expire
+func (ts *tokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.accessToken != "" && (ts.expiresAt.IsZero() || time.Now().Before(ts.expiresAt)) {
+		return ts.accessToken, nil
+	}
+
+	token, err := ts.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	ts.accessToken = token.AccessToken
+	ts.expiresAt = time.Time{}
+	if token.ExpiresIn > 0 {
+		lifetime := time.Duration(token.ExpiresIn) * time.Second
+		ts.expiresAt = time.Now().Add(lifetime - min(tokenExpiryDelta, lifetime/2))
+	}
+
+	return ts.accessToken, nil
+}
+
+// Invalidate drops the cached token, e.g. after the API rejected it with a 401
+func (ts *tokenSource) Invalidate() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.accessToken = ""
+	ts.expiresAt = time.Time{}
+}
+
+func (ts *tokenSource) fetch(ctx context.Context) (*oauthTokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(ts.scopes) > 0 {
+		form.Set("scope", strings.Join(ts.scopes, " "))
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.tokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.SetBasicAuth(url.QueryEscape(ts.clientId), url.QueryEscape(ts.clientSecret))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	log.Debug().Str("tokenUrl", ts.tokenUrl).Str("clientId", ts.clientId).Msg("Fetching OAuth2 token")
+
+	res, err := ts.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Got a Status '%s' instead of an '200 OK' response for OAuth2 token request", res.Status)
+	}
+
+	var token oauthTokenResponse
+	if err = json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("OAuth2 token response did not contain an access_token")
+	}
+
+	return &token, nil
+}
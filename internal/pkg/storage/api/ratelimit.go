@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRetryAfter caps the wait time requested by the API via the Retry-After header
+const maxRetryAfter = 5 * time.Minute
+
+// defaultRetryAfter is used if the API throttles without sending a (valid) Retry-After header
+const defaultRetryAfter = time.Second
+
+// rateLimiter spaces requests so that no more than requestsPerSecond requests are sent
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	limiter := &rateLimiter{}
+	if requestsPerSecond > 0 {
+		limiter.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return limiter
+}
+
+// Wait blocks until the next request may be sent or the context is done
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	wait := l.next.Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+	l.next = now.Add(wait + l.interval)
+	l.mu.Unlock()
+
+	return sleep(ctx, wait)
+}
+
+// isThrottled returns true for responses that ask the client to slow down and retry later
+func isThrottled(res *http.Response) bool {
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable
+}
+
+// retryAfter parses the Retry-After header which is either a number of seconds or a HTTP date
+func retryAfter(res *http.Response) time.Duration {
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return defaultRetryAfter
+	}
+
+	var wait time.Duration
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+	} else {
+		return defaultRetryAfter
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return wait
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// newTLSConfig builds the tls config for the api client from the client certificate and CA bundle given in the config,
+// based on the tls config of the transport. Returns nil if neither is set so the transport settings are used.
+func newTLSConfig(cfg *ApiConfig, base *tls.Config) (*tls.Config, error) {
+	if cfg.ApiClientCertFile == "" && cfg.ApiClientKeyFile == "" && cfg.ApiCaFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if base != nil {
+		tlsConfig = base.Clone()
+	}
+
+	if cfg.ApiClientCertFile != "" || cfg.ApiClientKeyFile != "" {
+		if cfg.ApiClientCertFile == "" || cfg.ApiClientKeyFile == "" {
+			return nil, fmt.Errorf("both client certificate and client key are required for mutual TLS")
+		}
+
+		certificate, err := tls.LoadX509KeyPair(cfg.ApiClientCertFile, cfg.ApiClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if cfg.ApiCaFile != "" {
+		caBytes, err := os.ReadFile(cfg.ApiCaFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle: %w", err)
+		}
+
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.ApiCaFile)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	return tlsConfig, nil
+}
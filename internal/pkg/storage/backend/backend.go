@@ -0,0 +1,120 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+)
+
+// Report is a serialized report together with the metadata a storage needs to persist it
+type Report struct {
+	Content         []byte
+	FileName        string
+	ContentType     string
+	ContentEncoding string
+	Environment     string
+
+	// NamedByFile is set for reports stored next to the main report, e.g. the groups of a split output or the skip audit
+	// object. Backends storing the main report under a fixed name, like the oci tag, derive the name from FileName
+	NamedByFile bool
+
+	// Checksum is the hex encoded SHA-256 of Content, if set backends store it alongside the report
+	Checksum string
+
+	// Metadata contains per-run information, e.g. the number of images, backends may attach it to the stored object
+	Metadata map[string]string
+}
+
+// StoreResult describes where and how a report was stored
+type StoreResult struct {
+	Location string
+	Size     int
+
+	// Metadata contains backend specific details, e.g. the commit hash or the request id of the API
+	Metadata map[string]string
+}
+
+// Storager is implemented by all storage backends
+type Storager interface {
+	Store(ctx context.Context, report Report) (StoreResult, error)
+}
+
+// StreamStorager is implemented by backends which store the content while it is produced, so the report does not
+// have to be held in memory as a whole. Report.Content is ignored.
+type StreamStorager interface {
+	StoreStream(ctx context.Context, report Report, content io.Reader) (StoreResult, error)
+}
+
+// StoreStream passes the content on to storagers supporting streams, the content is read into Report.Content for
+// all others
+func StoreStream(ctx context.Context, storager Storager, report Report, content io.Reader) (StoreResult, error) {
+	if streamStorager, ok := storager.(StreamStorager); ok {
+		return streamStorager.StoreStream(ctx, report, content)
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return StoreResult{}, err
+	}
+	report.Content = data
+	return storager.Store(ctx, report)
+}
+
+// CountingReader counts the bytes read, it reports the size of streamed content
+type CountingReader struct {
+	Reader io.Reader
+	N      int
+}
+
+func (r *CountingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.N += n
+	return n, err
+}
+
+// Checker is implemented by backends which can verify connectivity and credentials before the collection starts
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// ErrNotFound is wrapped by Readers if no report was stored under the file name yet
+var ErrNotFound = errors.New("report not found")
+
+// Reader is implemented by backends which can read a stored report back, e.g. the report of the previous run
+type Reader interface {
+	Read(ctx context.Context, fileName string) ([]byte, error)
+}
+
+// ChecksumFileName returns the name of the sidecar file holding the checksum of the report
+func ChecksumFileName(fileName string) string {
+	return fileName + ".sha256"
+}
+
+// ChecksumFileContent returns the checksum in the format of sha256sum so it can be verified with 'sha256sum -c'
+func ChecksumFileContent(checksum, fileName string) []byte {
+	return []byte(checksum + "  " + filepath.Base(fileName) + "\n")
+}
+
+type writer struct {
+	ctx      context.Context
+	storager Storager
+	template Report
+}
+
+// NewWriter adapts a Storager to an io.Writer, every Write stores the written content as a report based on template
+func NewWriter(ctx context.Context, storager Storager, template Report) io.Writer {
+	return &writer{ctx: ctx, storager: storager, template: template}
+}
+
+func (w *writer) Write(content []byte) (int, error) {
+	report := w.template
+	report.Content = content
+
+	result, err := w.storager.Store(w.ctx, report)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.Size, nil
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// checksumStorage adds the SHA-256 checksum of the content to the report, backends store it alongside the report. It
+// does not stream, the backends need the checksum before the content, e.g. as S3 header.
+type checksumStorage struct {
+	next Storager
+}
+
+func newChecksumStorage(next Storager) *checksumStorage {
+	return &checksumStorage{next: next}
+}
+
+func (c *checksumStorage) Store(ctx context.Context, report Report) (StoreResult, error) {
+	sum := sha256.Sum256(report.Content)
+	report.Checksum = hex.EncodeToString(sum[:])
+
+	result, err := c.next.Store(ctx, report)
+	if err != nil {
+		return result, err
+	}
+
+	if result.Metadata == nil {
+		result.Metadata = map[string]string{}
+	}
+	result.Metadata["sha256"] = report.Checksum
+
+	return result, nil
+}
+
+func (c *checksumStorage) Check(ctx context.Context) error {
+	return Preflight(ctx, c.next)
+}
+
+func (c *checksumStorage) Read(ctx context.Context, fileName string) ([]byte, error) {
+	return Read(ctx, c.next, fileName)
+}
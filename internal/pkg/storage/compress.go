@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+)
+
+// gzipStorage compresses the report before passing it on to the wrapped storage
+type gzipStorage struct {
+	next Storager
+}
+
+func newGzipStorage(next Storager) *gzipStorage {
+	return &gzipStorage{next: next}
+}
+
+func (g *gzipStorage) Store(ctx context.Context, report Report) (StoreResult, error) {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(report.Content); err != nil {
+		return StoreResult{}, err
+	}
+	if err := w.Close(); err != nil {
+		return StoreResult{}, err
+	}
+
+	report.Content = buf.Bytes()
+	return g.next.Store(ctx, gzipReport(report))
+}
+
+// StoreStream compresses the content while the wrapped storage reads it
+func (g *gzipStorage) StoreStream(ctx context.Context, report Report, content io.Reader) (StoreResult, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		w := gzip.NewWriter(pw)
+		_, err := io.Copy(w, content)
+		if err == nil {
+			err = w.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	result, err := backend.StoreStream(ctx, g.next, gzipReport(report), pr)
+	// Unblocks the compression if the wrapped storage stopped reading early
+	pr.Close()
+	return result, err
+}
+
+func gzipReport(report Report) Report {
+	report.ContentEncoding = "gzip"
+	if !strings.HasSuffix(report.FileName, ".gz") {
+		report.FileName += ".gz"
+	}
+	return report
+}
+
+// Read decompresses the report stored by Store
+func (g *gzipStorage) Read(ctx context.Context, fileName string) ([]byte, error) {
+	content, err := Read(ctx, g.next, gzipReport(Report{FileName: fileName}).FileName)
+	if err != nil {
+		return nil, err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (g *gzipStorage) Check(ctx context.Context) error {
+	return Preflight(ctx, g.next)
+}
@@ -0,0 +1,209 @@
+// Package crd stores the report as ImageCollectionReport custom resource, so consumers in the cluster can read it
+// with kubectl and RBAC instead of credentials of an external storage
+package crd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	Group   = "collector.sdase.org"
+	Version = "v1alpha1"
+
+	Kind        = "ImageCollectionReport"
+	ClusterKind = "ClusterImageCollectionReport"
+)
+
+// maxReportSize stays below the default request size limit of etcd, larger reports have to be split
+const maxReportSize = 1 << 20
+
+var (
+	// Resource is the namespaced report, ClusterResource the cluster-scoped one
+	Resource        = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "imagecollectionreports"}
+	ClusterResource = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "clusterimagecollectionreports"}
+
+	invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+)
+
+type CrdConfig struct {
+	// CrdNamespace of the namespaced report, ignored for cluster-scoped reports
+	CrdNamespace     string
+	CrdClusterScoped bool
+
+	// CrdKubeConfig is the kubernetes configuration of the collector, it is not set by a flag of its own
+	CrdKubeConfig kubeclient.KubeConfig
+}
+
+type crd struct {
+	client    dynamic.ResourceInterface
+	kind      string
+	namespace string
+}
+
+// NewCrd creates a storage which creates or updates one custom resource per report, named after the file name
+func NewCrd(cfg *CrdConfig) (*crd, error) {
+	config, err := kubeclient.RestConfig(&cfg.CrdKubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not create kubernetes config for the custom resource storage: %w", err)
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create kubernetes client for the custom resource storage: %w", err)
+	}
+	return newCrd(client, cfg)
+}
+
+func newCrd(client dynamic.Interface, cfg *CrdConfig) (*crd, error) {
+	if cfg.CrdClusterScoped {
+		return &crd{client: client.Resource(ClusterResource), kind: ClusterKind}, nil
+	}
+	if cfg.CrdNamespace == "" {
+		return nil, fmt.Errorf("the namespace of the custom resource storage is required unless it is cluster-scoped")
+	}
+	return &crd{client: client.Resource(Resource).Namespace(cfg.CrdNamespace), kind: Kind, namespace: cfg.CrdNamespace}, nil
+}
+
+// Store creates the custom resource or replaces its spec. The report has to be JSON, either the images or the
+// envelope of a partial report.
+func (c *crd) Store(ctx context.Context, report backend.Report) (backend.StoreResult, error) {
+	if report.ContentEncoding != "" {
+		return backend.StoreResult{}, fmt.Errorf("custom resource storage does not support the content encoding %s", report.ContentEncoding)
+	}
+	if len(report.Content) > maxReportSize {
+		return backend.StoreResult{}, fmt.Errorf("report of %d bytes is too large for a custom resource, split it with --split-by", len(report.Content))
+	}
+
+	spec, err := reportSpec(report)
+	if err != nil {
+		return backend.StoreResult{}, err
+	}
+
+	name := resourceName(report.FileName)
+	resource := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": Group + "/" + Version,
+		"kind":       c.kind,
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       spec,
+	}}
+	if c.namespace != "" {
+		resource.SetNamespace(c.namespace)
+	}
+	resource.SetAnnotations(report.Metadata)
+
+	existing, err := c.client.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		resource.Object["status"] = reportStatus(spec, nil, time.Now())
+		_, err = c.client.Create(ctx, resource, metav1.CreateOptions{})
+	case err == nil:
+		resource.Object["status"] = reportStatus(spec, existing, time.Now())
+		resource.SetResourceVersion(existing.GetResourceVersion())
+		_, err = c.client.Update(ctx, resource, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return backend.StoreResult{}, fmt.Errorf("could not store %s %s: %w", c.kind, name, err)
+	}
+
+	location := c.kind + "/" + name
+	if c.namespace != "" {
+		location = c.kind + "/" + c.namespace + "/" + name
+	}
+	return backend.StoreResult{Location: location, Size: len(report.Content)}, nil
+}
+
+// Check verifies the CRD is installed and the collector may list the reports
+func (c *crd) Check(ctx context.Context) error {
+	_, err := c.client.List(ctx, metav1.ListOptions{Limit: 1})
+	return err
+}
+
+// reportSpec returns the spec of the custom resource, the images are kept as reported
+func reportSpec(report backend.Report) (map[string]interface{}, error) {
+	spec := map[string]interface{}{"environment": report.Environment}
+
+	content := bytes.TrimSpace(report.Content)
+	if bytes.HasPrefix(content, []byte("{")) {
+		var envelope map[string]interface{}
+		if err := json.Unmarshal(content, &envelope); err != nil {
+			return nil, fmt.Errorf("custom resource storage requires json output: %w", err)
+		}
+		for key, value := range envelope {
+			spec[key] = value
+		}
+		return spec, nil
+	}
+
+	var images []interface{}
+	if err := json.Unmarshal(content, &images); err != nil {
+		return nil, fmt.Errorf("custom resource storage requires json output: %w", err)
+	}
+	if images == nil {
+		images = []interface{}{}
+	}
+	spec["images"] = images
+	return spec, nil
+}
+
+// reportStatus returns the status of a stored report with the time of the update and the Ready condition, which
+// tells whether namespaces or enrichments failed. The transition time is kept while the reason does not change.
+func reportStatus(spec map[string]interface{}, existing *unstructured.Unstructured, now time.Time) map[string]interface{} {
+	images, _ := spec["images"].([]interface{})
+	errs, _ := spec["errors"].([]interface{})
+
+	reason, message := "Collected", fmt.Sprintf("Collected %d images", len(images))
+	if len(errs) > 0 {
+		reason, message = "PartiallyCollected", fmt.Sprintf("Collected %d images, %d namespaces or enrichments failed", len(images), len(errs))
+	}
+
+	updated := now.UTC().Format(time.RFC3339)
+	transition := updated
+	if existing != nil {
+		conditions, _, _ := unstructured.NestedSlice(existing.Object, "status", "conditions")
+		for _, condition := range conditions {
+			if c, ok := condition.(map[string]interface{}); ok && c["type"] == "Ready" && c["reason"] == reason {
+				if previous, ok := c["lastTransitionTime"].(string); ok {
+					transition = previous
+				}
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"images":      int64(len(images)),
+		"lastUpdated": updated,
+		"conditions": []interface{}{map[string]interface{}{
+			"type":               "Ready",
+			"status":             "True",
+			"reason":             reason,
+			"message":            message,
+			"lastTransitionTime": transition,
+		}},
+	}
+}
+
+// resourceName derives a valid object name from the file name of the report, e.g. 'prod-output.json' is stored as
+// 'prod-output'
+func resourceName(fileName string) string {
+	name := strings.ToLower(strings.TrimSuffix(path.Base(fileName), path.Ext(fileName)))
+	name = strings.Trim(invalidNameChars.ReplaceAllString(name, "-"), "-.")
+	if len(name) > 253 {
+		name = strings.Trim(name[:253], "-.")
+	}
+	return name
+}
@@ -0,0 +1,132 @@
+package crd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newFakeClient() *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		Resource:        Kind + "List",
+		ClusterResource: ClusterKind + "List",
+	})
+}
+
+func TestStore(t *testing.T) {
+	client := newFakeClient()
+	storager, err := newCrd(client, &CrdConfig{CrdNamespace: "scanner"})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	ctx := context.Background()
+
+	for _, content := range []string{
+		`[{"namespace":"ns","image":"app:1.0"}]`,
+		`[{"namespace":"ns","image":"app:1.1"},{"namespace":"ns","image":"db:2.0"}]`,
+	} {
+		result, err := storager.Store(ctx, backend.Report{FileName: "prod-output.json", Environment: "prod", Content: []byte(content), Metadata: map[string]string{"images": "1"}})
+		if err != nil {
+			t.Fatalf("Got an error=%v\n", err)
+		}
+		if result.Location != "ImageCollectionReport/scanner/prod-output" {
+			t.Errorf("Expected location ImageCollectionReport/scanner/prod-output, got %s", result.Location)
+		}
+	}
+
+	report, err := client.Resource(Resource).Namespace("scanner").Get(ctx, "prod-output", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	images, _, _ := unstructured.NestedSlice(report.Object, "spec", "images")
+	if len(images) != 2 {
+		t.Errorf("Expected the images of the second report, got %v", images)
+	}
+	if environment, _, _ := unstructured.NestedString(report.Object, "spec", "environment"); environment != "prod" {
+		t.Errorf("Expected environment prod, got %s", environment)
+	}
+	if err = storager.Check(ctx); err != nil {
+		t.Errorf("Got an error=%v\n", err)
+	}
+}
+
+func TestStoreClusterScopedEnvelope(t *testing.T) {
+	client := newFakeClient()
+	storager, err := newCrd(client, &CrdConfig{CrdClusterScoped: true})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	ctx := context.Background()
+
+	_, err = storager.Store(ctx, backend.Report{FileName: "reports/Team_A.json", Content: []byte(`{"images":[],"errors":[{"stage":"namespace","error":"forbidden"}]}`)})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	report, err := client.Resource(ClusterResource).Get(ctx, "team-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if errs, _, _ := unstructured.NestedSlice(report.Object, "spec", "errors"); len(errs) != 1 {
+		t.Errorf("Expected the errors of the envelope, got %v", errs)
+	}
+
+	if _, err = storager.Store(ctx, backend.Report{FileName: "prod.csv", Content: []byte("namespace,image\n")}); err == nil {
+		t.Errorf("Expected an error for csv output")
+	}
+}
+
+func TestNewCrdRequiresNamespace(t *testing.T) {
+	if _, err := newCrd(newFakeClient(), &CrdConfig{}); err == nil {
+		t.Errorf("Expected an error without namespace")
+	}
+}
+
+func TestManifests(t *testing.T) {
+	manifests, err := Manifests()
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(string(manifests)))
+	var names []string
+	for {
+		var definition customResourceDefinition
+		if err := decoder.Decode(&definition); err != nil {
+			break
+		}
+		names = append(names, definition.Metadata.Name)
+	}
+	if strings.Join(names, ",") != "imagecollectionreports.collector.sdase.org,clusterimagecollectionreports.collector.sdase.org" {
+		t.Errorf("Unexpected CRDs %v", names)
+	}
+}
+
+func TestReportStatus(t *testing.T) {
+	first := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	spec := map[string]interface{}{"images": []interface{}{map[string]interface{}{}}}
+	status := reportStatus(spec, nil, first)
+
+	existing := &unstructured.Unstructured{Object: map[string]interface{}{"status": status}}
+	status = reportStatus(spec, existing, first.Add(time.Hour))
+	condition := status["conditions"].([]interface{})[0].(map[string]interface{})
+	if condition["lastTransitionTime"] != "2025-01-01T00:00:00Z" || status["lastUpdated"] != "2025-01-01T01:00:00Z" {
+		t.Errorf("Expected the transition time to be kept, got %v", status)
+	}
+
+	spec["errors"] = []interface{}{map[string]interface{}{"stage": "namespace"}}
+	status = reportStatus(spec, existing, first.Add(time.Hour))
+	condition = status["conditions"].([]interface{})[0].(map[string]interface{})
+	if condition["reason"] != "PartiallyCollected" || condition["lastTransitionTime"] != "2025-01-01T01:00:00Z" {
+		t.Errorf("Expected a transition to PartiallyCollected, got %v", status)
+	}
+}
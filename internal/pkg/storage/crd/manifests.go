@@ -0,0 +1,141 @@
+package crd
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The types cover the part of the CustomResourceDefinition used by the reports, so the manifests can be generated
+// without the apiextensions module
+type customResourceDefinition struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   objectMeta         `yaml:"metadata"`
+	Spec       customResourceSpec `yaml:"spec"`
+}
+
+type objectMeta struct {
+	Name string `yaml:"name"`
+}
+
+type customResourceSpec struct {
+	Group    string            `yaml:"group"`
+	Names    resourceNames     `yaml:"names"`
+	Scope    string            `yaml:"scope"`
+	Versions []resourceVersion `yaml:"versions"`
+}
+
+type resourceNames struct {
+	Kind       string   `yaml:"kind"`
+	ListKind   string   `yaml:"listKind"`
+	Plural     string   `yaml:"plural"`
+	Singular   string   `yaml:"singular"`
+	ShortNames []string `yaml:"shortNames"`
+}
+
+type resourceVersion struct {
+	Name                     string          `yaml:"name"`
+	Served                   bool            `yaml:"served"`
+	Storage                  bool            `yaml:"storage"`
+	Schema                   resourceSchema  `yaml:"schema"`
+	AdditionalPrinterColumns []printerColumn `yaml:"additionalPrinterColumns"`
+}
+
+type resourceSchema struct {
+	OpenAPIV3Schema map[string]interface{} `yaml:"openAPIV3Schema"`
+}
+
+type printerColumn struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`
+	JSONPath string `yaml:"jsonPath"`
+}
+
+// reportSchema accepts the fields of the images as reported, the report schema is versioned with the collector
+var reportSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"spec": map[string]interface{}{
+			"type":                                 "object",
+			"x-kubernetes-preserve-unknown-fields": true,
+			"properties": map[string]interface{}{
+				"environment": map[string]interface{}{"type": "string"},
+				"images": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type":                                 "object",
+						"x-kubernetes-preserve-unknown-fields": true,
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"images":      map[string]interface{}{"type": "integer"},
+				"lastUpdated": map[string]interface{}{"type": "string", "format": "date-time"},
+				"conditions": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type":                                 "object",
+						"x-kubernetes-preserve-unknown-fields": true,
+					},
+				},
+			},
+		},
+	},
+}
+
+// Manifests returns the CustomResourceDefinitions of the namespaced and the cluster-scoped report as YAML
+func Manifests() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, definition := range []struct {
+		kind     string
+		resource string
+		scope    string
+		short    string
+	}{
+		{Kind, Resource.Resource, "Namespaced", "icr"},
+		{ClusterKind, ClusterResource.Resource, "Cluster", "cicr"},
+	} {
+		err := encoder.Encode(customResourceDefinition{
+			APIVersion: "apiextensions.k8s.io/v1",
+			Kind:       "CustomResourceDefinition",
+			Metadata:   objectMeta{Name: definition.resource + "." + Group},
+			Spec: customResourceSpec{
+				Group: Group,
+				Names: resourceNames{
+					Kind:       definition.kind,
+					ListKind:   definition.kind + "List",
+					Plural:     definition.resource,
+					Singular:   strings.ToLower(definition.kind),
+					ShortNames: []string{definition.short},
+				},
+				Scope: definition.scope,
+				Versions: []resourceVersion{{
+					Name:    Version,
+					Served:  true,
+					Storage: true,
+					Schema:  resourceSchema{OpenAPIV3Schema: reportSchema},
+					AdditionalPrinterColumns: []printerColumn{
+						{Name: "Environment", Type: "string", JSONPath: ".spec.environment"},
+						{Name: "Images", Type: "integer", JSONPath: ".status.images"},
+						{Name: "Status", Type: "string", JSONPath: `.status.conditions[?(@.type=="Ready")].reason`},
+						{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+					},
+				}},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+)
+
+// encryptStorage encrypts the report for the configured age recipients or PGP keys before passing it on
+type encryptStorage struct {
+	next        Storager
+	encrypt     func(w io.Writer) (io.WriteCloser, error)
+	extension   string
+	contentType string
+}
+
+// newEncryptStorage returns nil if no recipients are configured
+func newEncryptStorage(next Storager, cfg *StorageConfig) (*encryptStorage, error) {
+	if len(cfg.EncryptAgeRecipients) > 0 && cfg.EncryptPgpPublicKeyFile != "" {
+		return nil, fmt.Errorf("either age recipients or a PGP public key can be used for encryption, not both")
+	}
+
+	if len(cfg.EncryptAgeRecipients) > 0 {
+		var recipients []age.Recipient
+		for _, r := range cfg.EncryptAgeRecipients {
+			recipient, err := age.ParseX25519Recipient(r)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse age recipient %s: %w", r, err)
+			}
+			recipients = append(recipients, recipient)
+		}
+
+		return &encryptStorage{
+			next: next,
+			encrypt: func(w io.Writer) (io.WriteCloser, error) {
+				return age.Encrypt(w, recipients...)
+			},
+			extension:   ".age",
+			contentType: "application/octet-stream",
+		}, nil
+	}
+
+	if cfg.EncryptPgpPublicKeyFile != "" {
+		keyFile, err := os.Open(cfg.EncryptPgpPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		defer keyFile.Close()
+
+		entities, err := openpgp.ReadArmoredKeyRing(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read PGP public key %s: %w", cfg.EncryptPgpPublicKeyFile, err)
+		}
+
+		return &encryptStorage{
+			next: next,
+			encrypt: func(w io.Writer) (io.WriteCloser, error) {
+				return openpgp.Encrypt(w, entities, nil, &openpgp.FileHints{IsBinary: true}, nil)
+			},
+			extension:   ".gpg",
+			contentType: "application/pgp-encrypted",
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func (e *encryptStorage) Store(ctx context.Context, report Report) (StoreResult, error) {
+	var buf bytes.Buffer
+
+	w, err := e.encrypt(&buf)
+	if err != nil {
+		return StoreResult{}, err
+	}
+	if _, err = w.Write(report.Content); err != nil {
+		return StoreResult{}, err
+	}
+	if err = w.Close(); err != nil {
+		return StoreResult{}, err
+	}
+
+	report.Content = buf.Bytes()
+	return e.next.Store(ctx, e.encryptedReport(report))
+}
+
+// StoreStream encrypts the content while the wrapped storage reads it
+func (e *encryptStorage) StoreStream(ctx context.Context, report Report, content io.Reader) (StoreResult, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := e.encrypt(pw)
+		if err == nil {
+			_, err = io.Copy(w, content)
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+
+	result, err := backend.StoreStream(ctx, e.next, e.encryptedReport(report), pr)
+	// Unblocks the encryption if the wrapped storage stopped reading early
+	pr.Close()
+	return result, err
+}
+
+func (e *encryptStorage) encryptedReport(report Report) Report {
+	report.FileName += e.extension
+	report.ContentType = e.contentType
+	// The encoding of the plaintext is not visible to the storage anymore
+	report.ContentEncoding = ""
+	return report
+}
+
+func (e *encryptStorage) Check(ctx context.Context) error {
+	return Preflight(ctx, e.next)
+}
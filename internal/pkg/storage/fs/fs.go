@@ -0,0 +1,107 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/atomicfile"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+
+	"github.com/rs/zerolog/log"
+)
+
+type FsConfig struct {
+	FsBaseDir  string
+	FsKeep     int
+	FsRotation string
+}
+
+type fs struct {
+	baseDir  string
+	keep     int
+	rotation string
+}
+
+// NewFs creates a new local filesystem storage, reports are placed relative to the configured base directory
+func NewFs(cfg *FsConfig) (*fs, error) {
+	if cfg.FsRotation != "" && cfg.FsRotation != RotationNumbered && cfg.FsRotation != RotationTimestamp {
+		return nil, fmt.Errorf("fs rotation %s is not supported", cfg.FsRotation)
+	}
+
+	return &fs{baseDir: cfg.FsBaseDir, keep: cfg.FsKeep, rotation: cfg.FsRotation}, nil
+}
+
+// path returns the location of the file, relative file names are placed in the base directory
+func (fs *fs) path(fileName string) string {
+	if fs.baseDir != "" && !filepath.IsAbs(fileName) {
+		return filepath.Join(fs.baseDir, fileName)
+	}
+	return fileName
+}
+
+// Check verifies the base directory can be created and is writable
+func (fs *fs) Check(ctx context.Context) error {
+	dir := fs.path(".")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("could not create directory %s: %w", dir, err)
+	}
+
+	file, err := os.CreateTemp(dir, ".preflight-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	file.Close()
+
+	return os.Remove(file.Name())
+}
+
+// Store writes the report to a temporary file in the target directory and renames it to the target file name
+// afterwards, so readers never see a partially written report
+func (fs *fs) Store(ctx context.Context, report backend.Report) (backend.StoreResult, error) {
+	return fs.StoreStream(ctx, report, bytes.NewReader(report.Content))
+}
+
+// StoreStream writes the content to the temporary file while it is read
+func (fs *fs) StoreStream(ctx context.Context, report backend.Report, content io.Reader) (backend.StoreResult, error) {
+	fileName := fs.path(report.FileName)
+
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return backend.StoreResult{}, fmt.Errorf("could not create directory for %s: %w", fileName, err)
+	}
+
+	n, err := atomicfile.Write(fileName, content, func() error {
+		if err := rotate(fileName, fs.keep, fs.rotation); err != nil {
+			return fmt.Errorf("could not rotate previous report: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return backend.StoreResult{}, err
+	}
+
+	log.Info().Str("fileName", fileName).Msg("Created new file")
+
+	if report.Checksum != "" {
+		checksumFileName := backend.ChecksumFileName(fileName)
+		if _, err = atomicfile.Write(checksumFileName, bytes.NewReader(backend.ChecksumFileContent(report.Checksum, fileName)), nil); err != nil {
+			return backend.StoreResult{}, fmt.Errorf("could not write checksum file: %w", err)
+		}
+		log.Info().Str("fileName", checksumFileName).Msg("Created new checksum file")
+	}
+
+	return backend.StoreResult{Location: fileName, Size: n}, nil
+}
+
+// Read returns the content of the report file
+func (fs *fs) Read(ctx context.Context, fileName string) ([]byte, error) {
+	content, err := os.ReadFile(fs.path(fileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", backend.ErrNotFound, fs.path(fileName))
+	}
+	return content, err
+}
@@ -0,0 +1,161 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/atomicfile"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+)
+
+func TestWrite(t *testing.T) {
+	baseDir := filepath.Join(t.TempDir(), "nested", "dir")
+
+	w, err := NewFs(&FsConfig{FsBaseDir: baseDir})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	for _, content := range []string{"first", "second"} {
+		if _, err = w.Store(context.Background(), backend.Report{FileName: "test-output.json", Content: []byte(content)}); err != nil {
+			t.Fatalf("Got an error=%v\n", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(baseDir, "test-output.json"))
+		if err != nil {
+			t.Fatalf("Got an error=%v\n", err)
+		}
+		if string(data) != content {
+			t.Fatalf("Expected content %s but got %s\n", content, string(data))
+		}
+	}
+
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected only the output file but got %d entries\n", len(entries))
+	}
+}
+
+func TestWriteRotation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		rotation      string
+		expectedFiles map[string]string
+	}{
+		{
+			name:     "NumberedKeepTwo",
+			rotation: RotationNumbered,
+			expectedFiles: map[string]string{
+				"output.json":   "4",
+				"output-1.json": "3",
+				"output-2.json": "2",
+			},
+		},
+		{
+			name:     "TimestampKeepTwo",
+			rotation: RotationTimestamp,
+			expectedFiles: map[string]string{
+				"output.json":                  "4",
+				"output-20240101T000300Z.json": "3",
+				"output-20240101T000200Z.json": "2",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			baseDir := t.TempDir()
+			fileName := filepath.Join(baseDir, "output.json")
+
+			w, err := NewFs(&FsConfig{FsBaseDir: baseDir, FsKeep: 2, FsRotation: tc.rotation})
+			if err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+
+			for i, content := range []string{"1", "2", "3", "4"} {
+				if _, err = w.Store(context.Background(), backend.Report{FileName: "output.json", Content: []byte(content)}); err != nil {
+					t.Fatalf("Got an error=%v\n", err)
+				}
+				modTime := time.Date(2024, 1, 1, 0, i+1, 0, 0, time.UTC)
+				if err = os.Chtimes(fileName, modTime, modTime); err != nil {
+					t.Fatalf("Got an error=%v\n", err)
+				}
+			}
+
+			entries, err := os.ReadDir(baseDir)
+			if err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+			if len(entries) != len(tc.expectedFiles) {
+				t.Fatalf("Expected %d files but got %d (%v)\n", len(tc.expectedFiles), len(entries), entries)
+			}
+
+			for name, expectedContent := range tc.expectedFiles {
+				data, err := os.ReadFile(filepath.Join(baseDir, name))
+				if err != nil {
+					t.Fatalf("Got an error=%v\n", err)
+				}
+				if string(data) != expectedContent {
+					t.Fatalf("Expected content %s in %s but got %s\n", expectedContent, name, string(data))
+				}
+			}
+		})
+	}
+}
+
+func TestRotateKeepsReport(t *testing.T) {
+	baseDir := t.TempDir()
+	fileName := filepath.Join(baseDir, "report[a]*.json")
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, content := range []string{"1", "2", "3", "4"} {
+		// The copies are hard links, reports are always replaced by a rename as Store does
+		if err := atomicfile.WriteFile(fileName, []byte(content)); err != nil {
+			t.Fatalf("Got an error=%v\n", err)
+		}
+		// Reports written within the same second have the same modification time
+		if err := os.Chtimes(fileName, modTime, modTime); err != nil {
+			t.Fatalf("Got an error=%v\n", err)
+		}
+
+		if err := rotate(fileName, 2, RotationTimestamp); err != nil {
+			t.Fatalf("Got an error=%v\n", err)
+		}
+
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatalf("Expected the report to stay in place during rotation %d, got %v\n", i, err)
+		}
+		if string(data) != content {
+			t.Fatalf("Expected content %s but got %s\n", content, string(data))
+		}
+	}
+
+	expectedFiles := map[string]string{
+		"report[a]*.json":                    "4",
+		"report[a]*-20240101T000000Z-2.json": "3",
+		"report[a]*-20240101T000000Z-3.json": "4",
+	}
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if len(entries) != len(expectedFiles) {
+		t.Fatalf("Expected %d files but got %d (%v)\n", len(expectedFiles), len(entries), entries)
+	}
+	for name, expectedContent := range expectedFiles {
+		data, err := os.ReadFile(filepath.Join(baseDir, name))
+		if err != nil {
+			t.Fatalf("Got an error=%v\n", err)
+		}
+		if string(data) != expectedContent {
+			t.Fatalf("Expected content %s in %s but got %s\n", expectedContent, name, string(data))
+		}
+	}
+}
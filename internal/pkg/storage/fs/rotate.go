@@ -0,0 +1,194 @@
+package fs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	RotationNumbered  = "numbered"
+	RotationTimestamp = "timestamp"
+)
+
+// timestampFormat is sortable so the oldest copies can be found by name
+const timestampFormat = "20060102T150405Z"
+
+// rotate keeps a copy of the existing report before it is replaced and removes copies exceeding keep. The report
+// stays in place, the atomic rename of the new report replaces it, so readers always find a complete report.
+func rotate(fileName string, keep int, mode string) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	info, err := os.Stat(fileName)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(fileName)
+	stem := strings.TrimSuffix(fileName, ext)
+
+	switch mode {
+	case RotationTimestamp:
+		return rotateTimestamp(fileName, stem, ext, info.ModTime(), keep)
+	case RotationNumbered, "":
+		return rotateNumbered(fileName, stem, ext, keep)
+	default:
+		return fmt.Errorf("fs rotation %s is not supported", mode)
+	}
+}
+
+// rotateNumbered shifts output-1.json to output-2.json and so on, the current report is kept as output-1.json
+func rotateNumbered(fileName, stem, ext string, keep int) error {
+	numbered := func(i int) string {
+		return fmt.Sprintf("%s-%d%s", stem, i, ext)
+	}
+
+	if err := os.Remove(numbered(keep)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for i := keep - 1; i >= 1; i-- {
+		if err := os.Rename(numbered(i), numbered(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	log.Debug().Str("fileName", numbered(1)).Msg("Rotated previous report")
+	return preserve(fileName, numbered(1))
+}
+
+// rotatedCopy is a copy of the report kept by the timestamp rotation, the counter tells copies with the same
+// modification time apart as the time has a resolution of seconds
+type rotatedCopy struct {
+	fileName  string
+	timestamp string
+	counter   int
+}
+
+// rotateTimestamp keeps the current report as output-<modification time>.json, or output-<time>-<n>.json if a copy
+// with the same time exists, and keeps only the newest copies
+func rotateTimestamp(fileName, stem, ext string, modTime time.Time, keep int) error {
+	copies, err := rotatedCopies(stem, ext)
+	if err != nil {
+		return err
+	}
+
+	current := rotatedCopy{timestamp: modTime.UTC().Format(timestampFormat), counter: -1}
+	for _, c := range copies {
+		if c.timestamp == current.timestamp && c.counter > current.counter {
+			current.counter = c.counter
+		}
+	}
+	current.counter++
+	current.fileName = fmt.Sprintf("%s-%s%s", stem, current.timestamp, ext)
+	if current.counter > 0 {
+		current.fileName = fmt.Sprintf("%s-%s-%d%s", stem, current.timestamp, current.counter, ext)
+	}
+
+	if err = preserve(fileName, current.fileName); err != nil {
+		return err
+	}
+	log.Debug().Str("fileName", current.fileName).Msg("Rotated previous report")
+
+	copies = append(copies, current)
+	sort.Slice(copies, func(i, j int) bool {
+		if copies[i].timestamp != copies[j].timestamp {
+			return copies[i].timestamp < copies[j].timestamp
+		}
+		return copies[i].counter < copies[j].counter
+	})
+	for len(copies) > keep {
+		if err := os.Remove(copies[0].fileName); err != nil {
+			return err
+		}
+		copies = copies[1:]
+	}
+
+	return nil
+}
+
+// rotatedCopies returns the copies of the timestamp rotation next to the report
+func rotatedCopies(stem, ext string) ([]rotatedCopy, error) {
+	matches, err := filepath.Glob(globEscape(stem) + "-*" + globEscape(ext))
+	if err != nil {
+		return nil, err
+	}
+
+	var copies []rotatedCopy
+	for _, match := range matches {
+		name := strings.TrimSuffix(strings.TrimPrefix(match, stem+"-"), ext)
+		timestamp, counter, found := strings.Cut(name, "-")
+		if _, err := time.Parse(timestampFormat, timestamp); err != nil {
+			continue
+		}
+		candidate := rotatedCopy{fileName: match, timestamp: timestamp}
+		if found {
+			if candidate.counter, err = strconv.Atoi(counter); err != nil {
+				continue
+			}
+		}
+		copies = append(copies, candidate)
+	}
+	return copies, nil
+}
+
+// preserve hard links the report to the rotated name, or copies it if the file system does not support hard links.
+// The report must only be replaced by a rename afterwards, writing to it would change the linked copy as well.
+func preserve(fileName, rotated string) error {
+	if err := os.Link(fileName, rotated); err == nil {
+		return nil
+	}
+
+	source, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
+	target, err := os.OpenFile(rotated, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(target, source); err != nil {
+		target.Close()
+		os.Remove(rotated)
+		return err
+	}
+	if err = target.Close(); err != nil {
+		os.Remove(rotated)
+		return err
+	}
+	return os.Chtimes(rotated, info.ModTime(), info.ModTime())
+}
+
+// globEscape quotes the characters of the file name which filepath.Glob treats as pattern
+func globEscape(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == '*' || r == '?' || r == '[':
+			b.WriteString("[" + string(r) + "]")
+		case r == '\\' && os.PathSeparator != '\\':
+			b.WriteString(`\\`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
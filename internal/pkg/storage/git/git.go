@@ -1,11 +1,15 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"time"
 
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+
 	"encoding/json"
 	"github.com/rs/zerolog/log"
 	"net/http"
@@ -13,9 +17,12 @@ import (
 
 	goGit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/golang-jwt/jwt/v5"
 	"strconv"
+	"sync"
 )
 
 type GitConfig struct {
@@ -23,6 +30,7 @@ type GitConfig struct {
 	GitDirectory         string
 	GitPrivateKeyFile    string
 	GitPassword          string
+	GitPasswordFile      string
 	GithubAppId          int64
 	GithubInstallationId int64
 }
@@ -45,15 +53,27 @@ type InstallationAuthResponse struct {
 	RepositorySelection string `json:"repository_selection"`
 }
 
+// githubTokenRenewal is the remaining lifetime at which a new installation token is requested, the tokens expire after
+// an hour
+const githubTokenRenewal = 5 * time.Minute
+
 func GetGithubToken(privateKeyFile string, githubAppId, githubInstallationId int64) (string, error) {
-	keyBytes, err := os.ReadFile(privateKeyFile)
+	response, err := requestGithubToken(privateKeyFile, githubAppId, githubInstallationId)
 	if err != nil {
 		return "", err
 	}
+	return response.Token, nil
+}
+
+func requestGithubToken(privateKeyFile string, githubAppId, githubInstallationId int64) (*InstallationAuthResponse, error) {
+	keyBytes, err := os.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
 
 	rsaPrivateKey, err := jwt.ParseRSAPrivateKeyFromPEM(keyBytes)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	jwtToken := jwt.New(jwt.SigningMethodRS256)
@@ -68,31 +88,66 @@ func GetGithubToken(privateKeyFile string, githubAppId, githubInstallationId int
 
 	tokenString, err := jwtToken.SignedString(rsaPrivateKey)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	client := &http.Client{}
+	client := &http.Client{Transport: version.Transport(nil)}
 	url := "https://api.github.com/app/installations/" + strconv.FormatInt(githubInstallationId, 10) + "/access_tokens"
 	req, _ := http.NewRequest("POST", url, nil)
 	req.Header.Set("Accept", "application/vnd.github.machine-man-preview+json")
 	req.Header.Set("Authorization", "Bearer "+tokenString)
-	res, _ := client.Do(req)
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not request GitHub installation token: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not request GitHub installation token: unexpected status %s", res.Status)
+	}
 
 	decoder := json.NewDecoder(res.Body)
 	var installationAuthResponse InstallationAuthResponse
 	err = decoder.Decode(&installationAuthResponse)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return installationAuthResponse.Token, nil
+	return &installationAuthResponse, nil
+}
+
+// githubToken authenticates with installation tokens of the GitHub App, a token is reused until it is about to expire,
+// so a clone reused across runs keeps working
+type githubToken struct {
+	privateKeyFile string
+	appId          int64
+	installationId int64
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (t *githubToken) auth() (transport.AuthMethod, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token == "" || time.Until(t.expiresAt) < githubTokenRenewal {
+		response, err := requestGithubToken(t.privateKeyFile, t.appId, t.installationId)
+		if err != nil {
+			return nil, err
+		}
+		t.token, t.expiresAt = response.Token, response.ExpiresAt
+	}
+	return &githttp.BasicAuth{Username: "x-access-token", Password: t.token}, nil
 }
 
 type git struct {
 	repository *goGit.Repository
-	fileName   string
+	directory  string
+	// auth returns the credentials of the next request, GitHub App tokens are renewed before they expire
+	auth func() (transport.AuthMethod, error)
 }
 
-func NewGit(cfg *GitConfig, filename string) (io.Writer, error) {
+func NewGit(cfg *GitConfig) (*git, error) {
 
 	if cfg.GitUrl == "" {
 		log.Info().Msg("git url not given, do not init git")
@@ -115,24 +170,14 @@ func NewGit(cfg *GitConfig, filename string) (io.Writer, error) {
 	// Clone the given repository to the given directory
 	log.Info().Str("url", cfg.GitUrl).Int64("githubInstallationId", cfg.GithubInstallationId).Msg("cloning")
 
-	var cloneOptions goGit.CloneOptions
+	var url string
+	var auth func() (transport.AuthMethod, error)
 
-	// TODO: Can this be cleaned up w/o mentioning GH?
 	if cfg.GithubInstallationId != 0 {
-
-		// TODO: Review lib
-		token, err := GetGithubToken(cfg.GitPrivateKeyFile, cfg.GithubAppId, cfg.GithubInstallationId)
-		if err != nil {
-			return nil, err
-		}
-
-		// TODO: Review is this GH specific or actually general?
-		// Do we need support for Bitbucket?
-		githubUrl := "https://x-access-token:" + token + "@" + cfg.GitUrl
-		cloneOptions = goGit.CloneOptions{
-			URL:      githubUrl,
-			Progress: os.Stdout,
-		}
+		// The token is passed with every request instead of the URL, it expires after an hour
+		token := &githubToken{privateKeyFile: cfg.GitPrivateKeyFile, appId: cfg.GithubAppId, installationId: cfg.GithubInstallationId}
+		url = "https://" + cfg.GitUrl
+		auth = token.auth
 	} else {
 
 		publicKeys, err := ssh.NewPublicKeysFromFile("git", cfg.GitPrivateKeyFile, cfg.GitPassword)
@@ -141,11 +186,18 @@ func NewGit(cfg *GitConfig, filename string) (io.Writer, error) {
 			return nil, err
 		}
 
-		cloneOptions = goGit.CloneOptions{
-			URL:      cfg.GitUrl,
-			Auth:     publicKeys,
-			Progress: os.Stdout,
-		}
+		url = cfg.GitUrl
+		auth = func() (transport.AuthMethod, error) { return publicKeys, nil }
+	}
+
+	cloneAuth, err := auth()
+	if err != nil {
+		return nil, err
+	}
+	cloneOptions := goGit.CloneOptions{
+		URL:      url,
+		Auth:     cloneAuth,
+		Progress: os.Stdout,
 	}
 
 	// What is set to false here?
@@ -158,22 +210,91 @@ func NewGit(cfg *GitConfig, filename string) (io.Writer, error) {
 
 	g := &git{
 		repository: repository,
-		fileName:   filepath.Join(cfg.GitDirectory, filename),
+		directory:  cfg.GitDirectory,
+		auth:       auth,
 	}
 
 	return g, nil
 }
 
-func (g git) Write(content []byte) (int, error) {
+// Check lists the references of the remote (ls-remote) to verify the credentials are still accepted
+func (g git) Check(ctx context.Context) error {
+	remote, err := g.repository.Remote(goGit.DefaultRemoteName)
+	if err != nil {
+		return err
+	}
+	auth, err := g.auth()
+	if err != nil {
+		return err
+	}
+
+	if _, err = remote.ListContext(ctx, &goGit.ListOptions{Auth: auth}); err != nil {
+		return fmt.Errorf("could not list remote references: %w", err)
+	}
+
+	return nil
+}
+
+// Read returns the report from the clone after pulling the commits pushed in the meantime
+func (g git) Read(ctx context.Context, fileName string) ([]byte, error) {
+	worktree, err := g.repository.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	auth, err := g.auth()
+	if err != nil {
+		return nil, err
+	}
+	err = worktree.PullContext(ctx, &goGit.PullOptions{Auth: auth})
+	if err != nil && !errors.Is(err, goGit.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("could not pull: %w", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(g.directory, fileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", backend.ErrNotFound, fileName)
+	}
+	return content, err
+}
+
+// Store commits the report to the cloned repository and pushes it, an unchanged report is not committed
+func (g git) Store(ctx context.Context, report backend.Report) (backend.StoreResult, error) {
 	worktree, _ := g.repository.Worktree()
+	fileName := filepath.Join(g.directory, report.FileName)
 
-	err := os.WriteFile(g.fileName, content, 0755)
+	auth, err := g.auth()
 	if err != nil {
-		log.Info().Stack().Err(err).Str("filename", g.fileName).Msg("Error during opening file")
+		return backend.StoreResult{}, err
+	}
+
+	// The clone is reused when running with an interval, fetch commits pushed by others in the meantime
+	err = worktree.PullContext(ctx, &goGit.PullOptions{Auth: auth})
+	if err != nil && !errors.Is(err, goGit.NoErrAlreadyUpToDate) {
+		log.Warn().Err(err).Msg("could not pull")
+		return backend.StoreResult{}, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return backend.StoreResult{}, fmt.Errorf("could not create directory for %s: %w", fileName, err)
+	}
+
+	if err = os.WriteFile(fileName, report.Content, 0755); err != nil {
+		return backend.StoreResult{}, fmt.Errorf("could not write %s: %w", fileName, err)
 	}
 
-	if _, err := worktree.Add(g.fileName); err != nil {
-		return 0, err
+	if _, err := worktree.Add(report.FileName); err != nil {
+		return backend.StoreResult{}, err
+	}
+
+	if report.Checksum != "" {
+		checksumFileName := backend.ChecksumFileName(report.FileName)
+		err = os.WriteFile(filepath.Join(g.directory, checksumFileName), backend.ChecksumFileContent(report.Checksum, report.FileName), 0644)
+		if err != nil {
+			return backend.StoreResult{}, err
+		}
+		if _, err := worktree.Add(checksumFileName); err != nil {
+			return backend.StoreResult{}, err
+		}
 	}
 
 	commit, err := worktree.Commit("example go-git commit", &goGit.CommitOptions{
@@ -184,23 +305,31 @@ func (g git) Write(content []byte) (int, error) {
 		},
 	})
 
+	if errors.Is(err, goGit.ErrEmptyCommit) {
+		log.Info().Str("filename", fileName).Msg("Report is unchanged, nothing to commit")
+		return backend.StoreResult{Location: fileName, Size: len(report.Content)}, nil
+	}
 	if err != nil {
 		log.Warn().Err(err).Msg("could not create worktree")
-		return 0, err
+		return backend.StoreResult{}, err
 	}
 
 	obj, err := g.repository.CommitObject(commit)
 	if err != nil {
 		log.Warn().Err(err).Msg("could not get committed object")
-		return 0, err
+		return backend.StoreResult{}, err
 	}
 	log.Info().Str("obj", obj.String()).Msg("committed")
 
-	err = g.repository.Push(&goGit.PushOptions{})
+	err = g.repository.PushContext(ctx, &goGit.PushOptions{Auth: auth})
 	if err != nil {
 		log.Warn().Err(err).Msg("could not push")
-		return 0, err
+		return backend.StoreResult{}, err
 	}
 
-	return len(content), nil
+	return backend.StoreResult{
+		Location: fileName,
+		Size:     len(report.Content),
+		Metadata: map[string]string{"commit": commit.String()},
+	}, nil
 }
@@ -0,0 +1,290 @@
+// Package grpc streams the images of a report to a ReportIngestion service, see internal/collector/schema/ingest.proto.
+//
+// The calls are framed by hand on top of HTTP/2 instead of using google.golang.org/grpc with generated stubs:
+//
+//   - The service has a single client streaming method. Its wire format, length-prefixed messages in the body of an
+//     HTTP/2 POST and the status in the trailers, is part of the gRPC protocol specification and does not change.
+//   - The protobuf output is encoded by hand without generated types, see internal/collector/protobuf.go. The encoded
+//     images are forwarded as they are, generated stubs would decode and encode every image again.
+//   - grpc-go and genproto would be the largest dependencies of the collector for one optional storage, and the
+//     build would need protoc and its plugins for the stubs.
+//
+// The tests serve the method with the HTTP/2 transport the way grpc-go does and decode every message with the
+// descriptors of the schema, see internal/collector/schema, so the framing and the encoding can not drift from it.
+//
+// Every image is a message of its own, the messages are written to the request body while it is sent, so the server
+// receives the images one by one. The report itself is encoded in memory before, the protobuf output is not streamed.
+// Compression, retries, keepalives and load balancing of grpc-go are not supported, --grpc-timeout bounds the call.
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// streamReportPath is the method StreamReport of the ReportIngestion service
+const streamReportPath = "/sdase.imagemetadatacollector.v1.ReportIngestion/StreamReport"
+
+// maxResponseSize limits the response message read into memory
+const maxResponseSize = 1 << 20
+
+// contentType of the protobuf output, the images are forwarded without decoding them
+const contentType = "application/x-protobuf"
+
+type GrpcConfig struct {
+	// GrpcEndpoint is the base URL of the service, http:// connects without TLS
+	GrpcEndpoint  string
+	GrpcToken     string
+	GrpcTokenFile string
+	GrpcTimeout   time.Duration
+}
+
+type grpc struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewGrpc creates a storage which streams the images of every report to the StreamReport method of the service
+func NewGrpc(cfg *GrpcConfig) (*grpc, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.GrpcEndpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "https" && endpoint.Scheme != "http") {
+		return nil, fmt.Errorf("gRPC endpoint '%s' must be given as https://<host>:<port> or http://<host>:<port>", cfg.GrpcEndpoint)
+	}
+
+	// gRPC requires HTTP/2, which net/http only negotiates with TLS
+	var transport http.RoundTripper = http.DefaultTransport
+	if endpoint.Scheme == "http" {
+		transport = &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, addr)
+			},
+		}
+	}
+
+	return &grpc{
+		url:    endpoint.String() + streamReportPath,
+		token:  cfg.GrpcToken,
+		client: &http.Client{Transport: version.Transport(transport), Timeout: cfg.GrpcTimeout},
+	}, nil
+}
+
+// Store streams the images of the protobuf report one message per image
+func (g *grpc) Store(ctx context.Context, report backend.Report) (backend.StoreResult, error) {
+	if report.ContentType != contentType || report.ContentEncoding != "" {
+		return backend.StoreResult{}, fmt.Errorf("gRPC storage requires the uncompressed protobuf output, got %s", report.ContentType)
+	}
+	images, err := splitImages(report.Content)
+	if err != nil {
+		return backend.StoreResult{}, fmt.Errorf("invalid protobuf report: %w", err)
+	}
+
+	body, writer := io.Pipe()
+	go func() {
+		err := writeMessage(writer, appendMetadata(nil, report))
+		for i := 0; err == nil && i < len(images); i++ {
+			err = writeMessage(writer, protowire.AppendBytes(protowire.AppendTag(nil, 2, protowire.BytesType), images[i]))
+		}
+		writer.CloseWithError(err)
+	}()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, body)
+	if err != nil {
+		body.Close()
+		return backend.StoreResult{}, err
+	}
+	request.Header.Set("Content-Type", "application/grpc+proto")
+	request.Header.Set("TE", "trailers")
+	if g.token != "" {
+		request.Header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	response, err := g.client.Do(request)
+	if err != nil {
+		return backend.StoreResult{}, fmt.Errorf("could not stream report to %s: %w", g.url, err)
+	}
+	defer response.Body.Close()
+
+	result, err := readResponse(response)
+	if err != nil {
+		return backend.StoreResult{}, fmt.Errorf("could not stream report to %s: %w", g.url, err)
+	}
+	result.Size = len(report.Content)
+	return result, nil
+}
+
+// splitImages returns the encoded Image messages of the Report message
+func splitImages(content []byte) ([][]byte, error) {
+	var images [][]byte
+	for len(content) > 0 {
+		number, typ, n := protowire.ConsumeTag(content)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		content = content[n:]
+		if number == 1 && typ == protowire.BytesType {
+			image, n := protowire.ConsumeBytes(content)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			images = append(images, image)
+			content = content[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(number, typ, content)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		content = content[n:]
+	}
+	return images, nil
+}
+
+// appendMetadata encodes the StreamReportRequest holding the ReportMetadata
+func appendMetadata(b []byte, report backend.Report) []byte {
+	var metadata []byte
+	metadata = appendString(metadata, 1, report.FileName)
+	metadata = appendString(metadata, 2, report.Environment)
+
+	keys := make([]string, 0, len(report.Metadata))
+	for key := range report.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		entry := appendString(appendString(nil, 1, key), 2, report.Metadata[key])
+		metadata = protowire.AppendTag(metadata, 3, protowire.BytesType)
+		metadata = protowire.AppendBytes(metadata, entry)
+	}
+	metadata = appendString(metadata, 4, report.Checksum)
+
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, metadata)
+}
+
+func appendString(b []byte, number protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+// writeMessage writes the message with the uncompressed length-prefixed framing of gRPC
+func writeMessage(w io.Writer, message []byte) error {
+	header := make([]byte, 5)
+	binary.BigEndian.PutUint32(header[1:], uint32(len(message)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(message)
+	return err
+}
+
+// readResponse decodes the StreamReportResponse and checks the gRPC status, which is sent as trailer or, if the call
+// failed right away, as header
+func readResponse(response *http.Response) (backend.StoreResult, error) {
+	if response.StatusCode != http.StatusOK {
+		return backend.StoreResult{}, fmt.Errorf("unexpected HTTP status %s", response.Status)
+	}
+	if response.ProtoMajor != 2 {
+		return backend.StoreResult{}, fmt.Errorf("the endpoint does not support HTTP/2")
+	}
+
+	var message []byte
+	header := make([]byte, 5)
+	_, err := io.ReadFull(response.Body, header)
+	switch {
+	case err == nil:
+		if header[0] != 0 {
+			return backend.StoreResult{}, errors.New("compressed responses are not supported")
+		}
+		size := binary.BigEndian.Uint32(header[1:])
+		if size > maxResponseSize {
+			return backend.StoreResult{}, fmt.Errorf("response of %d bytes is too large", size)
+		}
+		message = make([]byte, size)
+		if _, err = io.ReadFull(response.Body, message); err != nil {
+			return backend.StoreResult{}, err
+		}
+	case !errors.Is(err, io.EOF):
+		return backend.StoreResult{}, err
+	}
+	// The trailers are only available once the body is read completely
+	if _, err = io.Copy(io.Discard, response.Body); err != nil {
+		return backend.StoreResult{}, err
+	}
+
+	if err = grpcStatus(response); err != nil {
+		return backend.StoreResult{}, err
+	}
+	if message == nil {
+		return backend.StoreResult{}, errors.New("no response message")
+	}
+	return decodeResponse(message)
+}
+
+func grpcStatus(response *http.Response) error {
+	status, statusMessage := response.Trailer.Get("Grpc-Status"), response.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, statusMessage = response.Header.Get("Grpc-Status"), response.Header.Get("Grpc-Message")
+	}
+	if status == "" {
+		return errors.New("no gRPC status")
+	}
+	if status != "0" {
+		if decoded, err := url.PathUnescape(statusMessage); err == nil {
+			statusMessage = decoded
+		}
+		return fmt.Errorf("gRPC status %s: %s", status, statusMessage)
+	}
+	return nil
+}
+
+// decodeResponse decodes the StreamReportResponse, unknown fields are skipped
+func decodeResponse(message []byte) (backend.StoreResult, error) {
+	result := backend.StoreResult{Metadata: map[string]string{}}
+	for len(message) > 0 {
+		number, typ, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			return backend.StoreResult{}, protowire.ParseError(n)
+		}
+		message = message[n:]
+
+		switch {
+		case number == 1 && typ == protowire.BytesType:
+			var location string
+			location, n = protowire.ConsumeString(message)
+			result.Location = location
+		case number == 2 && typ == protowire.VarintType:
+			var images uint64
+			images, n = protowire.ConsumeVarint(message)
+			result.Metadata["images"] = fmt.Sprint(images)
+		default:
+			n = protowire.ConsumeFieldValue(number, typ, message)
+		}
+		if n < 0 {
+			return backend.StoreResult{}, protowire.ParseError(n)
+		}
+		message = message[n:]
+	}
+	return result, nil
+}
@@ -0,0 +1,294 @@
+package grpc
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/collector"
+	"github.com/SDA-SE/image-metadata-collector/internal/collector/schema"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// newFakeService returns a ReportIngestion service without TLS which counts the received images
+func newFakeService(t *testing.T, token string) (*httptest.Server, *[][]byte) {
+	var messages [][]byte
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		if r.URL.Path != streamReportPath || r.Header.Get("Authorization") != "Bearer "+token {
+			w.Header().Set("Grpc-Status", "16")
+			w.Header().Set("Grpc-Message", "invalid%20token")
+			return
+		}
+
+		header := make([]byte, 5)
+		for {
+			if _, err := io.ReadFull(r.Body, header); err != nil {
+				break
+			}
+			message := make([]byte, binary.BigEndian.Uint32(header[1:]))
+			if _, err := io.ReadFull(r.Body, message); err != nil {
+				t.Errorf("Got an error=%v\n", err)
+				return
+			}
+			messages = append(messages, message)
+		}
+
+		w.Header().Set("Trailer", "Grpc-Status")
+		response := protowire.AppendTag(nil, 1, protowire.BytesType)
+		response = protowire.AppendString(response, "reports/prod")
+		response = protowire.AppendTag(response, 2, protowire.VarintType)
+		response = protowire.AppendVarint(response, uint64(len(messages)-1))
+		if err := writeMessage(w, response); err != nil {
+			t.Errorf("Got an error=%v\n", err)
+		}
+		w.Header().Set("Grpc-Status", "0")
+	})
+	server := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	t.Cleanup(server.Close)
+	return server, &messages
+}
+
+func TestStore(t *testing.T) {
+	server, messages := newFakeService(t, "secret")
+	storager, err := NewGrpc(&GrpcConfig{GrpcEndpoint: server.URL, GrpcToken: "secret"})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	images := []collector.CollectorImage{{Namespace: "a", Image: "app:1.0"}, {Namespace: "b", Image: "db:2.0"}}
+	content, err := collector.ProtobufMarshal(&images)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	result, err := storager.Store(context.Background(), backend.Report{
+		FileName:    "prod-output.pb",
+		Environment: "prod",
+		ContentType: "application/x-protobuf",
+		Content:     content,
+		Metadata:    map[string]string{"images": "2"},
+	})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if result.Location != "reports/prod" || result.Metadata["images"] != "2" {
+		t.Errorf("Unexpected result %v", result)
+	}
+
+	if len(*messages) != 3 {
+		t.Fatalf("Expected the metadata and two images, got %d messages", len(*messages))
+	}
+	number, _, n := protowire.ConsumeTag((*messages)[0])
+	if number != 1 || n < 0 {
+		t.Errorf("Expected the metadata first, got field %d", number)
+	}
+	number, _, n = protowire.ConsumeTag((*messages)[2])
+	image, _ := protowire.ConsumeBytes((*messages)[2][n:])
+	if number != 2 || string(image) != string(appendImage(t, images[1])) {
+		t.Errorf("Expected the second image as is, got %v", (*messages)[2])
+	}
+}
+
+func appendImage(t *testing.T, image collector.CollectorImage) []byte {
+	content, err := collector.ProtobufMarshal(&[]collector.CollectorImage{image})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	images, err := splitImages(content)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	return images[0]
+}
+
+func TestStoreErrors(t *testing.T) {
+	server, _ := newFakeService(t, "secret")
+	storager, err := NewGrpc(&GrpcConfig{GrpcEndpoint: server.URL, GrpcToken: "wrong"})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	_, err = storager.Store(context.Background(), backend.Report{ContentType: "application/x-protobuf"})
+	if err == nil || err.Error() != "could not stream report to "+server.URL+streamReportPath+": gRPC status 16: invalid token" {
+		t.Errorf("Expected the gRPC status, got %v", err)
+	}
+
+	if _, err = storager.Store(context.Background(), backend.Report{ContentType: "application/json", Content: []byte("[]")}); err == nil {
+		t.Errorf("Expected an error for json output")
+	}
+
+	if _, err = NewGrpc(&GrpcConfig{GrpcEndpoint: "ingest.example.com:443"}); err == nil {
+		t.Errorf("Expected an error for an endpoint without scheme")
+	}
+}
+
+// newSchemaService serves the StreamReport method of ingest.proto like the HTTP/2 transport of grpc-go: the
+// connection speaks HTTP/2 without upgrade, the trailers are not announced and the messages are decoded with the
+// descriptors of the schema instead of by field number
+func newSchemaService(t *testing.T) (string, *[]*dynamicpb.Message) {
+	files, err := schema.Files()
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	descriptor, err := files.FindDescriptorByName("sdase.imagemetadatacollector.v1.ReportIngestion")
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	service := descriptor.(protoreflect.ServiceDescriptor)
+	method := service.Methods().ByName("StreamReport")
+
+	var requests []*dynamicpb.Message
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+string(service.FullName())+"/"+string(method.Name()) || r.Method != http.MethodPost ||
+			!strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") || r.Header.Get("TE") != "trailers" {
+			t.Errorf("Unexpected request %s %s %v", r.Method, r.URL.Path, r.Header)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		requests = nil
+		header := make([]byte, 5)
+		for {
+			if _, err := io.ReadFull(r.Body, header); err != nil {
+				break
+			}
+			message := make([]byte, binary.BigEndian.Uint32(header[1:]))
+			if _, err := io.ReadFull(r.Body, message); err != nil {
+				t.Errorf("Got an error=%v\n", err)
+				return
+			}
+			request := dynamicpb.NewMessage(method.Input())
+			if err := proto.Unmarshal(message, request); err != nil {
+				w.Header().Set(http.TrailerPrefix+"Grpc-Status", "3")
+				w.Header().Set(http.TrailerPrefix+"Grpc-Message", "invalid%20request")
+				return
+			}
+			requests = append(requests, request)
+		}
+
+		metadata := requests[0].Get(method.Input().Fields().ByName("metadata")).Message()
+		if metadata.Get(metadata.Descriptor().Fields().ByName("environment")).String() == "" {
+			w.Header().Set(http.TrailerPrefix+"Grpc-Status", "3")
+			w.Header().Set(http.TrailerPrefix+"Grpc-Message", "missing%20environment")
+			return
+		}
+
+		response := dynamicpb.NewMessage(method.Output())
+		response.Set(method.Output().Fields().ByName("location"), protoreflect.ValueOfString("reports/prod"))
+		response.Set(method.Output().Fields().ByName("images"), protoreflect.ValueOfInt64(int64(len(requests)-1)))
+		content, err := proto.Marshal(response)
+		if err != nil {
+			t.Errorf("Got an error=%v\n", err)
+			return
+		}
+		if err = writeMessage(w, content); err != nil {
+			t.Errorf("Got an error=%v\n", err)
+		}
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		server := &http2.Server{}
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+	return "http://" + listener.Addr().String(), &requests
+}
+
+func TestStoreSchema(t *testing.T) {
+	endpoint, requests := newSchemaService(t)
+	storager, err := NewGrpc(&GrpcConfig{GrpcEndpoint: endpoint})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	images := []collector.CollectorImage{
+		{Namespace: "a", Image: "app:1.0", Skip: true, EngagementTags: []string{"x", "y"}, Replicas: 3, ContainerPorts: []int32{8080, 8443}},
+		{Namespace: "b", Image: "db:2.0", RiskScore: 7},
+	}
+	content, err := collector.ProtobufMarshal(&images)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	report := backend.Report{
+		FileName:    "prod-output.pb",
+		Environment: "prod",
+		ContentType: "application/x-protobuf",
+		Content:     content,
+		Checksum:    "abc",
+		Metadata:    map[string]string{"images": "2", "namespace": "a"},
+	}
+	result, err := storager.Store(context.Background(), report)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if result.Location != "reports/prod" || result.Metadata["images"] != "2" {
+		t.Errorf("Unexpected result %v", result)
+	}
+
+	if len(*requests) != 3 {
+		t.Fatalf("Expected the metadata and two images, got %d messages", len(*requests))
+	}
+	for i, request := range *requests {
+		if len(request.GetUnknown()) > 0 {
+			t.Errorf("Expected message %d to match the schema, got unknown fields %v", i, request.GetUnknown())
+		}
+	}
+
+	payload := (*requests)[0].Descriptor().Oneofs().ByName("payload")
+	metadata := (*requests)[0].Get((*requests)[0].WhichOneof(payload)).Message()
+	fields := metadata.Descriptor().Fields()
+	if metadata.Descriptor().Name() != "ReportMetadata" || metadata.Get(fields.ByName("file_name")).String() != "prod-output.pb" ||
+		metadata.Get(fields.ByName("environment")).String() != "prod" || metadata.Get(fields.ByName("checksum")).String() != "abc" {
+		t.Errorf("Unexpected metadata %v", metadata)
+	}
+	entries := metadata.Get(fields.ByName("metadata")).Map()
+	if entries.Len() != 2 || entries.Get(protoreflect.ValueOfString("namespace").MapKey()).String() != "a" {
+		t.Errorf("Unexpected report metadata %v", entries)
+	}
+
+	image := (*requests)[1].Get((*requests)[1].WhichOneof(payload)).Message()
+	fields = image.Descriptor().Fields()
+	if image.Descriptor().Name() != "Image" || image.Get(fields.ByName("image")).String() != "app:1.0" ||
+		!image.Get(fields.ByName("skip")).Bool() || image.Get(fields.ByName("engagement_tags")).List().Len() != 2 ||
+		image.Get(fields.ByName("replicas")).Int() != 3 || image.Get(fields.ByName("container_ports")).List().Get(1).Int() != 8443 {
+		t.Errorf("Unexpected first image %v", image)
+	}
+	image = (*requests)[2].Get((*requests)[2].WhichOneof(payload)).Message()
+	if image.Get(fields.ByName("namespace")).String() != "b" || image.Get(fields.ByName("risk_score")).Int() != 7 {
+		t.Errorf("Unexpected second image %v", image)
+	}
+
+	report.Environment = ""
+	_, err = storager.Store(context.Background(), report)
+	if err == nil || !strings.HasSuffix(err.Error(), "gRPC status 3: missing environment") {
+		t.Errorf("Expected the gRPC status of the trailers, got %v", err)
+	}
+}
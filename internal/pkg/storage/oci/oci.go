@@ -0,0 +1,269 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/registry"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ArtifactType      = "application/vnd.sdase.image-metadata-collector.report.v1+json"
+	manifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	emptyMediaType    = "application/vnd.oci.empty.v1+json"
+)
+
+// emptyConfig is the OCI empty descriptor content used as config for artifacts
+var emptyConfig = []byte("{}")
+
+var invalidTagChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+type OciConfig struct {
+	OciRepository   string
+	OciTag          string
+	OciUsername     string
+	OciPassword     string
+	OciPasswordFile string
+	OciPlainHttp    bool
+}
+
+type oci struct {
+	registry   string
+	repository string
+	tag        string
+	scheme     string
+	client     *registry.Client
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+	Data        []byte            `json:"data,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType"`
+	Config        descriptor        `json:"config"`
+	Layers        []descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// NewOci creates a new storage pushing reports as OCI artifacts to the given repository, e.g. registry.example.com/team/reports
+func NewOci(cfg *OciConfig) (*oci, error) {
+	host, repository, found := strings.Cut(cfg.OciRepository, "/")
+	if !found || host == "" || repository == "" {
+		return nil, fmt.Errorf("OCI repository '%s' must be given as <registry>/<repository>", cfg.OciRepository)
+	}
+
+	scheme := "https"
+	if cfg.OciPlainHttp {
+		scheme = "http"
+	}
+
+	return &oci{
+		registry:   host,
+		repository: repository,
+		tag:        cfg.OciTag,
+		scheme:     scheme,
+		client:     registry.NewClient(&http.Client{Transport: version.Transport(nil)}, cfg.OciUsername, cfg.OciPassword),
+	}, nil
+}
+
+// Check pings the registry API to verify it is reachable and accepts the credentials
+func (o *oci) Check(ctx context.Context) error {
+	res, err := o.client.Do(ctx, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, fmt.Sprintf("%s://%s/v2/", o.scheme, o.registry), nil)
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return registry.CheckStatus(res, http.StatusOK, "registry ping")
+}
+
+// Store pushes the report as single layer artifact and tags it with the configured tag or the environment name.
+// Reports stored next to the main report are tagged with their file name instead, so they don't replace it.
+func (o *oci) Store(ctx context.Context, report backend.Report) (backend.StoreResult, error) {
+	tag := o.tag
+	if tag == "" {
+		tag = report.Environment
+	}
+	if report.NamedByFile {
+		tag = report.FileName
+	}
+	tag = sanitizeTag(tag)
+
+	mediaType := report.ContentType
+	if mediaType == "" {
+		mediaType = "application/json"
+	}
+	if report.ContentEncoding == "gzip" {
+		mediaType += "+gzip"
+	}
+
+	layer := descriptor{
+		MediaType:   mediaType,
+		Digest:      digest(report.Content),
+		Size:        len(report.Content),
+		Annotations: map[string]string{"org.opencontainers.image.title": report.FileName},
+	}
+	config := descriptor{
+		MediaType: emptyMediaType,
+		Digest:    digest(emptyConfig),
+		Size:      len(emptyConfig),
+		Data:      emptyConfig,
+	}
+
+	if err := o.pushBlob(ctx, config.Digest, emptyConfig); err != nil {
+		return backend.StoreResult{}, err
+	}
+	if err := o.pushBlob(ctx, layer.Digest, report.Content); err != nil {
+		return backend.StoreResult{}, err
+	}
+
+	annotations := map[string]string{"org.opencontainers.image.created": time.Now().UTC().Format(time.RFC3339)}
+	for key, value := range report.Metadata {
+		annotations["org.sdase.image-metadata-collector."+key] = value
+	}
+
+	manifestBytes, err := json.Marshal(manifest{
+		SchemaVersion: 2,
+		MediaType:     manifestMediaType,
+		ArtifactType:  ArtifactType,
+		Config:        config,
+		Layers:        []descriptor{layer},
+		Annotations:   annotations,
+	})
+	if err != nil {
+		return backend.StoreResult{}, err
+	}
+
+	manifestDigest, err := o.pushManifest(ctx, tag, manifestBytes)
+	if err != nil {
+		return backend.StoreResult{}, err
+	}
+
+	location := fmt.Sprintf("%s/%s:%s", o.registry, o.repository, tag)
+	log.Info().Str("reference", location).Str("digest", manifestDigest).Msg("Pushed report as OCI artifact")
+
+	return backend.StoreResult{
+		Location: location,
+		Size:     len(report.Content),
+		Metadata: map[string]string{"digest": manifestDigest},
+	}, nil
+}
+
+func (o *oci) url(path string) string {
+	return fmt.Sprintf("%s://%s/v2/%s/%s", o.scheme, o.registry, o.repository, path)
+}
+
+// pushBlob uploads content with a monolithic upload unless the registry already has it
+func (o *oci) pushBlob(ctx context.Context, blobDigest string, content []byte) error {
+	res, err := o.client.Do(ctx, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodHead, o.url("blobs/"+blobDigest), nil)
+	})
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		log.Debug().Str("digest", blobDigest).Msg("Blob already exists in registry")
+		return nil
+	}
+
+	res, err = o.client.Do(ctx, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodPost, o.url("blobs/uploads/"), nil)
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err = registry.CheckStatus(res, http.StatusAccepted, "blob upload start"); err != nil {
+		return err
+	}
+
+	uploadUrl, err := res.Request.URL.Parse(res.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("registry sent invalid upload location: %w", err)
+	}
+	query := uploadUrl.Query()
+	query.Set("digest", blobDigest)
+	uploadUrl.RawQuery = query.Encode()
+
+	res, err = o.client.Do(ctx, func() (*http.Request, error) {
+		request, err := http.NewRequest(http.MethodPut, uploadUrl.String(), bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", "application/octet-stream")
+		return request, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return registry.CheckStatus(res, http.StatusCreated, "blob upload")
+}
+
+// pushManifest uploads the manifest with the given tag and returns the manifest digest
+func (o *oci) pushManifest(ctx context.Context, tag string, manifestBytes []byte) (string, error) {
+	res, err := o.client.Do(ctx, func() (*http.Request, error) {
+		request, err := http.NewRequest(http.MethodPut, o.url("manifests/"+url.PathEscape(tag)), bytes.NewReader(manifestBytes))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", manifestMediaType)
+		return request, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if err = registry.CheckStatus(res, http.StatusCreated, "manifest upload"); err != nil {
+		return "", err
+	}
+
+	manifestDigest := res.Header.Get("Docker-Content-Digest")
+	if manifestDigest == "" {
+		manifestDigest = digest(manifestBytes)
+	}
+	return manifestDigest, nil
+}
+
+func digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// sanitizeTag replaces characters which are not allowed in tags, tags are limited to 128 characters
+func sanitizeTag(tag string) string {
+	tag = invalidTagChars.ReplaceAllString(tag, "-")
+	tag = strings.TrimLeft(tag, ".-")
+	if tag == "" {
+		tag = "latest"
+	}
+	if len(tag) > 128 {
+		tag = tag[:128]
+	}
+	return tag
+}
@@ -0,0 +1,139 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+)
+
+// newFakeRegistry returns a minimal registry requiring a bearer token, it stores blobs and manifests in memory
+func newFakeRegistry(t *testing.T, blobs map[string][]byte, manifests map[string][]byte) *httptest.Server {
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": "registry-token"})
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer registry-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="fake",scope="repository:team/reports:push,pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/v2/team/reports/blobs/"):
+			if _, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/team/reports/blobs/")]; ok {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/team/reports/blobs/uploads/":
+			w.Header().Set("Location", "/v2/team/reports/blobs/uploads/some-id?state=abc")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && r.URL.Path == "/v2/team/reports/blobs/uploads/some-id":
+			if r.URL.Query().Get("state") != "abc" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			content, _ := io.ReadAll(r.Body)
+			blobs[r.URL.Query().Get("digest")] = content
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v2/team/reports/manifests/"):
+			content, _ := io.ReadAll(r.Body)
+			manifests[strings.TrimPrefix(r.URL.Path, "/v2/team/reports/manifests/")] = content
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return server
+}
+
+func TestStore(t *testing.T) {
+	blobs := map[string][]byte{}
+	manifests := map[string][]byte{}
+	server := newFakeRegistry(t, blobs, manifests)
+	defer server.Close()
+
+	o, err := NewOci(&OciConfig{
+		OciRepository: strings.TrimPrefix(server.URL, "http://") + "/team/reports",
+		OciUsername:   "user",
+		OciPassword:   "password",
+		OciPlainHttp:  true,
+	})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	content := []byte(`[{"image":"quay.io/name:tag"}]`)
+	result, err := o.Store(context.Background(), backend.Report{Content: content, FileName: "my/env-output.json", Environment: "my/env"})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	if !strings.HasSuffix(result.Location, "/team/reports:my-env") {
+		t.Fatalf("Expected location with tag my-env but got %s\n", result.Location)
+	}
+
+	manifestBytes, ok := manifests["my-env"]
+	if !ok {
+		t.Fatalf("Expected manifest with tag my-env but got %v\n", manifests)
+	}
+
+	var m manifest
+	if err = json.Unmarshal(manifestBytes, &m); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	if m.ArtifactType != ArtifactType || len(m.Layers) != 1 {
+		t.Fatalf("Expected artifact with single layer but got %s\n", manifestBytes)
+	}
+	if string(blobs[m.Layers[0].Digest]) != string(content) {
+		t.Fatalf("Expected layer content %s but got %s\n", content, blobs[m.Layers[0].Digest])
+	}
+	if _, ok := blobs[m.Config.Digest]; !ok {
+		t.Fatalf("Expected config blob %s to be pushed\n", m.Config.Digest)
+	}
+}
+
+func TestStoreNamedByFile(t *testing.T) {
+	blobs := map[string][]byte{}
+	manifests := map[string][]byte{}
+	server := newFakeRegistry(t, blobs, manifests)
+	defer server.Close()
+
+	o, err := NewOci(&OciConfig{
+		OciRepository: strings.TrimPrefix(server.URL, "http://") + "/team/reports",
+		OciTag:        "latest",
+		OciUsername:   "user",
+		OciPassword:   "password",
+		OciPlainHttp:  true,
+	})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	for _, fileName := range []string{"reports/ns-a.json", "reports/ns-b.json"} {
+		_, err = o.Store(context.Background(), backend.Report{Content: []byte(fileName), FileName: fileName, Environment: "prod", NamedByFile: true})
+		if err != nil {
+			t.Fatalf("Got an error=%v\n", err)
+		}
+	}
+
+	if len(manifests) != 2 || manifests["reports-ns-a.json"] == nil || manifests["reports-ns-b.json"] == nil {
+		t.Fatalf("Expected one manifest tagged per file name but got %v\n", manifests)
+	}
+}
@@ -2,10 +2,20 @@ package s3
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	awsS3 "github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"io"
 	// "github.com/go-playground/validator/v10"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -27,11 +37,10 @@ type s3 struct {
 	insecure       bool
 	region         string
 	forcePathStyle bool
-	fileName       string
 }
 
 // NewS3 creates a new S3Parameter instance.
-func NewS3(cfg *S3Config, fileName string) (*s3, error) {
+func NewS3(cfg *S3Config) (*s3, error) {
 
 	forcePathStyle := false
 
@@ -45,7 +54,6 @@ func NewS3(cfg *S3Config, fileName string) (*s3, error) {
 		insecure:       cfg.S3Insecure,
 		region:         cfg.S3Region,
 		forcePathStyle: forcePathStyle,
-		fileName:       fileName,
 	}
 
 	if s3.bucket == "" {
@@ -55,23 +63,56 @@ func NewS3(cfg *S3Config, fileName string) (*s3, error) {
 	return s3, nil
 }
 
-// Upload uploads the content to an S3 Bucket with a key consisting of the environmentName and the fileName.
-func (s3 s3) Write(content []byte) (int, error) {
+// Check verifies the bucket exists and is accessible with the configured credentials
+func (s3 s3) Check(ctx context.Context) error {
+	sess, err := s3.newSession()
+	if err != nil {
+		return err
+	}
 
-	insecureStr := strconv.FormatBool(s3.insecure)
-	log.Info().Str("s3.insecure", insecureStr).Msg("in Upload")
+	_, err = awsS3.New(sess).HeadBucketWithContext(ctx, &awsS3.HeadBucketInput{Bucket: aws.String(s3.bucket)})
+	if err != nil {
+		return fmt.Errorf("could not access S3 bucket %s: %w", s3.bucket, err)
+	}
+
+	return nil
+}
 
+func (s3 s3) newSession() (*session.Session, error) {
 	sess, err := session.NewSession(&aws.Config{
 		DisableSSL:       aws.Bool(s3.insecure),
 		S3ForcePathStyle: aws.Bool(s3.forcePathStyle),
 		Region:           aws.String(s3.region),
 		LogLevel:         getAwsLoglevel(),
+		Logger:           awsLogger,
 		Endpoint:         aws.String(s3.endpoint),
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(version.Name, version.Version))
+
+	return sess, nil
+}
+
+// Store uploads the report to an S3 Bucket with the report fileName as key.
+func (s3 s3) Store(ctx context.Context, report backend.Report) (backend.StoreResult, error) {
+	return s3.StoreStream(ctx, report, bytes.NewReader(report.Content))
+}
+
+// StoreStream uploads the content while it is read, the upload manager splits it into parts so only a few parts are
+// held in memory
+func (s3 s3) StoreStream(ctx context.Context, report backend.Report, content io.Reader) (backend.StoreResult, error) {
+
+	insecureStr := strconv.FormatBool(s3.insecure)
+	log.Info().Str("s3.insecure", insecureStr).Msg("in Upload")
+
+	sess, err := s3.newSession()
 
 	if err != nil {
 		log.Error().Msg(fmt.Sprintf("Failed to create an aws session err: %v", err))
-		return len(content), err
+		return backend.StoreResult{}, err
 	}
 
 	// Setup the S3 Upload Manager. Also see the SDK doc for the Upload Manager
@@ -79,25 +120,80 @@ func (s3 s3) Write(content []byte) (int, error) {
 	// http://docs.aws.amazon.com/sdk-for-go/api/service/s3/s3manager/#NewUploader
 	uploader := s3manager.NewUploader(sess)
 
-	_, err = uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(s3.bucket),
-		Key:    aws.String(s3.fileName),
-		Body:   bytes.NewReader(content),
-	})
+	body := &backend.CountingReader{Reader: content}
+	input := &s3manager.UploadInput{
+		Bucket:   aws.String(s3.bucket),
+		Key:      aws.String(report.FileName),
+		Body:     body,
+		Metadata: aws.StringMap(report.Metadata),
+	}
+	if report.ContentType != "" {
+		input.ContentType = aws.String(report.ContentType)
+	}
+	if report.ContentEncoding != "" {
+		input.ContentEncoding = aws.String(report.ContentEncoding)
+	}
+	if report.Checksum != "" {
+		// S3 verifies the checksum header for single part uploads, the metadata is kept for multipart uploads
+		if checksum, err := hex.DecodeString(report.Checksum); err == nil {
+			input.ChecksumSHA256 = aws.String(base64.StdEncoding.EncodeToString(checksum))
+		}
+		if input.Metadata == nil {
+			input.Metadata = map[string]*string{}
+		}
+		input.Metadata["sha256"] = aws.String(report.Checksum)
+	}
+
+	output, err := uploader.UploadWithContext(ctx, input)
 
 	if err != nil {
 		log.Error().Msg(fmt.Sprintf("Failed to upload to S3 bucket %s, err: %v", s3.bucket, err))
-		return 0, err
+		return backend.StoreResult{}, err
+	}
+
+	log.Info().Str("fileName", report.FileName).Msg("Created new file in s3")
+
+	result := backend.StoreResult{
+		Location: output.Location,
+		Size:     body.N,
+		Metadata: map[string]string{},
+	}
+	if output.VersionID != nil {
+		result.Metadata["versionId"] = *output.VersionID
+	}
+	if output.ETag != nil {
+		result.Metadata["etag"] = *output.ETag
 	}
 
-	log.Info().Str("fileName", s3.fileName).Msg("Created new file in s3")
+	return result, nil
+}
 
-	return len(content), nil
+// Read downloads the object of the report
+func (s3 s3) Read(ctx context.Context, fileName string) ([]byte, error) {
+	sess, err := s3.newSession()
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := awsS3.New(sess).GetObjectWithContext(ctx, &awsS3.GetObjectInput{Bucket: aws.String(s3.bucket), Key: aws.String(fileName)})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == awsS3.ErrCodeNoSuchKey {
+		return nil, fmt.Errorf("%w: s3://%s/%s", backend.ErrNotFound, s3.bucket, fileName)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read %s from S3 bucket %s: %w", fileName, s3.bucket, err)
+	}
+	defer output.Body.Close()
+	return io.ReadAll(output.Body)
 }
 
+// awsLogger writes the debug output of the SDK through zerolog, so the signing details and credentials are masked
+var awsLogger = aws.LoggerFunc(func(args ...any) {
+	log.Debug().Str("component", "aws-sdk").Msg(fmt.Sprint(args...))
+})
+
 func getAwsLoglevel() *aws.LogLevelType {
 	logLevel := aws.LogLevel(aws.LogOff)
-	if zerolog.GlobalLevel() == zerolog.DebugLevel {
+	if zerolog.GlobalLevel() <= zerolog.DebugLevel {
 		logLevel = aws.LogLevel(aws.LogDebug | aws.LogDebugWithHTTPBody | aws.LogDebugWithRequestRetries | aws.LogDebugWithRequestErrors | aws.LogDebugWithSigning)
 	}
 	return logLevel
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/redact"
+)
+
+// withSecretFiles returns a copy of the config with the secrets read from their files. Passing secrets as flags
+// leaks them into the pod spec and the process list, mounted secret files do not.
+func withSecretFiles(cfg *StorageConfig) (*StorageConfig, error) {
+	loaded := *cfg
+
+	secrets := []struct {
+		name  string
+		value *string
+		file  string
+	}{
+		{"api-key", &loaded.ApiKey, cfg.ApiKeyFile},
+		{"api-signature", &loaded.ApiSignature, cfg.ApiSignatureFile},
+		{"api-oauth-client-secret", &loaded.ApiOAuthClientSecret, cfg.ApiOAuthClientSecretFile},
+		{"git-password", &loaded.GitPassword, cfg.GitPasswordFile},
+		{"oci-password", &loaded.OciPassword, cfg.OciPasswordFile},
+		{"servicenow-password", &loaded.ServiceNowPassword, cfg.ServiceNowPasswordFile},
+		{"grpc-token", &loaded.GrpcToken, cfg.GrpcTokenFile},
+	}
+
+	for _, secret := range secrets {
+		if secret.file == "" {
+			continue
+		}
+		if *secret.value != "" {
+			return nil, fmt.Errorf("%s and %s-file are mutually exclusive", secret.name, secret.name)
+		}
+
+		value, err := readSecretFile(secret.file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s-file: %w", secret.name, err)
+		}
+		*secret.value = value
+		redact.Register(value)
+	}
+
+	return &loaded, nil
+}
+
+// readSecretFile reads the file and trims trailing newlines, which editors and 'echo' add
+func readSecretFile(fileName string) (string, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
@@ -0,0 +1,290 @@
+package servicenow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxResponseSize limits the Table API responses read into memory
+const maxResponseSize = 1 << 20
+
+type ServiceNowConfig struct {
+	ServiceNowInstanceUrl  string
+	ServiceNowUsername     string
+	ServiceNowPassword     string
+	ServiceNowPasswordFile string
+	ServiceNowTable        string
+	ServiceNowMappingFile  string
+	ServiceNowTimeout      time.Duration
+}
+
+// Mapping maps the fields of the report to the fields of the CI records, the key fields identify the CI of an image
+type Mapping struct {
+	// Key are the CI fields to look up existing records, they have to be mapped in Fields
+	Key []string `json:"key"`
+	// Fields maps CI field names to report field names, e.g. "name": "image"
+	Fields map[string]string `json:"fields"`
+}
+
+// DefaultMapping creates one CI per image and environment
+var DefaultMapping = Mapping{
+	Key:    []string{"name", "environment"},
+	Fields: map[string]string{"name": "image", "environment": "environment", "short_description": "description"},
+}
+
+type serviceNow struct {
+	instanceUrl string
+	table       string
+	username    string
+	password    string
+	mapping     Mapping
+	client      *http.Client
+}
+
+// NewServiceNow creates a storage which upserts a CI record per image with the ServiceNow Table API
+func NewServiceNow(cfg *ServiceNowConfig) (*serviceNow, error) {
+	instanceUrl, err := url.Parse(strings.TrimSuffix(cfg.ServiceNowInstanceUrl, "/"))
+	if err != nil || instanceUrl.Scheme == "" || instanceUrl.Host == "" {
+		return nil, fmt.Errorf("ServiceNow instance URL '%s' must be given as https://<instance>.service-now.com", cfg.ServiceNowInstanceUrl)
+	}
+
+	mapping := DefaultMapping
+	if cfg.ServiceNowMappingFile != "" {
+		if mapping, err = readMapping(cfg.ServiceNowMappingFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return &serviceNow{
+		instanceUrl: instanceUrl.String(),
+		table:       cfg.ServiceNowTable,
+		username:    cfg.ServiceNowUsername,
+		password:    cfg.ServiceNowPassword,
+		mapping:     mapping,
+		client:      &http.Client{Transport: version.Transport(nil), Timeout: cfg.ServiceNowTimeout},
+	}, nil
+}
+
+func readMapping(fileName string) (Mapping, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return Mapping{}, fmt.Errorf("could not read ServiceNow mapping file: %w", err)
+	}
+
+	var mapping Mapping
+	if err = json.Unmarshal(content, &mapping); err != nil {
+		return Mapping{}, fmt.Errorf("invalid ServiceNow mapping file %s: %w", fileName, err)
+	}
+	if len(mapping.Key) == 0 {
+		return Mapping{}, fmt.Errorf("ServiceNow mapping file %s has no key fields", fileName)
+	}
+	for _, key := range mapping.Key {
+		if _, ok := mapping.Fields[key]; !ok {
+			return Mapping{}, fmt.Errorf("key field %s of ServiceNow mapping file %s is not mapped", key, fileName)
+		}
+	}
+	return mapping, nil
+}
+
+// Store creates or updates the CI record of every image of the JSON or NDJSON report. Images with the same key are
+// written once.
+func (s *serviceNow) Store(ctx context.Context, report backend.Report) (backend.StoreResult, error) {
+	if report.ContentEncoding != "" {
+		return backend.StoreResult{}, fmt.Errorf("ServiceNow storage does not support the content encoding %s", report.ContentEncoding)
+	}
+	images, err := decodeImages(report.Content)
+	if err != nil {
+		return backend.StoreResult{}, fmt.Errorf("ServiceNow storage requires json or ndjson output: %w", err)
+	}
+
+	created, updated := 0, 0
+	written := map[string]bool{}
+	for _, image := range images {
+		record := s.record(image)
+		query := s.query(record)
+		if written[query] {
+			continue
+		}
+		written[query] = true
+
+		sysId, err := s.find(ctx, query)
+		if err != nil {
+			return backend.StoreResult{}, err
+		}
+		if sysId == "" {
+			err = s.send(ctx, http.MethodPost, s.tableUrl(), record, http.StatusCreated)
+			created++
+		} else {
+			err = s.send(ctx, http.MethodPatch, s.tableUrl()+"/"+url.PathEscape(sysId), record, http.StatusOK)
+			updated++
+		}
+		if err != nil {
+			return backend.StoreResult{}, err
+		}
+	}
+
+	log.Debug().Int("created", created).Int("updated", updated).Str("table", s.table).Msg("Stored CI records in ServiceNow")
+	return backend.StoreResult{
+		Location: s.tableUrl(),
+		Size:     len(report.Content),
+		Metadata: map[string]string{"created": strconv.Itoa(created), "updated": strconv.Itoa(updated)},
+	}, nil
+}
+
+// Check verifies the credentials and the read permission on the table
+func (s *serviceNow) Check(ctx context.Context) error {
+	res, err := s.do(ctx, http.MethodGet, s.tableUrl()+"?sysparm_limit=1&sysparm_fields=sys_id", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return checkStatus(res, http.StatusOK)
+}
+
+func (s *serviceNow) tableUrl() string {
+	return s.instanceUrl + "/api/now/table/" + url.PathEscape(s.table)
+}
+
+// decodeImages reads a JSON array, the images of an envelope or one JSON object per line
+func decodeImages(content []byte) ([]map[string]any, error) {
+	var images []map[string]any
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("[")) {
+		err := json.Unmarshal(content, &images)
+		return images, err
+	}
+
+	var envelope struct {
+		Images *[]map[string]any `json:"images"`
+	}
+	if err := json.Unmarshal(content, &envelope); err == nil && envelope.Images != nil {
+		return *envelope.Images, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		var image map[string]any
+		if err := decoder.Decode(&image); errors.Is(err, io.EOF) {
+			return images, nil
+		} else if err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+}
+
+// record maps the report fields of the image to CI fields, lists are joined by commas
+func (s *serviceNow) record(image map[string]any) map[string]string {
+	record := map[string]string{}
+	for ciField, reportField := range s.mapping.Fields {
+		switch value := image[reportField].(type) {
+		case nil:
+			record[ciField] = ""
+		case string:
+			record[ciField] = value
+		case []any:
+			values := make([]string, 0, len(value))
+			for _, v := range value {
+				values = append(values, fmt.Sprint(v))
+			}
+			record[ciField] = strings.Join(values, ",")
+		default:
+			record[ciField] = fmt.Sprint(value)
+		}
+	}
+	return record
+}
+
+// query returns the encoded query matching the key fields of the record
+func (s *serviceNow) query(record map[string]string) string {
+	conditions := make([]string, 0, len(s.mapping.Key))
+	for _, key := range s.mapping.Key {
+		// '^' separates conditions, it is escaped by doubling it
+		conditions = append(conditions, key+"="+strings.ReplaceAll(record[key], "^", "^^"))
+	}
+	slices.Sort(conditions)
+	return strings.Join(conditions, "^")
+}
+
+// find returns the sys_id of the record matching the query or an empty string
+func (s *serviceNow) find(ctx context.Context, query string) (string, error) {
+	params := url.Values{"sysparm_query": {query}, "sysparm_fields": {"sys_id"}, "sysparm_limit": {"1"}}
+	res, err := s.do(ctx, http.MethodGet, s.tableUrl()+"?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if err = checkStatus(res, http.StatusOK); err != nil {
+		return "", err
+	}
+	var result struct {
+		Result []struct {
+			SysId string `json:"sys_id"`
+		} `json:"result"`
+	}
+	if err = json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid ServiceNow response: %w", err)
+	}
+	if len(result.Result) == 0 {
+		return "", nil
+	}
+	return result.Result[0].SysId, nil
+}
+
+func (s *serviceNow) send(ctx context.Context, method, address string, record map[string]string, expected int) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	res, err := s.do(ctx, method, address, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return checkStatus(res, expected)
+}
+
+func (s *serviceNow) do(ctx context.Context, method, address string, body []byte) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, address, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.SetBasicAuth(s.username, s.password)
+	return s.client.Do(request)
+}
+
+// checkStatus returns an error with the ServiceNow error message if the status is not the expected one
+func checkStatus(res *http.Response, expected int) error {
+	if res.StatusCode == expected {
+		return nil
+	}
+
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+			Detail  string `json:"detail"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&body)
+	return fmt.Errorf("ServiceNow %s %s returned status %s: %s %s", res.Request.Method, res.Request.URL.Path, res.Status, body.Error.Message, body.Error.Detail)
+}
@@ -0,0 +1,136 @@
+package servicenow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+)
+
+// newFakeInstance returns a minimal Table API holding the records of cmdb_ci_docker_image in memory
+func newFakeInstance(t *testing.T, records map[string]map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"User Not Authenticated"}}`))
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/now/table/cmdb_ci_docker_image":
+			result := []map[string]string{}
+			for sysId, record := range records {
+				if r.URL.Query().Get("sysparm_query") == "environment="+record["environment"]+"^name="+record["name"] {
+					result = append(result, map[string]string{"sys_id": sysId})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"result": result})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/now/table/cmdb_ci_docker_image":
+			var record map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&record)
+			records["new-"+record["name"]] = record
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/now/table/cmdb_ci_docker_image/existing":
+			var record map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&record)
+			records["existing"] = record
+		default:
+			t.Errorf("Unexpected request %s %s\n", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestStore(t *testing.T) {
+	records := map[string]map[string]string{"existing": {"name": "nginx:1", "environment": "prod"}}
+	server := newFakeInstance(t, records)
+	defer server.Close()
+
+	storage, err := NewServiceNow(&ServiceNowConfig{ServiceNowInstanceUrl: server.URL + "/", ServiceNowUsername: "user", ServiceNowPassword: "password", ServiceNowTable: "cmdb_ci_docker_image"})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	content := `[{"image":"nginx:1","environment":"prod","description":"web"},` +
+		`{"image":"nginx:1","environment":"prod","description":"duplicate"},` +
+		`{"image":"mongo:7","environment":"prod","description":"db"}]`
+	result, err := storage.Store(context.Background(), backend.Report{Content: []byte(content)})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	if result.Metadata["created"] != "1" || result.Metadata["updated"] != "1" {
+		t.Fatalf("Expected one created and one updated record but got %v\n", result.Metadata)
+	}
+	expected := map[string]map[string]string{
+		"existing":    {"name": "nginx:1", "environment": "prod", "short_description": "web"},
+		"new-mongo:7": {"name": "mongo:7", "environment": "prod", "short_description": "db"},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatalf("Expected records %v but got %v\n", expected, records)
+	}
+
+	if err = storage.Check(context.Background()); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	storage.password = "wrong"
+	if err = storage.Check(context.Background()); err == nil {
+		t.Fatalf("Expected an error for wrong credentials\n")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	storage := &serviceNow{mapping: Mapping{Key: []string{"name"}, Fields: map[string]string{"name": "image", "u_tags": "engagement_tags", "u_skip": "skip", "u_team": "team"}}}
+
+	images, err := decodeImages([]byte(`{"image":"nginx:1","engagement_tags":["a","b"],"skip":true}` + "\n"))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	record := storage.record(images[0])
+	expected := map[string]string{"name": "nginx:1", "u_tags": "a,b", "u_skip": "true", "u_team": ""}
+	if !reflect.DeepEqual(record, expected) {
+		t.Fatalf("Expected %v but got %v\n", expected, record)
+	}
+
+	images, err = decodeImages([]byte(`{"version":"1.0","images":[{"image":"nginx:1"},{"image":"redis:7"}],"errors":[]}`))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if len(images) != 2 || images[1]["image"] != "redis:7" {
+		t.Fatalf("Expected the images of the envelope but got %v\n", images)
+	}
+}
+
+func TestReadMapping(t *testing.T) {
+	dir := t.TempDir()
+	testCases := []struct {
+		name          string
+		content       string
+		expectSuccess bool
+	}{
+		{name: "Valid", content: `{"key":["name"],"fields":{"name":"image"}}`, expectSuccess: true},
+		{name: "NoKey", content: `{"fields":{"name":"image"}}`},
+		{name: "UnmappedKey", content: `{"key":["u_id"],"fields":{"name":"image"}}`},
+		{name: "Invalid", content: `key: name`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fileName := filepath.Join(dir, tc.name+".json")
+			if err := os.WriteFile(fileName, []byte(tc.content), 0o600); err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+			_, err := readMapping(fileName)
+			if tc.expectSuccess != (err == nil) {
+				t.Fatalf("Expected success=%t but got error=%v\n", tc.expectSuccess, err)
+			}
+		})
+	}
+}
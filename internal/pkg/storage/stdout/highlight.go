@@ -0,0 +1,87 @@
+package stdout
+
+import (
+	"bytes"
+)
+
+const (
+	colorReset   = "\x1b[0m"
+	colorKey     = "\x1b[34;1m"
+	colorString  = "\x1b[32m"
+	colorNumber  = "\x1b[36m"
+	colorLiteral = "\x1b[33m"
+)
+
+// highlight adds ANSI colors to valid JSON, keys, strings, numbers and literals (true, false, null) are colored differently
+func highlight(content []byte) []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(content) * 2)
+
+	for i := 0; i < len(content); {
+		c := content[i]
+
+		switch {
+		case c == '"':
+			end := stringEnd(content, i)
+			color := colorString
+			if isKey(content, end) {
+				color = colorKey
+			}
+			writeColored(&buf, color, content[i:end])
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(content) && bytes.IndexByte([]byte("0123456789.eE+-"), content[end]) >= 0 {
+				end++
+			}
+			writeColored(&buf, colorNumber, content[i:end])
+			i = end
+		case c == 't' || c == 'f' || c == 'n':
+			end := i + 1
+			for end < len(content) && content[end] >= 'a' && content[end] <= 'z' {
+				end++
+			}
+			writeColored(&buf, colorLiteral, content[i:end])
+			i = end
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// stringEnd returns the index after the closing quote of the string starting at start
+func stringEnd(content []byte, start int) int {
+	for i := start + 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(content)
+}
+
+// isKey returns true if the next non whitespace character is a colon
+func isKey(content []byte, from int) bool {
+	for i := from; i < len(content); i++ {
+		switch content[i] {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case ':':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func writeColored(buf *bytes.Buffer, color string, token []byte) {
+	buf.WriteString(color)
+	buf.Write(token)
+	buf.WriteString(colorReset)
+}
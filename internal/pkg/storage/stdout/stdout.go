@@ -0,0 +1,86 @@
+package stdout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+)
+
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+type StdoutConfig struct {
+	StdoutCompact bool
+	StdoutColor   string
+}
+
+type stdout struct {
+	out     io.Writer
+	compact bool
+	color   bool
+}
+
+// NewStdout creates a new storage printing the report to stdout, color "auto" highlights only if stdout is a terminal
+func NewStdout(cfg *StdoutConfig) (*stdout, error) {
+	var color bool
+
+	switch cfg.StdoutColor {
+	case ColorAuto, "":
+		color = isTerminal(os.Stdout)
+	case ColorAlways:
+		color = true
+	case ColorNever:
+		color = false
+	default:
+		return nil, fmt.Errorf("stdout color %s is not supported", cfg.StdoutColor)
+	}
+
+	return &stdout{out: os.Stdout, compact: cfg.StdoutCompact, color: color}, nil
+}
+
+// Store prints the report, JSON content is reformatted as configured and any other content is printed as is
+func (s *stdout) Store(ctx context.Context, report backend.Report) (backend.StoreResult, error) {
+	content := report.Content
+
+	var buf bytes.Buffer
+	var err error
+
+	if s.compact {
+		err = json.Compact(&buf, content)
+	} else {
+		err = json.Indent(&buf, content, "", "\t")
+	}
+
+	formatted := buf.Bytes()
+	if err != nil {
+		formatted = content
+	} else if s.color {
+		formatted = highlight(formatted)
+	}
+
+	if len(formatted) > 0 && formatted[len(formatted)-1] != '\n' {
+		formatted = append(formatted, '\n')
+	}
+
+	if _, err = s.out.Write(formatted); err != nil {
+		return backend.StoreResult{}, err
+	}
+
+	return backend.StoreResult{Location: "stdout", Size: len(content)}, nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
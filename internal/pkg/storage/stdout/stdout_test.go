@@ -0,0 +1,63 @@
+package stdout
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+)
+
+func TestWrite(t *testing.T) {
+	testCases := []struct {
+		name           string
+		compact        bool
+		color          bool
+		content        string
+		expectedResult string
+	}{
+		{
+			name:           "IndentedExpectTabs",
+			compact:        false,
+			color:          false,
+			content:        `[{"image":"a","skip":false}]`,
+			expectedResult: "[\n\t{\n\t\t\"image\": \"a\",\n\t\t\"skip\": false\n\t}\n]\n",
+		},
+		{
+			name:           "CompactExpectSingleLine",
+			compact:        true,
+			color:          false,
+			content:        "[\n\t{\n\t\t\"image\": \"a\"\n\t}\n]",
+			expectedResult: "[{\"image\":\"a\"}]\n",
+		},
+		{
+			name:           "ColorExpectHighlightedTokens",
+			compact:        true,
+			color:          true,
+			content:        `{"a":"b","c":1,"d":null}`,
+			expectedResult: "{" + colorKey + `"a"` + colorReset + ":" + colorString + `"b"` + colorReset + "," + colorKey + `"c"` + colorReset + ":" + colorNumber + "1" + colorReset + "," + colorKey + `"d"` + colorReset + ":" + colorLiteral + "null" + colorReset + "}\n",
+		},
+		{
+			name:           "InvalidJsonExpectUnchanged",
+			compact:        true,
+			color:          true,
+			content:        "not json",
+			expectedResult: "not json\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			s := &stdout{out: &out, compact: tc.compact, color: tc.color}
+
+			if _, err := s.Store(context.Background(), backend.Report{Content: []byte(tc.content)}); err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+
+			if out.String() != tc.expectedResult {
+				t.Fatalf("Expected %q, got %q", tc.expectedResult, out.String())
+			}
+		})
+	}
+}
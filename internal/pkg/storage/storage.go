@@ -1,52 +1,186 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"os"
+	"strings"
 
 	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/api"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/backend"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/crd"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/fs"
 	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/git"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/grpc"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/oci"
 	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/s3"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/servicenow"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/stdout"
+
+	"github.com/rs/zerolog/log"
+)
+
+type (
+	Checker     = backend.Checker
+	Reader      = backend.Reader
+	Storager    = backend.Storager
+	Report      = backend.Report
+	StoreResult = backend.StoreResult
 )
 
+// ErrNotFound is returned by Read if no report was stored under the file name yet
+var ErrNotFound = backend.ErrNotFound
+
+// ErrReadUnsupported is returned by Read for storages which can't read reports back, e.g. the api or encrypted output
+var ErrReadUnsupported = errors.New("storage can't read reports")
+
+// NewWriter adapts a Storager to an io.Writer for callers which only deal with serialized content
+var NewWriter = backend.NewWriter
+
 type StorageConfig struct {
 	s3.S3Config
 	git.GitConfig
 	api.ApiConfig
+	fs.FsConfig
+	stdout.StdoutConfig
+	oci.OciConfig
+	servicenow.ServiceNowConfig
+	crd.CrdConfig
+	grpc.GrpcConfig
+
+	StorageFlag    string
+	FileName       string
+	CompressOutput bool
+	Checksum       bool
+	Preflight      bool
 
-	StorageFlag string
-	FileName    string
+	EncryptAgeRecipients    []string
+	EncryptPgpPublicKeyFile string
 }
 
-func NewStorage(cfg *StorageConfig, environment string) (io.Writer, error) {
+// ReportFileName returns the configured file name, defaults to '<environment>-output<extension>' e.g. 'prod-output.json'
+func (cfg *StorageConfig) ReportFileName(environment, extension string) string {
+	if cfg.FileName != "" {
+		return cfg.FileName
+	}
+	return environment + "-output" + extension
+}
 
-	var w io.Writer
-	var err error
+// SplitReportFileName returns the file name of the report for a single group of a split output. A configured file
+// name has to contain the '{<splitBy>}' placeholder, e.g. 'reports/{namespace}.json', '{environment}' is replaced
+// as well. Defaults to '<environment>-<group>-output<extension>'
+func (cfg *StorageConfig) SplitReportFileName(environment, splitBy, group, extension string) (string, error) {
+	if cfg.FileName == "" {
+		return environment + "-" + group + "-output" + extension, nil
+	}
 
-	filename := cfg.FileName
+	placeholder := "{" + splitBy + "}"
+	if !strings.Contains(cfg.FileName, placeholder) {
+		return "", fmt.Errorf("file name %s has to contain %s when splitting the output by %s", cfg.FileName, placeholder, splitBy)
+	}
+
+	return strings.NewReplacer(placeholder, group, "{environment}", environment).Replace(cfg.FileName), nil
+}
 
-	if filename == "" {
-		filename = environment + "-output.json"
+// KeepsFileNames reports whether the storage keeps reports apart by their file name. The api posts every report to
+// the same endpoint, so a split output would overwrite itself
+func KeepsFileNames(storageFlag string) bool {
+	switch storageFlag {
+	case "api":
+		return false
+	default:
+		return true
+	}
+}
+
+func NewStorage(cfg *StorageConfig) (Storager, error) {
+	cfg, err := withSecretFiles(cfg)
+	if err != nil {
+		return nil, err
 	}
 
+	storager, err := newBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// The checksum is calculated on the stored (compressed) content, so it has to be wrapped first
+	if cfg.Checksum {
+		if _, ok := storager.(backend.StreamStorager); ok {
+			log.Info().Str("storage", cfg.StorageFlag).Msg("Reports are held in memory instead of streamed to the storage, as their checksum is stored with them")
+		}
+		storager = newChecksumStorage(storager)
+	}
+
+	// Encryption happens after compression as encrypted content does not compress
+	encryptStorage, err := newEncryptStorage(storager, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if encryptStorage != nil {
+		storager = encryptStorage
+	}
+
+	if cfg.CompressOutput {
+		switch cfg.StorageFlag {
+		case "s3", "git", "fs", "oci":
+			storager = newGzipStorage(storager)
+		default:
+			log.Warn().Str("storage", cfg.StorageFlag).Msg("Output compression is only supported for s3, git, fs and oci, ignoring it")
+		}
+	}
+
+	return storager, nil
+}
+
+func newBackend(cfg *StorageConfig) (Storager, error) {
 	switch cfg.StorageFlag {
 	case "s3":
-		w, err = s3.NewS3(&cfg.S3Config, filename)
+		return nilOnError(s3.NewS3(&cfg.S3Config))
 	case "api":
-		w = cfg.ApiConfig
+		return nilOnError(api.NewApi(&cfg.ApiConfig))
 	case "git":
-		w, err = git.NewGit(&cfg.GitConfig, filename)
+		return nilOnError(git.NewGit(&cfg.GitConfig))
 	case "fs":
-		var file *os.File
-		file, err = os.Create(filename)
-		w = file
+		return nilOnError(fs.NewFs(&cfg.FsConfig))
 	case "stdout":
-		w = os.Stdout
+		return nilOnError(stdout.NewStdout(&cfg.StdoutConfig))
+	case "oci":
+		return nilOnError(oci.NewOci(&cfg.OciConfig))
+	case "servicenow":
+		return nilOnError(servicenow.NewServiceNow(&cfg.ServiceNowConfig))
+	case "crd":
+		return nilOnError(crd.NewCrd(&cfg.CrdConfig))
+	case "grpc":
+		return nilOnError(grpc.NewGrpc(&cfg.GrpcConfig))
 	default:
-		w = nil
-		err = fmt.Errorf("Storage flag %s is not supported", cfg.StorageFlag)
+		return nil, fmt.Errorf("Storage flag %s is not supported", cfg.StorageFlag)
 	}
+}
+
+// Preflight verifies connectivity and credentials of the storage if the backend supports it
+func Preflight(ctx context.Context, storager Storager) error {
+	checker, ok := storager.(Checker)
+	if !ok {
+		return nil
+	}
+	return checker.Check(ctx)
+}
 
-	return w, err
+// Read returns a stored report, e.g. of the previous run, if the backend supports it. The content is decompressed
+// if the output is compressed.
+func Read(ctx context.Context, storager Storager, fileName string) ([]byte, error) {
+	reader, ok := storager.(Reader)
+	if !ok {
+		return nil, ErrReadUnsupported
+	}
+	return reader.Read(ctx, fileName)
+}
+
+// nilOnError avoids returning a non-nil Storager interface holding a nil pointer
+func nilOnError[T Storager](s T, err error) (Storager, error) {
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
 }
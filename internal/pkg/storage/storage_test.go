@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/fs"
+
+	"filippo.io/age"
+)
+
+type mockStorager struct {
+	report Report
+}
+
+func (m *mockStorager) Store(ctx context.Context, report Report) (StoreResult, error) {
+	m.report = report
+	return StoreResult{Location: report.FileName, Size: len(report.Content)}, nil
+}
+
+func TestGzipStorage(t *testing.T) {
+	next := &mockStorager{}
+	content := []byte(`[{"image":"quay.io/name:tag"}]`)
+
+	_, err := newGzipStorage(next).Store(context.Background(), Report{FileName: "env-output.json", Content: content})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	if next.report.FileName != "env-output.json.gz" {
+		t.Fatalf("Expected file name env-output.json.gz but got %s\n", next.report.FileName)
+	}
+	if next.report.ContentEncoding != "gzip" {
+		t.Fatalf("Expected content encoding gzip but got %s\n", next.report.ContentEncoding)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(next.report.Content))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	decompressed, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if !bytes.Equal(decompressed, content) {
+		t.Fatalf("Expected %s but got %s\n", content, decompressed)
+	}
+}
+
+func TestGzipStorageStream(t *testing.T) {
+	dir := t.TempDir()
+	next, err := fs.NewFs(&fs.FsConfig{FsBaseDir: dir})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	content := []byte(`[{"image":"quay.io/name:tag"}]`)
+
+	result, err := newGzipStorage(next).StoreStream(context.Background(), Report{FileName: "env-output.json"}, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, "env-output.json.gz"))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	defer file.Close()
+	r, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	decompressed, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if !bytes.Equal(decompressed, content) {
+		t.Fatalf("Expected %s but got %s\n", content, decompressed)
+	}
+	if info, _ := file.Stat(); int64(result.Size) != info.Size() {
+		t.Fatalf("Expected size %d but got %d\n", info.Size(), result.Size)
+	}
+
+	// Storagers without stream support receive the compressed content as a whole
+	buffered := &mockStorager{}
+	if _, err = newGzipStorage(buffered).StoreStream(context.Background(), Report{FileName: "env-output.json"}, bytes.NewReader(content)); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if buffered.report.ContentEncoding != "gzip" || len(buffered.report.Content) == 0 {
+		t.Fatalf("Expected the compressed content but got %+v\n", buffered.report)
+	}
+}
+
+type mockChecker struct {
+	mockStorager
+	checked bool
+}
+
+func (m *mockChecker) Check(ctx context.Context) error {
+	m.checked = true
+	return nil
+}
+
+func TestPreflightThroughWrappers(t *testing.T) {
+	next := &mockChecker{}
+
+	if err := Preflight(context.Background(), newGzipStorage(newChecksumStorage(next))); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if !next.checked {
+		t.Fatalf("Expected the wrapped storage to be checked\n")
+	}
+
+	if err := Preflight(context.Background(), &mockStorager{}); err != nil {
+		t.Fatalf("Expected no error for storages without check but got %v\n", err)
+	}
+}
+
+func TestReadThroughWrappers(t *testing.T) {
+	storager, err := NewStorage(&StorageConfig{StorageFlag: "fs", FsConfig: fs.FsConfig{FsBaseDir: t.TempDir()}, CompressOutput: true, Checksum: true})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	if _, err = Read(context.Background(), storager, "env-output.json"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound before the first report but got %v\n", err)
+	}
+
+	content := []byte(`[{"image":"quay.io/name:tag"}]`)
+	if _, err = storager.Store(context.Background(), Report{FileName: "env-output.json", Content: content}); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	read, err := Read(context.Background(), storager, "env-output.json")
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if !bytes.Equal(read, content) {
+		t.Fatalf("Expected %s but got %s\n", content, read)
+	}
+
+	if _, err = Read(context.Background(), &mockStorager{}, "env-output.json"); !errors.Is(err, ErrReadUnsupported) {
+		t.Fatalf("Expected ErrReadUnsupported but got %v\n", err)
+	}
+}
+
+func TestEncryptStorageAge(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	next := &mockStorager{}
+	encrypt, err := newEncryptStorage(next, &StorageConfig{EncryptAgeRecipients: []string{identity.Recipient().String()}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	content := []byte(`[{"image":"quay.io/name:tag"}]`)
+	if _, err = encrypt.Store(context.Background(), Report{FileName: "env-output.json.gz", Content: content, ContentEncoding: "gzip"}); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	if next.report.FileName != "env-output.json.gz.age" {
+		t.Fatalf("Expected file name env-output.json.gz.age but got %s\n", next.report.FileName)
+	}
+	if next.report.ContentEncoding != "" {
+		t.Fatalf("Expected no content encoding but got %s\n", next.report.ContentEncoding)
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(next.report.Content), identity)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	decrypted, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if !bytes.Equal(decrypted, content) {
+		t.Fatalf("Expected %s but got %s\n", content, decrypted)
+	}
+}
+
+func TestEncryptStorageStream(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	dir := t.TempDir()
+	next, err := fs.NewFs(&fs.FsConfig{FsBaseDir: dir})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	encrypt, err := newEncryptStorage(next, &StorageConfig{EncryptAgeRecipients: []string{identity.Recipient().String()}})
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	content := []byte(`[{"image":"quay.io/name:tag"}]`)
+	if _, err = encrypt.StoreStream(context.Background(), Report{FileName: "env-output.json"}, bytes.NewReader(content)); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, "env-output.json.age"))
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	defer file.Close()
+	r, err := age.Decrypt(file, identity)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	decrypted, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if !bytes.Equal(decrypted, content) {
+		t.Fatalf("Expected %s but got %s\n", content, decrypted)
+	}
+}
+
+func TestNewEncryptStorage(t *testing.T) {
+	storager, err := newEncryptStorage(&mockStorager{}, &StorageConfig{})
+	if err != nil || storager != nil {
+		t.Fatalf("Expected no encryption without recipients but got %v, %v\n", storager, err)
+	}
+
+	_, err = newEncryptStorage(&mockStorager{}, &StorageConfig{EncryptAgeRecipients: []string{"invalid"}})
+	if err == nil {
+		t.Fatalf("Expected an error for an invalid recipient but got none\n")
+	}
+}
+
+func TestSplitReportFileName(t *testing.T) {
+	testCases := []struct {
+		name             string
+		fileName         string
+		expectedFileName string
+		expectError      bool
+	}{
+		{name: "Default", fileName: "", expectedFileName: "prod-ns-output.json"},
+		{name: "Template", fileName: "{environment}/reports/{namespace}.json", expectedFileName: "prod/reports/ns.json"},
+		{name: "MissingPlaceholderExpectError", fileName: "reports.json", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &StorageConfig{FileName: tc.fileName}
+			fileName, err := cfg.SplitReportFileName("prod", "namespace", "ns", ".json")
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error but got none\n")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Got an error=%v\n", err)
+			}
+			if fileName != tc.expectedFileName {
+				t.Fatalf("Expected file name %s but got %s\n", tc.expectedFileName, fileName)
+			}
+		})
+	}
+}
+
+func TestWithSecretFiles(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(secretFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+
+	cfg := &StorageConfig{}
+	cfg.ApiKeyFile = secretFile
+	cfg.GitPassword = "password"
+
+	loaded, err := withSecretFiles(cfg)
+	if err != nil {
+		t.Fatalf("Got an error=%v\n", err)
+	}
+	if loaded.ApiKey != "secret" {
+		t.Fatalf("Expected api key secret but got %q\n", loaded.ApiKey)
+	}
+	if loaded.GitPassword != "password" {
+		t.Fatalf("Expected git password to be kept but got %q\n", loaded.GitPassword)
+	}
+	if cfg.ApiKey != "" {
+		t.Fatalf("Expected the config to be unchanged but got api key %q\n", cfg.ApiKey)
+	}
+
+	cfg.ApiKey = "flag"
+	if _, err = withSecretFiles(cfg); err == nil {
+		t.Fatalf("Expected an error if the secret and the secret file are set\n")
+	}
+
+	cfg.ApiKey = ""
+	cfg.ApiKeyFile = filepath.Join(t.TempDir(), "missing")
+	if _, err = withSecretFiles(cfg); err == nil {
+		t.Fatalf("Expected an error for a missing secret file\n")
+	}
+}
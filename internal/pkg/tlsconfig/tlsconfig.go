@@ -0,0 +1,122 @@
+// Package tlsconfig applies the TLS policy of the collector to all outbound HTTP clients
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+type TLSConfig struct {
+	// TlsMinVersion is the minimum TLS version, '1.2' or '1.3'
+	TlsMinVersion string
+	// TlsCipherSuites restricts the TLS 1.2 cipher suites by their IANA names, TLS 1.3 suites are not configurable
+	TlsCipherSuites []string
+	// TlsFips restricts cipher suites and curves to the FIPS 140 approved ones
+	TlsFips bool
+	// CaBundle is a PEM file of CAs trusted in addition to the system CAs, e.g. of a private PKI
+	CaBundle string
+}
+
+var versions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// fipsCipherSuites are the FIPS 140 approved TLS 1.2 cipher suites supported by crypto/tls
+var fipsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+var fipsCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+
+// New builds the tls config for the policy, it returns nil if the policy is empty so the Go defaults are used
+func New(cfg *TLSConfig) (*tls.Config, error) {
+	if cfg.TlsMinVersion == "" && len(cfg.TlsCipherSuites) == 0 && !cfg.TlsFips && cfg.CaBundle == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TlsMinVersion != "" {
+		version, ok := versions[strings.TrimPrefix(strings.ToLower(cfg.TlsMinVersion), "tls")]
+		if !ok {
+			return nil, fmt.Errorf("TLS version %s is not supported, use 1.2 or 1.3", cfg.TlsMinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if len(cfg.TlsCipherSuites) > 0 {
+		suites := map[string]uint16{}
+		for _, suite := range tls.CipherSuites() {
+			suites[suite.Name] = suite.ID
+		}
+		for _, name := range cfg.TlsCipherSuites {
+			id, ok := suites[name]
+			if !ok {
+				return nil, fmt.Errorf("TLS cipher suite %s is not supported or insecure", name)
+			}
+			if cfg.TlsFips && !isFips(id) {
+				return nil, fmt.Errorf("TLS cipher suite %s is not FIPS approved", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	} else if cfg.TlsFips {
+		tlsConfig.CipherSuites = fipsCipherSuites
+	}
+
+	if cfg.TlsFips {
+		tlsConfig.CurvePreferences = fipsCurves
+	}
+
+	if cfg.CaBundle != "" {
+		certPool, err := newCertPool(cfg.CaBundle)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = certPool
+	}
+	return tlsConfig, nil
+}
+
+// newCertPool adds the CAs of the bundle to the system CAs
+func newCertPool(caBundle string) (*x509.CertPool, error) {
+	caBytes, err := os.ReadFile(caBundle)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA bundle: %w", err)
+	}
+
+	certPool, err := x509.SystemCertPool()
+	if err != nil {
+		certPool = x509.NewCertPool()
+	}
+	if !certPool.AppendCertsFromPEM(caBytes) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", caBundle)
+	}
+	return certPool, nil
+}
+
+// Apply sets the policy on http.DefaultTransport, which the clients of the storages, the registry enrichment, the
+// AWS SDK and go-git are derived from
+func Apply(cfg *TLSConfig) error {
+	tlsConfig, err := New(cfg)
+	if err != nil || tlsConfig == nil {
+		return err
+	}
+	http.DefaultTransport.(*http.Transport).TLSClientConfig = tlsConfig
+	return nil
+}
+
+func isFips(id uint16) bool {
+	for _, suite := range fipsCipherSuites {
+		if suite == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	tlsConfig, err := New(&TLSConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	tlsConfig, err = New(&TLSConfig{TlsMinVersion: "1.3"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+	assert.Empty(t, tlsConfig.CipherSuites)
+
+	tlsConfig, err = New(&TLSConfig{TlsMinVersion: "1.2", TlsCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}})
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, tlsConfig.CipherSuites)
+
+	tlsConfig, err = New(&TLSConfig{TlsFips: true})
+	assert.NoError(t, err)
+	assert.Equal(t, fipsCipherSuites, tlsConfig.CipherSuites)
+	assert.Equal(t, fipsCurves, tlsConfig.CurvePreferences)
+}
+
+func TestNewInvalid(t *testing.T) {
+	for name, cfg := range map[string]TLSConfig{
+		"version":  {TlsMinVersion: "1.1"},
+		"unknown":  {TlsCipherSuites: []string{"TLS_UNKNOWN"}},
+		"insecure": {TlsCipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+		"fips":     {TlsCipherSuites: []string{"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"}, TlsFips: true},
+	} {
+		_, err := New(&cfg)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestCaBundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caBundle := filepath.Join(t.TempDir(), "ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, os.WriteFile(caBundle, pemBytes, 0600))
+
+	tlsConfig, err := New(&TLSConfig{CaBundle: caBundle})
+	assert.NoError(t, err)
+
+	res, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}).Get(server.URL)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+
+	_, err = New(&TLSConfig{CaBundle: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,184 @@
+// Package vault reads credentials from the KV secrets engine of HashiCorp Vault
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultJwtFile is the service account token used for the Kubernetes auth method
+const DefaultJwtFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// minRenewWait is the shortest wait between failed renewals
+const minRenewWait = 5 * time.Second
+
+// maxResponseSize limits the Vault responses read into memory
+const maxResponseSize = 1 << 20
+
+type VaultConfig struct {
+	VaultAddress string
+	// VaultToken is used instead of a login with the Kubernetes auth method
+	VaultToken     string
+	VaultRole      string
+	VaultAuthMount string
+	VaultJwtFile   string
+	// VaultSecrets map flags or environment variables to KV secrets in the format '<flag>=<path>#<key>' or
+	// 'env:<NAME>=<path>#<key>'
+	VaultSecrets []string
+}
+
+// Secret is a key of a KV secret and the flag or environment variable it is assigned to
+type Secret struct {
+	Target string
+	Path   string
+	Key    string
+}
+
+// ParseSecret parses a mapping in the format '<target>=<path>#<key>', e.g. 'api-key=secret/collector#api-key'
+func ParseSecret(mapping string) (Secret, error) {
+	target, ref, _ := strings.Cut(mapping, "=")
+	path, key, _ := strings.Cut(ref, "#")
+	if target == "" || path == "" || key == "" {
+		return Secret{}, fmt.Errorf("Vault secret %s has to be in the format <flag>=<path>#<key>", mapping)
+	}
+	return Secret{Target: target, Path: strings.Trim(path, "/"), Key: key}, nil
+}
+
+// Client is authenticated with a Vault token, KeepRenewed renews it while the collector runs continuously
+type Client struct {
+	httpClient *http.Client
+	address    string
+	token      string
+	renewable  bool
+	ttl        time.Duration
+}
+
+type authResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+// Login uses the configured token or logs in with the service account token at the Kubernetes auth method
+func Login(ctx context.Context, httpClient *http.Client, cfg *VaultConfig) (*Client, error) {
+	c := &Client{httpClient: httpClient, address: strings.TrimSuffix(cfg.VaultAddress, "/")}
+	if cfg.VaultToken != "" {
+		c.token = cfg.VaultToken
+		return c, nil
+	}
+
+	if cfg.VaultRole == "" {
+		return nil, fmt.Errorf("Vault role is required for the Kubernetes auth method")
+	}
+	jwt, err := os.ReadFile(cfg.VaultJwtFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read service account token: %w", err)
+	}
+
+	var res authResponse
+	body := map[string]string{"role": cfg.VaultRole, "jwt": strings.TrimSpace(string(jwt))}
+	if err = c.do(ctx, http.MethodPost, "auth/"+strings.Trim(cfg.VaultAuthMount, "/")+"/login", body, &res); err != nil {
+		return nil, fmt.Errorf("Vault login failed: %w", err)
+	}
+	c.setAuth(res)
+	return c, nil
+}
+
+func (c *Client) setAuth(res authResponse) {
+	c.token = res.Auth.ClientToken
+	c.renewable = res.Auth.Renewable
+	c.ttl = time.Duration(res.Auth.LeaseDuration) * time.Second
+}
+
+// Read returns the value of the key of a KV version 2 secret, the path starts with the mount of the engine, e.g.
+// 'secret/collector'
+func (c *Client) Read(ctx context.Context, secret Secret) (string, error) {
+	mount, path, _ := strings.Cut(secret.Path, "/")
+	var res struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, mount+"/data/"+path, nil, &res); err != nil {
+		return "", fmt.Errorf("could not read Vault secret %s: %w", secret.Path, err)
+	}
+
+	value, ok := res.Data.Data[secret.Key].(string)
+	if !ok {
+		return "", fmt.Errorf("Vault secret %s has no string key %s", secret.Path, secret.Key)
+	}
+	return value, nil
+}
+
+// KeepRenewed renews the token at two thirds of its TTL until the context is done, tokens given in the configuration
+// and tokens which are not renewable are left alone
+func (c *Client) KeepRenewed(ctx context.Context) {
+	if !c.renewable || c.ttl <= 0 {
+		return
+	}
+
+	wait := c.ttl * 2 / 3
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		var res authResponse
+		if err := c.do(ctx, http.MethodPost, "auth/token/renew-self", map[string]string{}, &res); err != nil {
+			// Retry sooner, the token is still valid for at least a third of its TTL
+			log.Warn().Err(err).Msg("Could not renew Vault token")
+			wait = max(wait/2, minRenewWait)
+			continue
+		}
+		c.setAuth(res)
+		wait = c.ttl * 2 / 3
+		log.Debug().Dur("ttl", c.ttl).Msg("Renewed Vault token")
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body any, v any) error {
+	var content io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, c.address+"/v1/"+path, content)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		request.Header.Set("X-Vault-Token", c.token)
+	}
+
+	res, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var errorResponse struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&errorResponse)
+		return fmt.Errorf("Vault returned status %s for %s: %s", res.Status, path, strings.Join(errorResponse.Errors, ", "))
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(v)
+}
@@ -0,0 +1,84 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSecret(t *testing.T) {
+	secret, err := ParseSecret("api-key=/secret/collector/#api-key")
+	assert.NoError(t, err)
+	assert.Equal(t, Secret{Target: "api-key", Path: "secret/collector", Key: "api-key"}, secret)
+
+	for _, mapping := range []string{"api-key", "api-key=secret/collector", "=secret/collector#key"} {
+		_, err = ParseSecret(mapping)
+		assert.Error(t, err, mapping)
+	}
+}
+
+func TestLoginAndRead(t *testing.T) {
+	renewed := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/kubernetes/login":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["role"] != "collector" || body["jwt"] != "sa-token" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"vault-token","lease_duration":1,"renewable":true}}`))
+		case "/v1/secret/data/collector":
+			if r.Header.Get("X-Vault-Token") != "vault-token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"data":{"api-key":"s3cr3t"}}}`))
+		case "/v1/auth/token/renew-self":
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"vault-token","lease_duration":3600,"renewable":true}}`))
+			select {
+			case renewed <- struct{}{}:
+			default:
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	jwtFile := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(jwtFile, []byte("sa-token\n"), 0o600))
+	cfg := &VaultConfig{VaultAddress: server.URL + "/", VaultRole: "collector", VaultAuthMount: "kubernetes", VaultJwtFile: jwtFile}
+
+	client, err := Login(context.Background(), server.Client(), cfg)
+	assert.NoError(t, err)
+
+	value, err := client.Read(context.Background(), Secret{Path: "secret/collector", Key: "api-key"})
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t", value)
+
+	_, err = client.Read(context.Background(), Secret{Path: "secret/collector", Key: "missing"})
+	assert.ErrorContains(t, err, "has no string key missing")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.KeepRenewed(ctx)
+	select {
+	case <-renewed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the token to be renewed")
+	}
+
+	cfg.VaultRole = "other"
+	_, err = Login(context.Background(), server.Client(), cfg)
+	assert.ErrorContains(t, err, "permission denied")
+}
@@ -0,0 +1,84 @@
+// Package version holds the build metadata of the collector, which is injected at build time via
+//
+//	go build -ldflags "-X github.com/SDA-SE/image-metadata-collector/internal/version.Version=1.2.3 \
+//	  -X github.com/SDA-SE/image-metadata-collector/internal/version.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/SDA-SE/image-metadata-collector/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+const Name = "image-metadata-collector"
+
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build metadata, the commit and build date fall back to the VCS information of the Go toolchain
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildDate: BuildDate, GoVersion: runtime.Version()}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+
+	return info
+}
+
+func (i Info) String() string {
+	return fmt.Sprintf("%s %s (commit %s, built %s, %s)", Name, i.Version, orUnknown(i.Commit), orUnknown(i.BuildDate), i.GoVersion)
+}
+
+// UserAgent is sent with every HTTP request of the storages, e.g. 'image-metadata-collector/1.2.3'
+func UserAgent() string {
+	return Name + "/" + Version
+}
+
+// Transport sets the User-Agent header on all requests which do not set one already
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &userAgentTransport{base: base}
+}
+
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Header.Get("User-Agent") != "" {
+		return t.base.RoundTrip(request)
+	}
+	// A RoundTripper must not modify the request
+	request = request.Clone(request.Context())
+	request.Header.Set("User-Agent", UserAgent())
+	return t.base.RoundTrip(request)
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,38 @@
+package version
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfoString(t *testing.T) {
+	info := Info{Version: "1.2.3", Commit: "abc", GoVersion: "go1.22"}
+	assert.Equal(t, "image-metadata-collector 1.2.3 (commit abc, built unknown, go1.22)", info.String())
+	assert.True(t, strings.HasPrefix(Get().GoVersion, "go"))
+}
+
+func TestTransport(t *testing.T) {
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+
+	res, err := client.Get(server.URL)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	res.Body.Close()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	request.Header.Set("User-Agent", "custom")
+	res, err = client.Do(request)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	res.Body.Close()
+
+	assert.Equal(t, []string{"image-metadata-collector/dev", "custom"}, userAgents)
+}
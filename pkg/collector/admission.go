@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/admission"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+
+	"github.com/rs/zerolog/log"
+)
+
+// mergeAdmitted adds the images recorded by the admission webhook which are not running in their namespace anymore,
+// so the images of pods which terminated since the last run are reported as well. Only the given namespaces are
+// merged unless namespaces is nil, deleted namespaces are left out.
+func (c *Collector) mergeAdmitted(ctx context.Context, opts Options, k8Images *[]kubeclient.Image, namespaces []string) (*[]kubeclient.Image, error) {
+	c.mu.Lock()
+	client, err := c.kubeClient()
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	admitted, err := admission.Load(ctx, client.Clientset, &opts.AdmissionConfig, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKube, err)
+	}
+
+	var names []string
+	for namespace := range admitted {
+		if namespaces == nil || slices.Contains(namespaces, namespace) {
+			names = append(names, namespace)
+		}
+	}
+	if len(names) == 0 {
+		return k8Images, nil
+	}
+	sort.Strings(names)
+	k8Namespaces, err := client.GetNamespacesByName(ctx, names)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not retrieve namespaces of admitted images: %w", ErrKube, err)
+	}
+
+	seen := map[string]bool{}
+	for _, image := range *k8Images {
+		seen[image.NamespaceName+"/"+image.Image] = true
+	}
+	merged := *k8Images
+	for _, namespace := range *k8Namespaces {
+		for _, record := range admitted[namespace.Name] {
+			if key := namespace.Name + "/" + record.Image; !seen[key] {
+				seen[key] = true
+				merged = append(merged, record.K8Image(namespace))
+			}
+		}
+	}
+	if added := len(merged) - len(*k8Images); added > 0 {
+		log.Info().Int("images", added).Msg("Added images of admitted pods which are not running anymore")
+	}
+	return &merged, nil
+}
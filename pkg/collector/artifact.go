@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WriteImages writes the images as JSON array, the intermediate artifact between collecting and publishing. It is
+// the same format as the default json report, so stored reports can be published again.
+func WriteImages(w io.Writer, images []Image) error {
+	if images == nil {
+		images = []Image{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(images)
+}
+
+// ReadImages reads images written by WriteImages
+func ReadImages(r io.Reader) ([]Image, error) {
+	var images []Image
+	if err := json.NewDecoder(r).Decode(&images); err != nil {
+		return nil, fmt.Errorf("%w: could not read images: %w", ErrConfig, err)
+	}
+	return images, nil
+}
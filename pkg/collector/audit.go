@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/collector"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage"
+
+	"github.com/rs/zerolog/log"
+)
+
+// auditStdout is used as audit file name to write to stdout
+const auditStdout = "-"
+
+// auditSkipped logs every skip decision and writes them to the audit file and object if configured
+func (c *Collector) auditSkipped(ctx context.Context, opts Options, decisions []SkipDecision) error {
+	for _, decision := range decisions {
+		log.Debug().
+			Str("namespace", decision.Namespace).
+			Str("image", decision.Image).
+			Str("reason", decision.Reason).
+			Str("detail", decision.Detail).
+			Msg("Skipped image")
+	}
+
+	cfg := opts.SkipAuditConfig
+	if cfg.SkipAuditFile == "" && cfg.SkipAuditObject == "" {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := collector.WriteSkipDecisions(&buf, decisions); err != nil {
+		return fmt.Errorf("%w: could not encode skip decisions: %w", ErrConfig, err)
+	}
+
+	switch cfg.SkipAuditFile {
+	case "":
+	case auditStdout:
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("%w: could not write skip decisions: %w", ErrStorage, err)
+		}
+	default:
+		if err := os.WriteFile(cfg.SkipAuditFile, buf.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("%w: could not write skip audit file: %w", ErrStorage, err)
+		}
+	}
+
+	if cfg.SkipAuditObject != "" {
+		_, err := c.storager.Store(ctx, storage.Report{
+			FileName:    cfg.SkipAuditObject,
+			Content:     buf.Bytes(),
+			ContentType: "application/x-ndjson",
+			Environment: opts.Defaults.Environment,
+			NamedByFile: true,
+			Metadata:    map[string]string{"skipped": strconv.Itoa(len(decisions))},
+		})
+		if err != nil {
+			return fmt.Errorf("%w: could not store skip audit object %s: %w", ErrStorage, cfg.SkipAuditObject, err)
+		}
+	}
+	return nil
+}
+
+// auditAnnotations logs the unknown scans and contact annotations of the pods and namespaces
+func auditAnnotations(k8Images []kubeclient.Image, annotationNames *AnnotationNames) []AnnotationWarning {
+	warnings := collector.LintAnnotations(k8Images, annotationNames)
+	for _, warning := range warnings {
+		log.Warn().
+			Str("namespace", warning.Namespace).
+			Str("annotation", warning.Annotation).
+			Str("suggestion", warning.Suggestion).
+			Msg("Unknown annotation")
+	}
+	return warnings
+}
@@ -0,0 +1,585 @@
+// Package collector exposes the image metadata collector as a library, so other tools can embed the collection
+// instead of running the binary.
+//
+//	report, err := collector.Run(ctx, collector.Options{
+//		Defaults:      collector.Image{Environment: "prod", Team: "platform"},
+//		StorageConfig: collector.StorageConfig{StorageFlag: "fs", FsConfig: fs.FsConfig{FsBaseDir: "reports"}},
+//	})
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/collector"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/admission"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/registry"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/kubernetes"
+)
+
+type (
+	Image             = collector.CollectorImage
+	AnnotationNames   = collector.AnnotationNames
+	RunConfig         = collector.RunConfig
+	OutputConfig      = collector.OutputConfig
+	SkipAuditConfig   = collector.SkipAuditConfig
+	SkipDecision      = collector.SkipDecision
+	RunError          = collector.RunError
+	PartialError      = collector.PartialError
+	PullWarning       = collector.PullWarning
+	AnnotationWarning = collector.AnnotationWarning
+	EnrichConfig      = collector.EnrichConfig
+	RiskConfig        = collector.RiskConfig
+	KubeConfig        = kubeclient.KubeConfig
+	AdmissionConfig   = admission.AdmissionConfig
+	StorageConfig     = storage.StorageConfig
+	Storager          = storage.Storager
+	StorageReport     = storage.Report
+	StoreResult       = storage.StoreResult
+)
+
+// The errors returned by the collector wrap one of these, so callers can distinguish failure classes with errors.Is
+var (
+	ErrConfig  = errors.New("invalid configuration")
+	ErrKube    = errors.New("kubernetes error")
+	ErrStorage = errors.New("storage error")
+)
+
+// DefaultAnnotationNames are the annotation name prefixes used by the collector binary
+var DefaultAnnotationNames = AnnotationNames{
+	Base:       "sdase.org/",
+	Scans:      "clusterscanner.sdase.org/",
+	Contact:    "contact.sdase.org/",
+	DefectDojo: "defectdojo.sdase.org/",
+}
+
+type Options struct {
+	// AnnotationNames default to DefaultAnnotationNames if none of the prefixes is set
+	AnnotationNames AnnotationNames
+	// Defaults are used for images without the corresponding labels or annotations, Defaults.Environment names the
+	// reports
+	Defaults     Image
+	RunConfig    RunConfig
+	OutputConfig OutputConfig
+
+	// EnrichConfig adds metadata from the registries to the images
+	EnrichConfig EnrichConfig
+	// ImageConfigResolver reads the image configs for the registry enrichment instead of the registries configured
+	// by EnrichConfig
+	ImageConfigResolver collector.ImageConfigResolver
+	// AttachmentResolver discovers the SBOMs and attestations for the SBOM enrichment instead of the registries
+	// configured by EnrichConfig
+	AttachmentResolver collector.AttachmentResolver
+	// HarborResolver reads the Harbor project metadata instead of the Harbor endpoints configured by EnrichConfig
+	HarborResolver collector.HarborResolver
+
+	// RiskConfig scores the images after the enrichment
+	RiskConfig RiskConfig
+
+	// SkipAuditConfig records why images were skipped in a file or storage object besides the debug log
+	SkipAuditConfig SkipAuditConfig
+
+	// KubeConfig is used to create a client unless a Clientset is given
+	KubeConfig KubeConfig
+	Clientset  kubernetes.Interface
+
+	// AdmissionConfig merges the images recorded by the admission webhook into the images of the cluster
+	AdmissionConfig AdmissionConfig
+
+	// StorageConfig creates the storage the reports are written to unless a Storager is given. Without a storage
+	// flag and Storager the images are only collected.
+	StorageConfig StorageConfig
+	Storager      Storager
+
+	// Observer is notified about the internals of the collection, e.g. to expose them as metrics. It is optional.
+	Observer Observer
+}
+
+// Observer records the kubernetes API calls, the collected and skipped images and the storage writes
+type Observer interface {
+	kubeclient.Observer
+	// ObserveImages is called with the number of collected images and the number of skipped images by reason
+	ObserveImages(images int, skipped map[string]int)
+	// ObserveStore is called for every report written to the storage
+	ObserveStore(duration time.Duration, size int, err error)
+}
+
+// Report is the result of a collection run
+type Report struct {
+	Images []Image
+	// Results contains one entry per stored report, several if the output is split
+	Results []StoreResult
+	// Warnings lists the images which currently can't be pulled
+	Warnings []PullWarning
+	// Errors lists the namespaces and enrichments which failed in a partial report
+	Errors []RunError
+	// AnnotationWarnings lists the unknown scans and contact annotations of the last collection
+	AnnotationWarnings []AnnotationWarning
+}
+
+// Collector collects images and stores reports, it can be run repeatedly
+type Collector struct {
+	storager     Storager
+	outputFormat *collector.OutputFormat
+
+	mu     sync.Mutex
+	opts   Options
+	client *kubeclient.Client
+
+	// pullSecrets holds the credentials of the imagePullSecrets of the last run
+	pullSecrets *registry.PullSecretKeychain
+	// annotationWarnings holds the unknown annotations of the last collection for the next published report
+	annotationWarnings []AnnotationWarning
+	// stored holds the images of the reports stored by this process by file name, so the first seen times don't
+	// have to be read back from the storage
+	stored map[string][]Image
+	// riskWeights are the weights of the risk factors, nil unless the images are scored
+	riskWeights map[string]int
+}
+
+// Run collects the images once with a new Collector
+func Run(ctx context.Context, opts Options) (Report, error) {
+	c, err := New(opts)
+	if err != nil {
+		return Report{}, err
+	}
+	return c.Run(ctx)
+}
+
+// New validates the options and creates the storage, the kubernetes client is created on the first collection
+func New(opts Options) (*Collector, error) {
+	if names := opts.AnnotationNames; names.Base == "" && names.Scans == "" && names.Contact == "" && names.DefectDojo == "" {
+		opts.AnnotationNames = DefaultAnnotationNames
+		opts.AnnotationNames.BackstageMapping = names.BackstageMapping
+	}
+
+	outputFormat, err := collector.NewOutputFormat(&opts.OutputConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid output configuration: %w", ErrConfig, err)
+	}
+
+	if opts.OutputConfig.SplitBy != "" {
+		if _, err = opts.StorageConfig.SplitReportFileName(opts.Defaults.Environment, opts.OutputConfig.SplitBy, "", outputFormat.Extension); err != nil {
+			return nil, fmt.Errorf("%w: invalid file name for split output: %w", ErrConfig, err)
+		}
+		if !storage.KeepsFileNames(opts.StorageConfig.StorageFlag) {
+			return nil, fmt.Errorf("%w: storage %s stores every report under the same name and can't split the output", ErrConfig, opts.StorageConfig.StorageFlag)
+		}
+	}
+
+	// The CI records are mapped from the report fields of the images
+	if opts.StorageConfig.StorageFlag == "servicenow" && (outputFormat.Extension != ".json" && outputFormat.Extension != ".ndjson" || opts.OutputConfig.OutputTemplateFile != "" || opts.OutputConfig.OutputCompat != "" || opts.OutputConfig.GroupBy != "") {
+		return nil, fmt.Errorf("%w: storage servicenow requires the json or ndjson output format without --output-template-file, --output-compat and --group-by", ErrConfig)
+	}
+
+	c := &Collector{storager: opts.Storager, outputFormat: outputFormat, opts: opts, stored: map[string][]Image{}}
+	if opts.Clientset != nil {
+		c.client = &kubeclient.Client{
+			Clientset:           opts.Clientset,
+			Observer:            opts.Observer,
+			ProgressInterval:    opts.KubeConfig.ProgressInterval,
+			NodeOperatingSystem: opts.KubeConfig.NodeOperatingSystem,
+			TerminatedLookback:  opts.KubeConfig.TerminatedLookback,
+			SuspendedCronJobs:   opts.KubeConfig.SuspendedCronJobs,
+			ServiceExposure:     opts.KubeConfig.ServiceExposure,
+		}
+	}
+
+	if c.storager == nil && opts.StorageConfig.StorageFlag != "" {
+		// The custom resource storage writes to the cluster the images are collected from
+		opts.StorageConfig.CrdKubeConfig = opts.KubeConfig
+		if c.storager, err = storage.NewStorage(&opts.StorageConfig); err != nil {
+			return nil, fmt.Errorf("%w: could not create storage %s: %w", ErrStorage, opts.StorageConfig.StorageFlag, err)
+		}
+	}
+
+	if err = c.newEnrichers(); err != nil {
+		return nil, err
+	}
+
+	if opts.RiskConfig.ScoreRisk {
+		if c.riskWeights, err = collector.ParseRiskWeights(opts.RiskConfig.RiskWeights); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+		}
+		// Without the SBOM enrichment no image has a signature, all of them would count as unsigned
+		if !opts.EnrichConfig.EnrichSbom && c.riskWeights[collector.RiskFactorUnsigned] > 0 {
+			log.Info().Msg("The unsigned risk factor requires --enrich-sbom, ignoring it")
+			c.riskWeights[collector.RiskFactorUnsigned] = 0
+		}
+	}
+
+	if opts.RunConfig.SkipSuspended && !opts.KubeConfig.SuspendedCronJobs {
+		return nil, fmt.Errorf("%w: skipping suspended CronJobs requires --suspended-cronjobs", ErrConfig)
+	}
+
+	if opts.SkipAuditConfig.SkipAuditObject != "" && c.storager == nil {
+		return nil, fmt.Errorf("%w: the skip audit object requires a storage", ErrConfig)
+	}
+
+	// The other storages don't keep objects apart by name, the audit would replace or be mistaken for the report
+	switch opts.StorageConfig.StorageFlag {
+	case "", "fs", "s3", "git", "oci":
+	default:
+		if opts.SkipAuditConfig.SkipAuditObject != "" {
+			return nil, fmt.Errorf("%w: the skip audit object is only supported by the fs, s3, git and oci storage", ErrConfig)
+		}
+	}
+
+	if opts.SkipAuditConfig.SkipAuditFile == auditStdout && opts.StorageConfig.StorageFlag == "stdout" {
+		return nil, fmt.Errorf("%w: the skip audit file can't be written to stdout together with the report", ErrConfig)
+	}
+
+	if opts.OutputConfig.TrackSeen || opts.OutputConfig.StaleRuns > 0 {
+		if err = c.checkReadable(opts); err != nil {
+			return nil, fmt.Errorf("%w: tracking the first seen time and stale images requires reading the previous report: %w", ErrConfig, err)
+		}
+	}
+
+	return c, nil
+}
+
+// Check verifies connectivity and credentials of the storage if it supports it
+func (c *Collector) Check(ctx context.Context) error {
+	if c.storager == nil {
+		return nil
+	}
+	if err := storage.Preflight(ctx, c.storager); err != nil {
+		return fmt.Errorf("%w: preflight check failed: %w", ErrStorage, err)
+	}
+	return nil
+}
+
+// Reconfigure replaces the annotation names, defaults and filters used by subsequent runs
+func (c *Collector) Reconfigure(annotationNames AnnotationNames, defaults Image, runConfig RunConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.opts.AnnotationNames = annotationNames
+	c.opts.Defaults = defaults
+	c.opts.RunConfig = runConfig
+}
+
+// Run retrieves the images from kubernetes, converts them and stores one report per group if a storage is configured
+func (c *Collector) Run(ctx context.Context) (Report, error) {
+	images, err := c.Collect(ctx)
+	var partial *PartialError
+	if errors.As(err, &partial) {
+		return c.Publish(ctx, images, partial.Errors...)
+	} else if err != nil {
+		return Report{}, err
+	}
+	return c.Publish(ctx, images)
+}
+
+// Collect retrieves the images from kubernetes and converts them with the configured defaults and filters. If
+// partial reports are allowed, failed namespaces and enrichments are returned as *PartialError with the images.
+func (c *Collector) Collect(ctx context.Context) ([]Image, error) {
+	return c.collect(ctx, nil)
+}
+
+// CollectNamespaces collects the images of the given namespaces only like Collect, e.g. to update the reports of
+// the namespaces whose pods changed
+func (c *Collector) CollectNamespaces(ctx context.Context, namespaces []string) ([]Image, error) {
+	if namespaces == nil {
+		namespaces = []string{}
+	}
+	return c.collect(ctx, namespaces)
+}
+
+// collect collects all namespaces if namespaces is nil
+func (c *Collector) collect(ctx context.Context, namespaces []string) ([]Image, error) {
+	c.mu.Lock()
+	opts := c.opts
+	c.mu.Unlock()
+
+	k8Images, runErrors, err := c.k8Images(ctx, namespaces, opts.OutputConfig.AllowPartial)
+	if err != nil {
+		return nil, err
+	}
+	if opts.AdmissionConfig.Enabled() && opts.KubeConfig.InputFile == "" {
+		if k8Images, err = c.mergeAdmitted(ctx, opts, k8Images, namespaces); err != nil {
+			return nil, err
+		}
+	}
+
+	// Convert & Clean k8 images to collector images
+	images, decisions, err := collector.ConvertImagesWithSkipDecisions(k8Images, &opts.Defaults, &opts.AnnotationNames, &opts.RunConfig)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Observer != nil {
+		opts.Observer.ObserveImages(len(*images), collector.CountSkipReasons(decisions))
+	}
+	if err = c.auditSkipped(ctx, opts, decisions); err != nil {
+		return nil, err
+	}
+	annotationWarnings := auditAnnotations(*k8Images, &opts.AnnotationNames)
+	c.mu.Lock()
+	c.annotationWarnings = annotationWarnings
+	c.mu.Unlock()
+
+	if opts.EnrichConfig.EnrichRegistry || opts.EnrichConfig.EnrichSbom || len(opts.EnrichConfig.HarborEndpoints) > 0 {
+		if err = c.loadPullSecrets(ctx, k8Images); err != nil {
+			return nil, err
+		}
+
+		var enrichers collector.Enrichers
+		if opts.EnrichConfig.EnrichRegistry {
+			enrichers.ImageConfig = opts.ImageConfigResolver
+		}
+		if opts.EnrichConfig.EnrichSbom {
+			enrichers.Attachments = opts.AttachmentResolver
+		}
+		if len(opts.EnrichConfig.HarborEndpoints) > 0 {
+			enrichers.Harbor = opts.HarborResolver
+		}
+		enrichErrors := collector.EnrichImages(ctx, *images, enrichers, opts.EnrichConfig.EnrichConcurrency)
+		runErrors = append(runErrors, enrichErrors...)
+	}
+	if c.riskWeights != nil {
+		collector.ScoreImages(*images, c.riskWeights)
+	}
+
+	if opts.OutputConfig.AllowPartial && len(runErrors) > 0 {
+		return *images, &PartialError{Errors: runErrors}
+	}
+	return *images, nil
+}
+
+// k8Images lists the images of the cluster or reads them from the input file, only of the given namespaces unless
+// namespaces is nil. Namespaces whose pods can't be listed are skipped and returned as errors if partial reports are
+// allowed.
+func (c *Collector) k8Images(ctx context.Context, namespaces []string, allowPartial bool) (*[]kubeclient.Image, []RunError, error) {
+	if c.opts.KubeConfig.InputFile != "" {
+		images, err := kubeclient.ReadImagesFromFile(c.opts.KubeConfig.InputFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: could not read images from %s: %w", ErrConfig, c.opts.KubeConfig.InputFile, err)
+		}
+		if namespaces != nil {
+			filtered := []kubeclient.Image{}
+			for _, image := range *images {
+				if slices.Contains(namespaces, image.NamespaceName) {
+					filtered = append(filtered, image)
+				}
+			}
+			images = &filtered
+		}
+		return images, nil, nil
+	}
+
+	var runErrors []RunError
+	c.mu.Lock()
+	client, err := c.kubeClient()
+	if err == nil {
+		client.OnNamespaceError = nil
+		if allowPartial {
+			client.OnNamespaceError = func(namespace string, err error) {
+				runErrors = append(runErrors, RunError{Stage: collector.RunErrorStageNamespace, Namespace: namespace, Error: err.Error()})
+			}
+		}
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var images *[]kubeclient.Image
+	if namespaces == nil {
+		images, err = client.GetAllImagesForAllNamespaces(ctx)
+	} else {
+		var k8Namespaces *[]kubeclient.Namespace
+		if k8Namespaces, err = client.GetNamespacesByName(ctx, namespaces); err == nil {
+			images, err = client.GetImages(ctx, k8Namespaces)
+		}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: could not retrieve images: %w", ErrKube, err)
+	}
+	return images, runErrors, nil
+}
+
+// Watch calls onChange with the namespace of every pod which is added, deleted or whose images changed until the
+// context is done, the pods existing at the start are reported once as well
+func (c *Collector) Watch(ctx context.Context, onChange func(namespace string)) error {
+	c.mu.Lock()
+	client, err := c.kubeClient()
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = client.WatchPods(ctx, onChange); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%w: %w", ErrKube, err)
+	}
+	return nil
+}
+
+// kubeClient returns the client, it is created on first use so publishing does not require cluster access
+func (c *Collector) kubeClient() (*kubeclient.Client, error) {
+	if c.client == nil {
+		client, err := kubeclient.NewClient(&c.opts.KubeConfig)
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not create kubernetes client: %w", ErrKube, err)
+		}
+		if c.opts.Observer != nil {
+			client.Observer = c.opts.Observer
+		}
+		c.client = client
+	}
+	return c.client, nil
+}
+
+// Publish stores the images, one report per group if the output is split. Without a storage only the images are
+// returned. The errors of a partial collection are added to the envelope of the report. Images left out by the
+// output filter are neither stored nor returned.
+func (c *Collector) Publish(ctx context.Context, images []Image, errs ...RunError) (Report, error) {
+	return c.publish(ctx, images, nil, errs)
+}
+
+// PublishNamespaces stores the images of CollectNamespaces like Publish, the output has to be split by namespace.
+// Namespaces without images, e.g. whose last pod or the namespace itself was deleted, get an empty report, so their
+// previous report doesn't keep listing the deleted workloads.
+func (c *Collector) PublishNamespaces(ctx context.Context, namespaces []string, images []Image, errs ...RunError) (Report, error) {
+	c.mu.Lock()
+	splitBy := c.opts.OutputConfig.SplitBy
+	c.mu.Unlock()
+	if splitBy != "namespace" {
+		return Report{}, fmt.Errorf("%w: publishing namespaces requires the output to be split by namespace", ErrConfig)
+	}
+	if namespaces == nil {
+		namespaces = []string{}
+	}
+	return c.publish(ctx, images, namespaces, errs)
+}
+
+// withEmptyGroups adds an empty group for every namespace without images, groups are kept sorted by key
+func withEmptyGroups(groups []collector.ImageGroup, namespaces []string) []collector.ImageGroup {
+	keys := map[string]bool{}
+	for _, group := range groups {
+		keys[group.Key] = true
+	}
+	added := false
+	for _, namespace := range namespaces {
+		if !keys[namespace] {
+			keys[namespace] = true
+			groups = append(groups, collector.ImageGroup{Key: namespace, Images: []Image{}})
+			added = true
+		}
+	}
+	if added {
+		sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	}
+	return groups
+}
+
+// publish stores the images, an empty report is stored for every namespace of namespaces without images
+func (c *Collector) publish(ctx context.Context, images []Image, namespaces []string, errs []RunError) (Report, error) {
+	c.mu.Lock()
+	opts := c.opts
+	annotationWarnings := c.annotationWarnings
+	c.mu.Unlock()
+
+	filtered, err := c.outputFormat.FilterImages(images)
+	if err != nil {
+		return Report{Images: images, Errors: errs}, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+	if excluded := len(images) - len(filtered); excluded > 0 {
+		log.Info().Int("images", excluded).Msg("Left images out of the report by the output filter")
+	}
+	images = filtered
+
+	report := Report{Images: images, Warnings: collector.PullWarnings(images), Errors: errs, AnnotationWarnings: annotationWarnings}
+	marshal := c.outputFormat.MarshalWithErrors(errs, annotationWarnings)
+	encode := c.outputFormat.EncodeWithErrors(errs, annotationWarnings)
+	if c.storager == nil {
+		return report, nil
+	}
+
+	groups, err := collector.SplitImages(images, opts.OutputConfig.SplitBy)
+	if err != nil {
+		return report, fmt.Errorf("%w: could not split collected images: %w", ErrConfig, err)
+	}
+	groups = withEmptyGroups(groups, namespaces)
+
+	// Published images may come from another process, the environment they were collected in names the report
+	environment := opts.Defaults.Environment
+	if environment == "" && len(images) > 0 {
+		environment = images[0].Environment
+	}
+
+	mergePrevious := opts.OutputConfig.TrackSeen || opts.OutputConfig.StaleRuns > 0
+	if mergePrevious {
+		fileNames := make([]string, len(groups))
+		for i, group := range groups {
+			if fileNames[i], err = c.reportFileName(opts, environment, group.Key); err != nil {
+				return report, err
+			}
+		}
+		if images, err = c.mergePrevious(ctx, opts, images, fileNames); err != nil {
+			return report, err
+		}
+		report.Images = images
+		if groups, err = collector.SplitImages(images, opts.OutputConfig.SplitBy); err != nil {
+			return report, fmt.Errorf("%w: could not split collected images: %w", ErrConfig, err)
+		}
+		groups = withEmptyGroups(groups, namespaces)
+	}
+
+	for _, group := range groups {
+		storageReport := storage.Report{
+			ContentType: c.outputFormat.ContentType,
+			Environment: environment,
+			NamedByFile: opts.OutputConfig.SplitBy != "",
+			Metadata:    map[string]string{},
+		}
+		if storageReport.FileName, err = c.reportFileName(opts, environment, group.Key); err != nil {
+			return report, err
+		}
+		if opts.OutputConfig.SplitBy != "" {
+			storageReport.Metadata[opts.OutputConfig.SplitBy] = group.Key
+		}
+
+		start := time.Now()
+		var result StoreResult
+		if encode != nil {
+			result, err = collector.StoreReportStream(ctx, &group.Images, c.storager, storageReport, encode)
+		} else {
+			result, err = collector.StoreReport(ctx, &group.Images, c.storager, storageReport, marshal)
+		}
+		if opts.Observer != nil {
+			opts.Observer.ObserveStore(time.Since(start), result.Size, err)
+		}
+		if err != nil {
+			return report, fmt.Errorf("%w: could not store collected images: %w", ErrStorage, err)
+		}
+		report.Results = append(report.Results, result)
+
+		if mergePrevious {
+			c.mu.Lock()
+			c.stored[storageReport.FileName] = group.Images
+			c.mu.Unlock()
+		}
+	}
+
+	return report, nil
+}
+
+// reportFileName names the report of a group of split images
+func (c *Collector) reportFileName(opts Options, environment, key string) (string, error) {
+	if opts.OutputConfig.SplitBy == "" {
+		return opts.StorageConfig.ReportFileName(environment, c.outputFormat.Extension), nil
+	}
+	fileName, err := opts.StorageConfig.SplitReportFileName(environment, opts.OutputConfig.SplitBy, key, c.outputFormat.Extension)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+	return fileName, nil
+}
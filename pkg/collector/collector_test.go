@@ -0,0 +1,364 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/admission"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage/fs"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+type mockStorager struct {
+	reports []storageReport
+	err     error
+}
+
+type storageReport struct {
+	fileName string
+	content  string
+}
+
+func (m *mockStorager) Store(ctx context.Context, report StorageReport) (StoreResult, error) {
+	if m.err != nil {
+		return StoreResult{}, m.err
+	}
+	m.reports = append(m.reports, storageReport{fileName: report.FileName, content: string(report.Content)})
+	return StoreResult{Location: report.FileName, Size: len(report.Content)}, nil
+}
+
+func newClientset() *testclient.Clientset {
+	return testclient.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-a"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-b", Annotations: map[string]string{"contact.sdase.org/team": "team-b"}}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-a", Namespace: "ns-a"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "mongo"}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-b", Namespace: "ns-b"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}},
+		},
+	)
+}
+
+func TestRun(t *testing.T) {
+	storager := &mockStorager{}
+
+	report, err := Run(context.Background(), Options{
+		Defaults:     Image{Environment: "prod", Team: "platform"},
+		OutputConfig: OutputConfig{OutputFormat: "csv", CsvColumns: []string{"namespace", "image", "team"}, SplitBy: "namespace"},
+		Clientset:    newClientset(),
+		Storager:     storager,
+	})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	assert.Len(t, report.Images, 2)
+	assert.Len(t, report.Results, 2)
+	assert.Equal(t, []storageReport{
+		{fileName: "prod-ns-a-output.csv", content: "namespace,image,team\nns-a,mongo,platform\n"},
+		{fileName: "prod-ns-b-output.csv", content: "namespace,image,team\nns-b,nginx,team-b\n"},
+	}, storager.reports)
+}
+
+func TestRunWithoutStorage(t *testing.T) {
+	c, err := New(Options{Clientset: newClientset()})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	report, err := c.Run(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Len(t, report.Images, 2)
+	assert.Empty(t, report.Results)
+
+	c.Reconfigure(DefaultAnnotationNames, Image{Team: "reconfigured"}, RunConfig{ImageFilter: []string{"mongo"}})
+	report, err = c.Run(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	for _, image := range report.Images {
+		assert.Equal(t, image.Image == "mongo", image.Skip)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	_, err := New(Options{OutputConfig: OutputConfig{OutputFormat: "unknown"}, Clientset: newClientset()})
+	assert.ErrorIs(t, err, ErrConfig)
+
+	_, err = New(Options{OutputConfig: OutputConfig{OutputFormat: "csv"}, StorageConfig: StorageConfig{StorageFlag: "servicenow"}, Clientset: newClientset()})
+	assert.ErrorIs(t, err, ErrConfig, "the CI records are mapped from json")
+
+	_, err = Run(context.Background(), Options{Clientset: newClientset(), Storager: &mockStorager{err: errors.New("failed")}})
+	assert.ErrorIs(t, err, ErrStorage)
+}
+
+func TestCollectAndPublish(t *testing.T) {
+	collector, err := New(Options{Defaults: Image{Environment: "prod"}, Clientset: newClientset()})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	images, err := collector.Collect(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	var artifact bytes.Buffer
+	assert.NoError(t, WriteImages(&artifact, images))
+
+	read, err := ReadImages(&artifact)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, images, read)
+
+	// The publisher has no cluster access, the environment is taken from the images
+	storager := &mockStorager{}
+	publisher, err := New(Options{OutputConfig: OutputConfig{OutputFormat: "csv", CsvColumns: []string{"namespace", "image"}}, Storager: storager})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	report, err := publisher.Publish(context.Background(), read)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Len(t, report.Results, 1)
+	assert.Equal(t, []storageReport{{fileName: "prod-output.csv", content: "namespace,image\nns-a,mongo\nns-b,nginx\n"}}, storager.reports)
+
+	_, err = ReadImages(strings.NewReader("{"))
+	assert.ErrorIs(t, err, ErrConfig)
+}
+
+func TestCollectFromInputFile(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "images.json")
+	err := os.WriteFile(inputFile, []byte(`[{"image":"nginx","namespaceName":"ns","annotations":{"contact.sdase.org/team":"team-a"}}]`), 0644)
+	assert.NoError(t, err)
+
+	// Without a Clientset a kubernetes client would be created, the input file skips it
+	c, err := New(Options{KubeConfig: KubeConfig{InputFile: inputFile}})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	images, err := c.Collect(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Len(t, images, 1)
+	assert.Equal(t, "team-a", images[0].Team)
+
+	c, err = New(Options{KubeConfig: KubeConfig{InputFile: filepath.Join(t.TempDir(), "missing.json")}})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	_, err = c.Collect(context.Background())
+	assert.ErrorIs(t, err, ErrConfig)
+}
+
+func TestSkipAudit(t *testing.T) {
+	_, err := New(Options{Clientset: newClientset(), SkipAuditConfig: SkipAuditConfig{SkipAuditObject: "skipped.ndjson"}})
+	assert.ErrorIs(t, err, ErrConfig)
+
+	_, err = New(Options{Clientset: newClientset(), StorageConfig: StorageConfig{StorageFlag: "api"}, SkipAuditConfig: SkipAuditConfig{SkipAuditObject: "skipped.ndjson"}})
+	assert.ErrorIs(t, err, ErrConfig, "Expected the skip audit object to be rejected for the api storage")
+
+	_, err = New(Options{Clientset: newClientset(), StorageConfig: StorageConfig{StorageFlag: "stdout"}, SkipAuditConfig: SkipAuditConfig{SkipAuditFile: "-"}})
+	assert.ErrorIs(t, err, ErrConfig, "Expected the skip audit file to be rejected on stdout next to the report")
+
+	storager := &mockStorager{}
+	auditFile := filepath.Join(t.TempDir(), "skipped.ndjson")
+	c, err := New(Options{
+		Defaults:        Image{Environment: "prod"},
+		RunConfig:       RunConfig{ImageFilter: []string{"^mon"}},
+		Clientset:       newClientset(),
+		Storager:        storager,
+		SkipAuditConfig: SkipAuditConfig{SkipAuditFile: auditFile, SkipAuditObject: "skipped.ndjson"},
+	})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	_, err = c.Collect(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	expected := `{"namespace":"ns-a","image":"mongo","image_id":"mongo","reason":"image-filter","detail":"^mon"}` + "\n"
+	content, err := os.ReadFile(auditFile)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, expected, string(content))
+	assert.Equal(t, []storageReport{{fileName: "skipped.ndjson", content: expected}}, storager.reports)
+}
+
+func TestCollectAdmitted(t *testing.T) {
+	admitted, err := json.Marshal([]admission.Record{
+		{Image: "mongo", Pod: "pod-a", Admitted: time.Now()},
+		{Image: "migrate", Pod: "job-a", Annotations: map[string]string{"contact.sdase.org/team": "team-a"}, Admitted: time.Now()},
+		{Image: "expired", Pod: "job-b", Admitted: time.Now().Add(-48 * time.Hour)},
+	})
+	assert.NoError(t, err)
+	clientset := newClientset()
+	_, err = clientset.CoreV1().ConfigMaps("scanner").Create(context.Background(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "admitted-images", Namespace: "scanner"},
+		Data:       map[string]string{"ns-a": string(admitted), "deleted": `[{"image":"gone","admitted":"2100-01-01T00:00:00Z"}]`},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	c, err := New(Options{Clientset: clientset, AdmissionConfig: AdmissionConfig{AdmissionConfigMap: "admitted-images", AdmissionNamespace: "scanner"}})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	// The running mongo image is reported once, images of deleted namespaces and expired images are left out
+	images, err := c.Collect(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Len(t, images, 3)
+	assert.Equal(t, "migrate", images[2].Image)
+	assert.Equal(t, "ns-a", images[2].Namespace)
+	assert.Equal(t, "team-a", images[2].Team)
+
+	images, err = c.CollectNamespaces(context.Background(), []string{"ns-b"})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Len(t, images, 1)
+}
+
+func TestPreviousImages(t *testing.T) {
+	c, err := New(Options{
+		Defaults:      Image{Environment: "prod"},
+		OutputConfig:  OutputConfig{OutputFormat: "json"},
+		Clientset:     newClientset(),
+		StorageConfig: StorageConfig{StorageFlag: "fs", FsConfig: fs.FsConfig{FsBaseDir: t.TempDir()}, CompressOutput: true},
+	})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	previous, err := c.PreviousImages(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Nil(t, previous, "Expected no images before the first report")
+
+	report, err := c.Run(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	previous, err = c.PreviousImages(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, report.Images, previous)
+
+	c, err = New(Options{OutputConfig: OutputConfig{OutputFormat: "csv"}, Storager: &mockStorager{}})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	_, err = c.PreviousImages(context.Background())
+	assert.ErrorIs(t, err, ErrConfig)
+
+	c, err = New(Options{Storager: &mockStorager{}})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	_, err = c.PreviousImages(context.Background())
+	assert.ErrorIs(t, err, ErrConfig, "Expected an error for a storage which can't read reports")
+}
+
+func TestTrackSeen(t *testing.T) {
+	opts := Options{
+		Defaults:      Image{Environment: "prod"},
+		OutputConfig:  OutputConfig{OutputFormat: "json", TrackSeen: true},
+		Clientset:     newClientset(),
+		StorageConfig: StorageConfig{StorageFlag: "fs", FsConfig: fs.FsConfig{FsBaseDir: t.TempDir()}},
+	}
+	c, err := New(opts)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	report, err := c.Run(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.NotEmpty(t, report.Images)
+	for _, image := range report.Images {
+		assert.NotEmpty(t, image.FirstSeen)
+		assert.Equal(t, image.FirstSeen, image.LastSeen, "Expected the images to be seen for the first time")
+	}
+
+	// A report of an earlier process is read back from the storage
+	stored := report.Images
+	for i := range stored {
+		stored[i].FirstSeen = "2024-01-01T00:00:00Z"
+	}
+	content, err := json.Marshal(stored)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.NoError(t, os.WriteFile(report.Results[0].Location, content, 0o644))
+
+	c, err = New(opts)
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	report, err = c.Run(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	for _, image := range report.Images {
+		assert.Equal(t, "2024-01-01T00:00:00Z", image.FirstSeen)
+		assert.NotEqual(t, image.FirstSeen, image.LastSeen)
+	}
+	previous, err := c.PreviousImages(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, report.Images, previous)
+
+	// The next run of the same process keeps the times in memory
+	assert.NoError(t, os.Remove(report.Results[0].Location))
+	report, err = c.Run(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	for _, image := range report.Images {
+		assert.Equal(t, "2024-01-01T00:00:00Z", image.FirstSeen)
+	}
+
+	_, err = New(Options{OutputConfig: OutputConfig{OutputFormat: "csv", TrackSeen: true}, Storager: &mockStorager{}})
+	assert.ErrorIs(t, err, ErrConfig)
+	_, err = New(Options{OutputConfig: OutputConfig{OutputFormat: "json", TrackSeen: true}})
+	assert.ErrorIs(t, err, ErrConfig, "Expected an error without storage")
+}
+
+func TestStaleRuns(t *testing.T) {
+	c, err := New(Options{
+		OutputConfig:  OutputConfig{OutputFormat: "json", StaleRuns: 2, SplitBy: "namespace"},
+		StorageConfig: StorageConfig{StorageFlag: "fs", FileName: "{namespace}.json", FsConfig: fs.FsConfig{FsBaseDir: t.TempDir()}},
+	})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	ctx := context.Background()
+	_, err = c.Publish(ctx, []Image{{Namespace: "ns", Image: "app:1.0"}, {Namespace: "ns", Image: "cron:1.0"}})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	for missedRuns := 1; missedRuns <= 2; missedRuns++ {
+		report, err := c.Publish(ctx, []Image{{Namespace: "ns", Image: "app:1.0"}})
+		assert.NoError(t, err, "Expected no error, got %v", err)
+		assert.Equal(t, []Image{{Namespace: "ns", Image: "app:1.0"}, {Namespace: "ns", Image: "cron:1.0", Stale: true, MissedRuns: missedRuns}}, report.Images)
+	}
+
+	report, err := c.Publish(ctx, []Image{{Namespace: "ns", Image: "app:1.0"}})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Equal(t, []Image{{Namespace: "ns", Image: "app:1.0"}}, report.Images, "Expected the image to be dropped after the grace period")
+}
+
+func TestSkipSuspendedRequiresCronJobs(t *testing.T) {
+	_, err := New(Options{Clientset: newClientset(), RunConfig: RunConfig{SkipSuspended: true}})
+	assert.ErrorIs(t, err, ErrConfig)
+
+	_, err = New(Options{Clientset: newClientset(), RunConfig: RunConfig{SkipSuspended: true}, KubeConfig: KubeConfig{SuspendedCronJobs: true}})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+}
+
+func TestSplitRequiresFileNames(t *testing.T) {
+	_, err := New(Options{Clientset: newClientset(), OutputConfig: OutputConfig{SplitBy: "namespace"}, StorageConfig: StorageConfig{StorageFlag: "api"}})
+	assert.ErrorIs(t, err, ErrConfig)
+
+	_, err = New(Options{Clientset: newClientset(), OutputConfig: OutputConfig{SplitBy: "namespace"}, StorageConfig: StorageConfig{StorageFlag: "fs", FsConfig: fs.FsConfig{FsBaseDir: t.TempDir()}}})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+}
+
+func TestScoreRisk(t *testing.T) {
+	c, err := New(Options{Clientset: newClientset(), RiskConfig: RiskConfig{ScoreRisk: true, RiskWeights: []string{"root=0"}}})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+
+	images, err := c.Collect(context.Background())
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Len(t, images, 2)
+	for _, image := range images {
+		// Without the SBOM enrichment only the mutable tag applies, a single replica rounds to no contribution
+		assert.Equal(t, []string{"mutable-tag"}, image.RiskFactors)
+		assert.Equal(t, 10, image.RiskScore)
+	}
+
+	_, err = New(Options{Clientset: newClientset(), RiskConfig: RiskConfig{ScoreRisk: true, RiskWeights: []string{"unknown=1"}}})
+	assert.ErrorIs(t, err, ErrConfig)
+}
+
+func TestOutputFilter(t *testing.T) {
+	storager := &mockStorager{}
+
+	report, err := Run(context.Background(), Options{
+		Defaults:     Image{Environment: "prod"},
+		OutputConfig: OutputConfig{OutputFilter: "namespace == 'ns-a'"},
+		Clientset:    newClientset(),
+		Storager:     storager,
+	})
+	assert.NoError(t, err, "Expected no error, got %v", err)
+	assert.Len(t, report.Images, 1)
+	assert.Equal(t, "ns-a", report.Images[0].Namespace)
+	assert.Len(t, storager.reports, 1)
+	assert.NotContains(t, storager.reports[0].content, "ns-b")
+}
@@ -0,0 +1,96 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/collector"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/storage"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PreviousImages reads the images of the last published report back from the storage, so a run can be compared with
+// the previous one without a local state file. Without a stored report, e.g. on the first run, no images are
+// returned. Only unsplit json and ndjson reports of the s3, git and fs storage can be read.
+func (c *Collector) PreviousImages(ctx context.Context) ([]Image, error) {
+	c.mu.Lock()
+	opts := c.opts
+	c.mu.Unlock()
+
+	if err := c.checkReadable(opts); err != nil {
+		return nil, err
+	}
+	if opts.OutputConfig.SplitBy != "" {
+		return nil, fmt.Errorf("%w: the previous report can't be read with --split-by", ErrConfig)
+	}
+	return c.readReport(ctx, opts, opts.StorageConfig.ReportFileName(opts.Defaults.Environment, c.outputFormat.Extension))
+}
+
+// checkReadable verifies the reports can be read back from the storage
+func (c *Collector) checkReadable(opts Options) error {
+	if c.storager == nil {
+		return fmt.Errorf("%w: reading the previous report requires a storage", ErrConfig)
+	}
+	if c.outputFormat.Extension != ".json" && c.outputFormat.Extension != ".ndjson" || opts.OutputConfig.OutputCompat != "" || opts.OutputConfig.GroupBy != "" {
+		return fmt.Errorf("%w: the previous report can only be read with the json or ndjson output format without --output-compat and --group-by", ErrConfig)
+	}
+	if _, ok := c.storager.(storage.Reader); !ok {
+		return fmt.Errorf("%w: the previous report can't be read from storage %s, only from s3, git and fs without encryption", ErrConfig, opts.StorageConfig.StorageFlag)
+	}
+	return nil
+}
+
+// readReport returns the images of the stored report, nil if it was not stored yet
+func (c *Collector) readReport(ctx context.Context, opts Options, fileName string) ([]Image, error) {
+	content, err := storage.Read(ctx, c.storager, fileName)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		log.Info().Str("fileName", fileName).Msg("No previous report stored yet")
+		return nil, nil
+	case errors.Is(err, storage.ErrReadUnsupported):
+		return nil, fmt.Errorf("%w: the previous report can't be read from storage %s, only from s3, git and fs without encryption", ErrConfig, opts.StorageConfig.StorageFlag)
+	case err != nil:
+		return nil, fmt.Errorf("%w: could not read previous report %s: %w", ErrStorage, fileName, err)
+	}
+
+	images, err := collector.UnmarshalImages(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid previous report %s: %w", ErrStorage, fileName, err)
+	}
+	log.Debug().Str("fileName", fileName).Int("images", len(images)).Msg("Read previous report")
+	return images, nil
+}
+
+// mergePrevious adds the first and last seen times to the images and keeps the stale images of the previous reports.
+// The previous images are kept in memory after a report was stored, so they are only read from the storage on the
+// first run of a process. A report of a group without any collected image is not read and not updated.
+func (c *Collector) mergePrevious(ctx context.Context, opts Options, images []Image, fileNames []string) ([]Image, error) {
+	var previous []Image
+	for _, fileName := range fileNames {
+		c.mu.Lock()
+		stored, ok := c.stored[fileName]
+		c.mu.Unlock()
+		if !ok {
+			var err error
+			if stored, err = c.readReport(ctx, opts, fileName); err != nil {
+				return nil, err
+			}
+		}
+		previous = append(previous, stored...)
+	}
+
+	if opts.OutputConfig.TrackSeen {
+		images = collector.TrackSeen(images, previous, time.Now())
+	}
+	if opts.OutputConfig.StaleRuns > 0 {
+		merged := collector.KeepStale(images, previous, opts.OutputConfig.StaleRuns)
+		if stale := len(merged) - len(images); stale > 0 {
+			log.Info().Int("images", stale).Msg("Kept stale images of the previous report")
+		}
+		images = merged
+	}
+	return images, nil
+}
@@ -0,0 +1,125 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/harbor"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/kubeclient"
+	"github.com/SDA-SE/image-metadata-collector/internal/pkg/registry"
+	"github.com/SDA-SE/image-metadata-collector/internal/version"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// newEnrichers creates the resolvers of the enabled enrichments which were not given in the options
+func (c *Collector) newEnrichers() error {
+	cfg := c.opts.EnrichConfig
+	needsConfigResolver := cfg.EnrichRegistry && c.opts.ImageConfigResolver == nil
+	needsAttachmentResolver := cfg.EnrichSbom && c.opts.AttachmentResolver == nil
+	needsHarborResolver := len(cfg.HarborEndpoints) > 0 && c.opts.HarborResolver == nil
+	if !needsConfigResolver && !needsAttachmentResolver && !needsHarborResolver {
+		return nil
+	}
+
+	httpClient := &http.Client{Transport: version.Transport(nil), Timeout: time.Minute}
+	keychain, err := c.newKeychain(httpClient)
+	if err != nil {
+		return err
+	}
+
+	// The limits only apply to the registries and Harbor, not to the token endpoints of the cloud credential helpers
+	rateLimits, err := registry.ParseRateLimits(cfg.RegistryRateLimits)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+	limits := registry.HostLimits{Concurrency: cfg.RegistryHostConcurrency, RateLimits: rateLimits}
+	httpClient = &http.Client{Transport: version.Transport(registry.LimitTransport(nil, limits)), Timeout: time.Minute}
+
+	resolver := registry.NewResolver(httpClient, keychain, cfg.RegistryPlainHttp)
+	if needsConfigResolver {
+		c.opts.ImageConfigResolver = resolver
+	}
+	if needsAttachmentResolver {
+		c.opts.AttachmentResolver = resolver
+	}
+	if needsHarborResolver {
+		if c.opts.HarborResolver, err = harbor.NewClient(httpClient, keychain, cfg.HarborEndpoints); err != nil {
+			return fmt.Errorf("%w: %w", ErrConfig, err)
+		}
+	}
+	return nil
+}
+
+// newKeychain combines the configured registry credentials, the docker config file takes precedence over the
+// imagePullSecrets and those over the cloud credential helpers
+func (c *Collector) newKeychain(httpClient *http.Client) (registry.Keychain, error) {
+	cfg := c.opts.EnrichConfig
+	var keychain registry.MultiKeychain
+
+	if cfg.RegistryConfigFile != "" {
+		dockerConfig, err := registry.NewDockerConfigKeychain(cfg.RegistryConfigFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not read registry config: %w", ErrConfig, err)
+		}
+		keychain = append(keychain, dockerConfig)
+	}
+
+	if cfg.RegistryPullSecrets {
+		c.pullSecrets = &registry.PullSecretKeychain{}
+		keychain = append(keychain, c.pullSecrets)
+	}
+
+	for _, name := range cfg.RegistryCredentialHelpers {
+		helper, err := registry.NewCredentialHelper(name, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+		}
+		keychain = append(keychain, helper)
+	}
+
+	return keychain, nil
+}
+
+// loadPullSecrets reads the imagePullSecrets of the images into the pull secret keychain, every image is resolved with
+// the secrets of its own pods
+func (c *Collector) loadPullSecrets(ctx context.Context, k8Images *[]kubeclient.Image) error {
+	if c.pullSecrets == nil {
+		return nil
+	}
+	if c.opts.KubeConfig.InputFile != "" {
+		log.Warn().Msg("The imagePullSecrets are not read when the images are read from an input file")
+		return nil
+	}
+
+	c.mu.Lock()
+	client, err := c.kubeClient()
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	pullSecrets, err := client.GetPullSecrets(ctx, k8Images)
+	if err != nil {
+		return fmt.Errorf("%w: could not retrieve imagePullSecrets: %w", ErrKube, err)
+	}
+
+	secrets := map[string]*registry.DockerConfigKeychain{}
+	for _, secret := range pullSecrets {
+		parse := registry.ParseDockerConfig
+		if secret.Type == corev1.SecretTypeDockercfg {
+			parse = registry.ParseDockercfg
+		}
+		secretKeychain, err := parse(secret.Data)
+		if err != nil {
+			log.Warn().Err(err).Str("namespace", secret.Namespace).Str("secret", secret.Name).Msg("Skipping invalid imagePullSecret")
+			continue
+		}
+		secrets[secret.Namespace+"/"+secret.Name] = secretKeychain
+	}
+	c.pullSecrets.Set(secrets)
+	return nil
+}